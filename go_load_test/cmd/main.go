@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"github.com/mancej/fileserver-challenge/go_load_test/load_test"
 	log "github.com/sirupsen/logrus"
@@ -18,24 +20,219 @@ import (
 // 1 Result aggregator that reads results and publishes them.
 
 func main() {
-	start := time.Now()
-	load_test.InitClear()
-	log.SetFormatter(&log.TextFormatter{
-		DisableColors: true,
-		FullTimestamp: true,
-	})
-	file, err := os.OpenFile("/tmp/load_test.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		panic("Cannot create log file. Does your system have permissions to create a file at /tmp/?")
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		os.Exit(runCompare(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "agent" {
+		os.Exit(runAgent(os.Args[2:]))
 	}
 
-	log.SetOutput(file)
-	log.SetLevel(log.InfoLevel)
+	outputFormat := flag.String("output", load_test.GetEnv("OUTPUT_FORMAT", "table"), "Final summary format: table or json")
+	displayMode := flag.String("display", load_test.GetEnv("DISPLAY_MODE", "dashboard"), "Live output: dashboard or table")
+	quietDefault, _ := strconv.ParseBool(load_test.GetEnv("QUIET", "false"))
+	quiet := flag.Bool("quiet", quietDefault, "Don't render live output, only print the final summary")
+	noColor := flag.Bool("no-color", load_test.GetEnv("NO_COLOR", "") != "", "Disable ANSI colors")
+	perWorkerDefault, _ := strconv.ParseBool(load_test.GetEnv("PER_WORKER", "false"))
+	perWorker := flag.Bool("per-worker", perWorkerDefault, "Also print requests + errors per worker")
+	resumeDefault, _ := strconv.ParseBool(load_test.GetEnv("RESUME", "false"))
+	resume := flag.Bool("resume", resumeDefault, "Resume cumulative results from CHECKPOINT_PATH, if a checkpoint exists")
+	profileSpec := flag.String("profile", load_test.GetEnv("LOAD_PROFILE", ""), "Load profile, I.E \"ramp from=10 to=500 over=5m\"")
+	planPath := flag.String("plan", load_test.GetEnv("TEST_PLAN", ""), "YAML file of sequential stages to run instead of a load profile, see load_test.TestPlan")
+	virtualUsersDefault, _ := strconv.Atoi(load_test.GetEnv("VIRTUAL_USERS", "0"))
+	virtualUsers := flag.Int("vus", virtualUsersDefault, "Run closed loop with this many virtual users instead of a request rate")
+	arrivalsSpec := flag.String("arrivals", load_test.GetEnv("ARRIVALS", ""), "Distribution of gaps between open loop tests' starts: fixed, uniform, exponential or \"lognormal cv=2\"")
+	thinkTimeSpec := flag.String("think-time", load_test.GetEnv("THINK_TIME", ""), "Pause between each of a virtual user's tests, I.E 250ms, \"uniform min=50ms max=500ms\" or \"lognormal mean=200ms stddev=300ms\"")
+	autoscaleSpec := flag.String("autoscale", load_test.GetEnv("AUTOSCALE", ""), "With --vus, scale virtual users to keep p99 near a target, I.E \"p99=250ms min=1 max=500 window=10s\"")
+	steadyStateSpec := flag.String("steady-state", load_test.GetEnv("STEADY_STATE", ""), "Stop once throughput + p99 settle, reporting only the steady windows, I.E \"tolerance=5% windows=5 window=10s max=30m\"")
+	pacingDefault, _ := time.ParseDuration(load_test.GetEnv("PACING", "0s"))
+	pacing := flag.Duration("pacing", pacingDefault, "Start each virtual user's tests at most this often, I.E 1s")
+	jitterSpec := flag.String("jitter", load_test.GetEnv("SCHEDULE_JITTER", ""), "Randomly offset each test's start within its pacing slot by up to this fraction of it, I.E 0.5 or 50%")
+	chaosSpec := flag.String("chaos", load_test.GetEnv("CHAOS", ""), "Inject client faults, I.E \"drop=5% truncate=2% abort=1% after=1m for=5m\"")
+	consistencyPoolSpec := flag.String("consistency-pool", load_test.GetEnv("CONSISTENCY_POOL", ""), "Run consistency checks from their own rate limited pool of workers, I.E \"workers=4 rate=2 queue=100\"")
+	consistencyRateDefault, _ := strconv.ParseFloat(load_test.GetEnv("CONSISTENCY_RATE", "0"), 64)
+	consistencyRate := flag.Float64("consistency-rate", consistencyRateDefault, "Start this many consistency checks per second regardless of load, I.E 1. Checks are picked at random with the rest of the load if 0")
+	seedDefault, _ := strconv.ParseInt(load_test.GetEnv("SEED", "0"), 10, 64)
+	seed := flag.Int64("seed", seedDefault, "Pick operations, keys + payloads from this seed, so runs with the same seed issue the same operations. Unseeded if 0")
+	durationDefault, _ := time.ParseDuration(load_test.GetEnv("DURATION", "0s"))
+	duration := flag.Duration("duration", durationDefault, "Stop after this long, I.E 10m. Runs until Ctrl+C if 0")
+	scenarioPath := flag.String("scenarios", load_test.GetEnv("SCENARIO_FILE", ""), "YAML file of multi step scenarios to run, see load_test.ScenarioFile")
+	requestLimitSpec := flag.String("requests", load_test.GetEnv("REQUEST_LIMIT", ""), "Stop after N requests, I.E 1000, or N per operation, I.E GET=500,PUT=100")
+	clientBackoffDefault, _ := strconv.ParseBool(load_test.GetEnv("CLIENT_BACKOFF", "false"))
+	clientBackoff := flag.Bool("backoff", clientBackoffDefault, "Back off per Retry-After on 429s like a well behaved client, counting them as self throttled rather than errors")
+	retrySpec := flag.String("retry", load_test.GetEnv("RETRY", ""), "Retry failed GETs + DELETEs with exponential backoff, I.E \"attempts=3 backoff=100ms max=5s size=1000\"")
+	extraOpsSpec := flag.String("ops", load_test.GetEnv("EXTRA_OPS", ""), "Run extra operations on existing files alongside the mix, each taking a share of tests, I.E HEAD=5%,RANGE=10%,CONDITIONAL=10%,STREAM=1%,LIST=1%,MOVE=1%,COPY=1%,FUZZ=1%,BOUNDARY=1%,DOWNLOAD=1%")
+	streamSizeDefault, _ := strconv.ParseInt(load_test.GetEnv("STREAM_SIZE", strconv.FormatInt(load_test.DefaultStreamSize, 10)), 10, 64)
+	streamSize := flag.Int64("stream-size", streamSizeDefault, "Size in bytes of STREAM uploads, generated as they're sent rather than held in memory")
+	downloadSizeDefault, _ := strconv.ParseInt(load_test.GetEnv("DOWNLOAD_SIZE", strconv.FormatInt(load_test.DefaultDownloadSize, 10)), 10, 64)
+	downloadSize := flag.Int64("download-size", downloadSizeDefault, "Size in bytes of DOWNLOAD files, uploaded + downloaded as a stream + verified by a SHA-256 of the body rather than held in memory")
+	listAPISpec := flag.String("list-api", load_test.GetEnv("LIST_API", ""), "The server's paginated listing endpoint for LIST extra ops, I.E \"path=api/fileserver/ items=files next=next page=100 pages=10\"")
+	listCheckSpec := flag.String("list-check", load_test.GetEnv("LIST_CHECK", ""), "Have consistency checks verify created files are listed + deleted ones unlisted within a bound, I.E \"path=api/fileserver/ bound=1s max=10s poll=100ms\"")
+	verifyChecksumsDefault, _ := strconv.ParseBool(load_test.GetEnv("VERIFY_CHECKSUMS", "false"))
+	deleteRecheckDefault, _ := time.ParseDuration(load_test.GetEnv("DELETE_RECHECK_DELAY", "0s"))
+	deleteRecheck := flag.Duration("delete-recheck", deleteRecheckDefault, "Have consistency checks GET the deleted file again this long after the DELETE, expecting a 404, I.E 2s")
+	verifyChecksums := flag.Bool("verify-checksums", verifyChecksumsDefault, "Check every GET against the SHA-256 of the last upload, reporting mismatches as corrupt downloads")
+	concurrencySpec := flag.String("concurrency", load_test.GetEnv("CONCURRENCY_LIMITS", ""), "Max tests in flight per operation, I.E PUT=4,GET=200")
+	timeoutsSpec := flag.String("timeouts", load_test.GetEnv("OPERATION_TIMEOUTS", ""), "Timeout per operation, I.E GET=5s,PUT=3m,DELETE=5s,CONSISTENCY=1m. Unlisted GET/PUT/DELETE time out after 20s")
+	agentsDefault, _ := strconv.Atoi(load_test.GetEnv("AGENTS", "0"))
+	agents := flag.Int("agents", agentsDefault, "Coordinate this many agents instead of generating load locally, see main agent")
+	coordinatorAddr := flag.String("listen", load_test.GetEnv("COORDINATOR_LISTEN", ":7070"), "Address agents connect to, with --agents")
+	controlAddr := flag.String("control", load_test.GetEnv("CONTROL_ADDR", ""), "Serve the control API (pause/resume, live rate, workers + mix) on this address, I.E localhost:7071")
+	soakEveryDefault, _ := time.ParseDuration(load_test.GetEnv("SOAK_REPORT_EVERY", "0s"))
+	soakEvery := flag.Duration("soak", soakEveryDefault, "Soak mode: write a timestamped summary to SOAK_DIR this often, I.E 15m")
+	sizesSpec := flag.String("sizes", load_test.GetEnv("FILE_SIZES", ""), "Draw CREATE + PUT payload sizes from a distribution instead of up to MAX_FILE_SIZE, I.E 4KB, \"uniform min=1KB max=1MB\", \"lognormal mean=64KB stddev=256KB\" or \"histogram 4KB=80% 1MB=19% 500MB=1%\"")
+	compressibleSpec := flag.String("compressible", load_test.GetEnv("COMPRESSIBLE", ""), "Share of payloads that are compressible text rather than random bytes, I.E 0.3 or 30%, to see if the server's compression or dedup changes its performance")
+	emptyFilesSpec := flag.String("empty-files", load_test.GetEnv("EMPTY_FILES", ""), "Share of CREATEs, PUTs + consistency checks that upload an empty file, I.E 0.05 or 5%. GETs of empty files must return Content-Length: 0")
+	boundarySpec := flag.String("boundary", load_test.GetEnv("BOUNDARY_SIZES", ""), "The server's buffer + max file sizes BOUNDARY tests upload files at + either side of, I.E \"buffer=64KB max=100MB\". Without it they only upload 0 + 1 byte files")
+	pathKeysSpec := flag.String("path-keys", load_test.GetEnv("PATH_KEYS", ""), "Nest new files' keys under directories instead of naming them flat, I.E \"depth=10 fanout=1000 min=1\". Keys are sent with literal /s, so the server must route hierarchical paths, the bundled server only serves single segment file names")
+	zipfDefault, _ := strconv.ParseFloat(load_test.GetEnv("ZIPF", "0"), 64)
+	zipf := flag.Float64("zipf", zipfDefault, "With --preseed, GETs pick seeded files by a Zipf distribution of this exponent so a few files get most GETs, I.E 1.1. Uniform if 0")
+	preseedSpec := flag.String("preseed", load_test.GetEnv("PRESEED", ""), "Create files before load starts, I.E \"count=10000 min=1024 max=65536 workers=32\"")
+	cleanupDefault, _ := strconv.ParseBool(load_test.GetEnv("CLEANUP", "false"))
+	cleanup := flag.Bool("cleanup", cleanupDefault, "Delete every file the run created once it finishes, so repeated runs don't fill the server's disk")
+	dryRunDefault, _ := strconv.ParseBool(load_test.GetEnv("DRY_RUN", "false"))
+	dryRun := flag.Bool("dry-run", dryRunDefault, "Print the resolved test plan (stages, rates, mixes, expected requests + bytes, duration) without sending any requests")
+	drainGraceDefault, _ := time.ParseDuration(load_test.GetEnv("DRAIN_GRACE", load_test.DrainTimeout.String()))
+	drainGrace := flag.Duration("drain-grace", drainGraceDefault, "On shutdown, wait up to this long for in-flight requests to finish, then abandon the rest")
+	replayPath := flag.String("replay", load_test.GetEnv("REPLAY_LOG", ""), "Re-issue the operations recorded in this result log (RESULT_LOG_PATH of an earlier run) instead of generating load")
+	replaySpeedDefault, _ := strconv.ParseFloat(load_test.GetEnv("REPLAY_SPEED", "1"), 64)
+	replaySpeed := flag.Float64("replay-speed", replaySpeedDefault, "With --replay, replay this many times faster than recorded, I.E 2. As fast as possible if 0")
+	outputFile := flag.String("output-file", load_test.GetEnv("OUTPUT_FILE", ""), "Write the final summary to this file instead of stdout")
+	flag.Parse()
+	if *noColor {
+		load_test.DisableColor()
+	}
+
+	start := time.Now()
+	load_test.InitClear()
+	setupLogging()
 
 	host := load_test.GetEnv("FILE_SERVER_HOST", "localhost")
 	port := load_test.GetEnv("FILE_SERVER_PORT", "1234")
 	proto := load_test.GetEnv("FILE_SERVER_PROTO", "http")
 	prefix := load_test.GetEnv("FILE_SERVER_PATH_PREFIX", "api/fileserver")
+	profile, err := load_test.ParseLoadProfile(*profileSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid load profile: %+v", err))
+	}
+	if *planPath != "" {
+		if profile != nil {
+			panic("Invalid load profile: --profile + --plan can't be combined")
+		}
+		plan, err := load_test.LoadTestPlan(*planPath)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid test plan: %+v", err))
+		}
+		profile = plan
+	}
+	requestLimit, err := load_test.ParseRequestLimit(*requestLimitSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid request limit: %+v", err))
+	}
+	concurrencyLimits, err := load_test.ParseConcurrencyLimits(*concurrencySpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid concurrency limits: %+v", err))
+	}
+	timeouts, err := load_test.ParseOperationTimeouts(*timeoutsSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid timeouts: %+v", err))
+	}
+	retries, err := load_test.ParseRetryQueue(*retrySpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid retry spec: %+v", err))
+	}
+	extraOps, err := load_test.ParseExtraOps(*extraOpsSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid extra ops: %+v", err))
+	}
+	listAPI, err := load_test.ParseListAPI(*listAPISpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid list API spec: %+v", err))
+	}
+	if _, ok := extraOps[load_test.LIST]; ok && listAPI == nil {
+		panic("Invalid extra ops: LIST needs the server's listing endpoint, see --list-api")
+	}
+	jitter, err := load_test.ParseJitter(*jitterSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid jitter: %+v", err))
+	}
+	chaos, err := load_test.ParseChaos(*chaosSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid chaos spec: %+v", err))
+	}
+	consistencyPool, err := load_test.ParseConsistencyPool(*consistencyPoolSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid consistency pool spec: %+v", err))
+	}
+	listCheck, err := load_test.ParseListCheck(*listCheckSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid list check spec: %+v", err))
+	}
+	sizes, err := load_test.ParseSizeDistribution(*sizesSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid size distribution: %+v", err))
+	}
+	compressible, err := load_test.ParseCompressible(*compressibleSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid compressible share: %+v", err))
+	}
+	pathKeys, err := load_test.ParsePathKeys(*pathKeysSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid path keys spec: %+v", err))
+	}
+	emptyFiles, err := load_test.ParseEmptyFiles(*emptyFilesSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid empty files share: %+v", err))
+	}
+	boundaries, err := load_test.ParseBoundarySizes(*boundarySpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid boundary sizes: %+v", err))
+	}
+	if *consistencyRate < 0 || (*consistencyRate > 0 && consistencyPool != nil) {
+		panic(fmt.Sprintf("Invalid consistency rate: %.2f. Must be >= 0, + can't be combined with --consistency-pool, use its rate param instead", *consistencyRate))
+	}
+	preseed, err := load_test.ParsePreseed(*preseedSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid preseed spec: %+v", err))
+	}
+	if *zipf < 0 || (*zipf > 0 && preseed == nil) {
+		panic(fmt.Sprintf("Invalid zipf exponent: %v. Must be >= 0, + > 0 needs --preseed, GETs are skewed over the seeded files", *zipf))
+	}
+	if preseed != nil && *agents > 0 {
+		panic("Invalid preseed spec: --preseed isn't supported with agents")
+	}
+	var replay *load_test.Replay
+	if *replayPath != "" {
+		if profile != nil || *virtualUsers > 0 || *agents > 0 || *scenarioPath != "" {
+			panic("Invalid replay: --replay can't be combined with --profile, --plan, --vus, --agents or --scenarios")
+		}
+		replay, err = load_test.LoadReplay(*replayPath, *replaySpeed)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid replay: %+v", err))
+		}
+	}
+	thinkTime, err := load_test.ParseThinkTime(*thinkTimeSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid think time: %+v", err))
+	}
+	arrivals, err := load_test.ParseArrivals(*arrivalsSpec)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid arrivals: %+v", err))
+	}
+	operationMix, err := load_test.ParseOperationMix(load_test.GetEnv("OPERATION_MIX", ""))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid OPERATION_MIX: %+v", err))
+	}
+	var scenarios *load_test.ScenarioFile
+	if *scenarioPath != "" {
+		scenarios, err = load_test.LoadScenarios(*scenarioPath)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid scenarios: %+v", err))
+		}
+	}
+	targets, err := load_test.ParseTargets(load_test.GetEnv("FILE_SERVER_TARGETS", ""))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid FILE_SERVER_TARGETS: %+v", err))
+	}
 	maxFileCount, _ := strconv.Atoi(load_test.GetEnv("MAX_FILE_COUNT", "500"))
 	maxFileSize, _ := strconv.ParseInt(load_test.GetEnv("MAX_FILE_SIZE", "1024"), 10, 64)
 	requestsPerSecond, _ := strconv.Atoi(load_test.GetEnv("REQUESTS_PER_SECOND", "1"))
@@ -43,83 +240,562 @@ func main() {
 	enableRequestRamp, _ := strconv.ParseBool(load_test.GetEnv("ENABLE_REQUEST_RAMP", "true"))
 	enableFileRamp, _ := strconv.ParseBool(load_test.GetEnv("ENABLE_FILE_RAMP", "true"))
 	uploadRandomLargeFile, _ := strconv.ParseBool(load_test.GetEnv("RANDOMLY_UPLOAD_LARGE_FILES", "true"))
+	runID := load_test.GetEnv("RUN_ID", load_test.NewRunID())
+	metricsAddr := load_test.GetEnv("METRICS_ADDR", "")
+	pushgatewayURL := load_test.GetEnv("PUSHGATEWAY_URL", "")
+	pushgatewayJob := load_test.GetEnv("PUSHGATEWAY_JOB", "fileserver_load_test")
+	intervalCSVPath := load_test.GetEnv("INTERVAL_CSV_PATH", "")
+	heatmapCSVPath := load_test.GetEnv("HEATMAP_CSV_PATH", "")
+	htmlReportPath := load_test.GetEnv("HTML_REPORT_PATH", "")
+	resultLogPath := load_test.GetEnv("RESULT_LOG_PATH", "")
+	statsDAddr := load_test.GetEnv("STATSD_ADDR", "")
+	statsDPrefix := load_test.GetEnv("STATSD_PREFIX", "loadtest")
+	dogStatsD, _ := strconv.ParseBool(load_test.GetEnv("DOGSTATSD", "false"))
+	otlpEndpoint := load_test.GetEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	otlpServiceName := load_test.GetEnv("OTEL_SERVICE_NAME", "fileserver-load-test")
+	otlpSlowSpanMs, _ := strconv.Atoi(load_test.GetEnv("OTEL_SLOW_SPAN_MS", "1000"))
+	influxWriteURL := load_test.GetEnv("INFLUX_WRITE_URL", "")
+	influxToken := load_test.GetEnv("INFLUX_TOKEN", "")
+	influxFilePath := load_test.GetEnv("INFLUX_FILE_PATH", "")
+	correctCoordinatedOmission, _ := strconv.ParseBool(load_test.GetEnv("CORRECT_COORDINATED_OMISSION", "false"))
+	apdexTargetMs, _ := strconv.Atoi(load_test.GetEnv("APDEX_T_MS", strconv.Itoa(int(load_test.DefaultApdexTarget.Milliseconds()))))
+	errorBudget, err := load_test.ParseErrorBudget(load_test.GetEnv("ERROR_BUDGET_PCT", strconv.FormatFloat(load_test.DefaultErrorBudget*100, 'f', -1, 64)))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid error budget: %+v", err))
+	}
+	recentWindow, _ := strconv.Atoi(load_test.GetEnv("RECENT_WINDOW_INTERVALS", strconv.Itoa(load_test.DefaultRecentWindow)))
+	warmupSec, _ := strconv.Atoi(load_test.GetEnv("WARMUP_SEC", "0"))
+	warmupRate, _ := strconv.Atoi(load_test.GetEnv("WARMUP_RATE", strconv.Itoa(load_test.DefaultWarmupRate)))
+	checkpointPath := load_test.GetEnv("CHECKPOINT_PATH", "")
+	checkpointEverySec, _ := strconv.Atoi(load_test.GetEnv("CHECKPOINT_INTERVAL_SEC", strconv.Itoa(int(load_test.CheckpointInterval.Seconds()))))
+	preseedManifestPath := load_test.GetEnv("PRESEED_MANIFEST", "preseed_manifest.json")
+	cleanupWorkers, _ := strconv.Atoi(load_test.GetEnv("CLEANUP_WORKERS", "16"))
+	cleanupRetries, _ := strconv.Atoi(load_test.GetEnv("CLEANUP_RETRIES", "3"))
+	cleanupBackoffMs, _ := strconv.Atoi(load_test.GetEnv("CLEANUP_BACKOFF_MS", "500"))
+	cleanupLeftoversPath := load_test.GetEnv("CLEANUP_LEFTOVERS_PATH", "cleanup_leftovers.txt")
+	soakDir := load_test.GetEnv("SOAK_DIR", "soak")
+	soakRotateLog, _ := strconv.ParseBool(load_test.GetEnv("SOAK_ROTATE_RESULT_LOG", "false"))
+	rateAlpha, _ := strconv.ParseFloat(load_test.GetEnv("RATE_EWMA_ALPHA", strconv.FormatFloat(load_test.DefaultRateAlpha, 'f', -1, 64)), 64)
+
+	// A test plan ends the run after its last stage, unless it's cut short.
+	if plan, ok := profile.(*load_test.TestPlan); ok && *duration == 0 {
+		*duration = time.Duration(warmupSec)*time.Second + plan.Duration()
+	}
+
+	// Closed loop runs only queue a test per virtual user, so tests aren't picked long before they can run.
+	queueSize := 50000
+	if *virtualUsers > 0 {
+		queueSize = *virtualUsers
+	}
 
 	cfg := load_test.TestSchedulerConfig{
+		RunID: runID,
 		EndpointCfg: load_test.TestEndpointConfig{
 			Proto:      proto,
 			Host:       host,
 			Port:       port,
 			PathPrefix: prefix,
 		},
+		Targets: targets,
 		SeedCadence: load_test.TestCadenceConfig{
 			Duration:         time.Second,
 			TestsPerDuration: requestsPerSecond,
 		},
 		SeedGrowthAmount:  seedGrowthAmount,
 		EnableRequestRamp: enableRequestRamp,
+		Profile:           profile,
+		VirtualUsers:      *virtualUsers,
+		ThinkTime:         thinkTime,
+		Pacing:            *pacing,
+		Jitter:            jitter,
+		Arrivals:          arrivals,
+		RandSeed:          *seed,
+		Chaos:             chaos,
+		Retries:           retries,
+		ExtraOps:          extraOps,
+		Stores:            load_test.NewRunStores(extraOps),
+		ConsistencyPool:   consistencyPool,
+		ConsistencyRate:   *consistencyRate,
+		Zipf:              *zipf,
+		Duration:          *duration,
+		RequestLimit:      requestLimit,
 		TestConfig: load_test.TestConfig{
 			MaxFileSize:           maxFileSize,
 			MaxFileCount:          maxFileCount,
 			FileSizeRamp:          enableFileRamp,
 			UploadRandomLargeFile: uploadRandomLargeFile,
+			TraceRequests:         otlpEndpoint != "",
+			ConcurrencyLimits:     concurrencyLimits,
+			ClientBackoff:         *clientBackoff,
+			RequestTags:           load_test.NewRequestTags(runID, 0),
+			Timeouts:              timeouts,
+			StreamSize:            *streamSize,
+			DownloadSize:          *downloadSize,
+			VerifyChecksums:       *verifyChecksums,
+			DeleteRecheckDelay:    *deleteRecheck,
+			ListCheck:             listCheck,
+			ListAPI:               listAPI,
+			Sizes:                 sizes,
+			Compressible:          compressible,
+			Keys:                  pathKeys,
+			Boundaries:            boundaries,
+			EmptyFiles:            emptyFiles,
 		},
-		SchedulerChan: make(chan load_test.Test, 50000),       // Tests scheduled to run asap are sent here
-		ResultChan:    make(chan load_test.TestResult, 15000), // Results of tests are sent here
-		ShutdownChan:  make(chan bool, 1),                     // If closed, shuts down scheduling
-		FailureChan:   make(chan load_test.TestResult, 1000),  // All test failures published here
-		SuccessChan:   make(chan load_test.TestResult, 20000), // All test successes published here
+		OperationMix:          operationMix,
+		Scenarios:             scenarios,
+		SchedulerChan:         make(chan load_test.Test, queueSize),   // Tests scheduled to run asap are sent here
+		ResultChan:            make(chan load_test.TestResult, 15000), // Results of tests are sent here
+		ShutdownChan:          make(chan bool, 1),                     // If closed, shuts down scheduling
+		FailureChan:           make(chan load_test.TestResult, 1000),  // All test failures published here
+		SuccessChan:           make(chan load_test.TestResult, 20000), // All test successes published here
+		MetricsAddr:           metricsAddr,
+		PushgatewayURL:        pushgatewayURL,
+		PushgatewayJob:        pushgatewayJob,
+		IntervalCSVPath:       intervalCSVPath,
+		HeatmapCSVPath:        heatmapCSVPath,
+		RateAlpha:             rateAlpha,
+		Thresholds:            parseThresholds(),
+		ResultLogPath:         resultLogPath,
+		StatsDAddr:            statsDAddr,
+		StatsDPrefix:          statsDPrefix,
+		DogStatsD:             dogStatsD,
+		OTLPEndpoint:          otlpEndpoint,
+		OTLPServiceName:       otlpServiceName,
+		OTLPSlowSpanThreshold: time.Duration(otlpSlowSpanMs) * time.Millisecond,
+		InfluxWriteURL:        influxWriteURL,
+		InfluxToken:           influxToken,
+		InfluxFilePath:        influxFilePath,
+		CorrectOmission:       correctCoordinatedOmission,
+		ApdexTarget:           time.Duration(apdexTargetMs) * time.Millisecond,
+		ErrorBudget:           errorBudget,
+		RecentWindow:          recentWindow,
+		CheckpointPath:        checkpointPath,
+		CheckpointEvery:       time.Duration(checkpointEverySec) * time.Second,
+		SoakEvery:             *soakEvery,
+		SoakDir:               soakDir,
+		SoakRotateLog:         soakRotateLog,
+		Warmup:                time.Duration(warmupSec) * time.Second,
+		WarmupRate:            warmupRate,
+		TrackCreated:          *cleanup,
+		Drain:                 load_test.NewDrain(*drainGrace),
+		Replay:                replay,
+	}
+	// Coordinators don't generate load themselves, so there's nothing to pause or queue.
+	if *agents == 0 {
+		cfg.Pause = load_test.NewPauseControl()
+		cfg.Live = load_test.NewLiveSettings(cfg.VirtualUsers)
+		if cfg.ConsistencyPool != nil {
+			cfg.ConsistencyChan = make(chan load_test.Test, cfg.ConsistencyPool.Queue)
+		}
+	}
+	cfg.Clock = load_test.NewRunClock(cfg.Pause)
+	if *autoscaleSpec != "" {
+		if *agents > 0 {
+			panic("Invalid autoscale spec: --autoscale isn't supported with agents, it needs results as they happen")
+		}
+		cfg.Autoscaler, err = load_test.NewAutoscaler(*autoscaleSpec, cfg.Live)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid autoscale spec: %+v", err))
+		}
+	}
+
+	if *steadyStateSpec != "" {
+		cfg.SteadyState, err = load_test.NewSteadyState(*steadyStateSpec, cfg.ShutdownChan)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid steady state spec: %+v", err))
+		}
+		log.Infof("Running until steady: %s", cfg.SteadyState)
+	}
+
+	// When stdout isn't a terminal (I.E CI), write a progress line per interval to stderr instead of redrawing tables.
+	interactive := load_test.IsTerminal(os.Stdout)
+	if !interactive {
+		cfg.ProgressOutput = os.Stderr
 	}
 
 	testRunnerCfg := load_test.TestRunnerConfig{
-		TestConfig:   cfg.TestConfig,
-		EndpointCfg:  cfg.EndpointCfg,
-		ResultChan:   cfg.ResultChan,
-		ScheduleChan: cfg.SchedulerChan,
+		TestConfig:      cfg.TestConfig,
+		EndpointCfg:     cfg.EndpointCfg,
+		Targets:         cfg.Targets,
+		ResultChan:      cfg.ResultChan,
+		ScheduleChan:    cfg.SchedulerChan,
+		ShutdownChan:    cfg.ShutdownChan,
+		VirtualUsers:    cfg.VirtualUsers,
+		ThinkTime:       cfg.ThinkTime,
+		Pacing:          cfg.Pacing,
+		Jitter:          cfg.Jitter,
+		Chaos:           cfg.Chaos,
+		Pause:           cfg.Pause,
+		Live:            cfg.Live,
+		ConsistencyPool: cfg.ConsistencyPool,
+		ConsistencyChan: cfg.ConsistencyChan,
+		Drain:           cfg.Drain,
+		Retries:         cfg.Retries,
+		Stores:          cfg.Stores,
+	}
+
+	if cfg.Chaos != nil {
+		log.Infof("Injecting client faults: %s", cfg.Chaos)
+	}
+	if cfg.TestConfig.Timeouts != nil {
+		log.Infof("Operation timeouts: %s", cfg.TestConfig.Timeouts)
+	}
+	if cfg.Retries != nil {
+		log.Infof("Retrying failed GETs + DELETEs: %s", cfg.Retries)
+	}
+	if sizes != nil {
+		log.Infof("Drawing payload sizes from: %s", sizes)
+	}
+	if compressible > 0 {
+		log.Infof("%.0f%% of payloads are compressible text", compressible*100)
+	}
+	if pathKeys != nil {
+		log.Infof("Nesting new files' keys %s", pathKeys)
+		log.Warnf("Nested keys are sent with literal /s, every request 404s unless the server routes hierarchical paths. The bundled server doesn't")
+	}
+	if emptyFiles > 0 {
+		log.Infof("%.0f%% of uploads are empty files", emptyFiles*100)
+	}
+	if boundaries != nil {
+		log.Infof("BOUNDARY tests probe sizes: %s", boundaries)
+	}
+	if cfg.ExtraOps != nil {
+		log.Infof("Running extra ops on existing files: %s", cfg.ExtraOps)
+	}
+	if cfg.ConsistencyPool != nil {
+		log.Infof("Running consistency checks from a dedicated pool: %s", cfg.ConsistencyPool)
+	}
+	if cfg.ConsistencyRate > 0 {
+		log.Infof("Starting %.2f consistency checks/sec regardless of load", cfg.ConsistencyRate)
+	}
+	if cfg.Arrivals.IsSet() {
+		log.Infof("Scheduling tests with %s arrivals", cfg.Arrivals)
+	}
+	if cfg.Replay != nil {
+		log.Infof("Replaying %s", cfg.Replay)
+	}
+	if cfg.RandSeed != 0 {
+		log.Infof("Seeded run, rerun with --seed %d to repeat its operations.", cfg.RandSeed)
 	}
 
-	log.Infof("Starting Scheduler.")
-	scheduler := load_test.NewTestScheduler(cfg)
-	go scheduler.Run()
+	if *dryRun {
+		load_test.NewDryRun(cfg, preseed).Print()
+		return
+	}
 
-	log.Info("Starting Runner.")
-	runner := load_test.NewTestRunner(testRunnerCfg)
-	go runner.Run()
+	// Cancelling ctx stops the scheduler, runner + aggregator, aborting in-flight requests.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if preseed != nil {
+		manifest, err := preseed.Run(ctx, cfg, preseedManifestPath)
+		if err != nil {
+			panic(fmt.Sprintf("Pre-seeding failed: %+v", err))
+		}
+		cfg.Preseeded = manifest.Keys()
+	}
+
+	if *agents > 0 {
+		plan, err := load_test.NewAgentPlan(cfg, *profileSpec)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid agent plan: %+v", err))
+		}
+
+		log.Infof("Starting Coordinator.")
+		go func() {
+			err := load_test.RunCoordinator(*coordinatorAddr, *agents, plan, cfg)
+			if err != nil {
+				log.Errorf("Coordinator stopped: %+v", err)
+				load_test.CloseShutdownChan(cfg.ShutdownChan)
+			}
+		}()
+	} else {
+		log.Infof("Starting Scheduler.")
+		scheduler := load_test.NewTestScheduler(cfg)
+		go scheduler.Run(ctx)
+
+		log.Info("Starting Runner.")
+		runner := load_test.NewTestRunner(testRunnerCfg)
+		go runner.Run(ctx)
+	}
 
 	log.Info("Starting Result Aggregator")
 	aggregator := load_test.NewResultAggregator(cfg)
-	go aggregator.Run()
+	if *resume && checkpointPath != "" {
+		checkpoint, err := aggregator.Results.LoadCheckpoint(checkpointPath)
+		if err != nil {
+			log.Warnf("Not resuming, starting from zero: %+v", err)
+		} else {
+			log.Infof("Resumed run %s from checkpoint saved at %s, %s in.", checkpoint.RunID,
+				checkpoint.SavedAt.Format(time.RFC3339), checkpoint.Elapsed.Truncate(time.Second))
+		}
+	}
+	go aggregator.Run(ctx)
 
 	// Repeatedly print results
+	dashboard := load_test.NewDashboard(aggregator.Results, cfg.RunID)
+	printerDone := make(chan struct{})
 	go func() {
+		defer close(printerDone)
 		keepRunning := true
+		render := interactive && !*quiet
+		if render && *displayMode == "dashboard" {
+			load_test.CallClear()
+		}
 		for keepRunning {
 			select {
 			case _, keepRunning = <-cfg.ShutdownChan:
 			default:
 				time.Sleep(time.Second)
+				if !render {
+					continue
+				}
+				if *displayMode == "dashboard" {
+					dashboard.Render(os.Stdout)
+					continue
+				}
 				load_test.CallClear()
 				aggregator.Results.PrintResults()
+				if *perWorker {
+					aggregator.Results.PrintWorkers()
+				}
 				aggregator.Results.PrintErrors()
 			}
 		}
 	}()
 
+	if cfg.Pause != nil {
+		serveControls(cfg.Pause, cfg.Live, *controlAddr)
+	}
+
 	// Wait for ctrl +c
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
-		fmt.Println("\r- Ctrl+C pressed in Terminal")
-		close(cfg.ShutdownChan)
+		fmt.Println("\r- Ctrl+C pressed in Terminal, waiting for in-flight requests. Press again to exit immediately.")
+		load_test.CloseShutdownChan(cfg.ShutdownChan)
+		<-c
+		os.Exit(130)
 	}()
 
-	// Wait for channel to close
-	<-cfg.ShutdownChan
-	time.Sleep(time.Second * 2)
+	// Wait for channel to close, or every test allowed by the request limit to finish. Then let the printer finish its
+	// current frame + drain in-flight results
+	select {
+	case <-cfg.ShutdownChan:
+	case <-aggregator.Done():
+		load_test.CloseShutdownChan(cfg.ShutdownChan)
+	}
+	<-printerDone
+	if !cfg.Drain.Wait(aggregator.Done()) {
+		log.Warnf("Grace period of %s ran out with %d tests in flight, abandoning them.", cfg.Drain.Grace, cfg.Drain.InFlight())
+		cancel()
+		// Aborted requests end straight away, wait for them to be counted as abandoned.
+		select {
+		case <-aggregator.Done():
+		case <-time.After(time.Second * 5):
+			log.Warnf("Abandoned tests still haven't finished, reporting partial results.")
+		}
+	}
 
 	finish := time.Now()
 	totalTime := finish.Sub(start)
 	log.Infof("Finished in %f seconds.", totalTime.Seconds())
-	aggregator.PrintScore()
+	if *perWorker && *outputFormat != "json" {
+		aggregator.Results.PrintWorkers()
+	}
+	writeSummary(aggregator, *outputFormat, *outputFile)
+	if htmlReportPath != "" {
+		err := aggregator.Results.WriteHTMLReportFile(htmlReportPath)
+		if err != nil {
+			log.Errorf("Failed to write HTML report: %+v", err)
+		}
+	}
+	if *cleanup {
+		// The run's ctx may have been cancelled by the drain timeout, cleanup still needs to go ahead.
+		cleaner := load_test.Cleanup{
+			Workers: cleanupWorkers,
+			Retries: cleanupRetries,
+			Backoff: time.Duration(cleanupBackoffMs) * time.Millisecond,
+		}
+		report := cleaner.Run(context.Background(), cfg, aggregator.Results.CreatedKeys())
+		fmt.Println(report)
+		if len(report.Leftovers) > 0 {
+			err := report.WriteLeftovers(cleanupLeftoversPath)
+			if err != nil {
+				log.Errorf("Failed to write cleanup leftovers: %+v", err)
+			} else {
+				fmt.Printf("Files that couldn't be deleted were written to %s\n", cleanupLeftoversPath)
+			}
+		}
+	}
+	thresholdsPassed := aggregator.Results.ThresholdsPassed()
+	if *outputFormat != "json" || *outputFile != "" {
+		aggregator.Results.PrintThresholds()
+	}
 	time.Sleep(time.Second * 1)
+
+	if !thresholdsPassed {
+		os.Exit(1)
+	}
+}
+
+func setupLogging() {
+	log.SetFormatter(&log.TextFormatter{
+		DisableColors: true,
+		FullTimestamp: true,
+	})
+	file, err := os.OpenFile("/tmp/load_test.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		panic("Cannot create log file. Does your system have permissions to create a file at /tmp/?")
+	}
+
+	log.SetOutput(file)
+	log.SetLevel(log.InfoLevel)
+}
+
+// serveControls toggles pause on SIGUSR1, + serves the control API on addr if set.
+func serveControls(pause *load_test.PauseControl, live *load_test.LiveSettings, addr string) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	go func() {
+		for range usr1 {
+			pause.Toggle()
+		}
+	}()
+
+	if addr == "" {
+		return
+	}
+	go func() {
+		err := load_test.NewControlServer(pause, live).Serve(addr)
+		if err != nil {
+			log.Errorf("Control API stopped: %+v", err)
+		}
+	}()
+}
+
+// parseThresholds reads optional SLA thresholds from the environment. Unset variables are not checked.
+func parseThresholds() load_test.Thresholds {
+	thresholds := load_test.Thresholds{}
+
+	if val := load_test.GetEnv("SLA_MAX_ERROR_RATE", ""); val != "" {
+		maxErrorRate, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid SLA_MAX_ERROR_RATE: %s", val))
+		}
+		thresholds.MaxErrorRate = &maxErrorRate
+	}
+
+	if val := load_test.GetEnv("SLA_MAX_P99_MS", ""); val != "" {
+		maxP99Ms, err := strconv.Atoi(val)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid SLA_MAX_P99_MS: %s", val))
+		}
+		maxP99 := time.Duration(maxP99Ms) * time.Millisecond
+		thresholds.MaxP99Latency = &maxP99
+	}
+
+	if val := load_test.GetEnv("SLA_MAX_CONSISTENCY_FAILURES", ""); val != "" {
+		maxConsistencyFailures, err := strconv.Atoi(val)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid SLA_MAX_CONSISTENCY_FAILURES: %s", val))
+		}
+		thresholds.MaxConsistencyFailures = &maxConsistencyFailures
+	}
+
+	return thresholds
+}
+
+// writeSummary prints the final score table, and/or the JSON summary if requested.
+func writeSummary(aggregator *load_test.ResultAggregator, outputFormat string, outputFile string) {
+	if outputFormat != "json" {
+		aggregator.Results.PrintThroughputChart()
+		aggregator.Results.PrintStages()
+		aggregator.Results.PrintScenarios()
+		aggregator.PrintScore()
+		return
+	}
+
+	if outputFile == "" {
+		err := aggregator.Results.WriteSummary(os.Stdout)
+		if err != nil {
+			log.Errorf("Failed to write summary: %+v", err)
+		}
+		return
+	}
+
+	aggregator.PrintScore()
+	file, err := os.Create(outputFile)
+	if err != nil {
+		log.Errorf("Failed to create summary file: %s. Error: %+v", outputFile, err)
+		return
+	}
+	defer file.Close()
+
+	err = aggregator.Results.WriteSummary(file)
+	if err != nil {
+		log.Errorf("Failed to write summary file: %s. Error: %+v", outputFile, err)
+		return
+	}
+	fmt.Printf("Summary written to %s", outputFile)
+	fmt.Println()
+}
+
+// runAgent generates load for a coordinator started with --agents. The coordinator sends the test plan, so the only
+// config an agent needs is where to connect.
+func runAgent(args []string) int {
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	coordinatorAddr := flags.String("coordinator", load_test.GetEnv("COORDINATOR_ADDR", "localhost:7070"), "Coordinator host:port to connect to")
+	_ = flags.Parse(args)
+
+	setupLogging()
+	fmt.Printf("Connecting to coordinator at %s\n", *coordinatorAddr)
+	err := load_test.RunAgent(context.Background(), *coordinatorAddr)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Println("Agent finished.")
+	return 0
+}
+
+// runCompare compares two JSON summaries, I.E `main compare baseline.json candidate.json`, returning the exit code.
+func runCompare(args []string) int {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	maxThroughputDrop := flags.Float64("max-throughput-drop", 10, "Max allowed % drop in throughput")
+	maxP99Increase := flags.Float64("max-p99-increase", 20, "Max allowed % increase in p99 latency")
+	maxErrorRateIncrease := flags.Float64("max-error-rate-increase", 0.5, "Max allowed increase in error rate, in percentage points")
+	maxConsistencyIncrease := flags.Int("max-consistency-failure-increase", 0, "Max allowed increase in consistency failures")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		fmt.Println("Usage: main compare [flags] <baseline.json> <candidate.json>")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	baseline, err := load_test.LoadRunSummary(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	candidate, err := load_test.LoadRunSummary(flags.Arg(1))
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	comparisons := load_test.CompareSummaries(baseline, candidate, load_test.RegressionThresholds{
+		MaxThroughputDropPct:          *maxThroughputDrop,
+		MaxP99IncreasePct:             *maxP99Increase,
+		MaxErrorRateIncrease:          *maxErrorRateIncrease,
+		MaxConsistencyFailureIncrease: *maxConsistencyIncrease,
+	})
+	if !load_test.PrintComparison(comparisons) {
+		return 1
+	}
+
+	return 0
 }
@@ -4,13 +4,13 @@ go 1.19
 
 require (
 	github.com/fatih/color v1.15.0
+	github.com/mattn/go-isatty v0.0.17
 	github.com/rodaine/table v1.1.0
 	github.com/sirupsen/logrus v1.9.0
-
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.17 // indirect
 	golang.org/x/sys v0.6.0 // indirect
 )
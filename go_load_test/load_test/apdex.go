@@ -0,0 +1,56 @@
+package load_test
+
+import (
+	"fmt"
+	"time"
+)
+
+// Apdex (Application Performance Index) buckets every result against a target latency T:
+// satisfied <= T, tolerating <= 4T, frustrated > 4T or failed. Score = (satisfied + tolerating/2) / total.
+
+type Apdex struct {
+	Target     time.Duration `json:"-"`
+	TargetMs   float64       `json:"target_ms"`
+	Satisfied  int           `json:"satisfied"`
+	Tolerating int           `json:"tolerating"`
+	Frustrated int           `json:"frustrated"`
+	Score      float64       `json:"score"`
+}
+
+func NewApdex(target time.Duration) Apdex {
+	return Apdex{Target: target, TargetMs: durationMs(target), Score: 1}
+}
+
+// Record buckets a single result.
+func (a *Apdex) Record(duration time.Duration, failed bool) {
+	switch {
+	case failed || duration > 4*a.Target:
+		a.Frustrated++
+	case duration > a.Target:
+		a.Tolerating++
+	default:
+		a.Satisfied++
+	}
+
+	a.Score = (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(a.Satisfied+a.Tolerating+a.Frustrated)
+}
+
+// Rating returns the standard Apdex rating for the score, I.E "Excellent" for >= 0.94.
+func (a Apdex) Rating() string {
+	switch {
+	case a.Score >= 0.94:
+		return "Excellent"
+	case a.Score >= 0.85:
+		return "Good"
+	case a.Score >= 0.70:
+		return "Fair"
+	case a.Score >= 0.50:
+		return "Poor"
+	default:
+		return "Unacceptable"
+	}
+}
+
+func (a Apdex) String() string {
+	return fmt.Sprintf("%.3f [%s]", a.Score, a.Rating())
+}
@@ -0,0 +1,34 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApdex(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		failed    int
+		want      float64
+		rating    string
+	}{
+		{"no results", nil, 0, 1, "Excellent"},
+		{"satisfied", []time.Duration{time.Millisecond, 500 * time.Millisecond}, 0, 1, "Excellent"},
+		{"tolerating", []time.Duration{time.Millisecond, 2 * time.Second}, 0, 0.75, "Fair"},
+		{"frustrated", []time.Duration{time.Millisecond, 2001 * time.Millisecond}, 0, 0.5, "Poor"},
+		{"failed", []time.Duration{time.Millisecond}, 3, 0.25, "Unacceptable"},
+	}
+	for _, test := range tests {
+		apdex := NewApdex(500 * time.Millisecond)
+		for _, latency := range test.latencies {
+			apdex.Record(latency, false)
+		}
+		for i := 0; i < test.failed; i++ {
+			apdex.Record(time.Millisecond, true)
+		}
+		if apdex.Score != test.want || apdex.Rating() != test.rating {
+			t.Errorf("%s: got %s, want %.3f [%s]", test.name, apdex, test.want, test.rating)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package load_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	ArrivalsFixed       = "fixed"
+	ArrivalsUniform     = "uniform"
+	ArrivalsExponential = "exponential"
+	ArrivalsLognormal   = "lognormal"
+)
+
+// Arrivals is the distribution of gaps between the starts of consecutive open loop tests. The mean gap always follows
+// the scheduled rate, the distribution only sets how bursty arrivals are, so the offered load has the burstiness of
+// real traffic rather than a perfectly even cadence. Specs are a distribution name, optionally followed by key=value
+// params, I.E
+//
+//	fixed            Evenly spaced, the default
+//	uniform          Anywhere between 0 + twice the mean gap
+//	exponential      A Poisson process, I.E independent clients
+//	lognormal cv=2   Heavy tailed, cv is the standard deviation as a multiple of the mean. Defaults to 1
+//
+// Unlike Jitter, arrivals aren't kept to their own slot, so the rate within a seed duration varies too.
+type Arrivals struct {
+	Distribution string
+	CV           float64 // Coefficient of variation, lognormal only
+}
+
+// ParseArrivals parses an arrivals spec. An empty spec is fixed arrivals.
+func ParseArrivals(spec string) (Arrivals, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return Arrivals{Distribution: ArrivalsFixed}, nil
+	}
+
+	params := make(profileParams, len(fields)-1)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Arrivals{}, fmt.Errorf("invalid arrivals param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	arrivals := Arrivals{Distribution: fields[0]}
+	var err error
+	switch arrivals.Distribution {
+	case ArrivalsFixed, ArrivalsUniform, ArrivalsExponential:
+		err = params.parse(map[string]interface{}{})
+	case ArrivalsLognormal:
+		arrivals.CV = 1
+		err = params.parse(map[string]interface{}{"cv": &arrivals.CV})
+		if err == nil && arrivals.CV <= 0 {
+			err = fmt.Errorf("cv must be > 0")
+		}
+	default:
+		return Arrivals{}, fmt.Errorf("unknown arrivals distribution: %s", arrivals.Distribution)
+	}
+	if err != nil {
+		return Arrivals{}, fmt.Errorf("invalid %s arrivals: %w", arrivals.Distribution, err)
+	}
+
+	return arrivals, nil
+}
+
+// next returns the gap until the next test starts, for a mean gap of mean.
+func (a Arrivals) next(mean time.Duration) time.Duration {
+	switch a.Distribution {
+	case ArrivalsUniform:
+		return time.Duration(rand.Float64() * 2 * float64(mean))
+	case ArrivalsExponential:
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	case ArrivalsLognormal:
+		return time.Duration(lognormal(float64(mean), a.CV*float64(mean)))
+	default:
+		return mean
+	}
+}
+
+// arrivalsSummary returns the arrivals distribution of an open loop run, or "" for a closed loop run whose virtual
+// users set their own pace. Caller must hold resultLock.
+func (tr *TestResults) arrivalsSummary() string {
+	if tr.users > 0 {
+		return ""
+	}
+
+	return tr.arrivals.String()
+}
+
+// IsSet returns true unless arrivals are evenly spaced.
+func (a Arrivals) IsSet() bool {
+	return a.Distribution != "" && a.Distribution != ArrivalsFixed
+}
+
+func (a Arrivals) String() string {
+	switch a.Distribution {
+	case ArrivalsLognormal:
+		return fmt.Sprintf("%s cv=%g", a.Distribution, a.CV)
+	case "":
+		return ArrivalsFixed
+	default:
+		return a.Distribution
+	}
+}
@@ -0,0 +1,55 @@
+package load_test
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseArrivals(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Arrivals
+		wantErr bool
+	}{
+		{"", Arrivals{Distribution: ArrivalsFixed}, false},
+		{"uniform", Arrivals{Distribution: ArrivalsUniform}, false},
+		{"exponential", Arrivals{Distribution: ArrivalsExponential}, false},
+		{"lognormal", Arrivals{Distribution: ArrivalsLognormal, CV: 1}, false},
+		{"lognormal cv=2.5", Arrivals{Distribution: ArrivalsLognormal, CV: 2.5}, false},
+		{"lognormal cv=0", Arrivals{}, true},
+		{"lognormal cv", Arrivals{}, true},
+		{"exponential cv=2", Arrivals{}, true},
+		{"poisson", Arrivals{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseArrivals(test.spec)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseArrivals(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestArrivalsKeepTheMeanGap(t *testing.T) {
+	const samples = 100000
+	mean := 10 * time.Millisecond
+	for _, spec := range []string{"fixed", "uniform", "exponential", "lognormal cv=2"} {
+		arrivals, err := ParseArrivals(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var total time.Duration
+		for i := 0; i < samples; i++ {
+			gap := arrivals.next(mean)
+			if gap < 0 {
+				t.Fatalf("%s: got a negative gap %s", spec, gap)
+			}
+			total += gap
+		}
+		// Loose enough for the heavy tailed lognormal to pass reliably
+		if got := total / samples; math.Abs(float64(got-mean)) > float64(mean)*0.1 {
+			t.Errorf("%s: got a mean gap of %s, want ~%s", spec, got, mean)
+		}
+	}
+}
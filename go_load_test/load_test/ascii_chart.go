@@ -0,0 +1,88 @@
+package load_test
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Renders simple terminal charts of the per interval history for the end of run report.
+
+const (
+	asciiChartWidth  = 72
+	asciiChartHeight = 10
+)
+
+// PrintThroughputChart prints req/sec and errors/sec over the whole run.
+func (tr *TestResults) PrintThroughputChart() {
+	tr.resultLock.RLock()
+	var throughput, errors []float64
+	for _, stats := range tr.history {
+		throughput = append(throughput, stats.PerSecond(stats.Requests))
+		errors = append(errors, stats.PerSecond(stats.Failures))
+	}
+	tr.resultLock.RUnlock()
+
+	if len(throughput) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Print(asciiChart("req/sec over time", throughput, asciiChartWidth, asciiChartHeight))
+	fmt.Println()
+	fmt.Print(asciiChart("errors/sec over time", errors, asciiChartWidth, asciiChartHeight))
+	fmt.Println()
+}
+
+// asciiChart draws values as columns of '#', downsampling (by averaging) to fit width.
+func asciiChart(title string, values []float64, width int, height int) string {
+	columns := downsample(values, width)
+	maxValue := 0.0
+	for _, v := range columns {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(title)
+	sb.WriteString("\n")
+	for row := height; row > 0; row-- {
+		threshold := maxValue * float64(row) / float64(height)
+		label := ""
+		if row == height {
+			label = fmt.Sprintf("%.0f", maxValue)
+		}
+		sb.WriteString(fmt.Sprintf("%8s |", label))
+		for _, v := range columns {
+			if maxValue > 0 && v >= threshold-maxValue/float64(2*height) {
+				sb.WriteString("#")
+			} else {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("%8s +%s\n", "0", strings.Repeat("-", len(columns))))
+	sb.WriteString(fmt.Sprintf("%8s  start%*s\n", "", Max(len(columns)-5, 0), "end"))
+
+	return sb.String()
+}
+
+func downsample(values []float64, width int) []float64 {
+	if len(values) <= width {
+		return values
+	}
+
+	columns := make([]float64, width)
+	for i := 0; i < width; i++ {
+		start := i * len(values) / width
+		end := (i + 1) * len(values) / width
+		sum := 0.0
+		for _, v := range values[start:end] {
+			sum += v
+		}
+		columns[i] = sum / float64(end-start)
+	}
+
+	return columns
+}
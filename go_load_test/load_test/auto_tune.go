@@ -0,0 +1,208 @@
+package load_test
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// AutoTune searches for the max rate the server sustains with request p99 latency + error rate within bounds. Each probe
+// holds a rate for Window: a probe within bounds raises the floor, one outside them lowers the ceiling. The rate
+// doubles until the first probe fails, then binary searches between floor + ceiling until they're within Precision
+// req/sec of each other, then holds at the floor for the rest of the run.
+//
+// AutoTune needs feedback from results, so the aggregator sends it every interval as an IntervalExporter.
+type AutoTune struct {
+	From         int
+	Max          int // If > 0, rates are never probed above this
+	MaxP99       time.Duration
+	MaxErrorRate float64
+	Window       time.Duration
+	Precision    int
+
+	lock      sync.Mutex
+	rate      int
+	floor     int // Highest rate within bounds so far
+	ceiling   int // Lowest rate outside bounds so far, 0 until a probe fails
+	probes    int
+	done      bool
+	current   autoTuneProbe
+	lastStage string
+}
+
+// autoTuneProbe accumulates the intervals of the probe in progress.
+type autoTuneProbe struct {
+	duration time.Duration
+	requests int
+	failures int
+	worstP99 float64
+}
+
+type AutoTuneResult struct {
+	MaxRate      int     `json:"max_rate"`  // Highest probed rate that stayed within bounds, 0 if none did
+	Converged    bool    `json:"converged"` // False if the run ended before the search finished
+	Probes       int     `json:"probes"`
+	MaxP99Ms     float64 `json:"max_p99_ms"`
+	MaxErrorRate float64 `json:"max_error_rate"`
+}
+
+func (r AutoTuneResult) String() string {
+	bounds := fmt.Sprintf("p99 <= %.0fms, error rate <= %.2f%%", r.MaxP99Ms, r.MaxErrorRate*100)
+	switch {
+	case r.MaxRate == 0 && r.Converged:
+		return fmt.Sprintf("No rate probed stayed within bounds (%s).", bounds)
+	case r.MaxRate == 0:
+		return fmt.Sprintf("The run ended before any rate was found within bounds (%s).", bounds)
+	case !r.Converged:
+		return fmt.Sprintf("Your maximum sustainable rate was at least %d req/sec (%s). The run ended before the search finished.",
+			r.MaxRate, bounds)
+	default:
+		return fmt.Sprintf("Your maximum sustainable rate was %d req/sec (%s).", r.MaxRate, bounds)
+	}
+}
+
+func newAutoTune(params profileParams) (LoadProfile, error) {
+	tune := &AutoTune{From: 10, MaxErrorRate: 0.01, Window: time.Second * 10, Precision: 10}
+	err := params.parse(map[string]interface{}{"from": &tune.From, "max": &tune.Max, "p99": &tune.MaxP99,
+		"error_rate": &tune.MaxErrorRate, "window": &tune.Window, "precision": &tune.Precision}, "p99")
+	if err != nil {
+		return nil, fmt.Errorf("invalid autotune profile: %w", err)
+	}
+	if tune.From <= 0 || tune.Window <= 0 || tune.Precision <= 0 {
+		return nil, fmt.Errorf("invalid autotune profile: from, window + precision must be > 0")
+	}
+
+	tune.rate = tune.From
+	if tune.Max > 0 {
+		tune.rate = Min(tune.rate, tune.Max)
+	}
+
+	return tune, nil
+}
+
+func (a *AutoTune) Rate(_ time.Duration) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.rate
+}
+
+// Stage returns the probe # (from 0) + its rate, I.E "probe-3@400", or "hold@N" once the search is done.
+func (a *AutoTune) Stage(_ time.Duration) string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.stage()
+}
+
+func (a *AutoTune) stage() string {
+	if a.done {
+		return fmt.Sprintf("hold@%d", a.rate)
+	}
+
+	return fmt.Sprintf("probe-%d@%d", a.probes, a.rate)
+}
+
+// Write adds an interval to the probe in progress, and moves on to the next probe once it has run for Window. The
+// first interval of each probe straddles the rate change, so it's left out.
+func (a *AutoTune) Write(stats IntervalStats) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.done || stats.Stage != a.stage() {
+		return nil
+	}
+	if stats.Stage != a.lastStage {
+		a.lastStage = stats.Stage
+		return nil
+	}
+
+	a.current.duration += stats.Duration
+	a.current.requests += stats.Successes + stats.Failures
+	a.current.failures += stats.Failures
+	if p99 := requestP99Ms(stats); p99 > a.current.worstP99 {
+		a.current.worstP99 = p99
+	}
+	if a.current.duration >= a.Window {
+		a.next()
+	}
+
+	return nil
+}
+
+func (a *AutoTune) Close() error {
+	return nil
+}
+
+// next records the result of the probe in progress + picks the rate to probe next. Caller must hold lock.
+func (a *AutoTune) next() {
+	errorRate := 1 - passRate(a.current.failures, a.current.requests)
+	passed := a.current.worstP99 <= float64(a.MaxP99.Milliseconds()) && errorRate <= a.MaxErrorRate
+	log.Infof("Auto tune probe %d at %d req/sec: p99 %.0fms, error rate %.2f%%, within bounds: %t", a.probes, a.rate,
+		a.current.worstP99, errorRate*100, passed)
+	if passed {
+		a.floor = a.rate
+	} else {
+		a.ceiling = a.rate
+	}
+	a.probes++
+	a.current = autoTuneProbe{}
+
+	switch {
+	case a.ceiling == 0 && a.Max > 0 && a.rate >= a.Max:
+		a.done = true
+	case a.ceiling == 0:
+		a.rate *= 2
+		if a.Max > 0 {
+			a.rate = Min(a.rate, a.Max)
+		}
+	case a.ceiling-a.floor <= a.Precision:
+		a.done = true
+		a.rate = a.floor
+	default:
+		a.rate = (a.floor + a.ceiling) / 2
+	}
+
+	if a.done {
+		log.Infof("Auto tune finished after %d probes, holding at %d req/sec", a.probes, a.rate)
+	}
+}
+
+// requestP99Ms returns the highest p99 of any single request operation in the interval. Consistency checks are left
+// out, they're several requests each.
+func requestP99Ms(stats IntervalStats) float64 {
+	worst := 0.0
+	for op, latency := range stats.Latency {
+		if op != CONSISTENCY && latency.P99Ms > worst {
+			worst = latency.P99Ms
+		}
+	}
+
+	return worst
+}
+
+// Result returns the outcome of the search so far.
+func (a *AutoTune) Result() AutoTuneResult {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return AutoTuneResult{
+		MaxRate:      a.floor,
+		Converged:    a.done,
+		Probes:       a.probes,
+		MaxP99Ms:     float64(a.MaxP99.Milliseconds()),
+		MaxErrorRate: a.MaxErrorRate,
+	}
+}
+
+// autoTuneResult returns nil unless the load profile is an auto tune.
+func (tr *TestResults) autoTuneResult() *AutoTuneResult {
+	tune, ok := tr.profile.(*AutoTune)
+	if !ok {
+		return nil
+	}
+
+	result := tune.Result()
+	return &result
+}
@@ -0,0 +1,79 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAutoTune(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *AutoTune
+		wantErr bool
+	}{
+		{"autotune p99=200ms", &AutoTune{From: 10, MaxP99: 200 * time.Millisecond, MaxErrorRate: 0.01,
+			Window: 10 * time.Second, Precision: 10}, false},
+		{"autotune p99=1s from=50 max=30 error_rate=0.05 window=5s precision=2", &AutoTune{From: 50, Max: 30,
+			MaxP99: time.Second, MaxErrorRate: 0.05, Window: 5 * time.Second, Precision: 2}, false},
+		{"autotune", nil, true},
+		{"autotune p99=200ms from=0", nil, true},
+		{"autotune p99=200ms precision=0", nil, true},
+	}
+	for _, test := range tests {
+		profile, err := ParseLoadProfile(test.spec)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: got error %v, want error %t", test.spec, err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got := profile.(*AutoTune)
+		if got.From != test.want.From || got.Max != test.want.Max || got.MaxP99 != test.want.MaxP99 ||
+			got.MaxErrorRate != test.want.MaxErrorRate || got.Window != test.want.Window ||
+			got.Precision != test.want.Precision {
+			t.Errorf("%q: got %+v, want %+v", test.spec, got, test.want)
+		}
+	}
+	// The first probe never goes above max
+	if profile, _ := ParseLoadProfile("autotune p99=1s from=50 max=30"); profile.Rate(0) != 30 {
+		t.Errorf("got a first probe of %d, want max 30", profile.Rate(0))
+	}
+}
+
+func TestAutoTuneSearch(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		sustained int // Highest rate the simulated server stays within bounds at
+		min, max  int
+	}{
+		{"binary search", "autotune p99=100ms from=10 window=1s precision=10", 300, 290, 300},
+		{"capped by max", "autotune p99=100ms from=10 max=100 window=1s", 1000, 100, 100},
+		{"nothing passes", "autotune p99=100ms from=10 window=1s", 5, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := ParseLoadProfile(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tune := profile.(*AutoTune)
+			for i := 0; i < 100 && !tune.Result().Converged; i++ {
+				stats := IntervalStats{Duration: time.Second, Stage: tune.Stage(0), Successes: 100,
+					Latency: map[TestType]LatencySummary{GET: {P99Ms: 10}}}
+				if tune.Rate(0) > test.sustained {
+					stats.Latency[GET] = LatencySummary{P99Ms: 500}
+				}
+				// The first interval of each probe straddles the rate change + is left out
+				_ = tune.Write(stats)
+				_ = tune.Write(stats)
+			}
+
+			result := tune.Result()
+			if !result.Converged || result.MaxRate < test.min || result.MaxRate > test.max {
+				t.Errorf("got %+v, want a converged max rate of %d-%d", result, test.min, test.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package load_test
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const autoscaleTolerance = 0.1 // p99 within this fraction of the target holds the # of workers steady
+
+// Autoscaler grows + shrinks a closed loop run's virtual users to keep request p99 latency near a target, which
+// probes the concurrency the server can sustain. Every Window it scales users by target / p99, so a p99 at half the
+// target roughly doubles them, limited to between half + 1.25x per step so one noisy window can't swing load far.
+// Specs are key=value params, I.E
+//
+//	p99=250ms min=1 max=500 window=10s
+//
+// The autoscaler needs feedback from results, so the aggregator sends it every interval as an IntervalExporter. Users
+// are changed through LiveSettings, so a change made over the control API is adopted as the new starting point.
+type Autoscaler struct {
+	TargetP99 time.Duration
+	Min       int
+	Max       int
+	Window    time.Duration
+
+	lock      sync.Mutex
+	live      *LiveSettings
+	users     int // Users the window in progress ran with
+	skip      bool
+	window    time.Duration
+	worstP99  float64
+	steps     int
+	sustained int // Most users a whole window stayed within target with
+}
+
+type AutoscaleResult struct {
+	TargetP99Ms      float64 `json:"target_p99_ms"`
+	Workers          int     `json:"workers"`           // # of users at the end of the run
+	SustainedWorkers int     `json:"sustained_workers"` // Most users a whole window stayed within target with, 0 if none did
+	Steps            int     `json:"steps"`
+}
+
+func (r AutoscaleResult) String() string {
+	if r.SustainedWorkers == 0 {
+		return fmt.Sprintf("No # of workers kept p99 within %.0fms. Ended with %d workers.", r.TargetP99Ms, r.Workers)
+	}
+
+	return fmt.Sprintf("Your server sustained %d concurrent workers with p99 within %.0fms. Ended with %d workers.",
+		r.SustainedWorkers, r.TargetP99Ms, r.Workers)
+}
+
+// NewAutoscaler parses spec + controls the users of live, which must be a closed loop run's settings.
+func NewAutoscaler(spec string, live *LiveSettings) (*Autoscaler, error) {
+	fields := strings.Fields(spec)
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid autoscale param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	scaler := &Autoscaler{Min: 1, Max: 1000, Window: time.Second * 10, live: live}
+	err := params.parse(map[string]interface{}{"p99": &scaler.TargetP99, "min": &scaler.Min, "max": &scaler.Max,
+		"window": &scaler.Window}, "p99")
+	if err != nil {
+		return nil, fmt.Errorf("invalid autoscale spec: %w", err)
+	}
+	if scaler.TargetP99 <= 0 || scaler.Window <= 0 || scaler.Min < 1 || scaler.Max < scaler.Min {
+		return nil, fmt.Errorf("invalid autoscale spec: p99 + window must be > 0, + 1 <= min <= max")
+	}
+	if live.Users() == 0 {
+		return nil, fmt.Errorf("invalid autoscale spec: autoscaling needs a closed loop run, see --vus")
+	}
+
+	scaler.users = live.Users()
+	return scaler, nil
+}
+
+// Write adds an interval to the window in progress, + rescales once it has run for Window. The first interval after
+// a change straddles it, so it's left out.
+func (w *Autoscaler) Write(stats IntervalStats) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if users := w.live.Users(); users != w.users {
+		w.users = users
+		w.reset()
+		return nil
+	}
+	if w.skip {
+		w.skip = false
+		return nil
+	}
+
+	w.window += stats.Duration
+	if p99 := requestP99Ms(stats); p99 > w.worstP99 {
+		w.worstP99 = p99
+	}
+	if w.window >= w.Window {
+		w.scale()
+	}
+
+	return nil
+}
+
+func (w *Autoscaler) Close() error {
+	return nil
+}
+
+// scale picks the # of users for the next window from the window that just finished. Caller must hold lock.
+func (w *Autoscaler) scale() {
+	target := float64(w.TargetP99.Milliseconds())
+	within := w.worstP99 <= target
+	if within && w.users > w.sustained {
+		w.sustained = w.users
+	}
+
+	next := w.users
+	if math.Abs(w.worstP99-target) > target*autoscaleTolerance {
+		factor := 1.25
+		if w.worstP99 > 0 {
+			factor = math.Max(0.5, math.Min(1.25, target/w.worstP99))
+		}
+		next = int(math.Round(float64(w.users) * factor))
+		// Always move at least one user, or small pools would never scale.
+		if next == w.users && within {
+			next++
+		} else if next == w.users {
+			next--
+		}
+		next = Max(w.Min, Min(w.Max, next))
+	}
+	log.Infof("Autoscale window: p99 %.0fms (target %.0fms) with %d workers, next %d", w.worstP99, target, w.users, next)
+	w.reset()
+	if next == w.users {
+		return
+	}
+
+	err := w.live.Update(SettingsUpdate{Workers: &next})
+	if err != nil {
+		log.Errorf("Failed to autoscale workers: %+v", err)
+		return
+	}
+	w.users = next
+	w.steps++
+}
+
+// reset starts a new window. Caller must hold lock.
+func (w *Autoscaler) reset() {
+	w.skip = true
+	w.window = 0
+	w.worstP99 = 0
+}
+
+// Result returns the outcome of autoscaling so far.
+func (w *Autoscaler) Result() AutoscaleResult {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return AutoscaleResult{
+		TargetP99Ms:      float64(w.TargetP99.Milliseconds()),
+		Workers:          w.users,
+		SustainedWorkers: w.sustained,
+		Steps:            w.steps,
+	}
+}
+
+// autoscaleResult returns nil unless the run was autoscaled.
+func (tr *TestResults) autoscaleResult() *AutoscaleResult {
+	if tr.autoscaler == nil {
+		return nil
+	}
+
+	result := tr.autoscaler.Result()
+	return &result
+}
@@ -0,0 +1,76 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAutoscaler(t *testing.T) {
+	tests := []struct {
+		spec    string
+		users   int
+		want    *Autoscaler
+		wantErr bool
+	}{
+		{"p99=250ms", 10, &Autoscaler{TargetP99: 250 * time.Millisecond, Min: 1, Max: 1000, Window: 10 * time.Second},
+			false},
+		{"p99=1s min=5 max=50 window=30s", 10,
+			&Autoscaler{TargetP99: time.Second, Min: 5, Max: 50, Window: 30 * time.Second}, false},
+		{"min=5", 10, nil, true},
+		{"p99=1s min=50 max=5", 10, nil, true},
+		{"p99=1s min=0", 10, nil, true},
+		{"p99", 10, nil, true},
+		{"p99=1s", 0, nil, true}, // Open loop
+	}
+	for _, test := range tests {
+		got, err := NewAutoscaler(test.spec, NewLiveSettings(test.users))
+		if (err != nil) != test.wantErr {
+			t.Errorf("NewAutoscaler(%q): got error %v, want error %t", test.spec, err, test.wantErr)
+			continue
+		}
+		if err == nil && (got.TargetP99 != test.want.TargetP99 || got.Min != test.want.Min ||
+			got.Max != test.want.Max || got.Window != test.want.Window) {
+			t.Errorf("NewAutoscaler(%q): got p99 %s, min %d, max %d, window %s, want %+v", test.spec, got.TargetP99,
+				got.Min, got.Max, got.Window, test.want)
+		}
+	}
+}
+
+func TestAutoscalerScale(t *testing.T) {
+	live := NewLiveSettings(10)
+	scaler, err := NewAutoscaler("p99=100ms window=1s", live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(p99Ms float64) {
+		stats := IntervalStats{Duration: time.Second, Latency: map[TestType]LatencySummary{GET: {P99Ms: p99Ms},
+			CONSISTENCY: {P99Ms: 10000}}}
+		if err := scaler.Write(stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		p99Ms float64
+		want  int
+	}{
+		{50, 13},  // Well under target, limited to 1.25x
+		{0, 13},   // Skipped, as it may have run partly with the old users
+		{200, 7},  // Double the target halves them
+		{0, 7},    // Skipped
+		{105, 7},  // Within tolerance
+		{0, 7},    // Skipped, every window starts a new one
+		{1000, 4}, // Limited to 0.5x
+	}
+	for i, test := range tests {
+		write(test.p99Ms)
+		if got := live.Users(); got != test.want {
+			t.Errorf("window %d, p99 %.0fms: got %d users, want %d", i, test.p99Ms, got, test.want)
+		}
+	}
+
+	want := AutoscaleResult{TargetP99Ms: 100, Workers: 4, SustainedWorkers: 10, Steps: 3}
+	if got := scaler.Result(); got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
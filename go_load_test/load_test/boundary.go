@@ -0,0 +1,212 @@
+package load_test
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BOUNDARY tests PUT a new file of a size at one of the server's limits, see boundaryCases, then GET it back. Sizes are
+// of the request body itself, so they land exactly on the server's buffer + max size. Files up to the max size must be
+// stored + returned faithfully, + a file over it must be rejected cleanly with a 4xx. Stored files are deleted
+// afterwards. Without BoundarySizes only 0 + 1 byte files are tested.
+const BOUNDARY TestType = "BOUNDARY"
+
+// BoundarySizes are the server's limits BOUNDARY tests probe, as request body sizes with KB, MB + GB suffixes in
+// powers of 1024. Specs are key=value params, I.E
+//
+//	buffer=64KB max=100MB
+//
+// Either may be left out to skip its cases.
+type BoundarySizes struct {
+	Buffer int64 // The server's read buffer or chunk size
+	Max    int64 // The largest file the server accepts
+}
+
+// ParseBoundarySizes parses a boundary sizes spec. An empty spec returns nil, only 0 + 1 byte files are tested.
+func ParseBoundarySizes(spec string) (*BoundarySizes, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid boundary sizes param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	var buffer, maxSize string
+	err := params.parse(map[string]interface{}{"buffer": &buffer, "max": &maxSize})
+	sizes := &BoundarySizes{}
+	if err == nil && buffer != "" {
+		sizes.Buffer, err = parseByteSize(buffer)
+	}
+	if err == nil && maxSize != "" {
+		sizes.Max, err = parseByteSize(maxSize)
+	}
+	if err == nil && (sizes.Buffer < 0 || sizes.Max < 0 || (buffer == "" && maxSize == "")) {
+		err = fmt.Errorf("buffer or max must be set, + > 0")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid boundary sizes spec: %w", err)
+	}
+
+	return sizes, nil
+}
+
+func (s *BoundarySizes) String() string {
+	return fmt.Sprintf("buffer=%d max=%d", s.Buffer, s.Max)
+}
+
+// boundaryCase is one size BOUNDARY tests upload.
+type boundaryCase struct {
+	name   string
+	size   int64
+	reject bool // The server must reject the file rather than store it
+}
+
+// boundaryCases returns the sizes to test, in increasing order: 0 + 1 bytes, the buffer size + either side of it, +
+// the max size + the byte over it, which must be rejected.
+func boundaryCases(sizes *BoundarySizes) []boundaryCase {
+	cases := []boundaryCase{{name: "0", size: 0}, {name: "1", size: 1}}
+	if sizes == nil {
+		return cases
+	}
+
+	if sizes.Buffer > 1 {
+		cases = append(cases, boundaryCase{name: "buffer-1", size: sizes.Buffer - 1},
+			boundaryCase{name: "buffer", size: sizes.Buffer}, boundaryCase{name: "buffer+1", size: sizes.Buffer + 1})
+	}
+	if sizes.Max > 1 {
+		cases = append(cases, boundaryCase{name: "max", size: sizes.Max},
+			boundaryCase{name: "max+1", size: sizes.Max + 1, reject: true})
+	}
+
+	return cases
+}
+
+// boundaryCaseNames are every case's name, in the order boundaryCases returns them.
+var boundaryCaseNames = []string{"0", "1", "buffer-1", "buffer", "buffer+1", "max", "max+1"}
+
+// BoundaryCase is the size a BOUNDARY test uploaded + whether the server stored it.
+type BoundaryCase struct {
+	Name   string
+	Stored bool // The server accepted the PUT, so the test also GETs + DELETEs the file
+}
+
+// boundaryPayload returns size bytes of base64 text drawn from r, like other payloads but cut to exactly size.
+func (tr *TestExecutor) boundaryPayload(r *Rand, size int64) (string, error) {
+	fileBytes := make([]byte, (size*3)/4+3)
+	if err := tr.fillPayload(r, fileBytes); err != nil {
+		return "", err
+	}
+
+	return b64.StdEncoding.EncodeToString(fileBytes)[:size], nil
+}
+
+func (tr *TestExecutor) BoundaryFile(test Test) {
+	ctx, result, done := tr.beginHeldTest(test, BOUNDARY)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+	payload := newSeededRand(test.payloadSeed)
+	cases := boundaryCases(tr.boundaries)
+	boundary := cases[payload.Intn(len(cases))]
+	outcome := &BoundaryCase{Name: boundary.name}
+	result.boundary = outcome
+
+	byteString, err := tr.boundaryPayload(payload, boundary.size)
+	if err != nil {
+		result.message = "Failed to generate random file bytes"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(byteString), trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for BoundaryFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	response, err := tr.do(req, phases)
+	result.response = response
+	result.bytesSent = int64(len(byteString))
+	if err != nil {
+		result.message = fmt.Sprintf("Error executing http PUT request of a %s byte file", boundary.name)
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	result.message = responseToString(response)
+	if response.StatusCode >= 400 {
+		if !boundary.reject || !cleanRejection(response.StatusCode) {
+			result.message = fmt.Sprintf("PUT of a %s byte file (%d bytes) failed with %d", boundary.name, boundary.size,
+				response.StatusCode)
+			result.mismatch = fmt.Sprintf("%s bytes %s", boundary.name, statusCodeLabel(response.StatusCode))
+			result.failed = true
+		}
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	outcome.Stored = true
+	defer func() {
+		_, _ = tr.removeFile(fileName)
+	}()
+	if boundary.reject {
+		result.message = fmt.Sprintf("PUT of a %s byte file (%d bytes) was accepted with %d, expected a 4xx", boundary.name,
+			boundary.size, response.StatusCode)
+		result.mismatch = fmt.Sprintf("%s bytes accepted", boundary.name)
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	response, err = tr.get(ctx, fileName, trace, phases)
+	result.response = response
+	if err != nil {
+		result.message = fmt.Sprintf("Error executing http GET request of a %s byte file", boundary.name)
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	body := responseToString(response)
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	if response.StatusCode != http.StatusOK {
+		result.message = fmt.Sprintf("GET of a %s byte file failed due to unexpected status code, got: %d but expected 200.",
+			boundary.name, response.StatusCode)
+		result.mismatch = fmt.Sprintf("%s bytes %s", boundary.name, statusMismatch(response.StatusCode, http.StatusOK))
+		result.failed = true
+	} else if body != byteString {
+		result.mismatch = fmt.Sprintf("%s bytes %s", boundary.name, bodyMismatch(byteString, body))
+		result.message = fmt.Sprintf("A %s byte file wasn't returned faithfully: %s", boundary.name, result.mismatch)
+		result.failed = true
+	} else {
+		result.message = ""
+	}
+	tr.emit(result)
+}
+
+// BoundaryCaseSummary is how a BOUNDARY case fared.
+type BoundaryCaseSummary struct {
+	Count    int `json:"count"`
+	Failures int `json:"failures"`
+}
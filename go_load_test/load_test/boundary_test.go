@@ -0,0 +1,57 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBoundarySizes(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *BoundarySizes
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"buffer=64KB max=100MB", &BoundarySizes{Buffer: 64 << 10, Max: 100 << 20}, false},
+		{"max=1GB", &BoundarySizes{Max: 1 << 30}, false},
+		{"buffer=-1", nil, true},
+		{"buffer=lots", nil, true},
+		{"limit=1MB", nil, true},
+		{"buffer", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseBoundarySizes(test.spec)
+		if !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("ParseBoundarySizes(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestBoundaryCases(t *testing.T) {
+	tests := []struct {
+		sizes *BoundarySizes
+		want  []boundaryCase
+	}{
+		{nil, []boundaryCase{{name: "0"}, {name: "1", size: 1}}},
+		{&BoundarySizes{Buffer: 64, Max: 1024}, []boundaryCase{{name: "0"}, {name: "1", size: 1},
+			{name: "buffer-1", size: 63}, {name: "buffer", size: 64}, {name: "buffer+1", size: 65},
+			{name: "max", size: 1024}, {name: "max+1", size: 1025, reject: true}}},
+		{&BoundarySizes{Max: 1024}, []boundaryCase{{name: "0"}, {name: "1", size: 1}, {name: "max", size: 1024},
+			{name: "max+1", size: 1025, reject: true}}},
+	}
+	for _, test := range tests {
+		if got := boundaryCases(test.sizes); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("boundaryCases(%+v): got %+v, want %+v", test.sizes, got, test.want)
+		}
+	}
+}
+
+func TestBoundaryPayload(t *testing.T) {
+	for _, size := range []int64{0, 1, 2, 3, 4, 63, 64, 65, 1025} {
+		payload, err := (&TestExecutor{}).boundaryPayload(NewRand(1), size)
+		if err != nil || int64(len(payload)) != size {
+			t.Errorf("size %d: got %d bytes, %v", size, len(payload), err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package load_test
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	BurstStageBurst = "burst"
+	BurstStageIdle  = "idle"
+)
+
+// Burst alternates between idle periods + short bursts, releasing Size tests all at once every Every rather than
+// pacing them over the second, to test how the server queues + accepts connections under bursty rather than smooth
+// load. Every test in a burst is scheduled for the instant the burst starts, so schedule lag shows how long the
+// load generator itself took to get the burst out. I.E
+//
+//	burst size=500 every=10s
+type Burst struct {
+	Size  int
+	Every time.Duration
+}
+
+func newBurst(params profileParams) (LoadProfile, error) {
+	burst := &Burst{}
+	err := params.parse(map[string]interface{}{"size": &burst.Size, "every": &burst.Every}, "size", "every")
+	if err != nil {
+		return nil, fmt.Errorf("invalid burst profile: %w", err)
+	}
+	if burst.Size <= 0 || burst.Every <= 0 {
+		return nil, fmt.Errorf("invalid burst profile: size + every must be > 0")
+	}
+
+	return burst, nil
+}
+
+// Rate returns Size for the second each burst starts in, + 0 while idle. The scheduler releases bursts all at once
+// rather than by rate, this is what the rest of the run (I.E dry runs) sees.
+func (b *Burst) Rate(elapsed time.Duration) int {
+	if b.Stage(elapsed) == BurstStageBurst {
+		return b.Size
+	}
+
+	return 0
+}
+
+func (b *Burst) Stage(elapsed time.Duration) string {
+	if elapsed%b.Every < time.Second {
+		return BurstStageBurst
+	}
+
+	return BurstStageIdle
+}
+
+// scheduleBurst releases burst's next burst if it's due, or waits a moment for it. Bursts missed while the scheduler
+// was held up are skipped rather than released back to back.
+func (ts *TestScheduler) scheduleBurst(burst *Burst) {
+	elapsed := ts.profileElapsed()
+	due := time.Duration(ts.bursts) * burst.Every
+	if elapsed < due {
+		wait := due - elapsed
+		if wait > time.Millisecond*10 {
+			wait = time.Millisecond * 10
+		}
+		time.Sleep(wait)
+		return
+	}
+
+	ts.bursts = int(elapsed/burst.Every) + 1
+	scheduledAt := time.Now()
+	for i := 0; i < burst.Size && !ts.limitReached(); i++ {
+		test := ts.GetTestFunc()
+		test.scheduledAt = scheduledAt
+		if !ts.admit(test) {
+			continue
+		}
+
+		select {
+		case ts.cfg.SchedulerChan <- test:
+			ts.totalScheduled++
+		case <-ts.cfg.ShutdownChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package load_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Chaos makes the load test a misbehaving client, to verify the server + the consistency checker behave sanely when
+// requests are dropped, uploads are cut off or downloads are abandoned part way through. Faults are injected on
+// purpose, so results they break are counted as injected faults rather than as errors. A consistency check whose
+// upload is truncated also checks the server didn't keep the partial file. Specs are key=value params, with faults
+// given as a fraction or a percentage, + an optional window of the run to inject faults in, I.E
+//
+//	drop=5% truncate=2% abort=1% after=1m for=5m
+type Chaos struct {
+	Drop     float64       // Fraction of requests dropped before they're sent
+	Truncate float64       // Fraction of uploads whose body is cut off half way through
+	Abort    float64       // Fraction of downloads whose connection is closed half way through the body
+	After    time.Duration // Faults are only injected once the run has been going this long
+	For      time.Duration // If > 0, faults stop being injected this long after After
+}
+
+type ChaosFault string
+
+const (
+	ChaosDrop     ChaosFault = "drop"
+	ChaosTruncate ChaosFault = "truncate"
+	ChaosAbort    ChaosFault = "abort"
+)
+
+// ChaosError is returned for a request broken by an injected fault.
+type ChaosError struct {
+	Fault ChaosFault
+	Err   error // Underlying request error, if the fault caused one
+}
+
+func (e ChaosError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("injected %s fault: %s", e.Fault, e.Err)
+	}
+
+	return fmt.Sprintf("injected %s fault", e.Fault)
+}
+
+func (e ChaosError) Unwrap() error {
+	return e.Err
+}
+
+var errUploadTruncated = errors.New("upload truncated")
+
+// chaosFault returns the fault that broke a request with err, or "" if it wasn't broken on purpose.
+func chaosFault(err error) ChaosFault {
+	var chaosErr ChaosError
+	if errors.As(err, &chaosErr) {
+		return chaosErr.Fault
+	}
+
+	return ""
+}
+
+// ParseChaos parses a chaos spec. An empty spec returns nil, no faults are injected.
+func ParseChaos(spec string) (*Chaos, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	chaos := &Chaos{}
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid chaos param: %s. Expected key=value", field)
+		}
+
+		var err error
+		switch key {
+		case "drop":
+			chaos.Drop, err = parseFraction(value)
+		case "truncate":
+			chaos.Truncate, err = parseFraction(value)
+		case "abort":
+			chaos.Abort, err = parseFraction(value)
+		case "after":
+			chaos.After, err = time.ParseDuration(value)
+		case "for":
+			chaos.For, err = time.ParseDuration(value)
+		default:
+			return nil, fmt.Errorf("unknown chaos param: %s. Expected drop, truncate, abort, after or for", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid chaos param: %s. Error: %w", field, err)
+		}
+	}
+	if chaos.Drop == 0 && chaos.Truncate == 0 && chaos.Abort == 0 {
+		return nil, fmt.Errorf("invalid chaos spec: at least one of drop, truncate or abort must be > 0")
+	}
+
+	return chaos, nil
+}
+
+func (c *Chaos) String() string {
+	window := ""
+	if c.After > 0 || c.For > 0 {
+		window = fmt.Sprintf(" from %s", c.After)
+		if c.For > 0 {
+			window += fmt.Sprintf(" for %s", c.For)
+		}
+	}
+
+	return fmt.Sprintf("drop %.1f%%, truncate %.1f%%, abort %.1f%%%s", c.Drop*100, c.Truncate*100, c.Abort*100, window)
+}
+
+// chaosInjector injects a run's faults, timing the window from when it was created. A nil chaosInjector never
+// injects a fault.
+type chaosInjector struct {
+	Chaos
+	started time.Time
+}
+
+func newChaosInjector(chaos *Chaos) *chaosInjector {
+	if chaos == nil {
+		return nil
+	}
+
+	return &chaosInjector{Chaos: *chaos, started: time.Now()}
+}
+
+func (c *chaosInjector) active() bool {
+	elapsed := time.Now().Sub(c.started)
+	return elapsed >= c.After && (c.For <= 0 || elapsed < c.After+c.For)
+}
+
+// pick returns the fault to inject into req, or "" to send it as normal.
+func (c *chaosInjector) pick(req *http.Request) ChaosFault {
+	if c == nil || !c.active() {
+		return ""
+	}
+
+	switch {
+	case rand.Float64() < c.Drop:
+		return ChaosDrop
+	case req.Method == http.MethodPut && req.ContentLength > 1 && rand.Float64() < c.Truncate:
+		return ChaosTruncate
+	case req.Method == http.MethodGet && rand.Float64() < c.Abort:
+		return ChaosAbort
+	}
+
+	return ""
+}
+
+// truncate makes req's body fail half way through, so the connection is dropped mid upload.
+func truncate(req *http.Request) {
+	req.Body = &truncatedBody{body: req.Body, remaining: req.ContentLength / 2}
+	// The transport mustn't replay the body in full.
+	req.GetBody = nil
+}
+
+type truncatedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, errUploadTruncated
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.body.Close()
+}
+
+// abortDownload reads half of response's body then closes it, dropping the connection mid download.
+func abortDownload(response *http.Response) {
+	if response.ContentLength > 0 {
+		_, _ = io.CopyN(io.Discard, response.Body, response.ContentLength/2)
+	}
+	_ = response.Body.Close()
+}
+
+type ChaosSummary struct {
+	Dropped   int `json:"dropped"`
+	Truncated int `json:"truncated"`
+	Aborted   int `json:"aborted"`
+}
+
+// Total returns the # of injected faults, 0 for a nil summary.
+func (s *ChaosSummary) Total() int {
+	if s == nil {
+		return 0
+	}
+
+	return s.Dropped + s.Truncated + s.Aborted
+}
+
+// chaosSummary returns the # of each fault injected, or nil if none were.
+func (tr *TestResults) chaosSummary() *ChaosSummary {
+	summary := &ChaosSummary{Dropped: tr.numDropped.Get(), Truncated: tr.numTruncated.Get(), Aborted: tr.numAborted.Get()}
+	if summary.Total() == 0 {
+		return nil
+	}
+
+	return summary
+}
@@ -0,0 +1,97 @@
+package load_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChaos(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *Chaos
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"drop=5% truncate=0.02 abort=1% after=1m for=5m",
+			&Chaos{Drop: 0.05, Truncate: 0.02, Abort: 0.01, After: time.Minute, For: 5 * time.Minute}, false},
+		{"abort=100%", &Chaos{Abort: 1}, false},
+		{"after=1m", nil, true}, // No faults
+		{"drop=0", nil, true},
+		{"drop=200%", nil, true},
+		{"drop", nil, true},
+		{"delay=5%", nil, true},
+		{"drop=5% for=soon", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseChaos(test.spec)
+		if (err != nil) != test.wantErr || (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("ParseChaos(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestChaosPick(t *testing.T) {
+	put := func(size int) *http.Request {
+		req, _ := http.NewRequest(http.MethodPut, "http://localhost/f", bytes.NewReader(make([]byte, size)))
+		return req
+	}
+	get, _ := http.NewRequest(http.MethodGet, "http://localhost/f", nil)
+	del, _ := http.NewRequest(http.MethodDelete, "http://localhost/f", nil)
+
+	tests := []struct {
+		name  string
+		chaos *Chaos
+		req   *http.Request
+		want  ChaosFault
+	}{
+		{"nil", nil, get, ""},
+		{"drop", &Chaos{Drop: 1, Truncate: 1}, put(10), ChaosDrop},
+		{"truncate", &Chaos{Truncate: 1, Abort: 1}, put(10), ChaosTruncate},
+		{"truncate too small", &Chaos{Truncate: 1}, put(1), ""},
+		{"abort", &Chaos{Truncate: 1, Abort: 1}, get, ChaosAbort},
+		{"no fault for method", &Chaos{Truncate: 1, Abort: 1}, del, ""},
+		{"before window", &Chaos{Drop: 1, After: time.Hour}, get, ""},
+		{"after window", &Chaos{Drop: 1, After: -2 * time.Hour, For: time.Hour}, get, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := newChaosInjector(test.chaos).pick(test.req); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestChaosPickRate(t *testing.T) {
+	injector := newChaosInjector(&Chaos{Drop: 0.1})
+	get, _ := http.NewRequest(http.MethodGet, "http://localhost/f", nil)
+	dropped := 0
+	for i := 0; i < 100000; i++ {
+		if injector.pick(get) == ChaosDrop {
+			dropped++
+		}
+	}
+	if dropped < 9500 || dropped > 10500 {
+		t.Errorf("got %d drops in 100000, want ~10000", dropped)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPut, "http://localhost/f", strings.NewReader("0123456789"))
+	truncate(req)
+	sent, err := io.ReadAll(req.Body)
+	if string(sent) != "01234" || !errors.Is(err, errUploadTruncated) {
+		t.Errorf("got %q, %v, want the first half then %v", sent, err, errUploadTruncated)
+	}
+
+	err = ChaosError{Fault: ChaosTruncate, Err: fmt.Errorf("put: %w", err)}
+	if chaosFault(fmt.Errorf("test: %w", err)) != ChaosTruncate || chaosFault(io.EOF) != "" {
+		t.Errorf("injected fault not found through wrapping")
+	}
+}
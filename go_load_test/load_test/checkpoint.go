@@ -0,0 +1,297 @@
+package load_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Periodically saves cumulative results to disk, so a crashed or restarted soak test can resume reporting where it
+// left off rather than from zero. Per interval "current" rates, recent errors, recent latency and per worker stats
+// describe the live process only, and start fresh on resume.
+
+type histogramCheckpoint struct {
+	Buckets map[int]int64 `json:"buckets"` // Bucket index -> count, empty buckets omitted
+	Count   int64         `json:"count"`
+	Sum     time.Duration `json:"sum"`
+	Min     time.Duration `json:"min"`
+	Max     time.Duration `json:"max"`
+}
+
+type targetCheckpoint struct {
+	Stats   TargetStats         `json:"stats"`
+	Latency histogramCheckpoint `json:"latency"`
+}
+
+type Checkpoint struct {
+	RunID                       string                               `json:"run_id"`
+	SavedAt                     time.Time                            `json:"saved_at"`
+	Elapsed                     time.Duration                        `json:"elapsed"`
+	Counters                    map[string]int64                     `json:"counters"`
+	Durations                   map[TestType]time.Duration           `json:"durations"` // Total duration per operation
+	MaxSuccessfulRequestsPerSec int                                  `json:"max_successful_requests_per_sec"`
+	ReusedConnections           int                                  `json:"reused_connections"`
+	NewConnections              int                                  `json:"new_connections"`
+	StatusCodes                 map[int]int                          `json:"status_codes"`
+	Latency                     histogramCheckpoint                  `json:"latency"`
+	Operations                  map[TestType]histogramCheckpoint     `json:"operations"`
+	Phases                      map[RequestPhase]histogramCheckpoint `json:"phases"`
+	StatusClasses               map[StatusClass]histogramCheckpoint  `json:"status_classes"`
+	ScheduleLag                 histogramCheckpoint                  `json:"schedule_lag"`
+	Warmup                      histogramCheckpoint                  `json:"warmup"`
+	Targets                     map[string]targetCheckpoint          `json:"targets,omitempty"`
+	Apdex                       Apdex                                `json:"apdex"`
+	ConsistencyFailures         ConsistencyFailures                  `json:"consistency_failures"`
+	ErrorGroups                 map[string]ErrorGroup                `json:"error_groups"`
+	History                     []IntervalStats                      `json:"history"` // Bounded, see appendHistory
+}
+
+func newHistogramCheckpoint(h *LatencyHistogram) histogramCheckpoint {
+	buckets := make(map[int]int64)
+	for i, c := range h.counts {
+		if c > 0 {
+			buckets[i] = c
+		}
+	}
+
+	return histogramCheckpoint{Buckets: buckets, Count: h.count, Sum: h.sum, Min: h.min, Max: h.max}
+}
+
+func (c histogramCheckpoint) restore(h *LatencyHistogram) {
+	h.Reset()
+	for i, count := range c.Buckets {
+		if i >= 0 && i < histogramBucketCount {
+			h.counts[i] = count
+		}
+	}
+	h.count = c.Count
+	h.sum = c.Sum
+	h.min = c.Min
+	h.max = c.Max
+}
+
+// checkpointCounters names every cumulative counter that is saved in a checkpoint.
+func (tr *TestResults) checkpointCounters() map[string]*Counter {
+	counters := map[string]*Counter{
+		"requests":            &tr.numRequests,
+		"successes":           &tr.numSuccess,
+		"failures":            &tr.numFailure,
+		"gets":                &tr.numGet,
+		"puts":                &tr.numPut,
+		"deletes":             &tr.numDelete,
+		"consistency":         &tr.numConsistency,
+		"consistency_failure": &tr.numFailedConsistency,
+		"throttled":           &tr.numThrottled,
+		"self_throttled":      &tr.numSelfThrottled,
+		"chaos_dropped":       &tr.numDropped,
+		"chaos_truncated":     &tr.numTruncated,
+		"chaos_aborted":       &tr.numAborted,
+		"5xx":                 &tr.num500s,
+		"bytes_uploaded":      &tr.bytesUploaded,
+		"bytes_downloaded":    &tr.bytesDownloaded,
+	}
+	for op, counter := range tr.numTimeouts {
+		counters["timeouts_"+strings.ToLower(string(op))] = counter
+	}
+
+	return counters
+}
+
+// Checkpoint captures the cumulative results so far.
+func (tr *TestResults) Checkpoint(runID string) Checkpoint {
+	tr.resultLock.RLock()
+	defer tr.resultLock.RUnlock()
+
+	cp := Checkpoint{
+		RunID:    runID,
+		SavedAt:  time.Now(),
+		Elapsed:  tr.elapsed(),
+		Counters: make(map[string]int64),
+		Durations: map[TestType]time.Duration{
+			GET:         tr.totalGetDuration,
+			PUT:         tr.totalPutDuration,
+			DELETE:      tr.totalDeleteDuration,
+			CONSISTENCY: tr.totalConsistencyDuration,
+		},
+		MaxSuccessfulRequestsPerSec: tr.maxSeenSuccessfulRequestPerSec,
+		ReusedConnections:           tr.numReusedConns,
+		NewConnections:              tr.numNewConns,
+		StatusCodes:                 make(map[int]int, len(tr.statusCodes)),
+		Latency:                     newHistogramCheckpoint(tr.latency),
+		Operations:                  make(map[TestType]histogramCheckpoint, len(latencyOperations)),
+		Phases:                      make(map[RequestPhase]histogramCheckpoint, len(requestPhases)),
+		StatusClasses:               make(map[StatusClass]histogramCheckpoint, len(statusClasses)),
+		ScheduleLag:                 newHistogramCheckpoint(tr.scheduleLag),
+		Warmup:                      newHistogramCheckpoint(tr.warmupLatency),
+		Apdex:                       tr.apdex,
+		ConsistencyFailures:         tr.checkFailures.Copy(),
+		ErrorGroups:                 make(map[string]ErrorGroup, len(tr.errorGroups)),
+		History:                     append([]IntervalStats(nil), tr.history...),
+	}
+	for name, counter := range tr.checkpointCounters() {
+		cp.Counters[name] = counter.Load()
+	}
+	for code, count := range tr.statusCodes {
+		cp.StatusCodes[code] = count
+	}
+	for _, op := range latencyOperations {
+		cp.Operations[op] = newHistogramCheckpoint(tr.opLatency[op])
+	}
+	for _, phase := range requestPhases {
+		cp.Phases[phase] = newHistogramCheckpoint(tr.phaseLatency[phase])
+	}
+	for _, class := range statusClasses {
+		cp.StatusClasses[class] = newHistogramCheckpoint(tr.statusLatency[class])
+	}
+	for key, group := range tr.errorGroups {
+		cp.ErrorGroups[key] = *group
+	}
+	if tr.targetStats != nil {
+		cp.Targets = make(map[string]targetCheckpoint, len(tr.targetStats))
+		for label, target := range tr.targetStats {
+			cp.Targets[label] = targetCheckpoint{Stats: *target, Latency: newHistogramCheckpoint(target.latency)}
+		}
+	}
+
+	return cp
+}
+
+// Restore replaces the cumulative results with those from cp. The run is treated as having started cp.Elapsed ago.
+// Must be called before the aggregator starts running.
+func (tr *TestResults) Restore(cp Checkpoint) {
+	tr.resultLock.Lock()
+	defer tr.resultLock.Unlock()
+
+	tr.startTime = time.Now().Add(-cp.Elapsed)
+	for name, counter := range tr.checkpointCounters() {
+		counter.Store(cp.Counters[name])
+	}
+	tr.totalGetDuration = cp.Durations[GET]
+	tr.totalPutDuration = cp.Durations[PUT]
+	tr.totalDeleteDuration = cp.Durations[DELETE]
+	tr.totalConsistencyDuration = cp.Durations[CONSISTENCY]
+	tr.maxSeenSuccessfulRequestPerSec = cp.MaxSuccessfulRequestsPerSec
+	tr.numReusedConns = cp.ReusedConnections
+	tr.numNewConns = cp.NewConnections
+	tr.statusCodes = make(map[int]int, len(cp.StatusCodes))
+	for code, count := range cp.StatusCodes {
+		tr.statusCodes[code] = count
+	}
+	cp.Latency.restore(tr.latency)
+	for _, op := range latencyOperations {
+		cp.Operations[op].restore(tr.opLatency[op])
+	}
+	for _, phase := range requestPhases {
+		cp.Phases[phase].restore(tr.phaseLatency[phase])
+	}
+	for _, class := range statusClasses {
+		cp.StatusClasses[class].restore(tr.statusLatency[class])
+	}
+	cp.ScheduleLag.restore(tr.scheduleLag)
+	cp.Warmup.restore(tr.warmupLatency)
+
+	// Keep the configured target, only the counts carry over.
+	target := tr.apdex.Target
+	tr.apdex = cp.Apdex
+	tr.apdex.Target = target
+	tr.apdex.TargetMs = durationMs(target)
+
+	tr.checkFailures = make(ConsistencyFailures)
+	if cp.ConsistencyFailures != nil {
+		tr.checkFailures = cp.ConsistencyFailures.Copy()
+	}
+	tr.errorGroups = make(ErrorGroups, len(cp.ErrorGroups))
+	for key, group := range cp.ErrorGroups {
+		group := group
+		tr.errorGroups[key] = &group
+	}
+	tr.history = boundHistory(cp.History)
+	// Targets that are no longer configured are dropped.
+	for label, target := range tr.targetStats {
+		saved, ok := cp.Targets[label]
+		if !ok {
+			continue
+		}
+		latency := target.latency
+		*target = saved.Stats
+		target.latency = latency
+		saved.Latency.restore(target.latency)
+	}
+}
+
+// WriteCheckpoint saves a checkpoint to path. The file is replaced atomically, so a crash mid write leaves the
+// previous checkpoint intact.
+func (tr *TestResults) WriteCheckpoint(path string, runID string) error {
+	data, err := json.Marshal(tr.Checkpoint(runID))
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint. Error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %s. Error: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %s. Error: %w", path, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %s. Error: %w", path, err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %s. Error: %w", path, err)
+	}
+
+	return nil
+}
+
+// LoadCheckpoint restores results from the checkpoint at path. Returns an error wrapping os.ErrNotExist if there is
+// no checkpoint yet.
+func (tr *TestResults) LoadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cp, fmt.Errorf("no checkpoint at: %s. Error: %w", path, err)
+		}
+		return cp, fmt.Errorf("failed to read checkpoint: %s. Error: %w", path, err)
+	}
+
+	if err = json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("failed to decode checkpoint: %s. Error: %w", path, err)
+	}
+	tr.Restore(cp)
+
+	return cp, nil
+}
+
+// runCheckpoints writes a checkpoint every cfg.CheckpointEvery, and once more when stop is closed.
+func (ra *ResultAggregator) runCheckpoints(stop <-chan struct{}) {
+	interval := ra.cfg.CheckpointEvery
+	if interval <= 0 {
+		interval = CheckpointInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			if err := ra.Results.WriteCheckpoint(ra.cfg.CheckpointPath, ra.cfg.RunID); err != nil {
+				log.Errorf("Failed to write final checkpoint: %+v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := ra.Results.WriteCheckpoint(ra.cfg.CheckpointPath, ra.cfg.RunID); err != nil {
+				log.Errorf("Failed to write checkpoint: %+v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package load_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"sync"
+)
+
+// ChecksumStore remembers the SHA-256 of every payload the run uploads, so every GET of the file can be checked for
+// corruption. A GET returning different bytes than the last successful upload is reported as a corrupt download, a
+// failure class of its own rather than an HTTP error. Checksums are forgotten once the file is deleted, or if an upload
+// fails + the content is unknown. GETs that overlap an upload or delete of the same file aren't checked, the content
+// they should return is ambiguous. Only checksums are kept, so every file can be checked however large the run gets.
+type ChecksumStore struct {
+	lock sync.RWMutex
+	sums map[string][sha256.Size]byte
+}
+
+func NewChecksumStore() *ChecksumStore {
+	return &ChecksumStore{sums: make(map[string][sha256.Size]byte)}
+}
+
+// record remembers payload's checksum as fileName's if the upload succeeded, otherwise forgets it. A nil
+// ChecksumStore remembers nothing, + doesn't hash the payload.
+func (s *ChecksumStore) record(fileName string, payload string, response *http.Response, err error) {
+	if s == nil {
+		return
+	}
+
+	s.recordSum(fileName, sha256.Sum256([]byte(payload)), response, err)
+}
+
+// recordSum is record for payloads hashed as they're sent, see StreamFile.
+func (s *ChecksumStore) recordSum(fileName string, sum [sha256.Size]byte, response *http.Response, err error) {
+	if s == nil {
+		return
+	}
+	if err != nil || response == nil || response.StatusCode >= 400 {
+		s.forget(fileName)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sums[fileName] = sum
+}
+
+func (s *ChecksumStore) forget(fileName string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.sums, fileName)
+}
+
+// expected returns fileName's checksum, or false if it isn't known.
+func (s *ChecksumStore) expected(fileName string) ([sha256.Size]byte, bool) {
+	if s == nil {
+		return [sha256.Size]byte{}, false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	sum, ok := s.sums[fileName]
+
+	return sum, ok
+}
+
+// checksumCheck is the checksum a GET's body should have, taken before the GET is sent.
+type checksumCheck struct {
+	sum     [sha256.Size]byte
+	checked bool // False if the checksum isn't known or the file was being written
+}
+
+// startChecksumCheck returns the checksum a GET of fileName should return, if it can be checked.
+func (tr *TestExecutor) startChecksumCheck(fileName string) checksumCheck {
+	sum, ok := tr.checksums.expected(fileName)
+	return checksumCheck{sum: sum, checked: ok && !tr.isInProcess(fileName)}
+}
+
+// checksumStatus is the outcome of checking a GET's body against the checksum of the last upload.
+type checksumStatus int
+
+const (
+	checksumUnchecked checksumStatus = iota // The checksum isn't known, or the file was written or deleted during the GET
+	checksumVerified
+	checksumCorrupt
+)
+
+// verifyChecksum checks body against the checksum taken before the GET, unless the file was written or deleted since,
+// in which case it can't be checked.
+func (tr *TestExecutor) verifyChecksum(check checksumCheck, fileName string, body string) checksumStatus {
+	if !check.checked || tr.isInProcess(fileName) {
+		return checksumUnchecked
+	}
+	if sum, ok := tr.checksums.expected(fileName); !ok || sum != check.sum {
+		return checksumUnchecked
+	}
+	if sha256.Sum256([]byte(body)) != check.sum {
+		return checksumCorrupt
+	}
+
+	return checksumVerified
+}
+
+func (tr *TestExecutor) isInProcess(fileName string) bool {
+	tr.inProcessLock.RLock()
+	defer tr.inProcessLock.RUnlock()
+
+	return tr.inProcess.Has(fileName)
+}
+
+// ChecksumSummary counts GETs checked against the checksum of the last upload, + those returning corrupt data.
+type ChecksumSummary struct {
+	Verified int `json:"verified"`
+	Corrupt  int `json:"corrupt"`
+}
+
+// checksumSummary returns nil unless checksums are verified.
+func (tr *TestResults) checksumSummary() *ChecksumSummary {
+	if !tr.verifyChecksums {
+		return nil
+	}
+
+	return &ChecksumSummary{Verified: tr.numVerified.Get(), Corrupt: tr.numCorrupt.Get()}
+}
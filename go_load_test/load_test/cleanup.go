@@ -0,0 +1,206 @@
+package load_test
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cleanup deletes every file the run may have left on the server once it finishes, so repeated runs don't fill the
+// server's disk. The aggregator tracks keys from results: successful writes + writes with an unknown outcome add a key,
+// deletes + 404s remove it, + pre-seeded files are included. Deletes that fail are retried with a doubling backoff,
+// + keys still left after every retry are reported as leftovers.
+type Cleanup struct {
+	Workers int           // # of deletes in flight at once
+	Retries int           // Extra attempts per file after a failed delete
+	Backoff time.Duration // Wait before the first retry, doubled for each one after
+}
+
+type CleanupReport struct {
+	Files           int      `json:"files"`
+	Deleted         int      `json:"deleted"`
+	Missing         int      `json:"missing"` // Already gone, I.E deleted by a test whose result was lost
+	Retries         int      `json:"retries"`
+	Leftovers       []string `json:"leftovers"`
+	DurationSeconds float64  `json:"duration_seconds"`
+}
+
+func (r CleanupReport) String() string {
+	report := fmt.Sprintf("Cleanup deleted %d of %d files in %.1f seconds (%d already gone, %d retries).", r.Deleted,
+		r.Files, r.DurationSeconds, r.Missing, r.Retries)
+	if len(r.Leftovers) > 0 {
+		report += fmt.Sprintf(" %d files could not be deleted.", len(r.Leftovers))
+	}
+
+	return report
+}
+
+// Run deletes keys against the run's targets. Keys not deleted before ctx is cancelled are leftovers.
+func (c Cleanup) Run(ctx context.Context, cfg TestSchedulerConfig, keys []string) CleanupReport {
+	exec := NewTestExecutor(ctx, newHTTPClient(cfg.TestConfig.Timeouts), resolveTargets(cfg.EndpointCfg, cfg.Targets), cfg.TestConfig, cfg.Stores, nil)
+	report := CleanupReport{Files: len(keys)}
+	log.Infof("Cleaning up %d files with %d workers", len(keys), c.Workers)
+	fmt.Printf("Cleaning up %d files...\n", len(keys))
+
+	started := time.Now()
+	var lock sync.Mutex
+	var deleting sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < Max(c.Workers, 1); i++ {
+		deleting.Add(1)
+		go func() {
+			defer deleting.Done()
+			for key := range queue {
+				status, retries := c.delete(ctx, exec, key)
+				lock.Lock()
+				report.Retries += retries
+				switch status {
+				case http.StatusNotFound:
+					report.Missing++
+				case http.StatusOK:
+					report.Deleted++
+				default:
+					report.Leftovers = append(report.Leftovers, key)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		queue <- key
+	}
+	close(queue)
+	deleting.Wait()
+	sort.Strings(report.Leftovers)
+	report.DurationSeconds = time.Now().Sub(started).Seconds()
+	log.Info(report)
+
+	return report
+}
+
+// delete removes key, retrying failures. Returns http.StatusOK if deleted, http.StatusNotFound if already gone, or the
+// last status (0 for a request error) if every attempt failed, + the # of retries it took.
+func (c Cleanup) delete(ctx context.Context, exec *TestExecutor, key string) (int, int) {
+	backoff := c.Backoff
+	status := 0
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return status, attempt - 1
+			}
+			backoff *= 2
+		}
+
+		var err error
+		status, err = exec.removeFile(key)
+		if err != nil {
+			log.Warnf("Failed to clean up file: %s, attempt %d. Error: %+v", key, attempt+1, err)
+			continue
+		}
+		if status == http.StatusNotFound || (status >= 200 && status < 300) {
+			if status != http.StatusNotFound {
+				status = http.StatusOK
+			}
+			return status, attempt
+		}
+		log.Warnf("Failed to clean up file: %s, attempt %d. Status: %d", key, attempt+1, status)
+	}
+
+	return status, c.Retries
+}
+
+// WriteLeftovers writes one leftover key per line to path, so they can be removed by hand.
+func (r CleanupReport) WriteLeftovers(path string) error {
+	err := os.WriteFile(path, []byte(strings.Join(r.Leftovers, "\n")+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write cleanup leftovers: %s. Error: %w", path, err)
+	}
+
+	return nil
+}
+
+// removeFile deletes fileName without reporting a result, returning the response status.
+func (tr *TestExecutor) removeFile(fileName string) (int, error) {
+	return tr.removeURL(tr.buildPath(fileName))
+}
+
+// removeURL is removeFile for a file's already built URL, see FUZZ.
+func (tr *TestExecutor) removeURL(fileURL string) (int, error) {
+	ctx, cancel := tr.testContext(DELETE)
+	defer cancel()
+	req, err := tr.newURLRequest(ctx, http.MethodDelete, fileURL, nil, traceContext{}, NewRequestPhases())
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize request. Error: %w", err)
+	}
+
+	response, err := tr.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_ = responseToString(response)
+
+	return response.StatusCode, nil
+}
+
+// newCreatedFiles returns the set of files to track for cleanup, starting with pre-seeded files, or nil if untracked.
+func newCreatedFiles(cfg TestSchedulerConfig) FileSet {
+	if !cfg.TrackCreated {
+		return nil
+	}
+
+	created := make(FileSet, len(cfg.Preseeded))
+	for _, key := range cfg.Preseeded {
+		created.Add(key)
+	}
+
+	return created
+}
+
+// trackCreated keeps the set of files the run may have left on the server up to date with result. A write that
+// didn't get a clear rejection may have happened, so it's tracked too. Caller must hold resultLock.
+func (tr *TestResults) trackCreated(result TestResult) {
+	if tr.created == nil || result.WasSelfThrottled() {
+		return
+	}
+
+	code := result.StatusCode()
+	switch result.TestType() {
+	case PUT, CREATE, STREAM:
+		if code < 400 || code >= 500 {
+			tr.created.Add(result.FileName())
+		}
+	case DELETE:
+		if result.WasSuccess() || result.Was404() {
+			tr.created.Delete(result.FileName())
+		}
+	case CONSISTENCY:
+		// A check deletes its file as its last step, so one that failed or was broken by chaos may have left it behind.
+		if result.WasTestFailure() || result.InjectedFault() != "" {
+			tr.created.Add(result.FileName())
+		}
+	}
+}
+
+// CreatedKeys returns the files the run may have left on the server, sorted. Only tracked with cleanup enabled.
+func (tr *TestResults) CreatedKeys() []string {
+	tr.resultLock.RLock()
+	defer tr.resultLock.RUnlock()
+
+	keys := make([]string, 0, len(tr.created))
+	for key := range tr.created {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
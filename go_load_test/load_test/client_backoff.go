@@ -0,0 +1,69 @@
+package load_test
+
+import (
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// ClientBackoff makes the load test a well behaved client: once the server throttles a request with a 429 +
+// Retry-After, no new tests start until the advertised retry time. The server throttles globally, so the backoff is
+// shared by every worker. Throttled responses the client backs off from are counted as self throttled rather than as
+// errors. Safe for concurrent use, + a nil ClientBackoff never backs off.
+type ClientBackoff struct {
+	lock  sync.Mutex
+	until time.Time // Latest time the server asked the client to wait until
+}
+
+func NewClientBackoff() *ClientBackoff {
+	return &ClientBackoff{}
+}
+
+// observe extends the backoff by result's Retry-After. Returns true if result is a 429 the client backs off from.
+func (b *ClientBackoff) observe(result TestResult) bool {
+	if b == nil || !result.WasThrottled() {
+		return false
+	}
+
+	receivedAt := time.Now()
+	backoff, ok := parseRetryAfter(result.response.Header.Get("Retry-After"), receivedAt)
+	if !ok {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if retryAt := receivedAt.Add(backoff); retryAt.After(b.until) {
+		b.until = retryAt
+		log.Debugf("Throttled, backing off for %s.", backoff)
+	}
+
+	return true
+}
+
+// Wait blocks until the backoff has elapsed, or until shutdown. Returns true if it had to wait.
+func (b *ClientBackoff) Wait(shutdown chan bool) bool {
+	if b == nil {
+		return false
+	}
+
+	waited := false
+	for {
+		b.lock.Lock()
+		remaining := time.Until(b.until)
+		b.lock.Unlock()
+		if remaining <= 0 {
+			return waited
+		}
+
+		// The backoff may be extended while waiting, so check again once it's up.
+		waited = true
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+		case <-shutdown:
+			timer.Stop()
+			return waited
+		}
+	}
+}
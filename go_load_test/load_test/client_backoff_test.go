@@ -0,0 +1,53 @@
+package load_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientBackoff(t *testing.T) {
+	result := func(code int, retryAfter string) TestResult {
+		response := &http.Response{StatusCode: code, Header: http.Header{}}
+		if retryAfter != "" {
+			response.Header.Set("Retry-After", retryAfter)
+		}
+		return TestResult{response: response}
+	}
+
+	tests := []struct {
+		name   string
+		result TestResult
+		want   bool
+	}{
+		{"success", result(http.StatusOK, "5"), false},
+		{"503", result(http.StatusServiceUnavailable, "5"), false},
+		{"429 without Retry-After", result(http.StatusTooManyRequests, ""), false},
+		{"429", result(http.StatusTooManyRequests, "0"), true},
+	}
+	for _, test := range tests {
+		backoff := NewClientBackoff()
+		if got := backoff.observe(test.result); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.name, got, test.want)
+		}
+		if backoff.Wait(make(chan bool)) {
+			t.Errorf("%s: waited without a backoff", test.name)
+		}
+	}
+
+	backoff := NewClientBackoff()
+	backoff.observe(result(http.StatusTooManyRequests, "60"))
+	// A shorter Retry-After doesn't cut the backoff short.
+	backoff.observe(result(http.StatusTooManyRequests, "0"))
+	if until := time.Until(backoff.until); until < 59*time.Second {
+		t.Errorf("got %s of backoff left, want ~60s", until)
+	}
+	shutdown := make(chan bool)
+	close(shutdown)
+	if !backoff.Wait(shutdown) {
+		t.Errorf("didn't wait out the backoff")
+	}
+	if (*ClientBackoff)(nil).observe(result(http.StatusTooManyRequests, "60")) || (*ClientBackoff)(nil).Wait(shutdown) {
+		t.Errorf("nil backoff backed off")
+	}
+}
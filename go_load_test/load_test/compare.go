@@ -0,0 +1,144 @@
+package load_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+	"math"
+	"os"
+)
+
+// Compares two saved run summaries (see WriteSummary) to detect performance regressions between server builds.
+
+// RegressionThresholds - how much worse the candidate run may be than the baseline before it's flagged.
+type RegressionThresholds struct {
+	MaxThroughputDropPct          float64 // % drop in avg + max successful req/sec
+	MaxP99IncreasePct             float64 // % increase in p99 latency
+	MaxErrorRateIncrease          float64 // Increase in error rate, in percentage points
+	MaxConsistencyFailureIncrease int     // Increase in # of consistency failures
+}
+
+type Comparison struct {
+	Metric    string
+	Baseline  float64
+	Candidate float64
+	Delta     string
+	Regressed bool
+}
+
+// LoadRunSummary reads a JSON summary previously written by WriteSummary.
+func LoadRunSummary(path string) (RunSummary, error) {
+	var summary RunSummary
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return summary, fmt.Errorf("failed to read summary: %s. Error: %w", path, err)
+	}
+
+	err = json.Unmarshal(data, &summary)
+	if err != nil {
+		return summary, fmt.Errorf("failed to parse summary: %s. Error: %w", path, err)
+	}
+
+	return summary, nil
+}
+
+// CompareSummaries returns a row per compared metric, flagging any that regressed beyond thresholds.
+func CompareSummaries(baseline RunSummary, candidate RunSummary, thresholds RegressionThresholds) []Comparison {
+	baseErrorRate := (1 - baseline.SuccessRate) * 100
+	candErrorRate := (1 - candidate.SuccessRate) * 100
+
+	return []Comparison{
+		higherIsBetter("Avg req/sec", averageThroughput(baseline), averageThroughput(candidate), thresholds.MaxThroughputDropPct),
+		higherIsBetter("Max successful req/sec", float64(baseline.MaxSuccessfulRequestsPerSec),
+			float64(candidate.MaxSuccessfulRequestsPerSec), thresholds.MaxThroughputDropPct),
+		lowerIsBetter("p99 latency (ms)", baseline.Latency.P99Ms, candidate.Latency.P99Ms, thresholds.MaxP99IncreasePct),
+		{
+			Metric:    "Error rate (%)",
+			Baseline:  baseErrorRate,
+			Candidate: candErrorRate,
+			Delta:     fmt.Sprintf("%+.3f pts", candErrorRate-baseErrorRate),
+			Regressed: candErrorRate-baseErrorRate > thresholds.MaxErrorRateIncrease,
+		},
+		{
+			Metric:    "Consistency failures",
+			Baseline:  float64(baseline.ConsistencyFailures),
+			Candidate: float64(candidate.ConsistencyFailures),
+			Delta:     fmt.Sprintf("%+d", candidate.ConsistencyFailures-baseline.ConsistencyFailures),
+			Regressed: candidate.ConsistencyFailures-baseline.ConsistencyFailures > thresholds.MaxConsistencyFailureIncrease,
+		},
+	}
+}
+
+// PrintComparison prints the comparison table and returns true if nothing regressed.
+func PrintComparison(comparisons []Comparison) bool {
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+	tbl := table.New("Metric", "Baseline", "Candidate", "Delta", "Result")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	passed := true
+	for _, c := range comparisons {
+		status := "OK"
+		if c.Regressed {
+			status = "REGRESSION"
+			passed = false
+		}
+		tbl.AddRow(c.Metric, fmt.Sprintf("%.2f", c.Baseline), fmt.Sprintf("%.2f", c.Candidate), c.Delta, status)
+	}
+	tbl.Print()
+
+	return passed
+}
+
+func averageThroughput(summary RunSummary) float64 {
+	if summary.DurationSeconds <= 0 {
+		return 0
+	}
+
+	return float64(summary.Requests) / summary.DurationSeconds
+}
+
+func higherIsBetter(metric string, baseline float64, candidate float64, maxDropPct float64) Comparison {
+	change := percentChange(baseline, candidate)
+	return Comparison{
+		Metric:    metric,
+		Baseline:  baseline,
+		Candidate: candidate,
+		Delta:     formatPercentChange(change),
+		Regressed: -change > maxDropPct,
+	}
+}
+
+func lowerIsBetter(metric string, baseline float64, candidate float64, maxIncreasePct float64) Comparison {
+	change := percentChange(baseline, candidate)
+	return Comparison{
+		Metric:    metric,
+		Baseline:  baseline,
+		Candidate: candidate,
+		Delta:     formatPercentChange(change),
+		Regressed: change > maxIncreasePct,
+	}
+}
+
+// percentChange returns the % change from baseline to candidate. Any rise from a baseline of 0 is +Inf, so it exceeds
+// every threshold.
+func percentChange(baseline float64, candidate float64) float64 {
+	if baseline == 0 {
+		if candidate > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+
+	return (candidate - baseline) / baseline * 100
+}
+
+// formatPercentChange formats change as a signed %, or "new" for a rise from 0.
+func formatPercentChange(change float64) string {
+	if math.IsInf(change, 1) {
+		return "new"
+	}
+
+	return fmt.Sprintf("%+.2f%%", change)
+}
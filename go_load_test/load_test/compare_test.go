@@ -0,0 +1,47 @@
+package load_test
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseline  float64
+		candidate float64
+		want      float64
+		delta     string
+	}{
+		{"rise", 100, 150, 50, "+50.00%"},
+		{"drop", 100, 75, -25, "-25.00%"},
+		{"unchanged 0", 0, 0, 0, "+0.00%"},
+		{"rise from 0", 0, 3, math.Inf(1), "new"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := percentChange(test.baseline, test.candidate)
+			if got != test.want || formatPercentChange(got) != test.delta {
+				t.Errorf("got %g (%s), want %g (%s)", got, formatPercentChange(got), test.want, test.delta)
+			}
+		})
+	}
+}
+
+func TestCompareSummariesFlagsRiseFromZero(t *testing.T) {
+	baseline := RunSummary{Latency: LatencySummary{P99Ms: 0}, MaxSuccessfulRequestsPerSec: 0}
+	candidate := RunSummary{Latency: LatencySummary{P99Ms: 20}, MaxSuccessfulRequestsPerSec: 50}
+	thresholds := RegressionThresholds{MaxThroughputDropPct: 10, MaxP99IncreasePct: 10}
+	for _, c := range CompareSummaries(baseline, candidate, thresholds) {
+		switch c.Metric {
+		case "p99 latency (ms)":
+			if !c.Regressed || c.Delta != "new" {
+				t.Errorf("p99 rising from 0: got regressed %t, delta %s, want a regression", c.Regressed, c.Delta)
+			}
+		case "Max successful req/sec":
+			if c.Regressed {
+				t.Errorf("throughput rising from 0 flagged as a regression")
+			}
+		}
+	}
+}
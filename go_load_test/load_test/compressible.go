@@ -0,0 +1,41 @@
+package load_test
+
+// ParseCompressible parses the share of payloads that are compressible text, I.E 0.3 or 30%, see fillPayload. An
+// empty value makes every payload random bytes, like they've always been.
+func ParseCompressible(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return parseFraction(value)
+}
+
+// payloadWords are what compressible payloads are made of. A small vocabulary repeated at random compresses about as
+// well as log files or JSON do, I.E gzip shrinks it about 5x even base64 encoded, while random bytes barely compress.
+var payloadWords = []string{"the", "file", "server", "request", "response", "status", "error", "value", "time",
+	"user", "id", "name", "data", "true", "false", "null", "{\"key\":", "},", "\n", "GET", "PUT", "DELETE", "200",
+	"404", "500", "INFO", "WARN", "2024-01-01T00:00:00Z", "upload", "download", "cache", "bytes"}
+
+// fillPayload fills b with a payload drawn from r: compressible text for TestConfig.Compressible's share of payloads,
+// random bytes for the rest. Whether a payload is compressible is drawn from r too, so seeded runs stay repeatable.
+// Runs without compressible payloads draw exactly what they always have. STREAM uploads are always random bytes.
+func (tr *TestExecutor) fillPayload(r *Rand, b []byte) error {
+	if tr.compressible <= 0 || (tr.compressible < 1 && r.Float64() >= tr.compressible) {
+		return r.Read(b)
+	}
+
+	// One random byte picks each word, words average over 4 bytes with the space after them.
+	picks := make([]byte, len(b)/4+1)
+	if err := r.Read(picks); err != nil {
+		return err
+	}
+	for n, i := 0, 0; n < len(b); i++ {
+		n += copy(b[n:], payloadWords[int(picks[i%len(picks)])%len(payloadWords)])
+		if n < len(b) {
+			b[n] = ' '
+			n++
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package load_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestParseCompressible(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0.3", 0.3, false},
+		{"100%", 1, false},
+		{"2", 0, true},
+		{"some", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseCompressible(test.value)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("ParseCompressible(%q): got %g, %v, want %g, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+// gzipRatio returns how many times smaller gzip makes b.
+func gzipRatio(t *testing.T, b []byte) float64 {
+	t.Helper()
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return float64(len(b)) / float64(compressed.Len())
+}
+
+func TestFillPayload(t *testing.T) {
+	tests := []struct {
+		compressible float64
+		minRatio     float64
+		maxRatio     float64
+	}{
+		{0, 0, 1.01},
+		{1, 3, 100},
+	}
+	for _, test := range tests {
+		exec := &TestExecutor{compressible: test.compressible}
+		payload, again := make([]byte, 64<<10), make([]byte, 64<<10)
+		if err := exec.fillPayload(NewRand(1), payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := exec.fillPayload(NewRand(1), again); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(payload, again) {
+			t.Errorf("compressible %g: payloads from the same seed differ", test.compressible)
+		}
+		if ratio := gzipRatio(t, payload); ratio < test.minRatio || ratio > test.maxRatio {
+			t.Errorf("compressible %g: gzip shrank the payload %.2fx, want %g-%gx", test.compressible, ratio,
+				test.minRatio, test.maxRatio)
+		}
+	}
+
+	// Runs without compressible payloads draw exactly what they always have.
+	payload, random := make([]byte, 100), make([]byte, 100)
+	if err := (&TestExecutor{}).fillPayload(NewRand(1), payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRand(1).Read(random); err != nil || !bytes.Equal(payload, random) {
+		t.Errorf("got %x, want %x", payload, random)
+	}
+}
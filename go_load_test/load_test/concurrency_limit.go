@@ -0,0 +1,77 @@
+package load_test
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConcurrencyLimits caps the # of tests of each operation in flight at once, I.E PUT=4,GET=200, since uploads +
+// downloads stress completely different server resources. A test that would go over its cap waits for a slot before
+// starting, so time spent waiting shows up as schedule lag. Operations that aren't listed are uncapped. CREATE shares
+// PUT's cap unless listed itself, + a scenario's cap applies to the whole scenario rather than its steps.
+type ConcurrencyLimits map[TestType]int
+
+// ParseConcurrencyLimits parses OP=max pairs, I.E PUT=4,GET=200. An empty value is no limits.
+func ParseConcurrencyLimits(value string) (ConcurrencyLimits, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	limits, err := parseOpCounts(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid concurrency limits: %w", err)
+	}
+	for op, limit := range limits {
+		if limit < 1 {
+			return nil, fmt.Errorf("invalid concurrency limit for %s: must be >= 1", op)
+		}
+	}
+
+	return limits, nil
+}
+
+// capped returns the operation whose cap applies to testType.
+func (l ConcurrencyLimits) capped(testType TestType) TestType {
+	if _, listed := l[testType]; !listed && testType == CREATE {
+		return PUT
+	}
+
+	return testType
+}
+
+// concurrencySlots holds a semaphore per capped operation. The zero value caps nothing.
+type concurrencySlots struct {
+	limits ConcurrencyLimits
+	slots  map[TestType]chan struct{}
+}
+
+func newConcurrencySlots(limits ConcurrencyLimits) *concurrencySlots {
+	slots := &concurrencySlots{limits: limits, slots: make(map[TestType]chan struct{}, len(limits))}
+	for op, limit := range limits {
+		slots.slots[op] = make(chan struct{}, limit)
+	}
+
+	return slots
+}
+
+// acquire blocks until there's a free slot for testType. Returns false if shutdown came first.
+func (s *concurrencySlots) acquire(testType TestType, shutdown chan bool) bool {
+	slot, ok := s.slots[s.limits.capped(testType)]
+	if !ok {
+		return true
+	}
+
+	select {
+	case slot <- struct{}{}:
+		return true
+	case <-shutdown:
+		return false
+	}
+}
+
+func (s *concurrencySlots) release(testType TestType) {
+	if slot, ok := s.slots[s.limits.capped(testType)]; ok {
+		<-slot
+	}
+}
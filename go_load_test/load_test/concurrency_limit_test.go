@@ -0,0 +1,62 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConcurrencyLimits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ConcurrencyLimits
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"PUT=4, get=200", ConcurrencyLimits{PUT: 4, GET: 200}, false},
+		{"PUT=0", nil, true},
+		{"PUT", nil, true},
+		{"UPLOAD=4", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseConcurrencyLimits(test.value)
+		if !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("ParseConcurrencyLimits(%q): got %+v, %v, want %+v, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestConcurrencyLimitsCapped(t *testing.T) {
+	tests := []struct {
+		limits   ConcurrencyLimits
+		testType TestType
+		want     TestType
+	}{
+		{ConcurrencyLimits{PUT: 4}, CREATE, PUT},
+		{ConcurrencyLimits{PUT: 4, CREATE: 2}, CREATE, CREATE},
+		{ConcurrencyLimits{PUT: 4}, GET, GET},
+	}
+	for _, test := range tests {
+		if got := test.limits.capped(test.testType); got != test.want {
+			t.Errorf("%v capped(%s): got %s, want %s", test.limits, test.testType, got, test.want)
+		}
+	}
+}
+
+func TestConcurrencySlots(t *testing.T) {
+	slots := newConcurrencySlots(ConcurrencyLimits{PUT: 1})
+	shutdown := make(chan bool)
+	if !slots.acquire(CREATE, shutdown) || !slots.acquire(GET, shutdown) {
+		t.Fatal("couldn't acquire a free slot")
+	}
+
+	// PUT's only slot is held by the CREATE, so this waits until shutdown.
+	close(shutdown)
+	if slots.acquire(PUT, shutdown) {
+		t.Error("acquired a slot over the cap")
+	}
+	slots.release(CREATE)
+	if !slots.acquire(PUT, make(chan bool)) {
+		t.Error("couldn't acquire a released slot")
+	}
+}
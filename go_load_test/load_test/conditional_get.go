@@ -0,0 +1,163 @@
+package load_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CONDITIONAL tests GET a file with If-None-Match set to the last ETag the server returned for it, expecting a 304.
+// A 200 with a new ETag means the file changed since, so it isn't a failure, but a 200 with the same ETag means the
+// server ignored If-None-Match. Only files the server has returned an ETag for are tested, see ETagStore. The share
+// answered with a 304 is reported as the hit rate.
+const CONDITIONAL TestType = "CONDITIONAL"
+
+// ETagStore remembers the last ETag the server returned for each file from a PUT or GET. ETags are forgotten once the
+// file is deleted, or if an upload fails + the content is unknown.
+type ETagStore struct {
+	lock  sync.RWMutex
+	files *SortedFileSet
+	etags map[string]string
+}
+
+// NewETagStore returns nil unless ops include one that needs ETags, I.E CONDITIONAL.
+func NewETagStore(ops ExtraOps) *ETagStore {
+	if _, ok := ops[CONDITIONAL]; !ok {
+		return nil
+	}
+
+	return &ETagStore{files: NewSortedFileSet(0), etags: make(map[string]string)}
+}
+
+// uploaded remembers the ETag a successful upload of fileName returned. Otherwise the content or its ETag is unknown +
+// it's forgotten. A nil ETagStore remembers nothing.
+func (s *ETagStore) uploaded(fileName string, response *http.Response, err error) {
+	if s == nil {
+		return
+	}
+	if err != nil || response == nil || response.StatusCode >= 400 || response.Header.Get("ETag") == "" {
+		s.forget(fileName)
+		return
+	}
+
+	s.set(fileName, response.Header.Get("ETag"))
+}
+
+// downloaded remembers the ETag a GET of fileName returned, or forgets it if the file is gone.
+func (s *ETagStore) downloaded(fileName string, response *http.Response) {
+	if s == nil || response == nil {
+		return
+	}
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		s.forget(fileName)
+	case response.StatusCode == http.StatusOK && response.Header.Get("ETag") != "":
+		s.set(fileName, response.Header.Get("ETag"))
+	}
+}
+
+func (s *ETagStore) set(fileName string, etag string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files.Add(fileName)
+	s.etags[fileName] = etag
+}
+
+func (s *ETagStore) forget(fileName string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files.Delete(fileName)
+	delete(s.etags, fileName)
+}
+
+// etag returns fileName's last ETag, or false if it isn't known.
+func (s *ETagStore) etag(fileName string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	etag, ok := s.etags[fileName]
+
+	return etag, ok
+}
+
+// randomFile returns a random file with a known ETag, or "" if there are none.
+func (s *ETagStore) randomFile(r *Rand) string {
+	if s == nil {
+		return ""
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.files.randomFile(r)
+}
+
+func (tr *TestExecutor) ConditionalGetFile(test Test) {
+	fileName := test.fileName
+	etag, ok := tr.etags.etag(fileName)
+	if !ok {
+		// Deleted since the test was scheduled, there's no ETag to send.
+		tr.GetFile(test)
+		return
+	}
+
+	ctx, result, cancel := tr.beginTest(test, CONDITIONAL)
+	defer cancel()
+	trace, phases, start := result.trace, result.phases, result.started
+
+	req, err := tr.newRequest(ctx, http.MethodGet, fileName, nil, trace, phases)
+	if err != nil {
+		result.message = "Failed to build conditional GET request."
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	req.Header.Set("If-None-Match", etag)
+
+	response, err := tr.do(req, phases)
+	if err != nil {
+		result.response = response
+		result.message = "Error executing http conditional GET request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	body := responseToString(response)
+	tr.etags.downloaded(fileName, response)
+	result.response = response
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	if response.StatusCode >= 400 {
+		result.message = body
+		result.failed = true
+	} else if response.StatusCode != http.StatusNotModified && response.Header.Get("ETag") == etag {
+		result.mismatch = fmt.Sprintf("status %d for the ETag it last returned, expected %d", response.StatusCode,
+			http.StatusNotModified)
+		result.message = fmt.Sprintf("If-None-Match ignored: %s", result.mismatch)
+		result.failed = true
+	}
+	tr.emit(result)
+}
+
+// conditionalHitRate returns the share of conditional GETs answered with a 304, or 0 if none ran.
+func (s ExtraOpSummary) conditionalHitRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	return float64(s.NotModified) / float64(s.Count)
+}
@@ -1,8 +1,27 @@
 package load_test
 
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
 const (
 	MaxFailuresBeforeExit       = 1000
 	HugeFileSize          int64 = 150000000
+	DefaultRateAlpha            = 0.3                    // EWMA smoothing factor for "current" rates, higher reacts faster
+	TopErrorCount               = 10                     // # of distinct errors shown by PrintErrors
+	DrainTimeout                = time.Second * 30       // Default grace period for in-flight requests after shutdown, see Drain
+	ErrorSampleSize             = 1000                   // # of most recent http + other error messages kept in memory
+	DefaultApdexTarget          = time.Millisecond * 500 // Target latency T for the Apdex score
+	DefaultErrorBudget          = 0.001                  // Allowed failure fraction, I.E 0.1% of tests
+	DefaultRecentWindow         = 30                     // # of intervals covered by "recent" latency percentiles
+	CheckpointInterval          = time.Minute            // How often results are checkpointed, if enabled
+	DefaultWarmupRate           = 10                     // req/sec scheduled during warmup, if enabled
+	MaxHistoryIntervals         = 3600                   // # of intervals kept before older ones are merged, see appendHistory
 )
 
 type TestEndpointConfig struct {
@@ -10,4 +29,125 @@ type TestEndpointConfig struct {
 	Host       string // localhost or google.com
 	Port       string // 1234
 	PathPrefix string // api/foo/bar   (no prefix or trailing slashes)
+	Label      string // Name results are broken down by when there are multiple targets. Defaults to host:port
+	Weight     int    // Share of files sent to this target relative to the others, with multiple targets. Defaults to 1
+}
+
+func (c TestEndpointConfig) Name() string {
+	if c.Label != "" {
+		return c.Label
+	}
+
+	return fmt.Sprintf("%s:%s", c.Host, c.Port)
+}
+
+// Targets spreads files over multiple file servers, I.E replicas behind different hostnames or nodes behind a load
+// balancer. Each file always maps to the same target, so a file is read + deleted on the server it was written to.
+// Files are spread by weight, I.E with weights 70 + 30, 70% of files map to the first target.
+type Targets []TestEndpointConfig
+
+// For returns the target fileName maps to.
+func (t Targets) For(fileName string) TestEndpointConfig {
+	if len(t) == 1 {
+		return t[0]
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(fileName))
+	n := int(hash.Sum32() % uint32(t.totalWeight()))
+	for _, target := range t {
+		n -= target.weight()
+		if n < 0 {
+			return target
+		}
+	}
+
+	return t[len(t)-1]
+}
+
+// Share returns the fraction of files that map to target.
+func (t Targets) Share(target TestEndpointConfig) float64 {
+	return float64(target.weight()) / float64(t.totalWeight())
+}
+
+func (t Targets) totalWeight() int {
+	total := 0
+	for _, target := range t {
+		total += target.weight()
+	}
+
+	return total
+}
+
+func (c TestEndpointConfig) weight() int {
+	if c.Weight <= 0 {
+		return 1
+	}
+
+	return c.Weight
+}
+
+// resolveTargets returns targets, or just endpoint if there are none.
+func resolveTargets(endpoint TestEndpointConfig, targets Targets) Targets {
+	if len(targets) == 0 {
+		return Targets{endpoint}
+	}
+
+	return targets
+}
+
+// ParseTargets parses a comma separated list of [label=]proto://host:port/path/prefix[;weight=N] base URLs. Targets
+// without a weight have a weight of 1, I.E a=http://node-a:1234;weight=70,b=http://node-b:1234;weight=30
+func ParseTargets(value string) (Targets, error) {
+	var targets Targets
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		label := ""
+		if i := strings.Index(entry, "="); i >= 0 && !strings.Contains(entry[:i], "/") {
+			label, entry = entry[:i], entry[i+1:]
+		}
+
+		weight := 1
+		if base, param, ok := strings.Cut(entry, ";"); ok {
+			parsed, err := strconv.Atoi(strings.TrimPrefix(param, "weight="))
+			if !strings.HasPrefix(param, "weight=") || err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid target weight: %s. Expected ;weight=N with N > 0", param)
+			}
+			entry, weight = base, parsed
+		}
+
+		u, err := url.Parse(entry)
+		if err != nil || u.Scheme == "" || u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid target: %s. Expected proto://host:port/path/prefix", entry)
+		}
+
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+
+		target := TestEndpointConfig{
+			Proto:      u.Scheme,
+			Host:       u.Hostname(),
+			Port:       port,
+			PathPrefix: strings.Trim(u.Path, "/"),
+			Label:      label,
+			Weight:     weight,
+		}
+		if seen[target.Name()] {
+			return nil, fmt.Errorf("duplicate target: %s. Give each target a unique label=", target.Name())
+		}
+		seen[target.Name()] = true
+		targets = append(targets, target)
+	}
+
+	return targets, nil
 }
@@ -0,0 +1,91 @@
+package load_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Targets
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"http://node-a:1234/api/files/, https://node-b", Targets{
+			{Proto: "http", Host: "node-a", Port: "1234", PathPrefix: "api/files", Weight: 1},
+			{Proto: "https", Host: "node-b", Port: "443", Weight: 1},
+		}, false},
+		{"a=http://lb:80,b=http://lb:80", Targets{
+			{Proto: "http", Host: "lb", Port: "80", Label: "a", Weight: 1},
+			{Proto: "http", Host: "lb", Port: "80", Label: "b", Weight: 1},
+		}, false},
+		{"http://lb:80,http://lb", nil, true}, // Same name without labels
+		{"node-a:1234", nil, true},
+		{"http://", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseTargets(test.value)
+		if !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("ParseTargets(%q): got %+v, %v, want %+v, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestTargetsFor(t *testing.T) {
+	targets := Targets{{Host: "a", Port: "1"}, {Host: "b", Port: "1"}}
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		fileName := strconv.Itoa(i)
+		target := targets.For(fileName)
+		if again := targets.For(fileName); again != target {
+			t.Fatalf("%s: mapped to %s, then %s", fileName, target.Name(), again.Name())
+		}
+		counts[target.Name()]++
+	}
+	if counts["a:1"] < 400 || counts["b:1"] < 400 {
+		t.Errorf("got %v, want files spread ~evenly", counts)
+	}
+	if got := (Targets{{Host: "only"}}).For("x").Host; got != "only" {
+		t.Errorf("single target: got %s", got)
+	}
+}
+
+func TestWeightedTargets(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []int
+		wantErr bool
+	}{
+		{"a=http://node-a:1234;weight=70,b=http://node-b:1234;weight=30", []int{70, 30}, false},
+		{"http://node-a:1234,http://node-b:1234;weight=3", []int{1, 3}, false},
+		{"http://node-a:1234;weight=0", nil, true},
+		{"http://node-a:1234;weight=x", nil, true},
+		{"http://node-a:1234;w=2", nil, true},
+	}
+	for _, test := range tests {
+		targets, err := ParseTargets(test.value)
+		var got []int
+		for _, target := range targets {
+			got = append(got, target.Weight)
+		}
+		if !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("ParseTargets(%q): got weights %v, %v, want %v, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+
+	targets := Targets{{Host: "a", Port: "1", Weight: 70}, {Host: "b", Port: "1", Weight: 30}}
+	if got := targets.Share(targets[0]); got != 0.7 {
+		t.Errorf("got share %g, want 0.7", got)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[targets.For(strconv.Itoa(i)).Name()]++
+	}
+	if counts["a:1"] < 6700 || counts["a:1"] > 7300 {
+		t.Errorf("got %v, want ~70%% of files on a", counts)
+	}
+}
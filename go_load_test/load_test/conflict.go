@@ -0,0 +1,190 @@
+package load_test
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CONFLICT tests PUT conflictWriters different payloads of the same size to a new file at the same time, then GET it.
+// Whichever write wins, the GET must return exactly one complete payload, never a mix of them or a truncated one. A
+// torn write fails the test at ConsistencyConflictRead + is reported with the consistency failures. Writes the server
+// rejects, I.E with a 409, can't win. The file is deleted afterwards.
+const CONFLICT TestType = "CONFLICT"
+
+// conflictWriters is how many PUTs race for the file.
+const conflictWriters = 2
+
+// conflictWrite is the outcome of one of the racing PUTs.
+type conflictWrite struct {
+	response *http.Response
+	err      error
+}
+
+// rejected returns true if the server answered the write with an error, so its payload can't have been stored.
+func (w conflictWrite) rejected() bool {
+	return w.err == nil && w.response.StatusCode >= 400
+}
+
+func (tr *TestExecutor) ConflictFile(test Test) {
+	ctx, result, done := tr.beginHeldTest(test, CONFLICT)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+	result.check = ConsistencyConflictWrite
+
+	payload := newSeededRand(test.payloadSeed)
+	fileSize := tr.randomFileSize(payload)
+	payloads := make([]string, conflictWriters)
+	for i := range payloads {
+		fileBytes := make([]byte, fileSize)
+		err := tr.fillPayload(payload, fileBytes)
+		if err != nil {
+			result.message = "Failed to generate random file bytes"
+			result.err = err
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+		payloads[i] = b64.StdEncoding.EncodeToString(fileBytes)
+		result.bytesSent += int64(len(payloads[i]))
+	}
+
+	writes, err := tr.writeConcurrently(ctx, fileName, payloads, trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for ConflictFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	defer func() {
+		_, _ = tr.removeFile(fileName)
+	}()
+
+	var accepted []string
+	statuses := make([]string, len(writes))
+	for i, write := range writes {
+		if !write.rejected() {
+			accepted = append(accepted, payloads[i])
+		}
+		statuses[i] = statusCodeLabel(0)
+		if write.response != nil {
+			statuses[i] = statusCodeLabel(write.response.StatusCode)
+		}
+	}
+	if len(accepted) == 0 {
+		result.response = writes[0].response
+		result.message = fmt.Sprintf("Every concurrent PUT was rejected, got: %s", strings.Join(statuses, ", "))
+		result.mismatch = "every write rejected"
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	result.check = ConsistencyConflictRead
+	response, err := tr.get(ctx, fileName, trace, phases)
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http GET request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	body := responseToString(response)
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	if response.StatusCode != http.StatusOK {
+		result.message = fmt.Sprintf("GET after concurrent PUTs failed due to unexpected status code, got: %d but expected 200.",
+			response.StatusCode)
+		result.mismatch = statusMismatch(response.StatusCode, http.StatusOK)
+		result.failed = true
+	} else if !containsString(accepted, body) {
+		result.message = fmt.Sprintf("Concurrent PUTs were torn! GET returned neither complete payload, PUTs got: %s",
+			strings.Join(statuses, ", "))
+		result.mismatch = tornMismatch(payloads, body)
+		result.failed = true
+	}
+	tr.emit(result)
+}
+
+// writeConcurrently PUTs each payload to fileName at the same time, returning each write's outcome in order. The
+// first write's request phases are recorded into phases, the rest overlap it + aren't recorded.
+func (tr *TestExecutor) writeConcurrently(ctx context.Context, fileName string, payloads []string, trace traceContext, phases *RequestPhases) ([]conflictWrite, error) {
+	requests := make([]*http.Request, len(payloads))
+	writerPhases := make([]*RequestPhases, len(payloads))
+	for i, payload := range payloads {
+		writerPhases[i] = phases
+		if i > 0 {
+			writerPhases[i] = NewRequestPhases()
+		}
+		req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(payload), trace, writerPhases[i])
+		if err != nil {
+			return nil, err
+		}
+		requests[i] = req
+	}
+
+	// Every writer waits for ready, so the PUTs are sent as close together as possible.
+	ready := make(chan struct{})
+	writes := make([]conflictWrite, len(requests))
+	var writing sync.WaitGroup
+	for i := range requests {
+		writing.Add(1)
+		go func(i int) {
+			defer writing.Done()
+			<-ready
+			response, err := tr.do(requests[i], writerPhases[i])
+			_ = responseToString(response)
+			writes[i] = conflictWrite{response: response, err: err}
+		}(i)
+	}
+	close(ready)
+	writing.Wait()
+
+	return writes, nil
+}
+
+// tornMismatch describes how body, returned after payloads were written concurrently, is neither of them.
+func tornMismatch(payloads []string, body string) string {
+	for _, payload := range payloads {
+		if len(body) < len(payload) && strings.HasPrefix(payload, body) {
+			return "truncated write"
+		}
+	}
+	if len(body) != len(payloads[0]) {
+		return bodyMismatch(payloads[0], body)
+	}
+
+	for i := 0; i < len(body); i++ {
+		fromPayload := false
+		for _, payload := range payloads {
+			fromPayload = fromPayload || body[i] == payload[i]
+		}
+		if !fromPayload {
+			return "body content differs"
+		}
+	}
+
+	return "interleaved writes"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
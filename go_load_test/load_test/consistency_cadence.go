@@ -0,0 +1,101 @@
+package load_test
+
+import (
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// By default consistency checks are picked at random alongside the rest of the load, so how often they run is tied to
+// the seed cadence, growth + file count. With a consistency rate (or a consistency pool, which has a rate of its own),
+// checks are paced separately at a fixed # per second regardless of the load level, + the main schedule no longer runs
+// them. Paced checks aren't counted towards the request limit. In a seeded run, paced checks draw their keys + payloads
+// from a Rand of their own, so they don't change what the main schedule draws however the two interleave.
+
+// pacedCheckSeedSalt is mixed into the run's seed to seed paced checks' Rand, so their keys don't repeat the keys the
+// main schedule, pre-seeding or other agents create.
+const pacedCheckSeedSalt = 0x5DEECE66D
+
+// consistencyRate returns the # of consistency checks to start per second, or 0 if checks are scheduled with the rest
+// of the load.
+func (cfg TestSchedulerConfig) consistencyRate() float64 {
+	if cfg.ConsistencyPool != nil {
+		return cfg.ConsistencyPool.Rate
+	}
+
+	return cfg.ConsistencyRate
+}
+
+// consistencyInterval returns the time between paced checks, at least 1ns however high the rate.
+func (cfg TestSchedulerConfig) consistencyInterval() time.Duration {
+	interval := time.Duration(float64(time.Second) / cfg.consistencyRate())
+	if interval < 1 {
+		return 1
+	}
+
+	return interval
+}
+
+// scheduleConsistencyChecks starts a consistency check every 1/rate seconds until stop is closed, then closes done.
+// Checks are queued for the consistency pool if there is one, or scheduled with the rest of the load. Pauses hold
+// checks back the same as the rest of the load.
+func (ts *TestScheduler) scheduleConsistencyChecks(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	var r *Rand
+	if ts.cfg.RandSeed != 0 {
+		r = NewRand(ts.cfg.RandSeed ^ pacedCheckSeedSalt)
+	}
+	ticker := time.NewTicker(ts.cfg.consistencyInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ts.cfg.ShutdownChan:
+			return
+		case <-ticker.C:
+		}
+		if ts.cfg.Pause.Wait(ts.cfg.ShutdownChan) {
+			continue
+		}
+
+		test := Test{TestType: CONSISTENCY, fileName: newKey(ts.cfg.TestConfig.Keys, r), scheduledAt: time.Now(),
+			payloadSeed: r.payloadSeed()}
+		if pool := ts.cfg.ConsistencyPool; pool != nil {
+			select {
+			case ts.cfg.ConsistencyChan <- test:
+			default:
+				pool.skipped.Inc()
+				log.Debugf("Consistency check queue is full, skipped check for file: %s", test.fileName)
+			}
+			continue
+		}
+
+		select {
+		case ts.cfg.SchedulerChan <- test:
+		case <-stop:
+			return
+		case <-ts.cfg.ShutdownChan:
+			return
+		}
+	}
+}
+
+// withoutPacedChecks returns tests without consistency checks if they're paced separately, or tests unchanged if not.
+// A mix of nothing but checks falls back to the default mix.
+func withoutPacedChecks(cfg TestSchedulerConfig, tests []TestType) []TestType {
+	if cfg.consistencyRate() <= 0 {
+		return tests
+	}
+
+	filtered := make([]TestType, 0, len(tests))
+	for _, test := range tests {
+		if test != CONSISTENCY {
+			filtered = append(filtered, test)
+		}
+	}
+	if len(filtered) == 0 {
+		return defaultTests()
+	}
+
+	return filtered
+}
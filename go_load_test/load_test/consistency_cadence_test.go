@@ -0,0 +1,60 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConsistencyInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  TestSchedulerConfig
+		want time.Duration
+	}{
+		{"rate", TestSchedulerConfig{ConsistencyRate: 4}, 250 * time.Millisecond},
+		{"pool rate", TestSchedulerConfig{ConsistencyPool: &ConsistencyPool{Rate: 0.5}}, 2 * time.Second},
+		{"rounds to 0", TestSchedulerConfig{ConsistencyRate: 1e12}, time.Nanosecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.cfg.consistencyInterval(); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// pacedChecks returns the keys + payload seeds of the first n paced checks of a run seeded with seed.
+func pacedChecks(t *testing.T, seed int64, n int) ([]Test, *TestScheduler) {
+	t.Helper()
+	scheduled := make(chan Test)
+	ts := NewTestScheduler(TestSchedulerConfig{ConsistencyRate: 1e6, RandSeed: seed, SchedulerChan: scheduled,
+		ShutdownChan: make(chan bool)})
+	stop, done := make(chan struct{}), make(chan struct{})
+	go ts.scheduleConsistencyChecks(stop, done)
+
+	tests := make([]Test, n)
+	for i := range tests {
+		tests[i] = <-scheduled
+	}
+	close(stop)
+	<-done
+
+	return tests, &ts
+}
+
+func TestPacedChecksDrawTheirOwnRand(t *testing.T) {
+	first, ts := pacedChecks(t, 42, 3)
+	second, _ := pacedChecks(t, 42, 3)
+	for i := range first {
+		if first[i].fileName != second[i].fileName || first[i].payloadSeed != second[i].payloadSeed {
+			t.Errorf("check %d: got %s/%d, then %s/%d with the same seed", i, first[i].fileName,
+				first[i].payloadSeed, second[i].fileName, second[i].payloadSeed)
+		}
+	}
+
+	// The scheduler's Rand is untouched, so the main schedule draws the same whatever the checks do.
+	if got, want := ts.newFileName(), newKey(nil, NewRand(42)); got != want {
+		t.Errorf("scheduler drew %s after paced checks, want %s", got, want)
+	}
+}
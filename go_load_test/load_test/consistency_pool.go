@@ -0,0 +1,125 @@
+package load_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ConsistencyPool runs consistency checks from their own pool of workers, at their own rate + with their own HTTP
+// client, so heavy GET/PUT load can't starve the checks of connections or workers, + checks can't crowd out the rest of
+// the load. The scheduler starts checks at Rate into a queue of up to Queue checks, + checks that would overflow the
+// queue are skipped. With a pool, the main schedule no longer runs consistency checks, + pool checks aren't counted
+// towards the request limit. Specs are key=value params, I.E
+//
+//	workers=4 rate=2 queue=100
+type ConsistencyPool struct {
+	Workers int
+	Rate    float64 // Checks started per second
+	Queue   int     // Max checks waiting for a worker
+	skipped Counter // Checks skipped because the queue was full
+}
+
+type ConsistencyPoolSummary struct {
+	Workers       int     `json:"workers"`
+	RatePerSec    float64 `json:"rate_per_sec"`
+	QueueDepth    int     `json:"queue_depth"`
+	MaxQueueDepth int     `json:"max_queue_depth"`
+	AvgQueueDepth float64 `json:"avg_queue_depth"`
+	Skipped       int     `json:"skipped"` // Checks skipped because the queue was full
+}
+
+// consistencyPoolWorkerBase is added to pool worker IDs, so they don't collide with the runner's.
+const consistencyPoolWorkerBase = AgentWorkerStride / 2
+
+// ParseConsistencyPool parses a consistency pool spec. An empty spec returns nil, checks run with the rest of the load.
+func ParseConsistencyPool(spec string) (*ConsistencyPool, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid consistency pool param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	pool := &ConsistencyPool{Workers: 4, Rate: 1}
+	err := params.parse(map[string]interface{}{"workers": &pool.Workers, "rate": &pool.Rate, "queue": &pool.Queue})
+	if err != nil {
+		return nil, fmt.Errorf("invalid consistency pool spec: %w", err)
+	}
+	if pool.Queue == 0 {
+		pool.Queue = pool.Workers * 10
+	}
+	if pool.Workers <= 0 || pool.Rate <= 0 || pool.Queue <= 0 {
+		return nil, fmt.Errorf("invalid consistency pool spec: workers, rate + queue must be > 0")
+	}
+
+	return pool, nil
+}
+
+func (p *ConsistencyPool) String() string {
+	return fmt.Sprintf("%d workers at %.2f checks/sec, queue of %d", p.Workers, p.Rate, p.Queue)
+}
+
+// runConsistencyPool runs queued consistency checks on Workers workers, with their own executor + HTTP client, until
+// the consistency chan is closed.
+func (tr *TestRunner) runConsistencyPool(exec *TestExecutor, running *sync.WaitGroup) {
+	for i := 0; i < tr.cfg.ConsistencyPool.Workers; i++ {
+		running.Add(1)
+		go func(worker int) {
+			defer running.Done()
+			for test := range tr.cfg.ConsistencyChan {
+				tr.waitWhilePaused(&test)
+				select {
+				case <-tr.cfg.ShutdownChan:
+					continue
+				default:
+				}
+
+				test.worker = consistencyPoolWorkerBase + worker
+				tr.runTest(exec, test)
+			}
+		}(i)
+	}
+}
+
+// consistencyQueueStats samples the depth of the consistency check queue once per interval.
+type consistencyQueueStats struct {
+	depth   int
+	max     int
+	total   int
+	samples int
+}
+
+func (s *consistencyQueueStats) record(depth int) {
+	s.depth = depth
+	s.max = Max(s.max, depth)
+	s.total += depth
+	s.samples++
+}
+
+// consistencyPoolSummary returns the pool's config + queue depth, or nil without a pool. Caller must hold resultLock.
+func (tr *TestResults) consistencyPoolSummary() *ConsistencyPoolSummary {
+	if tr.consistencyPool == nil {
+		return nil
+	}
+
+	summary := &ConsistencyPoolSummary{
+		Workers:       tr.consistencyPool.Workers,
+		RatePerSec:    tr.consistencyPool.Rate,
+		QueueDepth:    tr.consistencyQueue.depth,
+		MaxQueueDepth: tr.consistencyQueue.max,
+		Skipped:       tr.consistencyPool.skipped.Get(),
+	}
+	if tr.consistencyQueue.samples > 0 {
+		summary.AvgQueueDepth = float64(tr.consistencyQueue.total) / float64(tr.consistencyQueue.samples)
+	}
+
+	return summary
+}
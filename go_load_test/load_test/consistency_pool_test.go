@@ -0,0 +1,43 @@
+package load_test
+
+import (
+	"testing"
+)
+
+func TestParseConsistencyPool(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *ConsistencyPool
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"rate=2", &ConsistencyPool{Workers: 4, Rate: 2, Queue: 40}, false},
+		{"workers=2 rate=0.5 queue=100", &ConsistencyPool{Workers: 2, Rate: 0.5, Queue: 100}, false},
+		{"workers=0", nil, true},
+		{"rate=-1", nil, true},
+		{"queue=-1", nil, true},
+		{"workers", nil, true},
+		{"depth=3", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseConsistencyPool(test.spec)
+		if (err != nil) != test.wantErr || (got == nil) != (test.want == nil) ||
+			(got != nil && (got.Workers != test.want.Workers || got.Rate != test.want.Rate || got.Queue != test.want.Queue)) {
+			t.Errorf("ParseConsistencyPool(%q): got %v, %v, want %v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestConsistencyQueueStats(t *testing.T) {
+	var stats consistencyQueueStats
+	for _, depth := range []int{2, 8, 5} {
+		stats.record(depth)
+	}
+	results := &TestResults{consistencyPool: &ConsistencyPool{Workers: 1, Rate: 1, Queue: 10}, consistencyQueue: stats}
+	summary := results.consistencyPoolSummary()
+	if summary.QueueDepth != 5 || summary.MaxQueueDepth != 8 || summary.AvgQueueDepth != 5 {
+		t.Errorf("got depth %d, max %d, avg %g, want 5, 8, 5", summary.QueueDepth, summary.MaxQueueDepth,
+			summary.AvgQueueDepth)
+	}
+}
@@ -0,0 +1,110 @@
+package load_test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Breaks consistency failures down by which step of the check failed and what was observed, rather than lumping
+// them together as opaque messages.
+
+type ConsistencyStep string
+
+const (
+	ConsistencyCreate       ConsistencyStep = "CREATE"        // PUT of a new file
+	ConsistencyRead         ConsistencyStep = "READ"          // GET immediately after the PUT, body must match
+	ConsistencyDelete       ConsistencyStep = "DELETE"        // DELETE of the file
+	ConsistencyVerifyDelete ConsistencyStep = "VERIFY_DELETE" // GET immediately after the DELETE, must 404
+	// Listing after the READ, the file must be listed within ListCheck.Bound of the PUT. Only run with a ListCheck.
+	ConsistencyListed ConsistencyStep = "LISTED"
+	// Listing after VERIFY_DELETE, the file must be gone within ListCheck.Bound of the DELETE.
+	ConsistencyUnlisted ConsistencyStep = "UNLISTED"
+	// GET a while after the DELETE, must still 404. Only run if TestConfig.DeleteRecheckDelay is set.
+	ConsistencyVerifyDeleteLater ConsistencyStep = "VERIFY_DELETE_LATER"
+	// Racing PUTs of a CONFLICT test, at least one must be accepted.
+	ConsistencyConflictWrite ConsistencyStep = "CONFLICT_WRITE"
+	// GET after a CONFLICT test's racing PUTs, must return exactly one complete payload.
+	ConsistencyConflictRead ConsistencyStep = "CONFLICT_READ"
+	// GET after the PUT was truncated on purpose, must 404 since the server mustn't keep a partial file. See Chaos.
+	ConsistencyVerifyTruncate ConsistencyStep = "VERIFY_TRUNCATE"
+)
+
+var consistencySteps = []ConsistencyStep{ConsistencyCreate, ConsistencyRead, ConsistencyListed, ConsistencyDelete,
+	ConsistencyVerifyDelete, ConsistencyUnlisted, ConsistencyVerifyDeleteLater, ConsistencyVerifyTruncate,
+	ConsistencyConflictWrite, ConsistencyConflictRead}
+
+// requestErrorMismatch is recorded for failures where no response was compared, I.E transport errors.
+const requestErrorMismatch = "request error"
+
+// ConsistencyFailures counts failed consistency checks by step, then by observed mismatch.
+type ConsistencyFailures map[ConsistencyStep]map[string]int
+
+func (c ConsistencyFailures) Record(step ConsistencyStep, mismatch string) {
+	if mismatch == "" {
+		mismatch = requestErrorMismatch
+	}
+	if c[step] == nil {
+		c[step] = make(map[string]int)
+	}
+	c[step][mismatch]++
+}
+
+// Total returns the # of failures for the step.
+func (c ConsistencyFailures) Total(step ConsistencyStep) int {
+	total := 0
+	for _, count := range c[step] {
+		total += count
+	}
+
+	return total
+}
+
+// Describe summarizes the step's mismatches, most frequent first. I.E "status 429, expected 201 x3, body content differs x1"
+func (c ConsistencyFailures) Describe(step ConsistencyStep) string {
+	mismatches := make([]string, 0, len(c[step]))
+	for mismatch := range c[step] {
+		mismatches = append(mismatches, mismatch)
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		if c[step][mismatches[i]] != c[step][mismatches[j]] {
+			return c[step][mismatches[i]] > c[step][mismatches[j]]
+		}
+		return mismatches[i] < mismatches[j]
+	})
+
+	described := make([]string, len(mismatches))
+	for i, mismatch := range mismatches {
+		described[i] = fmt.Sprintf("%s x%d", mismatch, c[step][mismatch])
+	}
+
+	return strings.Join(described, ", ")
+}
+
+// Copy returns a deep copy, safe to hand out while results keep merging.
+func (c ConsistencyFailures) Copy() ConsistencyFailures {
+	copied := make(ConsistencyFailures, len(c))
+	for step, mismatches := range c {
+		copied[step] = make(map[string]int, len(mismatches))
+		for mismatch, count := range mismatches {
+			copied[step][mismatch] = count
+		}
+	}
+
+	return copied
+}
+
+func statusMismatch(got int, expected int) string {
+	return fmt.Sprintf("status %d, expected %d", got, expected)
+}
+
+func bodyMismatch(written string, read string) string {
+	switch {
+	case len(read) < len(written):
+		return "body shorter than written"
+	case len(read) > len(written):
+		return "body longer than written"
+	default:
+		return "body content differs"
+	}
+}
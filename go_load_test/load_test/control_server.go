@@ -0,0 +1,88 @@
+package load_test
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// Local HTTP API to control a run while it's going, I.E
+//
+//	curl -X POST localhost:7071/pause
+//	curl -X POST localhost:7071/resume
+//	curl localhost:7071/status
+//	curl -X POST localhost:7071/settings -d '{"rate": 500, "mix": "80/15/4/1"}'
+
+type ControlStatus struct {
+	Paused        bool               `json:"paused"`
+	PausedSeconds float64            `json:"paused_seconds"` // Total time spent paused so far
+	Settings      LiveSettingsStatus `json:"settings"`
+}
+
+type ControlServer struct {
+	pause *PauseControl
+	live  *LiveSettings
+}
+
+func NewControlServer(pause *PauseControl, live *LiveSettings) *ControlServer {
+	return &ControlServer{pause: pause, live: live}
+}
+
+// Serve blocks serving the control API on addr. It's unauthenticated, so bind it to localhost.
+func (c *ControlServer) Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pause", c.post(func() { c.pause.Pause() }))
+	mux.HandleFunc("/resume", c.post(func() { c.pause.Resume() }))
+	mux.HandleFunc("/status", c.HandleStatus)
+	mux.HandleFunc("/settings", c.HandleSettings)
+
+	log.Infof("Serving control API on %s", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// post returns a handler that runs action for POST requests, then responds with the status.
+func (c *ControlServer) post(action func()) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			http.Error(response, "method not allowed, use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		action()
+		c.HandleStatus(response, request)
+	}
+}
+
+// HandleSettings applies a JSON SettingsUpdate on POST, then responds with the status.
+func (c *ControlServer) HandleSettings(response http.ResponseWriter, request *http.Request) {
+	if request.Method == http.MethodPost {
+		var update SettingsUpdate
+		err := json.NewDecoder(request.Body).Decode(&update)
+		if err == nil {
+			err = c.live.Update(update)
+		}
+		if err != nil {
+			http.Error(response, fmt.Sprintf("invalid settings: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	c.HandleStatus(response, request)
+}
+
+func (c *ControlServer) HandleStatus(response http.ResponseWriter, _ *http.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(response).Encode(c.Status())
+	if err != nil {
+		log.Errorf("Failed to write control status response: %+v", err)
+	}
+}
+
+func (c *ControlServer) Status() ControlStatus {
+	return ControlStatus{
+		Paused:        c.pause.Paused(),
+		PausedSeconds: c.pause.PausedFor().Seconds(),
+		Settings:      c.live.Status(),
+	}
+}
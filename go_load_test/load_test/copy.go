@@ -0,0 +1,109 @@
+package load_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// COPY tests duplicate an existing file to a new key with a WebDAV style COPY, the new key given in the Destination
+// header, then GET both + check their checksums match each other, + the checksum of the last upload if it's known, see
+// ChecksumStore. The source is held like a write for the test, so it can't change underneath the check. The copy is
+// deleted afterwards. How long the COPY itself took is reported by the source's size, see sizeBucket, since a server
+// copying bytes rather than references slows with size. Only run it against servers that support COPY, others fail
+// it.
+const COPY TestType = "COPY"
+
+// CopyTiming is how long a COPY request took to copy a file of Bytes.
+type CopyTiming struct {
+	Bytes   int64
+	Latency time.Duration
+}
+
+func (tr *TestExecutor) CopyFile(test Test) {
+	ctx, result, done := tr.beginHeldTest(test, COPY)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+	copyName := fmt.Sprintf("%s.copy-%s", fileName, newSeededRand(test.payloadSeed).String(8))
+	// Taken while the source is held, so nothing can change it until the test is done.
+	expected, known := tr.checksums.expected(fileName)
+
+	req, err := tr.newRequest(ctx, "COPY", fileName, nil, trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for CopyFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	req.Header.Set("Destination", tr.buildPath(copyName))
+	copyStart := time.Now()
+	response, err := tr.do(req, phases)
+	copyLatency := time.Now().Sub(copyStart)
+	defer func() {
+		_, _ = tr.removeFile(copyName)
+	}()
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http COPY request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	result.message = responseToString(response)
+	if response.StatusCode >= 400 {
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	bodies := make([]string, 0, 2)
+	for i, name := range []string{fileName, copyName} {
+		which := "source"
+		if i > 0 {
+			which = "copy"
+		}
+		response, err = tr.get(ctx, name, trace, phases)
+		result.response = response
+		if err != nil {
+			result.message = fmt.Sprintf("Error executing http GET request for the %s", which)
+			result.err = err
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+		body := responseToString(response)
+		result.bytesReceived += int64(len(body))
+		if response.StatusCode != http.StatusOK {
+			result.message = fmt.Sprintf("GET of the %s after COPY failed due to unexpected status code, got: %d but expected 200.",
+				which, response.StatusCode)
+			result.mismatch = statusMismatch(response.StatusCode, http.StatusOK)
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+		bodies = append(bodies, body)
+	}
+
+	result.copied = &CopyTiming{Bytes: int64(len(bodies[0])), Latency: copyLatency}
+	result.duration = time.Now().Sub(start)
+	source, copied := sha256.Sum256([]byte(bodies[0])), sha256.Sum256([]byte(bodies[1]))
+	if known && source != expected {
+		result.mismatch = "source checksum mismatch"
+		result.message = "Source's bytes don't match the checksum of the last upload"
+		result.failed = true
+	} else if copied != source {
+		result.mismatch = bodyMismatch(bodies[0], bodies[1])
+		result.message = fmt.Sprintf("Copy doesn't match its source: %s", result.mismatch)
+		result.failed = true
+	}
+	tr.emit(result)
+}
@@ -0,0 +1,40 @@
+package load_test
+
+import (
+	"sync/atomic"
+)
+
+// Counter is an int64 counter that is safe for concurrent use. TestResults' counters are only updated by apply, under
+// resultLock, so readers that need them to agree with each other + the histograms hold resultLock too. Counters outside
+// TestResults, I.E RetryQueue's + Drain's, are updated by executors without a lock.
+type Counter struct {
+	value atomic.Int64
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+func (c *Counter) Add(n int64) {
+	c.value.Add(n)
+}
+
+// Load returns the current value.
+func (c *Counter) Load() int64 {
+	return c.value.Load()
+}
+
+// Get returns the current value as an int, for counts that are displayed alongside other ints.
+func (c *Counter) Get() int {
+	return int(c.value.Load())
+}
+
+// Reset sets the counter to 0, returning the value it held.
+func (c *Counter) Reset() int64 {
+	return c.value.Swap(0)
+}
+
+// Store sets the counter to n, I.E when restoring from a checkpoint.
+func (c *Counter) Store(n int64) {
+	c.value.Store(n)
+}
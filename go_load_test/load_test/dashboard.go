@@ -0,0 +1,131 @@
+package load_test
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"strings"
+	"time"
+)
+
+// Live terminal dashboard. Redraws a single screen in place rather than dumping a new table every second.
+
+const (
+	maxRecentErrors     = 100
+	dashboardErrorLines = 10
+	sparklineWidth      = 60
+	ansiHome            = "\033[H"
+	ansiClearLine       = "\033[K"
+	ansiClearBelow      = "\033[J"
+	ansiBold            = "\033[1m"
+	ansiReset           = "\033[0m"
+)
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+type Dashboard struct {
+	results *TestResults
+	runID   string
+}
+
+func NewDashboard(results *TestResults, runID string) *Dashboard {
+	return &Dashboard{results: results, runID: runID}
+}
+
+// Render draws one frame of the dashboard.
+func (d *Dashboard) Render(w io.Writer) {
+	tr := d.results
+	tr.resultLock.RLock()
+	elapsed := tr.elapsed().Truncate(time.Second)
+	recentLatency := tr.recentLatency.Merged()
+	var throughput, errors []float64
+	for _, stats := range tr.history[Max(0, len(tr.history)-sparklineWidth):] {
+		throughput = append(throughput, stats.PerSecond(stats.Requests))
+		errors = append(errors, stats.PerSecond(stats.Failures))
+	}
+	lines := []string{
+		fmt.Sprintf("%s   run: %s   elapsed: %s   %s%s", bold("File Server Load Test"), d.runID, elapsed, tr.stage(), pausedLabel(tr.pause)),
+		"",
+		fmt.Sprintf("Current req/sec: %-8d Successful: %-8d Throttled: %-8d Max successful: %d",
+			tr.numLastInterval, tr.numSuccessLastInterval, tr.numThrottledLastInterval, tr.maxSeenSuccessfulRequestPerSec),
+		fmt.Sprintf("GET/sec: %-8d PUT/sec: %-8d DELETE/sec: %-8d CONSISTENCY/sec: %d",
+			tr.numGetLastInterval, tr.numPutLastInterval, tr.numDeleteLastInterval, tr.numConsistencyLastInterval),
+		fmt.Sprintf("Upload MB/sec: %-8s Download MB/sec: %-8s Total up: %sMB  Total down: %sMB",
+			bytesToMB(int64(tr.bytesUploadedLastInterval)), bytesToMB(int64(tr.bytesDownloadedLastInterval)),
+			bytesToMB(tr.bytesUploaded.Load()), bytesToMB(tr.bytesDownloaded.Load())),
+		fmt.Sprintf("Requests: %-9d Success: %-9d Failures: %-9d 5XX: %-9d Consistency failures: %d",
+			tr.numRequests.Get(), tr.numSuccess.Get(), tr.numFailure.Get(), tr.num500s.Get(), tr.numFailedConsistency.Get()),
+		fmt.Sprintf("Latency p50: %dms  p90: %dms  p99: %dms  p99.9: %dms  Apdex: %s",
+			tr.latency.Percentile(50).Milliseconds(), tr.latency.Percentile(90).Milliseconds(),
+			tr.latency.Percentile(99).Milliseconds(), tr.latency.Percentile(99.9).Milliseconds(), tr.apdex),
+		fmt.Sprintf("Last %s  p50: %dms  p90: %dms  p99: %dms", tr.recentWindow(),
+			recentLatency.Percentile(50).Milliseconds(), recentLatency.Percentile(90).Milliseconds(), recentLatency.Percentile(99).Milliseconds()),
+		"",
+		fmt.Sprintf("Throughput  %s", sparkline(throughput)),
+		fmt.Sprintf("Errors/sec  %s", sparkline(errors)),
+		"",
+		bold("Recent errors"),
+	}
+	recent := tr.recentErrors.Recent(dashboardErrorLines)
+	tr.resultLock.RUnlock()
+
+	lines = append(lines, recent...)
+
+	var sb strings.Builder
+	sb.WriteString(ansiHome)
+	for _, line := range lines {
+		sb.WriteString(line)
+		sb.WriteString(ansiClearLine)
+		sb.WriteString("\n")
+	}
+	sb.WriteString(ansiClearBelow)
+	_, _ = io.WriteString(w, sb.String())
+}
+
+// bold styles s, unless colors are disabled.
+func bold(s string) string {
+	if color.NoColor {
+		return s
+	}
+
+	return ansiBold + s + ansiReset
+}
+
+// sparkline scales values to the max value in the series.
+func sparkline(values []float64) string {
+	maxValue := 0.0
+	for _, v := range values {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		idx := 0
+		if maxValue > 0 {
+			idx = int(v / maxValue * float64(len(sparkTicks)-1))
+		}
+		sb.WriteRune(sparkTicks[idx])
+	}
+	sb.WriteString(fmt.Sprintf(" %.0f", lastValue(values)))
+
+	return sb.String()
+}
+
+func lastValue(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	return values[len(values)-1]
+}
+
+// pausedLabel marks the dashboard header while load generation is paused.
+func pausedLabel(pause *PauseControl) string {
+	if !pause.Paused() {
+		return ""
+	}
+
+	return fmt.Sprintf("   %s", bold(fmt.Sprintf("PAUSED %s", pause.PausedFor().Truncate(time.Second))))
+}
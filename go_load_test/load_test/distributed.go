@@ -0,0 +1,567 @@
+package load_test
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Distributed load generation, for when one machine can't saturate the file server. A coordinator waits for agents
+// to connect, sends each an AgentPlan with its share of the load, then merges every agent's results into its own
+// ResultAggregator. Agents run the normal scheduler + runner against the file server + stream results back.
+//
+// Messages are gob encoded over one TCP connection per agent. Connections aren't authenticated or encrypted, so only
+// run agents on a trusted network.
+
+const AgentWorkerStride = 100000 // Agent N's workers are reported as N*AgentWorkerStride + worker ID, so IDs don't collide
+
+// AgentPlan is everything an agent needs to run its share of a test.
+type AgentPlan struct {
+	RunID             string
+	Agent             int // Index of the agent the plan was sent to, from 0
+	Agents            int
+	EndpointCfg       TestEndpointConfig
+	Targets           Targets
+	TestConfig        TestConfig
+	SeedCadence       TestCadenceConfig
+	SeedGrowthAmount  float64
+	EnableRequestRamp bool
+	Profile           string    // Load profile spec. Each agent schedules its share of the profile's rate
+	TestPlan          *TestPlan // If set, replaces Profile
+	OperationMix      OperationMix
+	Scenarios         *ScenarioFile
+	VirtualUsers      int
+	ThinkTime         ThinkTime
+	Pacing            time.Duration
+	Jitter            Jitter
+	Arrivals          Arrivals
+	Chaos             *Chaos
+	Retries           *RetryQueue
+	ExtraOps          ExtraOps
+	ConsistencyPool   *ConsistencyPool // Each agent runs its own pool at its share of the rate
+	ConsistencyRate   float64
+	RandSeed          int64
+	Duration          time.Duration
+	RequestLimit      RequestLimit
+	Warmup            time.Duration
+	WarmupRate        int
+}
+
+// NewAgentPlan builds the plan for a whole run from cfg. Agents are each sent their share with For.
+func NewAgentPlan(cfg TestSchedulerConfig, profileSpec string) (AgentPlan, error) {
+	if _, ok := cfg.Profile.(*AutoTune); ok {
+		return AgentPlan{}, fmt.Errorf("the autotune profile isn't supported with agents, it needs results as they happen")
+	}
+
+	testPlan, _ := cfg.Profile.(*TestPlan)
+	return AgentPlan{
+		RunID:             cfg.RunID,
+		EndpointCfg:       cfg.EndpointCfg,
+		Targets:           cfg.Targets,
+		TestConfig:        cfg.TestConfig,
+		SeedCadence:       cfg.SeedCadence,
+		SeedGrowthAmount:  cfg.SeedGrowthAmount,
+		EnableRequestRamp: cfg.EnableRequestRamp,
+		Profile:           profileSpec,
+		TestPlan:          testPlan,
+		OperationMix:      cfg.OperationMix,
+		Scenarios:         cfg.Scenarios,
+		VirtualUsers:      cfg.VirtualUsers,
+		ThinkTime:         cfg.ThinkTime,
+		Pacing:            cfg.Pacing,
+		Jitter:            cfg.Jitter,
+		Arrivals:          cfg.Arrivals,
+		Chaos:             cfg.Chaos,
+		Retries:           cfg.Retries,
+		ExtraOps:          cfg.ExtraOps,
+		ConsistencyPool:   cfg.ConsistencyPool,
+		ConsistencyRate:   cfg.ConsistencyRate,
+		RandSeed:          cfg.RandSeed,
+		Duration:          cfg.Duration,
+		RequestLimit:      cfg.RequestLimit,
+		Warmup:            cfg.Warmup,
+		WarmupRate:        cfg.WarmupRate,
+	}, nil
+}
+
+// For returns agent's share of the plan. Rates, users + request limits are split as evenly as possible.
+func (p AgentPlan) For(agent int, agents int) AgentPlan {
+	plan := p
+	plan.Agent = agent
+	plan.Agents = agents
+	plan.SeedCadence.TestsPerDuration = share(p.SeedCadence.TestsPerDuration, agent, agents)
+	plan.SeedGrowthAmount = p.SeedGrowthAmount / float64(agents)
+	plan.VirtualUsers = share(p.VirtualUsers, agent, agents)
+	plan.WarmupRate = share(p.WarmupRate, agent, agents)
+	plan.ConsistencyRate = p.ConsistencyRate / float64(agents)
+	plan.RequestLimit.Total = share(p.RequestLimit.Total, agent, agents)
+	if p.RandSeed != 0 {
+		// Each agent gets its own stream, or every agent would create the same keys.
+		plan.RandSeed = p.RandSeed + int64(agent)
+	}
+	if p.ConsistencyPool != nil {
+		// Every agent needs at least one worker, or its checks would never run.
+		plan.ConsistencyPool = &ConsistencyPool{
+			Workers: Max(share(p.ConsistencyPool.Workers, agent, agents), 1),
+			Rate:    p.ConsistencyPool.Rate / float64(agents),
+			Queue:   Max(share(p.ConsistencyPool.Queue, agent, agents), 1),
+		}
+	}
+	if p.RequestLimit.PerOp != nil {
+		plan.RequestLimit.PerOp = make(map[TestType]int, len(p.RequestLimit.PerOp))
+		for op, count := range p.RequestLimit.PerOp {
+			plan.RequestLimit.PerOp[op] = share(count, agent, agents)
+		}
+	}
+	if p.TestConfig.ConcurrencyLimits != nil {
+		plan.TestConfig.ConcurrencyLimits = make(ConcurrencyLimits, len(p.TestConfig.ConcurrencyLimits))
+		for op, limit := range p.TestConfig.ConcurrencyLimits {
+			// Every agent needs at least one slot, or it could never run the operation.
+			plan.TestConfig.ConcurrencyLimits[op] = share(limit, agent, agents)
+			if plan.TestConfig.ConcurrencyLimits[op] < 1 {
+				plan.TestConfig.ConcurrencyLimits[op] = 1
+			}
+		}
+	}
+
+	return plan
+}
+
+// share returns agent's share of n, with the remainder going to the first agents.
+func share(n int, agent int, agents int) int {
+	s := n / agents
+	if agent < n%agents {
+		s++
+	}
+
+	return s
+}
+
+// agentShare schedules one agent's share of a load profile's rate.
+type agentShare struct {
+	profile LoadProfile
+	agent   int
+	agents  int
+}
+
+func (a agentShare) Rate(elapsed time.Duration) int {
+	return share(a.profile.Rate(elapsed), a.agent, a.agents)
+}
+
+// Mix passes through the profile's mix, if it has one.
+func (a agentShare) Mix(elapsed time.Duration) OperationMix {
+	if mixed, ok := a.profile.(MixedProfile); ok {
+		return mixed.Mix(elapsed)
+	}
+
+	return OperationMix{}
+}
+
+// agentMessage is sent from the coordinator to an agent. The first message carries the plan, the second asks the
+// agent to stop.
+type agentMessage struct {
+	Plan *AgentPlan
+	Stop bool
+}
+
+// wireResult is a TestResult as streamed from an agent to the coordinator. An agent that retries failures ends the
+// stream with a message carrying only Retries.
+type wireResult struct {
+	TestType      TestType
+	Duration      time.Duration
+	FileName      string
+	Message       string
+	Err           string
+	Failed        bool
+	Responded     bool
+	StatusCode    int
+	RetryAfter    string
+	SelfThrottled bool
+	Fault         ChaosFault
+	Timeout       bool
+	Attempt       int
+	Checksum      checksumStatus
+	Listing       []ListingObservation
+	Walk          *ListWalk
+	Copied        *CopyTiming
+	Fuzz          *FuzzKey
+	Boundary      *BoundaryCase
+	Downloaded    *DownloadTiming
+	Seq           int64
+	TraceID       [16]byte
+	SpanID        [8]byte
+	Phases        map[RequestPhase]time.Duration
+	ReusedConns   int
+	NewConns      int
+	Lag           time.Duration
+	Started       time.Time
+	Worker        int
+	Check         ConsistencyStep
+	Mismatch      string
+	Scenario      *ScenarioStepResult
+	BytesSent     int64
+	BytesReceived int64
+	Retries       *retryCounts
+}
+
+func toWire(result TestResult) wireResult {
+	w := wireResult{
+		TestType:      result.testType,
+		Duration:      result.duration,
+		FileName:      result.fileName,
+		Message:       result.message,
+		Failed:        result.failed,
+		TraceID:       result.trace.traceID,
+		SpanID:        result.trace.spanID,
+		Phases:        result.phases.Durations(),
+		Lag:           result.lag,
+		Started:       result.started,
+		Worker:        result.worker,
+		Check:         result.check,
+		Mismatch:      result.mismatch,
+		Scenario:      result.scenario,
+		BytesSent:     result.bytesSent,
+		BytesReceived: result.bytesReceived,
+		SelfThrottled: result.selfThrottled,
+		Fault:         result.fault,
+		Timeout:       result.timedOut,
+		Attempt:       result.attempt,
+		Checksum:      result.checksum,
+		Listing:       result.listing,
+		Walk:          result.walk,
+		Copied:        result.copied,
+		Fuzz:          result.fuzz,
+		Boundary:      result.boundary,
+		Downloaded:    result.downloaded,
+		Seq:           result.RequestSeq(),
+	}
+	w.ReusedConns, w.NewConns = result.phases.Connections()
+	if result.err != nil {
+		w.Err = result.err.Error()
+	}
+	if result.response != nil {
+		w.Responded = true
+		w.StatusCode = result.response.StatusCode
+		w.RetryAfter = result.response.Header.Get("Retry-After")
+	}
+
+	return w
+}
+
+// fromWire rebuilds a result received from agent. Only the parts of the response results are aggregated from are kept.
+func fromWire(w wireResult, agent int) TestResult {
+	result := TestResult{
+		testType:      w.TestType,
+		duration:      w.Duration,
+		fileName:      w.FileName,
+		message:       w.Message,
+		failed:        w.Failed,
+		trace:         traceContext{traceID: w.TraceID, spanID: w.SpanID},
+		phases:        NewRequestPhases(),
+		lag:           w.Lag,
+		started:       w.Started,
+		worker:        agent*AgentWorkerStride + w.Worker,
+		check:         w.Check,
+		mismatch:      w.Mismatch,
+		scenario:      w.Scenario,
+		bytesSent:     w.BytesSent,
+		bytesReceived: w.BytesReceived,
+		selfThrottled: w.SelfThrottled,
+		fault:         w.Fault,
+		timedOut:      w.Timeout,
+		attempt:       w.Attempt,
+		checksum:      w.Checksum,
+		listing:       w.Listing,
+		walk:          w.Walk,
+		copied:        w.Copied,
+		fuzz:          w.Fuzz,
+		boundary:      w.Boundary,
+		downloaded:    w.Downloaded,
+	}
+	for phase, d := range w.Phases {
+		result.phases.durations[phase] = d
+	}
+	result.phases.reusedConns, result.phases.newConns = w.ReusedConns, w.NewConns
+	result.phases.lastSeq = w.Seq
+	if w.Err != "" {
+		result.err = errors.New(w.Err)
+	}
+	if w.Responded {
+		result.response = &http.Response{StatusCode: w.StatusCode, Header: make(http.Header)}
+		if w.RetryAfter != "" {
+			result.response.Header.Set("Retry-After", w.RetryAfter)
+		}
+	}
+
+	return result
+}
+
+// agentConn is the coordinator's side of a connected agent.
+type agentConn struct {
+	conn     net.Conn
+	encLock  sync.Mutex
+	enc      *gob.Encoder
+	dec      *gob.Decoder
+	agentIdx int
+}
+
+func (a *agentConn) send(msg agentMessage) error {
+	a.encLock.Lock()
+	defer a.encLock.Unlock()
+
+	return a.enc.Encode(msg)
+}
+
+// RunCoordinator waits for agents to connect on addr, sends each its share of plan, then sends every result they
+// stream back to cfg.ResultChan until they've all finished. Agents are asked to stop when cfg.ShutdownChan is closed.
+// cfg.ResultChan is always closed on return, so the aggregator finishes even if agents never connected.
+func RunCoordinator(addr string, agents int, plan AgentPlan, cfg TestSchedulerConfig) error {
+	defer close(cfg.ResultChan)
+	// Nothing schedules tests on the coordinator, so nothing reads tracked file updates from the aggregator.
+	go drainResults(cfg.FailureChan)
+	go drainResults(cfg.SuccessChan)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for agents on: %s. Error: %w", addr, err)
+	}
+	stopAccepting := make(chan struct{})
+	go func() {
+		select {
+		case <-cfg.ShutdownChan:
+		case <-stopAccepting:
+		}
+		_ = listener.Close()
+	}()
+
+	log.Infof("Waiting for %d agents on %s", agents, addr)
+	var conns []*agentConn
+	for len(conns) < agents {
+		conn, err := listener.Accept()
+		if err != nil {
+			break
+		}
+		log.Infof("Agent %d of %d connected from %s", len(conns)+1, agents, conn.RemoteAddr())
+		conns = append(conns, &agentConn{conn: conn, enc: gob.NewEncoder(conn), dec: gob.NewDecoder(conn), agentIdx: len(conns)})
+	}
+	close(stopAccepting)
+	if len(conns) < agents {
+		log.Warnf("Shut down while waiting for agents, only %d of %d connected", len(conns), agents)
+	}
+
+	var streams sync.WaitGroup
+	for _, agent := range conns {
+		agentPlan := plan.For(agent.agentIdx, agents)
+		err := agent.send(agentMessage{Plan: &agentPlan})
+		if err != nil {
+			log.Errorf("Failed to send plan to agent %d: %+v", agent.agentIdx, err)
+			_ = agent.conn.Close()
+			continue
+		}
+
+		streams.Add(1)
+		go func(agent *agentConn) {
+			defer streams.Done()
+			defer agent.conn.Close()
+			for {
+				var w wireResult
+				err := agent.dec.Decode(&w)
+				if err != nil {
+					if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+						log.Errorf("Lost connection to agent %d: %+v", agent.agentIdx, err)
+					}
+					return
+				}
+				if w.Retries != nil {
+					cfg.Retries.add(*w.Retries)
+					continue
+				}
+				cfg.ResultChan <- fromWire(w, agent.agentIdx)
+			}
+		}(agent)
+	}
+
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-cfg.ShutdownChan:
+		case <-finished:
+			return
+		}
+		for _, agent := range conns {
+			err := agent.send(agentMessage{Stop: true})
+			if err != nil {
+				log.Warnf("Failed to stop agent %d: %+v", agent.agentIdx, err)
+			}
+		}
+	}()
+
+	streams.Wait()
+	log.Infof("All agents finished.")
+	return nil
+}
+
+func drainResults(results chan TestResult) {
+	for range results {
+	}
+}
+
+// RunAgent connects to the coordinator at addr, runs the plan it sends against the file server, + streams results
+// back until the plan finishes, the coordinator asks it to stop or ctx is cancelled.
+func RunAgent(ctx context.Context, addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to coordinator: %s. Error: %w", addr, err)
+	}
+	defer conn.Close()
+
+	enc := gob.NewEncoder(conn)
+	dec := gob.NewDecoder(conn)
+	var msg agentMessage
+	err = dec.Decode(&msg)
+	if err != nil {
+		return fmt.Errorf("failed to receive plan from coordinator: %s. Error: %w", addr, err)
+	}
+	if msg.Plan == nil {
+		return fmt.Errorf("coordinator: %s sent a message without a plan", addr)
+	}
+
+	plan := *msg.Plan
+	cfg, err := plan.schedulerConfig()
+	if err != nil {
+		return err
+	}
+	log.Infof("Running agent %d of %d for run %s", plan.Agent+1, plan.Agents, plan.RunID)
+
+	go func() {
+		var msg agentMessage
+		err := dec.Decode(&msg)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Warnf("Lost connection to coordinator, stopping: %+v", err)
+		}
+		CloseShutdownChan(cfg.ShutdownChan)
+	}()
+
+	scheduler := NewTestScheduler(cfg)
+	go scheduler.Run(ctx)
+	runner := NewTestRunner(TestRunnerConfig{
+		TestConfig:      cfg.TestConfig,
+		EndpointCfg:     cfg.EndpointCfg,
+		Targets:         cfg.Targets,
+		ResultChan:      cfg.ResultChan,
+		ScheduleChan:    cfg.SchedulerChan,
+		ShutdownChan:    cfg.ShutdownChan,
+		VirtualUsers:    cfg.VirtualUsers,
+		ThinkTime:       cfg.ThinkTime,
+		Pacing:          cfg.Pacing,
+		Jitter:          cfg.Jitter,
+		Chaos:           cfg.Chaos,
+		ConsistencyPool: cfg.ConsistencyPool,
+		ConsistencyChan: cfg.ConsistencyChan,
+		Retries:         cfg.Retries,
+		Stores:          cfg.Stores,
+	})
+	go runner.Run(ctx)
+
+	// Stands in for the aggregator: the scheduler still needs results to track which files exist.
+	defer close(cfg.FailureChan)
+	defer close(cfg.SuccessChan)
+	streaming := true
+	for result := range cfg.ResultChan {
+		publishTracking(result, cfg.FailureChan, cfg.SuccessChan)
+		if !streaming {
+			continue
+		}
+
+		err := enc.Encode(toWire(result))
+		if err != nil {
+			log.Errorf("Failed to send results to coordinator, stopping: %+v", err)
+			streaming = false
+			CloseShutdownChan(cfg.ShutdownChan)
+		}
+	}
+	if streaming && cfg.Retries != nil {
+		counts := cfg.Retries.counts()
+		err = enc.Encode(wireResult{Retries: &counts})
+		if err != nil {
+			log.Errorf("Failed to send retry counts to coordinator: %+v", err)
+		}
+	}
+
+	log.Infof("Agent %d of %d finished", plan.Agent+1, plan.Agents)
+	return nil
+}
+
+// schedulerConfig builds the config an agent runs the plan with.
+func (p AgentPlan) schedulerConfig() (TestSchedulerConfig, error) {
+	profile, err := ParseLoadProfile(p.Profile)
+	if err != nil {
+		return TestSchedulerConfig{}, fmt.Errorf("invalid load profile in plan: %w", err)
+	}
+	if p.TestPlan != nil {
+		profile = p.TestPlan
+	}
+	if burst, ok := profile.(*Burst); ok {
+		// Bursts are released all at once rather than by rate, so each agent releases its share of each burst.
+		shared := *burst
+		shared.Size = share(burst.Size, p.Agent, p.Agents)
+		profile = &shared
+	} else if profile != nil {
+		profile = agentShare{profile: profile, agent: p.Agent, agents: p.Agents}
+	}
+
+	queueSize := 50000
+	if p.VirtualUsers > 0 {
+		queueSize = p.VirtualUsers
+	}
+	// The coordinator's own requests (I.E cleanup) keep the sequence #s below the first agent's.
+	testConfig := p.TestConfig
+	testConfig.RequestTags = NewRequestTags(p.RunID, int64(p.Agent+1)*AgentRequestSeqStride)
+	var consistencyChan chan Test
+	if p.ConsistencyPool != nil {
+		consistencyChan = make(chan Test, p.ConsistencyPool.Queue)
+	}
+
+	return TestSchedulerConfig{
+		RunID:             p.RunID,
+		EndpointCfg:       p.EndpointCfg,
+		Targets:           p.Targets,
+		SeedCadence:       p.SeedCadence,
+		SeedGrowthAmount:  p.SeedGrowthAmount,
+		Profile:           profile,
+		VirtualUsers:      p.VirtualUsers,
+		ThinkTime:         p.ThinkTime,
+		Pacing:            p.Pacing,
+		Jitter:            p.Jitter,
+		Arrivals:          p.Arrivals,
+		Chaos:             p.Chaos,
+		Retries:           p.Retries,
+		ExtraOps:          p.ExtraOps,
+		ConsistencyPool:   p.ConsistencyPool,
+		ConsistencyChan:   consistencyChan,
+		ConsistencyRate:   p.ConsistencyRate,
+		RandSeed:          p.RandSeed,
+		EnableRequestRamp: p.EnableRequestRamp,
+		Duration:          p.Duration,
+		RequestLimit:      p.RequestLimit,
+		TestConfig:        testConfig,
+		Stores:            NewRunStores(p.ExtraOps),
+		OperationMix:      p.OperationMix,
+		Scenarios:         p.Scenarios,
+		SchedulerChan:     make(chan Test, queueSize),
+		ResultChan:        make(chan TestResult, 15000),
+		FailureChan:       make(chan TestResult, 1000),
+		SuccessChan:       make(chan TestResult, 20000),
+		ShutdownChan:      make(chan bool, 1),
+		Warmup:            p.Warmup,
+		WarmupRate:        p.WarmupRate,
+	}, nil
+}
@@ -0,0 +1,205 @@
+package load_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// roundTrip gob encodes + decodes v into out, the way coordinator + agents exchange messages.
+func roundTrip(t *testing.T, v interface{}, out interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		t.Fatalf("encoding %T: %+v", v, err)
+	}
+	if err := gob.NewDecoder(&buf).Decode(out); err != nil {
+		t.Fatalf("decoding %T: %+v", v, err)
+	}
+}
+
+func TestAgentPlanRoundTrip(t *testing.T) {
+	ops := ExtraOps{HEAD: 0.05, RANGE: 0.05, CONDITIONAL: 0.05}
+	cfg := TestSchedulerConfig{
+		RunID:        "run",
+		SeedCadence:  TestCadenceConfig{TestsPerDuration: 10},
+		VirtualUsers: 5,
+		ExtraOps:     ops,
+		RandSeed:     42,
+		RequestLimit: RequestLimit{Total: 7, PerOp: map[TestType]int{GET: 3}},
+		Stores:       NewRunStores(ops),
+		TestConfig: TestConfig{
+			MaxFileSize:       1024,
+			ConcurrencyLimits: ConcurrencyLimits{PUT: 3},
+			Timeouts:          OperationTimeouts{GET: time.Second},
+			Sizes:             &SizeDistribution{Distribution: SizeFixed, Mean: 10},
+			Keys:              &PathKeys{Depth: 2, MinDepth: 1, FanOut: 3},
+		},
+	}
+	plan, err := NewAgentPlan(cfg, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for agent := 0; agent < 2; agent++ {
+		var got agentMessage
+		share := plan.For(agent, 2)
+		roundTrip(t, agentMessage{Plan: &share}, &got)
+		if got.Plan == nil {
+			t.Fatalf("agent %d: plan lost in transit", agent)
+		}
+		if !reflect.DeepEqual(*got.Plan, share) {
+			t.Errorf("agent %d: got plan %+v, want %+v", agent, *got.Plan, share)
+		}
+		if _, err := got.Plan.schedulerConfig(); err != nil {
+			t.Errorf("agent %d: %+v", agent, err)
+		}
+	}
+
+	var stop agentMessage
+	roundTrip(t, agentMessage{Stop: true}, &stop)
+	if !stop.Stop || stop.Plan != nil {
+		t.Errorf("got stop message %+v", stop)
+	}
+}
+
+func TestWireResultRoundTrip(t *testing.T) {
+	phases := NewRequestPhases()
+	phases.durations[PhaseTTFB] = 3 * time.Millisecond
+	result := TestResult{
+		testType:      COPY,
+		duration:      5 * time.Millisecond,
+		fileName:      "file",
+		message:       "message",
+		err:           errors.New("boom"),
+		failed:        true,
+		response:      &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"2"}}},
+		phases:        phases,
+		started:       time.Unix(100, 0).UTC(),
+		worker:        3,
+		attempt:       1,
+		mismatch:      "mismatch",
+		bytesSent:     10,
+		bytesReceived: 20,
+		checksum:      checksumCorrupt,
+		walk:          &ListWalk{Pages: 2, Items: 3},
+		copied:        &CopyTiming{Bytes: 10, Latency: time.Millisecond},
+		fuzz:          &FuzzKey{Kind: "space", Stored: true},
+		boundary:      &BoundaryCase{Name: "max+1"},
+		downloaded:    &DownloadTiming{Bytes: 10, Duration: time.Millisecond},
+	}
+
+	var w wireResult
+	roundTrip(t, toWire(result), &w)
+	got := fromWire(w, 2)
+
+	if got.testType != result.testType || got.duration != result.duration || got.fileName != result.fileName ||
+		got.message != result.message || got.failed != result.failed || got.mismatch != result.mismatch ||
+		got.attempt != result.attempt || got.checksum != result.checksum || !got.started.Equal(result.started) ||
+		got.bytesSent != result.bytesSent || got.bytesReceived != result.bytesReceived {
+		t.Errorf("got %+v, want %+v", got, result)
+	}
+	if got.worker != 2*AgentWorkerStride+3 {
+		t.Errorf("got worker %d, want %d", got.worker, 2*AgentWorkerStride+3)
+	}
+	if got.err == nil || got.err.Error() != "boom" {
+		t.Errorf("got error %v, want boom", got.err)
+	}
+	if got.StatusCode() != http.StatusTooManyRequests || got.response.Header.Get("Retry-After") != "2" {
+		t.Errorf("got response %+v", got.response)
+	}
+	if got.phases.durations[PhaseTTFB] != 3*time.Millisecond {
+		t.Errorf("got phases %+v", got.phases.durations)
+	}
+	if !reflect.DeepEqual(got.walk, result.walk) || !reflect.DeepEqual(got.copied, result.copied) ||
+		!reflect.DeepEqual(got.fuzz, result.fuzz) || !reflect.DeepEqual(got.boundary, result.boundary) ||
+		!reflect.DeepEqual(got.downloaded, result.downloaded) {
+		t.Errorf("extra op details lost: %+v", got)
+	}
+}
+
+func TestAgentRetryCounts(t *testing.T) {
+	agent := &RetryQueue{}
+	agent.queued.Add(5)
+	agent.exhausted.Add(2)
+	agent.dropped.Add(1)
+	counts := agent.counts()
+
+	var w wireResult
+	roundTrip(t, wireResult{Retries: &counts}, &w)
+	coordinator := &RetryQueue{}
+	coordinator.dropped.Inc()
+	for i := 0; i < 2; i++ {
+		coordinator.add(*w.Retries)
+	}
+	(*RetryQueue)(nil).add(*w.Retries)
+
+	want := retryCounts{Queued: 10, Exhausted: 4, Dropped: 3}
+	if got := coordinator.counts(); got != want {
+		t.Errorf("got %+v, want %+v summed across agents", got, want)
+	}
+}
+
+func TestAgentPlanFor(t *testing.T) {
+	plan := AgentPlan{
+		SeedCadence:     TestCadenceConfig{TestsPerDuration: 10},
+		VirtualUsers:    5,
+		WarmupRate:      3,
+		ConsistencyRate: 4,
+		RandSeed:        42,
+		RequestLimit:    RequestLimit{Total: 7, PerOp: map[TestType]int{GET: 3, PUT: 1}},
+		ConsistencyPool: &ConsistencyPool{Workers: 2, Rate: 6, Queue: 1},
+		TestConfig:      TestConfig{ConcurrencyLimits: ConcurrencyLimits{PUT: 3, DELETE: 1}},
+	}
+
+	agents := 3
+	var tests, users, warmup, requests, gets, puts, workers int
+	var consistency float64
+	seeds := make(map[int64]bool)
+	for agent := 0; agent < agents; agent++ {
+		share := plan.For(agent, agents)
+		if share.Agent != agent || share.Agents != agents {
+			t.Errorf("agent %d: got agent %d of %d", agent, share.Agent, share.Agents)
+		}
+		tests += share.SeedCadence.TestsPerDuration
+		users += share.VirtualUsers
+		warmup += share.WarmupRate
+		requests += share.RequestLimit.Total
+		gets += share.RequestLimit.PerOp[GET]
+		puts += share.RequestLimit.PerOp[PUT]
+		consistency += share.ConsistencyRate
+		workers += share.ConsistencyPool.Workers
+		seeds[share.RandSeed] = true
+
+		// Every agent needs at least one worker, queue slot + concurrency slot, or it could never run checks or PUTs.
+		if share.ConsistencyPool.Workers < 1 || share.ConsistencyPool.Queue < 1 {
+			t.Errorf("agent %d: got consistency pool %+v", agent, share.ConsistencyPool)
+		}
+		if share.TestConfig.ConcurrencyLimits[DELETE] != 1 {
+			t.Errorf("agent %d: got DELETE concurrency %d, want 1", agent, share.TestConfig.ConcurrencyLimits[DELETE])
+		}
+		if share.TestConfig.ConcurrencyLimits[PUT] != 1 {
+			t.Errorf("agent %d: got PUT concurrency %d, want 1", agent, share.TestConfig.ConcurrencyLimits[PUT])
+		}
+	}
+
+	if tests != 10 || users != 5 || warmup != 3 || requests != 7 || gets != 3 || puts != 1 || workers != 3 {
+		t.Errorf("shares don't add up: tests %d, users %d, warmup %d, requests %d, GETs %d, PUTs %d, workers %d",
+			tests, users, warmup, requests, gets, puts, workers)
+	}
+	if consistency < 3.999 || consistency > 4.001 {
+		t.Errorf("got consistency rate %.3f, want 4", consistency)
+	}
+	if len(seeds) != agents {
+		t.Errorf("agents share seeds: %v", seeds)
+	}
+
+	// Sharing a plan doesn't change it.
+	if plan.RequestLimit.PerOp[GET] != 3 || plan.TestConfig.ConcurrencyLimits[PUT] != 3 || plan.ConsistencyPool.Workers != 2 {
+		t.Errorf("For modified the original plan: %+v", plan)
+	}
+}
@@ -0,0 +1,120 @@
+package load_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DOWNLOAD tests upload a new file of TestConfig.DownloadSize bytes, streamed like STREAM uploads, then download it
+// hashing the body as it arrives rather than buffering it, so files of several GB can be verified without running out
+// of memory. The download must return exactly the bytes uploaded, + its sustained bandwidth, from the response's
+// headers to its last byte, is reported. The file is deleted afterwards.
+const DOWNLOAD TestType = "DOWNLOAD"
+
+// DefaultDownloadSize is the size of DOWNLOAD files, before base64 encoding.
+const DefaultDownloadSize int64 = 2 << 30
+
+// DownloadTiming is how much a DOWNLOAD test's GET read + how long reading its body took.
+type DownloadTiming struct {
+	Bytes    int64
+	Duration time.Duration
+}
+
+func (tr *TestExecutor) DownloadFile(test Test) {
+	ctx, result, done := tr.beginHeldTest(test, DOWNLOAD)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+
+	size := tr.downloadSize
+	if size <= 0 {
+		size = DefaultDownloadSize
+	}
+	// The payload is hashed as it's sent, so its checksum is known without keeping it.
+	uploaded := sha256.New()
+	payload := io.TeeReader(newStreamPayload(newSeededRand(test.payloadSeed), size), uploaded)
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, payload, trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for DownloadFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	req.ContentLength = int64(b64.StdEncoding.EncodedLen(int(size)))
+
+	response, err := tr.do(req, phases)
+	defer func() {
+		_, _ = tr.removeFile(fileName)
+	}()
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http streaming PUT request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	result.message = responseToString(response)
+	result.bytesSent = req.ContentLength
+	if response.StatusCode >= 400 {
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	response, err = tr.get(ctx, fileName, trace, phases)
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http GET request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		result.message = fmt.Sprintf("GET failed due to unexpected status code, got: %d but expected 200.", response.StatusCode)
+		result.mismatch = statusMismatch(response.StatusCode, http.StatusOK)
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		_ = responseToString(response)
+		tr.emit(result)
+		return
+	}
+
+	downloaded := sha256.New()
+	readStart := time.Now()
+	n, err := io.Copy(downloaded, response.Body)
+	result.downloaded = &DownloadTiming{Bytes: n, Duration: time.Now().Sub(readStart)}
+	result.bytesReceived = n
+	result.duration = time.Now().Sub(start)
+	if err != nil {
+		result.message = fmt.Sprintf("Error reading the download after %d of %d bytes", n, req.ContentLength)
+		result.err = err
+		result.failed = true
+		tr.emit(result)
+		return
+	}
+
+	result.message = ""
+	if n != req.ContentLength {
+		result.mismatch = "length mismatch"
+		result.message = fmt.Sprintf("Download was %d bytes but %d were uploaded", n, req.ContentLength)
+		result.failed = true
+	} else if !bytes.Equal(downloaded.Sum(nil), uploaded.Sum(nil)) {
+		result.mismatch = "digest mismatch"
+		result.message = "Downloaded bytes don't match the SHA-256 of the upload"
+		result.failed = true
+	}
+	tr.emit(result)
+}
@@ -0,0 +1,126 @@
+package load_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Drain lets tests already in flight finish once the run shuts down, for up to Grace, so the final numbers include the
+// run's tail rather than a burst of errors from requests cut off part way through. No new tests are started while
+// draining. Tests still in flight once Grace is up are aborted + counted as abandoned rather than merged as errors.
+// In flight tests are only tracked for load generated by this process, not by agents. A nil Drain tracks nothing.
+type Drain struct {
+	Grace     time.Duration
+	inFlight  Counter
+	abandoned Counter // Results of tests aborted once Grace was up
+	lock      sync.Mutex
+	atStart   int // Tests in flight when draining started
+	started   time.Time
+	finished  time.Time
+}
+
+type DrainSummary struct {
+	InFlight     int     `json:"in_flight"` // Tests in flight when shutdown began
+	Completed    int     `json:"completed"`
+	Abandoned    int     `json:"abandoned"` // Excluded from every other stat
+	GraceSeconds float64 `json:"grace_seconds"`
+	Seconds      float64 `json:"seconds"` // How long draining took
+}
+
+func NewDrain(grace time.Duration) *Drain {
+	return &Drain{Grace: grace}
+}
+
+func (d *Drain) start() {
+	if d != nil {
+		d.inFlight.Inc()
+	}
+}
+
+func (d *Drain) finish() {
+	if d != nil {
+		d.inFlight.Add(-1)
+	}
+}
+
+// InFlight returns the # of tests currently running.
+func (d *Drain) InFlight() int {
+	if d == nil {
+		return 0
+	}
+
+	return d.inFlight.Get()
+}
+
+// begin starts the grace period, noting how many tests are in flight. Only the first call has any effect.
+func (d *Drain) begin() {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.started.IsZero() {
+		d.started = time.Now()
+		d.atStart = d.InFlight()
+	}
+}
+
+// Wait waits for done to be closed, I.E for the aggregator to merge every in-flight test's result, until the grace
+// period that started at shutdown runs out. Returns false if it ran out first, in which case the caller should abort the
+// tests still in flight.
+func (d *Drain) Wait(done <-chan struct{}) bool {
+	d.begin()
+	d.lock.Lock()
+	remaining := d.Grace - time.Now().Sub(d.started)
+	d.lock.Unlock()
+	defer func() {
+		d.lock.Lock()
+		d.finished = time.Now()
+		d.lock.Unlock()
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(remaining):
+		return false
+	}
+}
+
+// Summary returns how draining went, or nil if the run hasn't finished draining.
+func (d *Drain) Summary() *DrainSummary {
+	if d == nil {
+		return nil
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.finished.IsZero() {
+		return nil
+	}
+
+	abandoned := d.abandoned.Get()
+	return &DrainSummary{
+		InFlight:     d.atStart,
+		Completed:    Max(d.atStart-abandoned, 0),
+		Abandoned:    abandoned,
+		GraceSeconds: d.Grace.Seconds(),
+		Seconds:      d.finished.Sub(d.started).Seconds(),
+	}
+}
+
+// abandon counts the result of a test aborted because the grace period ran out.
+func (d *Drain) abandon() {
+	if d != nil {
+		d.abandoned.Inc()
+	}
+}
+
+// wasAbandoned returns true if a request failed with err because the run's ctx was cancelled, I.E once the drain's
+// grace period ran out. Per operation timeouts end in context.DeadlineExceeded instead, so aren't abandoned.
+func wasAbandoned(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
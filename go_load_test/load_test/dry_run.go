@@ -0,0 +1,311 @@
+package load_test
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+	"math"
+	"strings"
+	"time"
+)
+
+// dryRunHorizon is how much of a run with no duration or request limit a dry run covers.
+const dryRunHorizon = time.Hour
+
+// maxDryRunLength caps how far a dry run looks for the end of a run bounded only by its request limit.
+const maxDryRunLength = 24 * time.Hour
+
+// DryRun is the fully resolved plan for a run, worked out without issuing any requests, so config can be sanity checked
+// before a long run. Rates, stages + mixes are resolved one seed duration at a time, the same way the scheduler resolves
+// them. Request + byte totals are estimates: the default mix depends on how many files exist, file sizes are random +
+// a closed loop run's rate depends on how fast the server responds, so isn't estimated at all.
+type DryRun struct {
+	Stages        []DryRunStage
+	Requests      int64
+	BytesSent     float64 // Upload bodies, base64 encoded
+	BytesReceived float64 // Download bodies, base64 encoded
+	Duration      time.Duration
+	EndsBy        string   // What ends the run, I.E "duration" or "request limit". Empty if it runs until stopped
+	Notes         []string // Caveats about the estimate
+}
+
+type DryRunStage struct {
+	Name     string
+	Start    time.Duration
+	Duration time.Duration
+	MinRate  int // req/sec
+	MaxRate  int
+	Mix      string
+	Requests int64
+}
+
+// dryRunState is what the scheduler + executor would have built up by a point in the run.
+type dryRunState struct {
+	files        float64
+	maxFileSize  float64
+	growthFactor int
+	rampFactor   int
+	rampAmount   int
+	lastRamp     time.Duration
+	tick         time.Duration // Seed duration the scheduler paces tests over
+}
+
+// NewDryRun resolves the plan cfg would run, + the pre-seeding it would start with if preseed is set.
+func NewDryRun(cfg TestSchedulerConfig, preseed *Preseed) DryRun {
+	dry := DryRun{}
+	if cfg.WarmupRate <= 0 {
+		cfg.WarmupRate = DefaultWarmupRate
+	}
+	state := dryRunState{files: float64(len(cfg.Preseeded)), maxFileSize: float64(cfg.TestConfig.MaxFileSize), rampFactor: 1,
+		tick: cfg.SeedCadence.Duration}
+	if state.tick <= 0 {
+		state.tick = time.Second
+	}
+	if preseed != nil {
+		state.files = float64(preseed.Count)
+		avgSize := float64(preseed.MinSize+preseed.MaxSize) / 2
+		dry.Requests += int64(preseed.Count)
+		dry.BytesSent += float64(preseed.Count) * encodedSize(avgSize)
+		dry.Notes = append(dry.Notes, fmt.Sprintf("Pre-seeding uploads %d files before load starts, included in the totals.",
+			preseed.Count))
+	}
+
+	_, autoTuned := cfg.Profile.(*AutoTune)
+	if cfg.VirtualUsers > 0 || autoTuned {
+		reason := fmt.Sprintf("%d virtual users run closed loop", cfg.VirtualUsers)
+		if autoTuned {
+			reason = "The autotune profile picks its rate from results"
+		}
+		dry.Notes = append(dry.Notes, reason+", so the rate depends on how fast the server responds + isn't estimated.")
+		if cfg.Duration > 0 {
+			dry.Duration, dry.EndsBy = cfg.Duration, "duration"
+		}
+		return dry
+	}
+
+	if cfg.Replay != nil {
+		dry.Notes = append(dry.Notes, fmt.Sprintf("Replaying %s, payload sizes aren't recorded so bytes aren't estimated.",
+			cfg.Replay))
+		stage := DryRunStage{Name: "replay", Duration: cfg.Replay.Duration(), Mix: "recorded"}
+		for _, op := range cfg.Replay.ops {
+			stage.Requests += Test{TestType: op.testType}.requests()
+		}
+		if stage.Duration > 0 {
+			stage.MinRate = int(float64(stage.Requests) / stage.Duration.Seconds())
+			stage.MaxRate = stage.MinRate
+		}
+		dry.Stages = append(dry.Stages, stage)
+		dry.Requests += stage.Requests
+		dry.Duration, dry.EndsBy = stage.Duration, "end of replay"
+		if cfg.Duration > 0 && (stage.Duration == 0 || cfg.Duration < stage.Duration) {
+			dry.Duration, dry.EndsBy = cfg.Duration, "duration"
+		}
+		return dry
+	}
+
+	length := cfg.Duration
+	switch {
+	case cfg.Duration > 0:
+		dry.EndsBy = "duration"
+	case cfg.RequestLimit.Total > 0:
+		length = maxDryRunLength
+		dry.EndsBy = "request limit"
+	default:
+		length = dryRunHorizon
+	}
+	if len(cfg.RequestLimit.PerOp) > 0 {
+		dry.Notes = append(dry.Notes, "Per operation request limits aren't estimated, the run may end sooner.")
+	}
+	if cfg.TestConfig.FileSizeRamp {
+		dry.Notes = append(dry.Notes, "The max file size ramps up 50% every 15s with no upper limit, so byte estimates "+
+			"grow exponentially with the length of the run.")
+	}
+	if cfg.Scenarios != nil {
+		dry.Notes = append(dry.Notes, "Scenario steps are estimated as the run's mix of operations.")
+	}
+
+	// Pre-seeding + pool consistency checks don't count towards the request limit.
+	requestLimit := int64(cfg.RequestLimit.Total)
+	var scheduled int64
+	for elapsed := time.Duration(0); elapsed < length; elapsed += state.tick {
+		warmup := cfg.Warmup > 0 && elapsed < cfg.Warmup
+		rate, stage, mix := state.resolve(cfg, elapsed, warmup)
+		perSec := int(float64(rate) * float64(time.Second) / float64(state.tick))
+		limitReached := requestLimit > 0 && scheduled+int64(rate) >= requestLimit
+		if limitReached {
+			rate = int(requestLimit - scheduled)
+		}
+		scheduled += int64(rate)
+
+		requests, sent, received := state.estimate(rate, mix, cfg)
+		dry.Requests += requests
+		dry.BytesSent += sent
+		dry.BytesReceived += received
+		dry.Duration += state.tick
+		dry.addTick(stage, elapsed, state.tick, perSec, mix, requests)
+		if limitReached {
+			break
+		}
+		state.advance(cfg, elapsed+state.tick, warmup)
+	}
+	if requestLimit > 0 && scheduled < requestLimit {
+		dry.Notes = append(dry.Notes, fmt.Sprintf("The request limit isn't reached within %s.", length))
+	}
+
+	return dry
+}
+
+// resolve returns the rate, stage + mix the scheduler would run at elapsed.
+func (s *dryRunState) resolve(cfg TestSchedulerConfig, elapsed time.Duration, warmup bool) (int, string, OperationMix) {
+	if warmup {
+		return cfg.WarmupRate, StageWarmup, cfg.OperationMix
+	}
+
+	profileElapsed := elapsed - cfg.Warmup
+	stage := StageMeasured
+	if staged, ok := cfg.Profile.(StagedProfile); ok {
+		stage = staged.Stage(profileElapsed)
+	}
+	mix := cfg.OperationMix
+	if mixed, ok := cfg.Profile.(MixedProfile); ok && mixed.Mix(profileElapsed).IsSet() {
+		mix = mixed.Mix(profileElapsed)
+	}
+	if cfg.Profile != nil {
+		return Max(cfg.Profile.Rate(profileElapsed), 0), stage, mix
+	}
+
+	return cfg.SeedCadence.TestsPerDuration + int(float64(s.growthFactor)*cfg.SeedGrowthAmount) + s.rampAmount, stage, mix
+}
+
+// advance moves on to the seed duration starting at elapsed, growing + ramping the rate the way ScheduleTests does,
+// + ramping the max file size the way the runner does.
+func (s *dryRunState) advance(cfg TestSchedulerConfig, elapsed time.Duration, warmup bool) {
+	if !warmup {
+		s.growthFactor++
+	}
+	if cfg.EnableRequestRamp {
+		if elapsed-s.lastRamp > time.Minute {
+			s.rampFactor++
+			s.lastRamp = elapsed
+		}
+		s.rampAmount += int(cfg.SeedGrowthAmount * float64(s.rampFactor))
+	}
+	if cfg.TestConfig.FileSizeRamp && elapsed%(15*time.Second) < s.tick {
+		s.maxFileSize *= 1.5
+	}
+}
+
+// estimate returns the # of requests + bytes sent + received for rate requests, updating the # of files on the server.
+func (s *dryRunState) estimate(rate int, mix OperationMix, cfg TestSchedulerConfig) (int64, float64, float64) {
+	maxFiles := float64(Max(cfg.TestConfig.MaxFileCount, 1))
+	tests := float64(rate)
+	var uploads, downloads float64
+	if mix.IsSet() {
+		shares := mix.Shares()
+		if cfg.consistencyRate() > 0 {
+			shares = mixShares(map[TestType]int{GET: mix.Get, PUT: mix.Put, DELETE: mix.Delete})
+		}
+		// A consistency check is 4 requests: a PUT, a GET + a DELETE, verified by a GET that 404s.
+		tests /= 1 + 3*shares[CONSISTENCY]
+		uploads = tests * (shares[PUT] + shares[CONSISTENCY])
+		downloads = tests * (shares[GET] + shares[CONSISTENCY])
+		s.files += tests * (shares[PUT]*math.Max(1-s.files/maxFiles, 0) - shares[DELETE])
+	} else {
+		// The default mix creates files until there are MaxFileCount, then runs defaultTests on them.
+		creates := tests * math.Max(1-s.files/maxFiles, 0)
+		existing := tests - creates
+		uploads = creates + existing/77
+		downloads = existing * 75 / 77
+		s.files += creates - existing/77
+	}
+	s.files = math.Max(s.files, 0)
+
+	requests := int64(rate)
+	if rate := cfg.consistencyRate(); rate > 0 {
+		checks := rate * s.tick.Seconds()
+		requests += int64(4 * checks)
+		uploads += checks
+		downloads += checks
+	}
+	fileSize := encodedSize(avgFileSize(s.maxFileSize, cfg.TestConfig.UploadRandomLargeFile))
+	if cfg.TestConfig.Sizes != nil {
+		fileSize = encodedSize(cfg.TestConfig.Sizes.mean())
+	}
+	// Empty files upload + download nothing.
+	fileSize *= 1 - cfg.TestConfig.EmptyFiles
+
+	return requests, uploads * fileSize, downloads * fileSize
+}
+
+// addTick adds a seed duration's requests to the current stage, starting a new one if the stage changed.
+func (d *DryRun) addTick(name string, start time.Duration, tick time.Duration, rate int, mix OperationMix, requests int64) {
+	mixName := "default"
+	if mix.IsSet() {
+		mixName = mix.String()
+	}
+
+	if n := len(d.Stages); n == 0 || d.Stages[n-1].Name != name || d.Stages[n-1].Mix != mixName {
+		d.Stages = append(d.Stages, DryRunStage{Name: name, Start: start, MinRate: rate, MaxRate: rate, Mix: mixName})
+	}
+	stage := &d.Stages[len(d.Stages)-1]
+	stage.Duration += tick
+	stage.MinRate = Min(stage.MinRate, rate)
+	stage.MaxRate = Max(stage.MaxRate, rate)
+	stage.Requests += requests
+}
+
+// avgFileSize returns the mean of randomFileSize for maxFileSize: half of sizes are under maxFileSize/2, the rest are
+// mostly halved, + 1 in 100 is a huge file if enabled.
+func avgFileSize(maxFileSize float64, uploadRandomLargeFile bool) float64 {
+	size := 0.35 * maxFileSize
+	if uploadRandomLargeFile {
+		size = 0.99*size + 0.01*float64(HugeFileSize)
+	}
+
+	return size
+}
+
+// encodedSize returns the size of size bytes once base64 encoded.
+func encodedSize(size float64) float64 {
+	return size * 4 / 3
+}
+
+func (d DryRun) Print() {
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	fmt.Println("Dry run, no requests will be sent.")
+	if len(d.Stages) > 0 {
+		tbl := table.New("Stage", "Start", "Duration", "req/sec", "Mix GET/PUT/DELETE/CONSISTENCY", "Requests")
+		tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+		for _, stage := range d.Stages {
+			rate := fmt.Sprintf("%d", stage.MinRate)
+			if stage.MaxRate != stage.MinRate {
+				rate = fmt.Sprintf("%d-%d", stage.MinRate, stage.MaxRate)
+			}
+			tbl.AddRow(stage.Name, stage.Start, stage.Duration, rate, stage.Mix, stage.Requests)
+		}
+		fmt.Println()
+		tbl.Print()
+	}
+
+	fmt.Println()
+	switch {
+	case d.EndsBy != "" && d.Duration > 0:
+		fmt.Printf("Estimated duration: %s (ends by %s)\n", d.Duration, d.EndsBy)
+	case d.EndsBy != "":
+		fmt.Printf("Estimated duration: depends on how fast the server responds (ends by %s)\n", d.EndsBy)
+	case len(d.Stages) > 0:
+		fmt.Printf("Estimated duration: runs until stopped, estimates cover the first %s\n", d.Duration)
+	default:
+		fmt.Println("Estimated duration: runs until stopped")
+	}
+	if len(d.Stages) > 0 {
+		fmt.Printf("Estimated requests: %d\n", d.Requests)
+		fmt.Printf("Estimated upload: %.2fMB, download: %.2fMB\n", d.BytesSent/1024/1024, d.BytesReceived/1024/1024)
+	}
+	if len(d.Notes) > 0 {
+		fmt.Println()
+		fmt.Println(strings.Join(d.Notes, "\n"))
+	}
+}
@@ -0,0 +1,112 @@
+package load_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ParseEmptyFiles parses the share of CREATEs, PUTs + consistency checks that upload an empty file, I.E 0.05 or 5%.
+// An empty value uploads no empty files, like runs always have.
+func ParseEmptyFiles(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return parseFraction(value)
+}
+
+// emptyPayload returns true if an upload drawing its payload from r should upload an empty file, see
+// TestConfig.EmptyFiles. r is only drawn from if some uploads are empty, so seeded runs without them are unchanged.
+func (tr *TestExecutor) emptyPayload(r *Rand) bool {
+	if tr.emptyFiles == nil {
+		return false
+	}
+
+	return tr.emptyFiles.share >= 1 || r.Float64() < tr.emptyFiles.share
+}
+
+// uploadFileSize returns the size of a PUT or consistency check's payload drawn from r: 0 for an empty file, otherwise
+// see randomFileSize.
+func (tr *TestExecutor) uploadFileSize(r *Rand) int64 {
+	if tr.emptyPayload(r) {
+		return 0
+	}
+
+	return tr.randomFileSize(r)
+}
+
+// EmptyFileStore remembers which files the run last uploaded empty, so GETs of them can check the server returns
+// an empty 200 with Content-Length: 0 rather than an error, a chunked body or stale bytes. Like ChecksumStore, files
+// are forgotten once deleted or if an upload fails, + GETs overlapping an upload or delete of the file aren't checked.
+type EmptyFileStore struct {
+	share float64 // Share of uploads that are empty files
+	lock  sync.RWMutex
+	files FileSet
+}
+
+// NewEmptyFileStore returns nil if share is 0, no uploads are empty.
+func NewEmptyFileStore(share float64) *EmptyFileStore {
+	if share <= 0 {
+		return nil
+	}
+
+	return &EmptyFileStore{share: share, files: make(FileSet)}
+}
+
+// record remembers fileName as empty if payload is + the upload succeeded, otherwise forgets it.
+func (s *EmptyFileStore) record(fileName string, payload string, response *http.Response, err error) {
+	if s == nil {
+		return
+	}
+	if payload != "" || err != nil || response == nil || response.StatusCode >= 400 {
+		s.forget(fileName)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files.Add(fileName)
+}
+
+func (s *EmptyFileStore) forget(fileName string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files.Delete(fileName)
+}
+
+func (s *EmptyFileStore) has(fileName string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.files.Has(fileName)
+}
+
+// startEmptyCheck returns true if a GET of fileName should return an empty file, taken before the GET is sent.
+func (tr *TestExecutor) startEmptyCheck(fileName string) bool {
+	return tr.emptyFiles.has(fileName) && !tr.isInProcess(fileName)
+}
+
+// emptyMismatch returns why a 200 for an empty file isn't one, or "" if it's empty with Content-Length: 0.
+func emptyMismatch(response *http.Response, body string) string {
+	if response.ContentLength < 0 {
+		// The header was missing, I.E the body was chunked
+		return "empty file without Content-Length"
+	}
+	if response.ContentLength != 0 {
+		return fmt.Sprintf("empty file Content-Length %d", response.ContentLength)
+	}
+	if body != "" {
+		return fmt.Sprintf("empty file %d byte body", len(body))
+	}
+
+	return ""
+}
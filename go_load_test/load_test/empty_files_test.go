@@ -0,0 +1,100 @@
+package load_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseEmptyFiles(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0.05", 0.05, false},
+		{"5%", 0.05, false},
+		{"-5%", 0, true},
+		{"few", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseEmptyFiles(test.value)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("ParseEmptyFiles(%q): got %g, %v, want %g, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestEmptyPayload(t *testing.T) {
+	tests := []struct {
+		share float64
+		want  int
+	}{
+		{0, 0},
+		{0.1, 10000},
+		{1, 100000},
+	}
+	for _, test := range tests {
+		exec := &TestExecutor{emptyFiles: NewEmptyFileStore(test.share)}
+		r := NewRand(1)
+		empty := 0
+		for i := 0; i < 100000; i++ {
+			if exec.emptyPayload(r) {
+				empty++
+			}
+		}
+		if empty < test.want*95/100 || empty > test.want*105/100 {
+			t.Errorf("share %g: got %d empty in 100000, want ~%d", test.share, empty, test.want)
+		}
+	}
+
+	// r is untouched without empty files, so seeded runs draw what they always have.
+	r := NewRand(1)
+	(&TestExecutor{}).emptyPayload(r)
+	if got, want := r.Float64(), NewRand(1).Float64(); got != want {
+		t.Errorf("drew from r without empty files")
+	}
+}
+
+func TestEmptyFileStore(t *testing.T) {
+	ok := &http.Response{StatusCode: http.StatusCreated}
+	store := NewEmptyFileStore(0.1)
+	store.record("empty", "", ok, nil)
+	store.record("full", "abc", ok, nil)
+	store.record("failed", "", &http.Response{StatusCode: http.StatusInternalServerError}, nil)
+	store.record("errored", "", nil, errors.New("reset"))
+	for fileName, want := range map[string]bool{"empty": true, "full": false, "failed": false, "errored": false} {
+		if got := store.has(fileName); got != want {
+			t.Errorf("%s: got empty %t, want %t", fileName, got, want)
+		}
+	}
+
+	// Overwriting an empty file with a full one forgets it.
+	store.record("empty", "abc", ok, nil)
+	if store.has("empty") {
+		t.Errorf("overwritten file still empty")
+	}
+	if NewEmptyFileStore(0).has("empty") {
+		t.Errorf("nil store has an empty file")
+	}
+}
+
+func TestEmptyMismatch(t *testing.T) {
+	tests := []struct {
+		contentLength int64
+		body          string
+		want          string
+	}{
+		{0, "", ""},
+		{-1, "", "empty file without Content-Length"},
+		{3, "abc", "empty file Content-Length 3"},
+		{0, "abc", "empty file 3 byte body"},
+	}
+	for _, test := range tests {
+		if got := emptyMismatch(&http.Response{ContentLength: test.contentLength}, test.body); got != test.want {
+			t.Errorf("Content-Length %d, body %q: got %q, want %q", test.contentLength, test.body, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package load_test
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Error budget tracking. The budget is the fraction of tests allowed to fail over the run, I.E 0.001 allows 1 failure
+// per 1000 tests. Remaining budget counts down as failures accumulate, so a slow burn shows up well before it's spent.
+
+type ErrorBudget struct {
+	Allowed         float64 `json:"allowed"` // Allowed failure fraction
+	Tests           int     `json:"tests"`
+	Failures        int     `json:"failures"`
+	AllowedFailures int     `json:"allowed_failures"`
+	Remaining       int     `json:"remaining"`       // Failures left before the budget is spent, negative once overspent
+	RemainingRatio  float64 `json:"remaining_ratio"` // Fraction of the budget left, negative once overspent
+}
+
+// ParseErrorBudget parses an error budget in percent, I.E 0.1 for 0.1% of tests, into the allowed failure fraction.
+// 0 allows no failures, + budgets of 100% or more are rejected since they could never be spent.
+func ParseErrorBudget(pct string) (float64, error) {
+	value, err := strconv.ParseFloat(pct, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s. Error: %w", pct, err)
+	}
+	if value < 0 || value >= 100 {
+		return 0, fmt.Errorf("%s. Must be >= 0 + < 100", pct)
+	}
+
+	return value / 100, nil
+}
+
+func NewErrorBudget(allowed float64, failures int, tests int) ErrorBudget {
+	allowedFailures := int(math.Floor(allowed * float64(tests)))
+	remainingRatio := 1.0
+	if tests > 0 && allowed > 0 {
+		remainingRatio = 1 - float64(failures)/(allowed*float64(tests))
+	} else if failures > 0 {
+		remainingRatio = -1
+	}
+
+	return ErrorBudget{
+		Allowed:         allowed,
+		Tests:           tests,
+		Failures:        failures,
+		AllowedFailures: allowedFailures,
+		Remaining:       allowedFailures - failures,
+		RemainingRatio:  remainingRatio,
+	}
+}
+
+func (b ErrorBudget) Exhausted() bool {
+	return b.Remaining < 0
+}
+
+func (b ErrorBudget) String() string {
+	if b.Exhausted() {
+		return fmt.Sprintf("EXHAUSTED (%d over)", -b.Remaining)
+	}
+
+	return fmt.Sprintf("%.1f%% left (%d failures)", b.RemainingRatio*100, b.Remaining)
+}
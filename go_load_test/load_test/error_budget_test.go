@@ -0,0 +1,55 @@
+package load_test
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseErrorBudget(t *testing.T) {
+	tests := []struct {
+		pct     string
+		want    float64
+		wantErr bool
+	}{
+		{"0.1", 0.001, false},
+		{"0", 0, false},
+		{"99.9", 0.999, false},
+		{"100", 0, true},
+		{"-1", 0, true},
+		{"abc", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseErrorBudget(test.pct)
+		if (err != nil) != test.wantErr || math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("ParseErrorBudget(%q): got %g, %v, want %g, error %t", test.pct, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestNewErrorBudget(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowed        float64
+		failures       int
+		tests          int
+		allowedFails   int
+		remaining      int
+		remainingRatio float64
+		exhausted      bool
+	}{
+		{"untouched", 0.01, 0, 1000, 10, 10, 1, false},
+		{"half spent", 0.01, 5, 1000, 10, 5, 0.5, false},
+		{"overspent", 0.01, 15, 1000, 10, -5, -0.5, true},
+		{"zero budget, no failures", 0, 0, 1000, 0, 0, 1, false},
+		{"zero budget, a failure", 0, 1, 1000, 0, -1, -1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			budget := NewErrorBudget(test.allowed, test.failures, test.tests)
+			if budget.AllowedFailures != test.allowedFails || budget.Remaining != test.remaining ||
+				math.Abs(budget.RemainingRatio-test.remainingRatio) > 1e-9 || budget.Exhausted() != test.exhausted {
+				t.Errorf("got %+v, exhausted %t", budget, budget.Exhausted())
+			}
+		})
+	}
+}
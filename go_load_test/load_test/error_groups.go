@@ -0,0 +1,71 @@
+package load_test
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Groups errors by status code + normalized message so repeated errors are counted rather than listed.
+
+var digitRuns = regexp.MustCompile(`[0-9]+`)
+
+type ErrorGroup struct {
+	StatusCode int       `json:"status_code"`
+	TestType   TestType  `json:"test_type"`
+	Message    string    `json:"message"`
+	Count      int       `json:"count"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// ErrorGroups is not safe for concurrent use; callers are expected to hold their own lock.
+type ErrorGroups map[string]*ErrorGroup
+
+// Record adds an occurrence of message for the given result.
+func (g ErrorGroups) Record(result TestResult, message string, seenAt time.Time) {
+	normalized := normalizeErrorMessage(message, result.FileName())
+	key := fmt.Sprintf("%d|%s|%s", result.StatusCode(), result.TestType(), normalized)
+
+	group, ok := g[key]
+	if !ok {
+		group = &ErrorGroup{
+			StatusCode: result.StatusCode(),
+			TestType:   result.TestType(),
+			Message:    normalized,
+			FirstSeen:  seenAt,
+		}
+		g[key] = group
+	}
+	group.Count++
+	group.LastSeen = seenAt
+}
+
+// Top returns copies of the n most frequent error groups, most frequent first.
+func (g ErrorGroups) Top(n int) []ErrorGroup {
+	groups := make([]ErrorGroup, 0, len(g))
+	for _, group := range g {
+		groups = append(groups, *group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count == groups[j].Count {
+			return groups[i].LastSeen.After(groups[j].LastSeen)
+		}
+		return groups[i].Count > groups[j].Count
+	})
+
+	return groups[:Min(len(groups), n)]
+}
+
+// normalizeErrorMessage strips per request details (file names, numbers) so equivalent errors share a group.
+func normalizeErrorMessage(message string, fileName string) string {
+	if fileName != "" {
+		message = strings.ReplaceAll(message, fileName, "<file>")
+	}
+	message = digitRuns.ReplaceAllString(message, "#")
+
+	return strings.TrimSpace(message)
+}
@@ -0,0 +1,88 @@
+package load_test
+
+import (
+	"time"
+)
+
+// Exponentially weighted moving averages used to smooth the "current" per interval rates. Each update moves the
+// average alpha of the way towards the newest value, so recent intervals dominate without a fixed size window.
+
+type EWMA struct {
+	alpha       float64
+	value       float64
+	initialized bool
+}
+
+func NewEWMA(alpha float64) *EWMA {
+	return &EWMA{alpha: alpha}
+}
+
+// Update folds value into the average. The first value seeds the average directly.
+func (e *EWMA) Update(value float64) {
+	if !e.initialized {
+		e.value = value
+		e.initialized = true
+		return
+	}
+
+	e.value += e.alpha * (value - e.value)
+}
+
+// UpdateDuration folds d into the average. Zero durations mean nothing was measured and are skipped, so an idle
+// interval doesn't drag the average latency down.
+func (e *EWMA) UpdateDuration(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	e.Update(float64(d))
+}
+
+func (e *EWMA) Value() float64 {
+	return e.value
+}
+
+func (e *EWMA) Int() int {
+	return int(e.value + 0.5)
+}
+
+func (e *EWMA) Duration() time.Duration {
+	return time.Duration(e.value)
+}
+
+// intervalRates holds the smoothed "current" rates shown by PrintResults.
+type intervalRates struct {
+	requests, successes, gets, puts, deletes, throttles, consistency, bytesUploaded, bytesDownloaded *EWMA
+	getDuration, putDuration, deleteDuration, consistencyDuration                                    *EWMA
+}
+
+func newIntervalRates(alpha float64) *intervalRates {
+	return &intervalRates{
+		requests:            NewEWMA(alpha),
+		successes:           NewEWMA(alpha),
+		gets:                NewEWMA(alpha),
+		puts:                NewEWMA(alpha),
+		deletes:             NewEWMA(alpha),
+		throttles:           NewEWMA(alpha),
+		consistency:         NewEWMA(alpha),
+		bytesUploaded:       NewEWMA(alpha),
+		bytesDownloaded:     NewEWMA(alpha),
+		getDuration:         NewEWMA(alpha),
+		putDuration:         NewEWMA(alpha),
+		deleteDuration:      NewEWMA(alpha),
+		consistencyDuration: NewEWMA(alpha),
+	}
+}
+
+// update folds the interval's counts into the rates.
+func (r *intervalRates) update(stats IntervalStats) {
+	r.requests.Update(float64(stats.Requests))
+	r.successes.Update(float64(stats.Successes))
+	r.gets.Update(float64(stats.Gets))
+	r.puts.Update(float64(stats.Puts))
+	r.deletes.Update(float64(stats.Deletes))
+	r.throttles.Update(float64(stats.Throttles))
+	r.consistency.Update(float64(stats.Consistency))
+	r.bytesUploaded.Update(stats.PerSecond(int(stats.BytesSent)))
+	r.bytesDownloaded.Update(stats.PerSecond(int(stats.BytesReceived)))
+}
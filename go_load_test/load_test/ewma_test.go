@@ -0,0 +1,50 @@
+package load_test
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEWMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		alpha  float64
+		values []float64
+		want   float64
+	}{
+		{"first value seeds", 0.3, []float64{10}, 10},
+		{"decays towards newest", 0.5, []float64{10, 20}, 15},
+		{"decays geometrically", 0.5, []float64{0, 8, 8, 8}, 7},
+		{"alpha 1 follows newest", 1, []float64{5, 100, 3}, 3},
+		{"steady input stays put", 0.3, []float64{4, 4, 4, 4}, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := NewEWMA(test.alpha)
+			for _, value := range test.values {
+				e.Update(value)
+			}
+			if math.Abs(e.Value()-test.want) > 1e-9 {
+				t.Errorf("got %g, want %g", e.Value(), test.want)
+			}
+		})
+	}
+}
+
+func TestEWMASkipsIdleDurations(t *testing.T) {
+	e := NewEWMA(0.5)
+	e.UpdateDuration(10 * time.Millisecond)
+	e.UpdateDuration(0)
+	e.UpdateDuration(20 * time.Millisecond)
+
+	if e.Duration() != 15*time.Millisecond {
+		t.Errorf("got %s, want 15ms", e.Duration())
+	}
+	rate := NewEWMA(0.5)
+	rate.Update(2)
+	rate.Update(3)
+	if rate.Int() != 3 {
+		t.Errorf("got %d, want %g rounded to 3", rate.Int(), rate.Value())
+	}
+}
@@ -0,0 +1,293 @@
+package load_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExtraOps are operations run alongside the main mix, I.E HEAD=5%, each taking its share of every test scheduled. The
+// operations in extraOperations only ever run this way, never as part of the main mix. All but STREAM, CONFLICT,
+// PARTIAL, MOVE, FUZZ, BOUNDARY + DOWNLOAD run on existing files, + an extra op picked before there are any files to run
+// it on runs the main mix instead. Extra ops are reported on rows of their own, + in the latency breakdown by operation
+// with the operation they're closest to, I.E HEAD with GETs, STREAM, PARTIAL, MOVE, COPY, FUZZ + BOUNDARY with PUTs +
+// CONFLICT with consistency checks. Their executors start with beginTest.
+type ExtraOps map[TestType]float64
+
+// extraOperations are the operations that can be run as extra ops.
+var extraOperations = map[TestType]bool{HEAD: true, RANGE: true, CONDITIONAL: true, STREAM: true, CONFLICT: true,
+	PARTIAL: true, LIST: true, MOVE: true, COPY: true,
+	FUZZ: true, BOUNDARY: true, DOWNLOAD: true}
+
+// ParseExtraOps parses OP=share pairs, I.E HEAD=5%,RANGE=0.02. An empty value runs no extra ops.
+func ParseExtraOps(value string) (ExtraOps, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	ops := make(ExtraOps)
+	total := 0.0
+	for _, entry := range strings.Split(value, ",") {
+		op, share, found := strings.Cut(strings.TrimSpace(entry), "=")
+		testType := TestType(strings.ToUpper(strings.TrimSpace(op)))
+		if !found || !extraOperations[testType] {
+			return nil, fmt.Errorf("invalid extra op: %s. Expected OP=share of %s, I.E HEAD=5%%", entry,
+				strings.Join(ExtraOps(nil).supported(), ", "))
+		}
+
+		fraction, err := parseFraction(strings.TrimSpace(share))
+		if err != nil {
+			return nil, fmt.Errorf("invalid share for %s: %w", testType, err)
+		}
+		ops[testType] = fraction
+		total += fraction
+	}
+	if total > 1 {
+		return nil, fmt.Errorf("invalid extra ops: %s. Shares add up to more than 100%%", value)
+	}
+
+	return ops, nil
+}
+
+// supported returns the operations that can be run as extra ops, sorted.
+func (ExtraOps) supported() []string {
+	names := make([]string, 0, len(extraOperations))
+	for op := range extraOperations {
+		names = append(names, string(op))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// sorted returns the configured ops in a fixed order, so a seeded run picks the same ops every time.
+func (o ExtraOps) sorted() []TestType {
+	ops := make([]TestType, 0, len(o))
+	for op := range o {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+
+	return ops
+}
+
+// sortedExtraOps returns the ops summarized, sorted for printing.
+func sortedExtraOps(summaries map[TestType]ExtraOpSummary) []TestType {
+	ops := make([]TestType, 0, len(summaries))
+	for op := range summaries {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+
+	return ops
+}
+
+func (o ExtraOps) String() string {
+	entries := make([]string, 0, len(o))
+	for _, op := range o.sorted() {
+		entries = append(entries, fmt.Sprintf("%s=%g%%", op, o[op]*100))
+	}
+
+	return strings.Join(entries, ",")
+}
+
+// pick returns the extra op to run next, or "" to run the main mix.
+func (o ExtraOps) pick(r *Rand) TestType {
+	if len(o) == 0 {
+		return ""
+	}
+
+	roll := r.Float64()
+	for _, op := range o.sorted() {
+		if roll < o[op] {
+			return op
+		}
+		roll -= o[op]
+	}
+
+	return ""
+}
+
+// extraOpTest returns a test running op on an existing file, or false if there are no files to run it on yet. RANGE
+// tests only run on files with a known payload + CONDITIONAL tests on files with a known ETag. STREAM, CONFLICT,
+// PARTIAL, MOVE, FUZZ, BOUNDARY + DOWNLOAD tests write a new file.
+func (ts *TestScheduler) extraOpTest(op TestType) (Test, bool) {
+	switch op {
+	case RANGE:
+		fileName := ts.cfg.Stores.Payloads.randomFile(ts.rand)
+		return Test{TestType: op, fileName: fileName}, fileName != ""
+	case CONDITIONAL:
+		fileName := ts.cfg.Stores.ETags.randomFile(ts.rand)
+		return Test{TestType: op, fileName: fileName}, fileName != ""
+	case STREAM, CONFLICT, PARTIAL, MOVE, FUZZ, BOUNDARY, DOWNLOAD:
+		return Test{TestType: op, fileName: ts.newFileName()}, true
+	}
+
+	ts.trackedFileLock.RLock()
+	defer ts.trackedFileLock.RUnlock()
+	if ts.trackedFiles.Len() == 0 {
+		return Test{}, false
+	}
+
+	return Test{TestType: op, fileName: ts.trackedFiles.randomFile(ts.rand)}, true
+}
+
+type ExtraOpSummary struct {
+	Count       int               `json:"count"`
+	Failures    int               `json:"failures"`
+	NotModified int               `json:"not_modified,omitempty"` // 304s, see CONDITIONAL
+	Rejected    int               `json:"rejected,omitempty"`     // Keys or files rejected cleanly, see FUZZ + BOUNDARY
+	Upload      *BandwidthSummary `json:"upload,omitempty"`       // Per request upload bandwidth, see STREAM
+	Download    *BandwidthSummary `json:"download,omitempty"`     // Per request sustained download bandwidth, see DOWNLOAD
+	Pages       int               `json:"pages,omitempty"`        // Listing pages walked, see LIST
+	Items       int               `json:"items,omitempty"`        // Files listed
+	Latency     LatencySummary    `json:"latency"`
+	// COPY latency by the size of the file copied, see sizeBucket.
+	BySize map[string]LatencySummary `json:"by_size,omitempty"`
+	// BOUNDARY outcomes by the size uploaded, see boundaryCases.
+	Cases map[string]BoundaryCaseSummary `json:"cases,omitempty"`
+}
+
+// extraOpStats are the results of one extra op.
+type extraOpStats struct {
+	count       int
+	failures    int
+	notModified int
+	rejected    int
+	upload      bandwidthStats
+	download    bandwidthStats
+	pages       int
+	items       int
+	bySize      map[string]*LatencyHistogram
+	cases       map[string]*BoundaryCaseSummary
+	latency     *LatencyHistogram
+}
+
+func newExtraOpStats(ops ExtraOps) map[TestType]*extraOpStats {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	stats := make(map[TestType]*extraOpStats, len(ops))
+	for op := range ops {
+		stats[op] = &extraOpStats{bySize: make(map[string]*LatencyHistogram), cases: make(map[string]*BoundaryCaseSummary),
+			latency: NewLatencyHistogram()}
+	}
+
+	return stats
+}
+
+// recordExtraOp adds result to its op's stats, if it's an extra op. Caller must hold resultLock.
+func (tr *TestResults) recordExtraOp(result TestResult, duration time.Duration) {
+	stats, ok := tr.extraOps[result.testType]
+	if !ok {
+		return
+	}
+
+	stats.count++
+	if result.WasTestFailure() {
+		stats.failures++
+	}
+	if result.StatusCode() == http.StatusNotModified {
+		stats.notModified++
+	}
+	if result.fuzz != nil && !result.fuzz.Stored && !result.WasTestFailure() {
+		stats.rejected++
+	}
+	if result.boundary != nil {
+		if !result.boundary.Stored && !result.WasTestFailure() {
+			stats.rejected++
+		}
+		if stats.cases[result.boundary.Name] == nil {
+			stats.cases[result.boundary.Name] = &BoundaryCaseSummary{}
+		}
+		stats.cases[result.boundary.Name].Count++
+		if result.WasTestFailure() {
+			stats.cases[result.boundary.Name].Failures++
+		}
+	}
+	if result.TestType() == STREAM && !result.WasTestFailure() {
+		stats.upload.record(result.BytesSent(), result.Duration())
+	}
+	if result.downloaded != nil && !result.WasTestFailure() {
+		stats.download.record(result.downloaded.Bytes, result.downloaded.Duration)
+	}
+	if result.walk != nil {
+		stats.pages += result.walk.Pages
+		stats.items += result.walk.Items
+	}
+	if result.copied != nil {
+		bucket := sizeBucket(result.copied.Bytes)
+		if stats.bySize[bucket] == nil {
+			stats.bySize[bucket] = NewLatencyHistogram()
+		}
+		stats.bySize[bucket].Record(result.copied.Latency)
+	}
+	stats.latency.Record(duration)
+}
+
+// extraOpSummaries returns nil unless extra ops are run. Caller must hold resultLock.
+func (tr *TestResults) extraOpSummaries() map[TestType]ExtraOpSummary {
+	if len(tr.extraOps) == 0 {
+		return nil
+	}
+
+	summaries := make(map[TestType]ExtraOpSummary, len(tr.extraOps))
+	for op, stats := range tr.extraOps {
+		summary := ExtraOpSummary{Count: stats.count, Failures: stats.failures, NotModified: stats.notModified,
+			Rejected: stats.rejected, Upload: stats.upload.summary(), Download: stats.download.summary(), Pages: stats.pages, Items: stats.items, Latency: summarizeLatency(stats.latency)}
+		if len(stats.bySize) > 0 {
+			summary.BySize = make(map[string]LatencySummary, len(stats.bySize))
+			for bucket, latency := range stats.bySize {
+				summary.BySize[bucket] = summarizeLatency(latency)
+			}
+		}
+		if len(stats.cases) > 0 {
+			summary.Cases = make(map[string]BoundaryCaseSummary, len(stats.cases))
+			for name, outcome := range stats.cases {
+				summary.Cases[name] = *outcome
+			}
+		}
+		summaries[op] = summary
+	}
+
+	return summaries
+}
+
+// beginTest starts test, returning its context + the result to fill in, which holds its trace, phases + start time.
+// Call cancel once the test is done.
+func (tr *TestExecutor) beginTest(test Test, testType TestType) (context.Context, TestResult, context.CancelFunc) {
+	ctx, cancel := tr.testContext(testType)
+	start := time.Now()
+	result := TestResult{
+		fileName: test.fileName,
+		trace:    tr.newTraceContext(),
+		phases:   NewRequestPhases(),
+		lag:      scheduleLag(test.scheduledAt, start),
+		started:  start,
+		worker:   test.worker,
+		attempt:  test.attempt,
+		testType: testType,
+	}
+
+	return ctx, result, cancel
+}
+
+// beginHeldTest is beginTest for tests that need the file to themselves. It waits out other tests on the file first +
+// holds it until done is called.
+func (tr *TestExecutor) beginHeldTest(test Test, testType TestType) (context.Context, TestResult, func()) {
+	tr.waitForOpenInProcess(test.fileName)
+	ctx, result, cancel := tr.beginTest(test, testType)
+	done := func() {
+		cancel()
+		tr.inProcessLock.Lock()
+		tr.inProcess.Delete(test.fileName)
+		tr.inProcessLock.Unlock()
+	}
+
+	return ctx, result, done
+}
@@ -0,0 +1,48 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExtraOps(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ExtraOps
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"HEAD=5%, range=0.02", ExtraOps{HEAD: 0.05, RANGE: 0.02}, false},
+		{"HEAD=60%,COPY=50%", nil, true}, // Over 100%
+		{"GET=5%", nil, true},            // Main mix only
+		{"HEAD=150%", nil, true},
+		{"HEAD", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseExtraOps(test.value)
+		if !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("ParseExtraOps(%q): got %v, %v, want %v, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestExtraOpsPick(t *testing.T) {
+	if got := ExtraOps(nil).pick(NewRand(1)); got != "" {
+		t.Errorf("no extra ops: got %s", got)
+	}
+
+	ops := ExtraOps{HEAD: 0.1, RANGE: 0.2}
+	r := NewRand(1)
+	counts := make(map[TestType]int)
+	for i := 0; i < 100000; i++ {
+		counts[ops.pick(r)]++
+	}
+	want := map[TestType]int{HEAD: 10000, RANGE: 20000, "": 70000}
+	for op, n := range want {
+		if counts[op] < n*95/100 || counts[op] > n*105/100 {
+			t.Errorf("got %v, want ~%v", counts, want)
+			break
+		}
+	}
+}
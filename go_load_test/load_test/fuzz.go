@@ -0,0 +1,174 @@
+package load_test
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// FUZZ tests PUT a new file under a hostile key, I.E with spaces, characters that need percent encoding, unicode,
+// a very long segment or a name that looks like path traversal, see fuzzKinds. The server may either store the file
+// faithfully, so a GET of the same key returns exactly the bytes uploaded, or reject the key cleanly with a 4xx. A
+// 5xx, a mangled file or a traversal key accepted outside the server's root fails the test. Stored files are deleted
+// afterwards.
+const FUZZ TestType = "FUZZ"
+
+// fuzzKind turns a unique name into a hostile key.
+type fuzzKind struct {
+	name string
+	key  func(name string) string
+	// If true, the key's / separated segments are escaped one by one, so the server sees literal ../ segments.
+	// Otherwise the whole key is escaped as one segment, I.E ../ is sent as ..%2F.
+	segments bool
+}
+
+// fuzzKinds are the hostile keys FUZZ tests pick from, evenly.
+var fuzzKinds = []fuzzKind{
+	{name: "space", key: func(name string) string { return " " + name + " with spaces " }},
+	{name: "reserved", key: func(name string) string { return name + " #?%&+=;,'\"<>`|\\{}[]^~!@$()*:" }},
+	{name: "unicode", key: func(name string) string { return name + "-日本語-ñandú-é-😀-\u202eevil-\u0000" }},
+	{name: "long", key: func(name string) string { return name + strings.Repeat("x", 1024) }},
+	{name: "dots", key: func(name string) string { return "..." + name + ".." }},
+	{name: "traversal", key: func(name string) string { return "../../" + name }, segments: true},
+	{name: "encoded-traversal", key: func(name string) string { return "../../" + name }},
+}
+
+// FuzzKey is the hostile key a FUZZ test used + what the server did with it.
+type FuzzKey struct {
+	Kind   string
+	Stored bool // The server accepted the PUT, so the test also GETs + DELETEs the file
+}
+
+// fuzzURL returns the URL of key on fileName's target, escaped per kind.
+func (tr *TestExecutor) fuzzURL(fileName string, key string, kind fuzzKind) string {
+	escaped := url.PathEscape(key)
+	if kind.segments {
+		segments := strings.Split(key, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		escaped = strings.Join(segments, "/")
+	}
+
+	target := tr.targets.For(fileName)
+	return fmt.Sprintf("%s://%s:%s/%s/%s", target.Proto, target.Host, target.Port, target.PathPrefix, escaped)
+}
+
+// escapesRoot returns true if key, sent with literal segments, resolves outside the target's path prefix.
+func (tr *TestExecutor) escapesRoot(fileName string, key string) bool {
+	root := path.Clean("/" + tr.targets.For(fileName).PathPrefix)
+	return !strings.HasPrefix(path.Clean(root+"/"+key), root+"/")
+}
+
+// cleanRejection returns true if the server refused a fuzzed key the way it should, with a 4xx other than a 429.
+func cleanRejection(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests
+}
+
+func (tr *TestExecutor) FuzzFile(test Test) {
+	ctx, result, cancel := tr.beginTest(test, FUZZ)
+	defer cancel()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+	payload := newSeededRand(test.payloadSeed)
+	kind := fuzzKinds[payload.Intn(len(fuzzKinds))]
+	key := kind.key(fileName)
+	fileURL := tr.fuzzURL(fileName, key, kind)
+	fuzz := &FuzzKey{Kind: kind.name}
+	result.fuzz = fuzz
+
+	fileBytes := make([]byte, tr.randomFileSize(payload))
+	err := tr.fillPayload(payload, fileBytes)
+	if err != nil {
+		result.message = "Failed to generate random file bytes"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	byteString := b64.StdEncoding.EncodeToString(fileBytes)
+
+	response, err := tr.fuzzRequest(ctx, http.MethodPut, fileURL, byteString, trace, phases)
+	result.response = response
+	result.bytesSent = int64(len(byteString))
+	if err != nil {
+		result.message = fmt.Sprintf("Error executing http PUT request for a %s key", kind.name)
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	body := responseToString(response)
+	if response.StatusCode >= 400 {
+		result.message = body
+		if !cleanRejection(response.StatusCode) {
+			result.message = fmt.Sprintf("PUT of a %s key failed with %d rather than being rejected cleanly", kind.name,
+				response.StatusCode)
+			result.mismatch = fmt.Sprintf("%s key %s", kind.name, statusCodeLabel(response.StatusCode))
+			result.failed = true
+		}
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	fuzz.Stored = true
+	defer func() {
+		_, _ = tr.removeURL(fileURL)
+	}()
+	if kind.segments && tr.escapesRoot(fileName, key) {
+		result.message = fmt.Sprintf("PUT of a %s key outside the server's root was accepted with %d", kind.name,
+			response.StatusCode)
+		result.mismatch = fmt.Sprintf("%s key accepted", kind.name)
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	response, err = tr.fuzzRequest(ctx, http.MethodGet, fileURL, "", trace, phases)
+	result.response = response
+	if err != nil {
+		result.message = fmt.Sprintf("Error executing http GET request for a %s key", kind.name)
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	body = responseToString(response)
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	if response.StatusCode != http.StatusOK {
+		result.message = fmt.Sprintf("GET of a stored %s key failed due to unexpected status code, got: %d but expected 200.",
+			kind.name, response.StatusCode)
+		result.mismatch = fmt.Sprintf("%s key %s", kind.name, statusMismatch(response.StatusCode, http.StatusOK))
+		result.failed = true
+	} else if body != byteString {
+		result.mismatch = fmt.Sprintf("%s key %s", kind.name, bodyMismatch(byteString, body))
+		result.message = fmt.Sprintf("File stored under a %s key wasn't returned faithfully: %s", kind.name, result.mismatch)
+		result.failed = true
+	}
+	tr.emit(result)
+}
+
+// fuzzRequest sends a request to an already escaped fileURL, with body if it isn't empty.
+func (tr *TestExecutor) fuzzRequest(ctx context.Context, method string, fileURL string, body string, trace traceContext, phases *RequestPhases) (*http.Response, error) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := tr.newURLRequest(ctx, method, fileURL, reader, trace, phases)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.do(req, phases)
+}
@@ -0,0 +1,103 @@
+package load_test
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HEAD tests send a HEAD for an existing file, then GET it, + check the HEAD described the same response the GET
+// returned: the same status, a Content-Length matching the GET's body + the same metadata headers.
+const HEAD TestType = "HEAD"
+
+// headMetadata are the headers a HEAD must return the same as a GET of the same file, when the GET returns them.
+var headMetadata = []string{"Content-Type", "ETag", "Last-Modified"}
+
+func (tr *TestExecutor) HeadFile(test Test) {
+	ctx, result, cancel := tr.beginTest(test, HEAD)
+	defer cancel()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+
+	req, err := tr.newRequest(ctx, http.MethodHead, fileName, nil, trace, phases)
+	if err != nil {
+		result.message = "Failed to build HEAD request."
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	head, err := tr.do(req, phases)
+	if err != nil {
+		result.response = head
+		result.message = "Error executing http HEAD request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	_ = responseToString(head)
+	if head.StatusCode >= 400 {
+		result.response = head
+		result.message = fmt.Sprintf("HEAD returned %d", head.StatusCode)
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	response, err := tr.get(ctx, fileName, trace, phases)
+	if err != nil {
+		result.response = response
+		result.message = "Error executing http GET request after HEAD"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	body := responseToString(response)
+	result.response = response
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	result.mismatch = headMismatch(head, response, len(body))
+	if response.StatusCode >= 400 {
+		result.message = body
+		result.failed = true
+	} else if result.mismatch != "" {
+		result.message = fmt.Sprintf("HEAD doesn't match GET: %s", result.mismatch)
+		result.failed = true
+	}
+	tr.emit(result)
+}
+
+// headMismatch describes how a HEAD response differs from a GET of the same file, or returns "" if it doesn't.
+func headMismatch(head *http.Response, get *http.Response, bodyLength int) string {
+	if head.StatusCode != get.StatusCode {
+		return fmt.Sprintf("status %d, GET returned %d", head.StatusCode, get.StatusCode)
+	}
+	if get.StatusCode >= 400 {
+		return ""
+	}
+
+	contentLength := head.Header.Get("Content-Length")
+	if contentLength == "" {
+		return fmt.Sprintf("no Content-Length, GET returned %d bytes", bodyLength)
+	}
+	if length, err := strconv.Atoi(contentLength); err != nil || length != bodyLength {
+		return fmt.Sprintf("Content-Length %s, GET returned %d bytes", contentLength, bodyLength)
+	}
+
+	for _, header := range headMetadata {
+		if want := get.Header.Get(header); want != "" && head.Header.Get(header) != want {
+			return fmt.Sprintf("%s %q, GET returned %q", header, head.Header.Get(header), want)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,73 @@
+package load_test
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Exports a latency heatmap, one row per interval with the # of requests in each latency bucket. Columns are named by
+// bucket upper bound in seconds (I.E Prometheus "le" labels), which Grafana's heatmap panel reads as "time series
+// buckets", so latency spikes over time (GC pauses, compactions) stand out.
+
+type HeatmapCSVWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func NewHeatmapCSVWriter(path string) (*HeatmapCSVWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create heatmap csv: %s. Error: %w", path, err)
+	}
+
+	header := []string{"timestamp"}
+	for _, bound := range latencyBucketBounds {
+		header = append(header, strconv.FormatFloat(bound.Seconds(), 'g', -1, 64))
+	}
+	header = append(header, "+Inf")
+
+	w := &HeatmapCSVWriter{file: file, writer: csv.NewWriter(file)}
+	err = w.write(header)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write adds a row with the bucket counts of every operation combined.
+func (w *HeatmapCSVWriter) Write(stats IntervalStats) error {
+	counts := make([]int64, len(latencyBucketBounds)+1)
+	for _, op := range latencyOperations {
+		for i, count := range stats.LatencyBuckets[op] {
+			counts[i] += count
+		}
+	}
+
+	row := []string{stats.Timestamp.Format(time.RFC3339)}
+	for _, count := range counts {
+		row = append(row, strconv.FormatInt(count, 10))
+	}
+
+	return w.write(row)
+}
+
+func (w *HeatmapCSVWriter) Close() error {
+	w.writer.Flush()
+	return w.file.Close()
+}
+
+// write flushes after every row so the file is usable even if the run is killed.
+func (w *HeatmapCSVWriter) write(record []string) error {
+	err := w.writer.Write(record)
+	if err != nil {
+		return err
+	}
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
@@ -0,0 +1,107 @@
+package load_test
+
+import "math"
+
+// Keeps the per interval history bounded on multi-day soak tests. Once it holds MaxHistoryIntervals, adjacent
+// intervals of the same stage are merged pairwise, so history still covers the whole run + stage reports, charts +
+// checkpoints stay the same size however long it goes on. The last sparklineWidth intervals are never merged, so live
+// output always shows single intervals.
+
+// appendHistory appends stats to the history, compacting it once it's full. Caller must hold resultLock.
+func (tr *TestResults) appendHistory(stats IntervalStats) {
+	tr.history = boundHistory(append(tr.history, stats))
+}
+
+// boundHistory compacts history until it's below MaxHistoryIntervals, or can't be compacted any further.
+func boundHistory(history []IntervalStats) []IntervalStats {
+	for len(history) >= MaxHistoryIntervals {
+		compacted := compactHistory(history)
+		if len(compacted) == len(history) {
+			break
+		}
+		history = compacted
+	}
+
+	return history
+}
+
+// compactHistory merges each pair of adjacent intervals from the same stage, apart from the last sparklineWidth.
+func compactHistory(history []IntervalStats) []IntervalStats {
+	recent := Max(len(history)-sparklineWidth, 0)
+	compacted := make([]IntervalStats, 0, len(history)/2+sparklineWidth+1)
+	for i := 0; i < recent; i++ {
+		if i+1 < recent && history[i].Stage == history[i+1].Stage {
+			compacted = append(compacted, mergeIntervals(history[i], history[i+1]))
+			i++
+			continue
+		}
+		compacted = append(compacted, history[i])
+	}
+
+	return append(compacted, history[recent:]...)
+}
+
+// mergeIntervals returns a then b as a single interval. Counts + latency buckets are summed, but percentiles can't be
+// merged exactly so the worse of the two is kept, like the worst p99 of a stage.
+func mergeIntervals(a IntervalStats, b IntervalStats) IntervalStats {
+	merged := IntervalStats{
+		Timestamp:      b.Timestamp,
+		Duration:       a.Duration + b.Duration,
+		Requests:       a.Requests + b.Requests,
+		Successes:      a.Successes + b.Successes,
+		Failures:       a.Failures + b.Failures,
+		Gets:           a.Gets + b.Gets,
+		Puts:           a.Puts + b.Puts,
+		Deletes:        a.Deletes + b.Deletes,
+		Consistency:    a.Consistency + b.Consistency,
+		Throttles:      a.Throttles + b.Throttles,
+		BytesSent:      a.BytesSent + b.BytesSent,
+		BytesReceived:  a.BytesReceived + b.BytesReceived,
+		Latency:        make(map[TestType]LatencySummary, len(a.Latency)),
+		LatencyBuckets: make(map[TestType][]int64, len(a.LatencyBuckets)),
+		Stage:          a.Stage,
+	}
+	for op, latency := range a.Latency {
+		merged.Latency[op] = mergeLatencySummaries(latency, b.Latency[op])
+	}
+	for op, latency := range b.Latency {
+		if _, ok := a.Latency[op]; !ok {
+			merged.Latency[op] = latency
+		}
+	}
+	for _, buckets := range []map[TestType][]int64{a.LatencyBuckets, b.LatencyBuckets} {
+		for op, counts := range buckets {
+			sum := merged.LatencyBuckets[op]
+			if sum == nil {
+				sum = make([]int64, len(counts))
+				merged.LatencyBuckets[op] = sum
+			}
+			for i := 0; i < len(counts) && i < len(sum); i++ {
+				sum[i] += counts[i]
+			}
+		}
+	}
+
+	return merged
+}
+
+func mergeLatencySummaries(a LatencySummary, b LatencySummary) LatencySummary {
+	if a.Count == 0 {
+		return b
+	}
+	if b.Count == 0 {
+		return a
+	}
+
+	count := a.Count + b.Count
+	return LatencySummary{
+		Count:  count,
+		MinMs:  math.Min(a.MinMs, b.MinMs),
+		MeanMs: (a.MeanMs*float64(a.Count) + b.MeanMs*float64(b.Count)) / float64(count),
+		P50Ms:  math.Max(a.P50Ms, b.P50Ms),
+		P90Ms:  math.Max(a.P90Ms, b.P90Ms),
+		P99Ms:  math.Max(a.P99Ms, b.P99Ms),
+		P999Ms: math.Max(a.P999Ms, b.P999Ms),
+		MaxMs:  math.Max(a.MaxMs, b.MaxMs),
+	}
+}
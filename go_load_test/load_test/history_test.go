@@ -0,0 +1,51 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendHistoryStaysBounded(t *testing.T) {
+	results := NewResultAggregator(TestSchedulerConfig{}).Results
+	start := time.Unix(0, 0)
+	intervals := 5 * MaxHistoryIntervals
+	for i := 0; i < intervals; i++ {
+		stage := "ramp"
+		if i >= intervals/2 {
+			stage = "steady"
+		}
+		results.appendHistory(IntervalStats{
+			Timestamp:      start.Add(time.Duration(i+1) * time.Second),
+			Duration:       time.Second,
+			Requests:       10,
+			Latency:        map[TestType]LatencySummary{GET: {Count: 10, MeanMs: float64(i % 7), P99Ms: float64(i)}},
+			LatencyBuckets: map[TestType][]int64{GET: {10, 0}},
+			Stage:          stage,
+		})
+	}
+
+	if len(results.history) >= MaxHistoryIntervals {
+		t.Fatalf("got %d intervals of history, want < %d", len(results.history), MaxHistoryIntervals)
+	}
+	var requests, buckets int64
+	var duration time.Duration
+	for _, stats := range results.history {
+		requests += int64(stats.Requests)
+		buckets += stats.LatencyBuckets[GET][0]
+		duration += stats.Duration
+	}
+	if requests != int64(10*intervals) || buckets != int64(10*intervals) || duration != time.Duration(intervals)*time.Second {
+		t.Errorf("history lost intervals: %d requests, %d bucketed, %s", requests, buckets, duration)
+	}
+
+	// Stages + the most recent intervals are kept apart.
+	stages := results.stageSummaries()
+	if len(stages) != 2 || stages[0].Requests != 5*intervals || stages[1].WorstP99Ms != float64(intervals-1) {
+		t.Errorf("got stages %+v", stages)
+	}
+	for _, stats := range results.history[len(results.history)-sparklineWidth:] {
+		if stats.Duration != time.Second {
+			t.Fatalf("recent interval was merged: %+v", stats)
+		}
+	}
+}
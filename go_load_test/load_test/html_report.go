@@ -0,0 +1,257 @@
+package load_test
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Renders a self-contained HTML report (no external assets) of a finished run, charts are drawn as inline SVG.
+
+const (
+	chartWidth  = 900
+	chartHeight = 260
+	chartMargin = 40
+)
+
+type chartSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+type reportBar struct {
+	Label string
+	Count int64
+}
+
+type reportRow struct {
+	Name  string
+	Value string
+}
+
+type htmlReportData struct {
+	Title             string
+	GeneratedAt       string
+	Summary           RunSummary
+	Totals            []reportRow
+	ThroughputChart   template.HTML
+	LatencyChart      template.HTML
+	ErrorBreakdown    []reportRow
+	HttpErrors        []string
+	ConsistencyErrors []string
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #eee; }
+pre { background: #f6f6f6; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Started {{.Summary.StartTime.Format "2006-01-02 15:04:05"}}, ran {{printf "%.0f" .Summary.DurationSeconds}} seconds. Generated {{.GeneratedAt}}.</p>
+
+<h2>Totals</h2>
+<table>
+{{range .Totals}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Throughput</h2>
+{{.ThroughputChart}}
+
+<h2>Latency</h2>
+<table>
+<tr><th>Operation</th><th>Count</th><th>Min (ms)</th><th>Avg (ms)</th><th>p50 (ms)</th><th>p90 (ms)</th><th>p99 (ms)</th><th>Max (ms)</th></tr>
+<tr><td>ALL</td><td>{{.Summary.Latency.Count}}</td><td>{{.Summary.Latency.MinMs}}</td><td>{{.Summary.Latency.MeanMs}}</td><td>{{.Summary.Latency.P50Ms}}</td><td>{{.Summary.Latency.P90Ms}}</td><td>{{.Summary.Latency.P99Ms}}</td><td>{{.Summary.Latency.MaxMs}}</td></tr>
+{{range $op, $l := .Summary.Operations}}<tr><td>{{$op}}</td><td>{{$l.Count}}</td><td>{{$l.MinMs}}</td><td>{{$l.MeanMs}}</td><td>{{$l.P50Ms}}</td><td>{{$l.P90Ms}}</td><td>{{$l.P99Ms}}</td><td>{{$l.MaxMs}}</td></tr>
+{{end}}</table>
+{{.LatencyChart}}
+
+<h2>Errors</h2>
+<table>
+{{range .ErrorBreakdown}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h3>Recent HTTP errors</h3>
+<pre>{{range .HttpErrors}}{{.}}
+{{else}}None
+{{end}}</pre>
+
+<h3>Recent consistency + other errors</h3>
+<pre>{{range .ConsistencyErrors}}{{.}}
+{{else}}None
+{{end}}</pre>
+</body>
+</html>
+`))
+
+// WriteHTMLReportFile writes the HTML report to the given path.
+func (tr *TestResults) WriteHTMLReportFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create html report: %s. Error: %w", path, err)
+	}
+	defer file.Close()
+
+	return tr.WriteHTMLReport(file)
+}
+
+// WriteHTMLReport renders the HTML report for the run so far.
+func (tr *TestResults) WriteHTMLReport(w io.Writer) error {
+	summary := tr.Summary()
+
+	tr.resultLock.RLock()
+	history := make([]IntervalStats, len(tr.history))
+	copy(history, tr.history)
+	bars := latencyBars(tr.latency)
+	httpErrors := tr.httpErrors.Last(25)
+	otherErrors := tr.otherErrors.Last(25)
+	tr.resultLock.RUnlock()
+
+	var requests, successes, failures []float64
+	for _, stats := range history {
+		requests = append(requests, stats.PerSecond(stats.Requests))
+		successes = append(successes, stats.PerSecond(stats.Successes))
+		failures = append(failures, stats.PerSecond(stats.Failures))
+	}
+
+	data := htmlReportData{
+		Title:       "File Server Load Test Report",
+		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Summary:     summary,
+		Totals: []reportRow{
+			{"Requests", fmt.Sprintf("%d", summary.Requests)},
+			{"Successes", fmt.Sprintf("%d", summary.Successes)},
+			{"Failures", fmt.Sprintf("%d", summary.Failures)},
+			{"Success rate", fmt.Sprintf("%.2f%%", summary.SuccessRate*100)},
+			{"Consistency rate", fmt.Sprintf("%.2f%%", summary.ConsistencyRate*100)},
+			{"Max successful req/sec", fmt.Sprintf("%d", summary.MaxSuccessfulRequestsPerSec)},
+			{"Score", fmt.Sprintf("%d", summary.Score)},
+		},
+		ThroughputChart: lineChartSVG("req/sec per interval", []chartSeries{
+			{Name: "requests", Color: "#1f77b4", Values: requests},
+			{Name: "successes", Color: "#2ca02c", Values: successes},
+			{Name: "failures", Color: "#d62728", Values: failures},
+		}),
+		LatencyChart: barChartSVG("requests by latency", bars),
+		ErrorBreakdown: []reportRow{
+			{"Test failures", fmt.Sprintf("%d", summary.Failures)},
+			{"5XX responses", fmt.Sprintf("%d", summary.Http5XX)},
+			{"Throttled (429)", fmt.Sprintf("%d", summary.Throttled)},
+			{"Self throttled (backed off)", fmt.Sprintf("%d", summary.SelfThrottled)},
+			{"Injected faults (chaos)", fmt.Sprintf("%d", summary.Chaos.Total())},
+			{"Consistency failures", fmt.Sprintf("%d", summary.ConsistencyFailures)},
+		},
+		HttpErrors:        httpErrors,
+		ConsistencyErrors: otherErrors,
+	}
+
+	return htmlReportTemplate.Execute(w, data)
+}
+
+// latencyBars groups the histogram into the coarse latencyBucketBounds ranges.
+func latencyBars(hist *LatencyHistogram) []reportBar {
+	counts := hist.CoarseCounts(latencyBucketBounds)
+	bars := make([]reportBar, len(counts))
+	lowerBound := time.Duration(0)
+	for i, bound := range latencyBucketBounds {
+		bars[i] = reportBar{Label: fmt.Sprintf("%s-%s", shortDuration(lowerBound), shortDuration(bound)), Count: counts[i]}
+		lowerBound = bound
+	}
+	bars[len(counts)-1] = reportBar{Label: fmt.Sprintf(">%s", shortDuration(lowerBound)), Count: counts[len(counts)-1]}
+
+	return bars
+}
+
+func shortDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+func lineChartSVG(title string, series []chartSeries) template.HTML {
+	maxValue := 1.0
+	maxPoints := 0
+	for _, s := range series {
+		maxPoints = Max(maxPoints, len(s.Values))
+		for _, v := range s.Values {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, chartWidth, chartHeight))
+	writeAxes(&sb, title, fmt.Sprintf("%.0f", maxValue))
+
+	plotWidth := float64(chartWidth - 2*chartMargin)
+	plotHeight := float64(chartHeight - 2*chartMargin)
+	for i, s := range series {
+		var points []string
+		for j, v := range s.Values {
+			x := float64(chartMargin)
+			if maxPoints > 1 {
+				x += plotWidth * float64(j) / float64(maxPoints-1)
+			}
+			y := float64(chartHeight-chartMargin) - plotHeight*v/maxValue
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		sb.WriteString(fmt.Sprintf(`<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, s.Color, strings.Join(points, " ")))
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" fill="%s" font-size="12">%s</text>`, chartWidth-chartMargin-100, chartMargin+14*i, s.Color, template.HTMLEscapeString(s.Name)))
+	}
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+func barChartSVG(title string, bars []reportBar) template.HTML {
+	var maxCount int64 = 1
+	for _, bar := range bars {
+		if bar.Count > maxCount {
+			maxCount = bar.Count
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, chartWidth, chartHeight))
+	writeAxes(&sb, title, fmt.Sprintf("%d", maxCount))
+
+	if len(bars) > 0 {
+		slotWidth := float64(chartWidth-2*chartMargin) / float64(len(bars))
+		plotHeight := float64(chartHeight - 2*chartMargin)
+		for i, bar := range bars {
+			height := plotHeight * float64(bar.Count) / float64(maxCount)
+			x := float64(chartMargin) + slotWidth*float64(i)
+			y := float64(chartHeight-chartMargin) - height
+			sb.WriteString(fmt.Sprintf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#1f77b4"><title>%s: %d</title></rect>`,
+				x+2, y, slotWidth-4, height, bar.Label, bar.Count))
+			sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%d" font-size="9" text-anchor="middle">%s</text>`,
+				x+slotWidth/2, chartHeight-chartMargin+14, bar.Label))
+		}
+	}
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+func writeAxes(sb *strings.Builder, title string, maxLabel string) {
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" font-size="14">%s</text>`, chartMargin, chartMargin-16, template.HTMLEscapeString(title)))
+	sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, chartMargin, chartMargin, chartMargin, chartHeight-chartMargin))
+	sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, chartMargin, chartHeight-chartMargin, chartWidth-chartMargin, chartHeight-chartMargin))
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" font-size="10" text-anchor="end">%s</text>`, chartMargin-4, chartMargin+4, maxLabel))
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" font-size="10" text-anchor="end">0</text>`, chartMargin-4, chartHeight-chartMargin))
+}
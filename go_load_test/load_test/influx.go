@@ -0,0 +1,106 @@
+package load_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Writes interval stats in InfluxDB line protocol, either to an InfluxDB write endpoint or to a local file.
+
+type InfluxWriter struct {
+	runID  string
+	url    string
+	token  string
+	file   *os.File
+	client *http.Client
+}
+
+// NewInfluxWriter - writeURL is a full write endpoint, I.E http://localhost:8086/api/v2/write?org=o&bucket=b or
+// http://localhost:8086/write?db=loadtest. If filePath is set, lines are appended to that file as well.
+func NewInfluxWriter(runID string, writeURL string, token string, filePath string) (*InfluxWriter, error) {
+	w := &InfluxWriter{
+		runID:  runID,
+		url:    writeURL,
+		token:  token,
+		client: &http.Client{Timeout: time.Second * 10},
+	}
+
+	if filePath != "" {
+		file, err := os.Create(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create line protocol file: %s. Error: %w", filePath, err)
+		}
+		w.file = file
+	}
+
+	return w, nil
+}
+
+func (w *InfluxWriter) Write(stats IntervalStats) error {
+	lines := w.lines(stats)
+
+	if w.file != nil {
+		_, err := w.file.WriteString(lines)
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.url == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader([]byte(lines)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	response, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write to influx: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("influx write returned %d: %s", response.StatusCode, responseToString(response))
+	}
+
+	return nil
+}
+
+func (w *InfluxWriter) Close() error {
+	if w.file != nil {
+		return w.file.Close()
+	}
+
+	return nil
+}
+
+func (w *InfluxWriter) lines(stats IntervalStats) string {
+	ts := stats.Timestamp.UnixNano()
+	runID := escapeInfluxTag(w.runID)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("loadtest_interval,run_id=%s requests=%di,successes=%di,failures=%di,throttled=%di,success_rate=%f %d\n",
+		runID, stats.Requests, stats.Successes, stats.Failures, stats.Throttles, stats.SuccessRate(), ts))
+
+	for _, op := range latencyOperations {
+		latency := stats.Latency[op]
+		sb.WriteString(fmt.Sprintf("loadtest_operation,run_id=%s,operation=%s count=%di,mean_ms=%f,p50_ms=%f,p90_ms=%f,p99_ms=%f,max_ms=%f %d\n",
+			runID, op, latency.Count, latency.MeanMs, latency.P50Ms, latency.P90Ms, latency.P99Ms, latency.MaxMs, ts))
+	}
+
+	return sb.String()
+}
+
+func escapeInfluxTag(value string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(value)
+}
@@ -0,0 +1,112 @@
+package load_test
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Raw counts for a single aggregation interval, plus writers that export them as a time-series.
+
+type IntervalStats struct {
+	Timestamp      time.Time
+	Duration       time.Duration
+	Requests       int
+	Successes      int
+	Failures       int
+	Gets           int
+	Puts           int
+	Deletes        int
+	Consistency    int
+	Throttles      int
+	BytesSent      int64
+	BytesReceived  int64
+	Latency        map[TestType]LatencySummary
+	LatencyBuckets map[TestType][]int64 // Counts per latencyBucketBounds range, see LatencyHistogram.CoarseCounts
+	Stage          string               // Load profile stage the interval ran in, if the profile has stages
+}
+
+// IntervalExporter receives the stats for every completed interval.
+type IntervalExporter interface {
+	Write(stats IntervalStats) error
+	Close() error
+}
+
+// PerSecond converts a count collected during this interval to a per second rate.
+func (s IntervalStats) PerSecond(count int) float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+
+	return float64(count) / s.Duration.Seconds()
+}
+
+// SuccessRate returns the fraction of requests in this interval that succeeded.
+func (s IntervalStats) SuccessRate() float64 {
+	if s.Requests == 0 {
+		return 1
+	}
+
+	return float64(s.Successes) / float64(s.Requests)
+}
+
+// IntervalCSVWriter appends one row per interval to a CSV file.
+type IntervalCSVWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func NewIntervalCSVWriter(path string) (*IntervalCSVWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create interval csv: %s. Error: %w", path, err)
+	}
+
+	w := &IntervalCSVWriter{file: file, writer: csv.NewWriter(file)}
+	err = w.write([]string{"timestamp", "req_per_sec", "gets_per_sec", "puts_per_sec", "deletes_per_sec",
+		"consistency_per_sec", "success_rate", "throttles", "upload_mb_per_sec", "download_mb_per_sec", "stage"})
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *IntervalCSVWriter) Write(stats IntervalStats) error {
+	return w.write([]string{
+		stats.Timestamp.Format(time.RFC3339),
+		formatRate(stats.PerSecond(stats.Requests)),
+		formatRate(stats.PerSecond(stats.Gets)),
+		formatRate(stats.PerSecond(stats.Puts)),
+		formatRate(stats.PerSecond(stats.Deletes)),
+		formatRate(stats.PerSecond(stats.Consistency)),
+		strconv.FormatFloat(stats.SuccessRate(), 'f', 4, 64),
+		strconv.Itoa(stats.Throttles),
+		formatRate(stats.PerSecond(int(stats.BytesSent)) / 1024 / 1024),
+		formatRate(stats.PerSecond(int(stats.BytesReceived)) / 1024 / 1024),
+		stats.Stage,
+	})
+}
+
+func (w *IntervalCSVWriter) Close() error {
+	w.writer.Flush()
+	return w.file.Close()
+}
+
+// write flushes after every row so the file is usable even if the run is killed.
+func (w *IntervalCSVWriter) write(record []string) error {
+	err := w.writer.Write(record)
+	if err != nil {
+		return err
+	}
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 2, 64)
+}
@@ -0,0 +1,62 @@
+package load_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jitter randomly offsets when each test starts within its pacing slot, as a fraction of the slot. At 0 every test
+// starts exactly on a slot boundary, so tests from every seed duration + every virtual user line up in micro bursts
+// real traffic doesn't have. At 1 a test can start anywhere in its slot. Tests stay in their own slot, so jitter
+// never changes the rate, only when within each slot tests arrive.
+type Jitter float64
+
+// ParseJitter parses a fraction of the slot, I.E 0.5 or 50%. An empty value is no jitter.
+func ParseJitter(value string) (Jitter, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	fraction, err := parseFraction(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid jitter: %w", err)
+	}
+
+	return Jitter(fraction), nil
+}
+
+// parseFraction parses a fraction between 0 + 1, I.E 0.5 or 50%.
+func parseFraction(value string) (float64, error) {
+	scale := 1.0
+	if strings.HasSuffix(value, "%") {
+		value = strings.TrimSuffix(value, "%")
+		scale = 100
+	}
+	fraction, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s. Error: %w", value, err)
+	}
+	fraction /= scale
+	if fraction < 0 || fraction > 1 {
+		return 0, fmt.Errorf("%s. Must be between 0 + 1, or 0%% + 100%%", value)
+	}
+
+	return fraction, nil
+}
+
+// offset returns a random offset into a slot of length slot.
+func (j Jitter) offset(slot time.Duration) time.Duration {
+	if j <= 0 || slot <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Float64() * float64(j) * float64(slot))
+}
+
+func (j Jitter) String() string {
+	return fmt.Sprintf("%g%%", float64(j)*100)
+}
@@ -0,0 +1,42 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseJitter(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Jitter
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0.5", 0.5, false},
+		{"25%", 0.25, false},
+		{"1", 1, false},
+		{"150%", 0, true},
+		{"-0.1", 0, true},
+		{"half", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseJitter(test.value)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("ParseJitter(%q): got %v, %v, want %v, error %t", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestJitterOffset(t *testing.T) {
+	if got := Jitter(0).offset(time.Second); got != 0 {
+		t.Errorf("no jitter: got offset %s, want 0", got)
+	}
+	if got := Jitter(1).offset(0); got != 0 {
+		t.Errorf("empty slot: got offset %s, want 0", got)
+	}
+	for i := 0; i < 1000; i++ {
+		if got := Jitter(0.5).offset(time.Second); got < 0 || got >= 500*time.Millisecond {
+			t.Fatalf("50%% of 1s: got offset %s, want within [0, 500ms)", got)
+		}
+	}
+}
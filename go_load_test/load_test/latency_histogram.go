@@ -0,0 +1,220 @@
+package load_test
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Records request latencies into log-linear buckets (similar to an HDR histogram) so percentiles can be computed
+// cheaply without keeping every sample around. Values are tracked with microsecond resolution and ~1.5% precision.
+
+const (
+	histogramSubBucketBits  = 7
+	histogramSubBucketCount = 1 << histogramSubBucketBits // 128 exact buckets for values below 128µs
+	histogramSubBucketHalf  = histogramSubBucketCount / 2 // each doubling after that is split into 64 buckets
+	histogramMaxShift       = 26                          // covers values up to ~2.4 hours
+	histogramBucketCount    = histogramSubBucketCount + histogramMaxShift*histogramSubBucketHalf
+)
+
+// LatencyHistogram is not safe for concurrent use; callers are expected to hold their own lock.
+type LatencyHistogram struct {
+	counts [histogramBucketCount]int64
+	count  int64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{}
+}
+
+// Record adds a single latency sample to the histogram.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	h.counts[bucketIndex(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+func (h *LatencyHistogram) Count() int64 {
+	return h.count
+}
+
+func (h *LatencyHistogram) Min() time.Duration {
+	return h.min
+}
+
+func (h *LatencyHistogram) Max() time.Duration {
+	return h.max
+}
+
+func (h *LatencyHistogram) Sum() time.Duration {
+	return h.sum
+}
+
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	return time.Duration(int64(h.sum) / h.count)
+}
+
+// Percentile returns the latency at or below which p percent (0-100) of samples fall.
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	target := int64(math.Ceil(p / 100 * float64(h.count)))
+	if target < 1 {
+		target = 1
+	}
+
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			// Never report beyond what was actually observed.
+			value := bucketUpperBound(i)
+			if value > h.max {
+				value = h.max
+			}
+			if value < h.min {
+				value = h.min
+			}
+			return value
+		}
+	}
+
+	return h.max
+}
+
+// CountAtOrBelow returns the number of samples whose bucket lies entirely at or below d.
+func (h *LatencyHistogram) CountAtOrBelow(d time.Duration) int64 {
+	var total int64
+	for i, c := range h.counts {
+		if bucketUpperBound(i) > d {
+			break
+		}
+		total += c
+	}
+
+	return total
+}
+
+// CoarseCounts returns the # of samples falling into each range delimited by bounds. The final entry counts samples
+// above the last bound, so the result has len(bounds)+1 entries.
+func (h *LatencyHistogram) CoarseCounts(bounds []time.Duration) []int64 {
+	counts := make([]int64, len(bounds)+1)
+	var previous int64
+	for i, bound := range bounds {
+		atOrBelow := h.CountAtOrBelow(bound)
+		counts[i] = atOrBelow - previous
+		previous = atOrBelow
+	}
+	counts[len(bounds)] = h.count - previous
+
+	return counts
+}
+
+// Merge adds all samples from other into this histogram.
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other == nil || other.count == 0 {
+		return
+	}
+
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// Subtract removes other's samples, which must also have been recorded into this histogram, I.E an earlier copy of it.
+// Min + max are narrowed to the buckets still holding samples.
+func (h *LatencyHistogram) Subtract(other *LatencyHistogram) {
+	if other == nil || other.count == 0 {
+		return
+	}
+
+	for i, c := range other.counts {
+		h.counts[i] -= c
+	}
+	h.count -= other.count
+	h.sum -= other.sum
+	if h.count <= 0 {
+		h.Reset()
+		return
+	}
+
+	lowest, highest := -1, 0
+	for i, c := range h.counts {
+		if c > 0 {
+			if lowest < 0 {
+				lowest = i
+			}
+			highest = i
+		}
+	}
+	if lowest > 0 && bucketUpperBound(lowest-1) >= h.min {
+		h.min = bucketUpperBound(lowest-1) + time.Microsecond
+	}
+	if bucketUpperBound(highest) < h.max {
+		h.max = bucketUpperBound(highest)
+	}
+}
+
+func (h *LatencyHistogram) Copy() *LatencyHistogram {
+	copied := *h
+	return &copied
+}
+
+func (h *LatencyHistogram) Reset() {
+	*h = LatencyHistogram{}
+}
+
+func bucketIndex(d time.Duration) int {
+	v := uint64(d.Microseconds())
+	if v < histogramSubBucketCount {
+		return int(v)
+	}
+
+	shift := bits.Len64(v) - histogramSubBucketBits
+	if shift > histogramMaxShift {
+		return histogramBucketCount - 1
+	}
+	sub := int(v >> uint(shift))
+
+	return histogramSubBucketCount + (shift-1)*histogramSubBucketHalf + (sub - histogramSubBucketHalf)
+}
+
+// bucketUpperBound returns the largest latency that maps to the bucket at idx.
+func bucketUpperBound(idx int) time.Duration {
+	if idx < histogramSubBucketCount {
+		return time.Duration(idx) * time.Microsecond
+	}
+
+	offset := idx - histogramSubBucketCount
+	shift := offset/histogramSubBucketHalf + 1
+	sub := uint64(offset%histogramSubBucketHalf + histogramSubBucketHalf)
+
+	return time.Duration(((sub+1)<<uint(shift))-1) * time.Microsecond
+}
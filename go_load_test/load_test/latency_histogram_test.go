@@ -0,0 +1,98 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBucketIndex(t *testing.T) {
+	tests := []struct {
+		latency time.Duration
+		want    int
+	}{
+		{0, 0},
+		{127 * time.Microsecond, 127},
+		{128 * time.Microsecond, 128},
+		{129 * time.Microsecond, 128}, // Each doubling past 128µs splits into 64 buckets, 2µs wide for the first
+		{130 * time.Microsecond, 129},
+		{256 * time.Microsecond, 192},
+		{10 * time.Hour, histogramBucketCount - 1},
+	}
+	for _, test := range tests {
+		if got := bucketIndex(test.latency); got != test.want {
+			t.Errorf("bucketIndex(%s): got %d, want %d", test.latency, got, test.want)
+		}
+	}
+}
+
+func TestBucketUpperBound(t *testing.T) {
+	// Every latency up to an hour falls at or below its bucket's upper bound, within ~1.6%.
+	for d := time.Microsecond; d < time.Hour; d = d*11/10 + time.Microsecond {
+		upper := bucketUpperBound(bucketIndex(d))
+		if upper < d.Truncate(time.Microsecond) {
+			t.Fatalf("%s: upper bound %s is below it", d, upper)
+		}
+		if float64(upper-d) > float64(d)*0.016+float64(time.Microsecond) {
+			t.Fatalf("%s: upper bound %s is more than 1.6%% above it", d, upper)
+		}
+	}
+}
+
+func TestLatencyHistogramPercentile(t *testing.T) {
+	hist := NewLatencyHistogram()
+	for i := 1; i <= 100; i++ {
+		hist.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		percentile float64
+		want       time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 50 * time.Millisecond},
+		{90, 90 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		got := hist.Percentile(test.percentile)
+		// Percentiles report their bucket's upper bound, never beyond the max recorded.
+		if got < test.want || float64(got-test.want) > float64(test.want)*0.016 {
+			t.Errorf("p%g: got %s, want ~%s", test.percentile, got, test.want)
+		}
+	}
+	if hist.Count() != 100 || hist.Min() != time.Millisecond || hist.Max() != 100*time.Millisecond ||
+		hist.Mean() != 50500*time.Microsecond {
+		t.Errorf("got count %d, min %s, max %s, mean %s", hist.Count(), hist.Min(), hist.Max(), hist.Mean())
+	}
+	if NewLatencyHistogram().Percentile(99) != 0 {
+		t.Errorf("empty histogram has a p99")
+	}
+}
+
+func TestLatencyHistogramCoarseCounts(t *testing.T) {
+	hist := NewLatencyHistogram()
+	for _, ms := range []int{1, 5, 10, 50, 500, 5000} {
+		hist.Record(time.Duration(ms) * time.Millisecond)
+	}
+
+	got := hist.CoarseCounts([]time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second})
+	if want := []int64{2, 2, 1, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLatencyHistogramMerge(t *testing.T) {
+	a, b := NewLatencyHistogram(), NewLatencyHistogram()
+	a.Record(10 * time.Millisecond)
+	b.Record(time.Millisecond)
+	b.Record(100 * time.Millisecond)
+	a.Merge(b)
+	a.Merge(NewLatencyHistogram())
+
+	if a.Count() != 3 || a.Min() != time.Millisecond || a.Max() != 100*time.Millisecond ||
+		a.Sum() != 111*time.Millisecond {
+		t.Errorf("got count %d, min %s, max %s, sum %s", a.Count(), a.Min(), a.Max(), a.Sum())
+	}
+}
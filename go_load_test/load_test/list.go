@@ -0,0 +1,235 @@
+package load_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// LIST tests walk the server's listing of a prefix page by page, + check every page is well formed: a 200 with a JSON
+// body listing at most ListAPI.PageSize file names, all under the prefix, none repeated across pages + no cursor
+// handed out twice. Offset cursors repeat files when files are created mid walk, so they fail under write load.
+// Prefixes are the first 0-2 characters of an existing file's name, so listings range from every file to a few, +
+// listing a hot prefix shows up in the latency. Needs a ListAPI.
+const LIST TestType = "LIST"
+
+// ListAPI describes the server's listing endpoint for LIST tests. Each page is requested with prefix, limit + cursor
+// query params. The response is a JSON object with the page's file names under Items + the next page's cursor under
+// Next, empty or missing on the last page, or a bare JSON array of file names if listings aren't paginated. File names
+// may be strings or objects with a name.
+type ListAPI struct {
+	Path     string // Listing endpoint, relative to the target, I.E api/fileserver/
+	Items    string // JSON key of the page's file names
+	Next     string // JSON key of the next page's cursor
+	PageSize int    // Sent as limit, a page listing more files fails the test
+	MaxPages int    // Pages walked per test at most
+}
+
+// ParseListAPI parses a listing endpoint spec, I.E "path=api/fileserver/ items=files next=next page=100 pages=10".
+// path is required. An empty spec returns nil, LIST tests can't run without one.
+func ParseListAPI(spec string) (*ListAPI, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid list API param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	api := &ListAPI{Items: "files", Next: "next", PageSize: 100, MaxPages: 10}
+	err := params.parse(map[string]interface{}{"path": &api.Path, "items": &api.Items, "next": &api.Next,
+		"page": &api.PageSize, "pages": &api.MaxPages}, "path")
+	if err != nil {
+		return nil, fmt.Errorf("invalid list API spec: %w", err)
+	}
+	if api.PageSize <= 0 || api.MaxPages <= 0 {
+		return nil, fmt.Errorf("invalid list API spec: page + pages must be > 0")
+	}
+
+	return api, nil
+}
+
+func (a *ListAPI) String() string {
+	return fmt.Sprintf("%s, %d per page, up to %d pages", a.Path, a.PageSize, a.MaxPages)
+}
+
+// ListWalk is how much of a prefix's listing a LIST test walked.
+type ListWalk struct {
+	Prefix string
+	Pages  int
+	Items  int
+}
+
+// listPage is one page of a listing, as returned by the server.
+type listPage struct {
+	items []string
+	next  string
+}
+
+func (tr *TestExecutor) ListFiles(test Test) {
+	ctx, result, cancel := tr.beginTest(test, LIST)
+	defer cancel()
+	trace, phases, start := result.trace, result.phases, result.started
+	prefixLength := newSeededRand(test.payloadSeed).Intn(3)
+	if prefixLength > len(test.fileName) {
+		prefixLength = len(test.fileName)
+	}
+	walk := &ListWalk{Prefix: test.fileName[:prefixLength]}
+	result.walk = walk
+	if tr.listAPI == nil {
+		result.message = "LIST tests need the server's listing endpoint, see ListAPI"
+		result.err = fmt.Errorf("no listing endpoint configured")
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	seen := make(FileSet)
+	cursors := make(map[string]bool)
+	cursor := ""
+	for walk.Pages < tr.listAPI.MaxPages {
+		response, err := tr.listPage(ctx, test.fileName, walk.Prefix, cursor, trace, phases)
+		result.response = response
+		if err != nil {
+			result.message = "Error executing http listing request"
+			result.err = err
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+
+		body := responseToString(response)
+		walk.Pages++
+		result.bytesReceived += int64(len(body))
+		if response.StatusCode != http.StatusOK {
+			result.message = body
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+
+		page, mismatch := tr.parseListPage(body)
+		if mismatch == "" {
+			mismatch = tr.pageMismatch(page, walk.Prefix, seen, cursors)
+		}
+		if mismatch != "" {
+			result.mismatch = mismatch
+			result.message = fmt.Sprintf("Listing is malformed on page %d: %s", walk.Pages, mismatch)
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+		walk.Items += len(page.items)
+		if page.next == "" {
+			break
+		}
+		cursor = page.next
+	}
+
+	result.duration = time.Now().Sub(start)
+	tr.emit(result)
+}
+
+// listPage requests the page of prefix's listing starting at cursor, from fileName's target.
+func (tr *TestExecutor) listPage(ctx context.Context, fileName string, prefix string, cursor string, trace traceContext, phases *RequestPhases) (*http.Response, error) {
+	query := url.Values{}
+	query.Set("prefix", prefix)
+	query.Set("limit", fmt.Sprint(tr.listAPI.PageSize))
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	target := tr.targets.For(fileName)
+	listURL := fmt.Sprintf("%s://%s:%s/%s?%s", target.Proto, target.Host, target.Port,
+		strings.TrimPrefix(tr.listAPI.Path, "/"), query.Encode())
+
+	req, err := tr.newURLRequest(ctx, http.MethodGet, listURL, nil, trace, phases)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.do(req, phases)
+}
+
+// parseListPage parses a page of a listing, or describes why it's malformed.
+func (tr *TestExecutor) parseListPage(body string) (listPage, string) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		return listPage{}, "malformed JSON"
+	}
+
+	var page listPage
+	var items []interface{}
+	switch parsed := raw.(type) {
+	case []interface{}:
+		items = parsed
+	case map[string]interface{}:
+		var ok bool
+		items, ok = parsed[tr.listAPI.Items].([]interface{})
+		if !ok {
+			return listPage{}, fmt.Sprintf("no %q array", tr.listAPI.Items)
+		}
+		if next, ok := parsed[tr.listAPI.Next]; ok && next != nil {
+			page.next, ok = next.(string)
+			if !ok {
+				return listPage{}, fmt.Sprintf("%q isn't a string", tr.listAPI.Next)
+			}
+		}
+	default:
+		return listPage{}, "not a JSON object or array"
+	}
+
+	for _, item := range items {
+		switch parsed := item.(type) {
+		case string:
+			page.items = append(page.items, parsed)
+		case map[string]interface{}:
+			name, ok := parsed["name"].(string)
+			if !ok {
+				return listPage{}, "file without a name"
+			}
+			page.items = append(page.items, name)
+		default:
+			return listPage{}, "file name isn't a string"
+		}
+	}
+
+	return page, ""
+}
+
+// pageMismatch describes how page breaks the listing's pagination, or returns "" if it doesn't. seen + cursors are
+// the files + cursors of the pages before, page's are added to them.
+func (tr *TestExecutor) pageMismatch(page listPage, prefix string, seen FileSet, cursors map[string]bool) string {
+	if len(page.items) > tr.listAPI.PageSize {
+		return "page over limit"
+	}
+	for _, item := range page.items {
+		if !strings.HasPrefix(path.Base(item), prefix) {
+			return "file outside prefix"
+		}
+		if seen.Has(item) {
+			return "file listed twice"
+		}
+		seen.Add(item)
+	}
+	if page.next != "" && cursors[page.next] {
+		return "cursor repeated"
+	}
+	cursors[page.next] = true
+
+	return ""
+}
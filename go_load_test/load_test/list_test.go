@@ -0,0 +1,78 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseListAPI(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *ListAPI
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"path=api/fileserver/", &ListAPI{Path: "api/fileserver/", Items: "files", Next: "next", PageSize: 100,
+			MaxPages: 10}, false},
+		{"path=list items=keys next=cursor page=5 pages=2", &ListAPI{Path: "list", Items: "keys", Next: "cursor",
+			PageSize: 5, MaxPages: 2}, false},
+		{"page=5", nil, true}, // No path
+		{"path=list page=0", nil, true},
+		{"path=list pages=0", nil, true},
+		{"path", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseListAPI(test.spec)
+		if (err != nil) != test.wantErr || (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("ParseListAPI(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestParseListPage(t *testing.T) {
+	exec := &TestExecutor{listAPI: &ListAPI{Items: "files", Next: "next"}}
+	tests := []struct {
+		body     string
+		want     listPage
+		mismatch string
+	}{
+		{`["a", "b"]`, listPage{items: []string{"a", "b"}}, ""},
+		{`{"files": ["a", {"name": "b"}], "next": "2"}`, listPage{items: []string{"a", "b"}, next: "2"}, ""},
+		{`{"files": [], "next": null}`, listPage{}, ""},
+		{`{"keys": []}`, listPage{}, `no "files" array`},
+		{`{"files": [], "next": 2}`, listPage{}, `"next" isn't a string`},
+		{`{"files": [{"key": "a"}]}`, listPage{}, "file without a name"},
+		{`[1]`, listPage{}, "file name isn't a string"},
+		{`"a"`, listPage{}, "not a JSON object or array"},
+		{`[`, listPage{}, "malformed JSON"},
+	}
+	for _, test := range tests {
+		got, mismatch := exec.parseListPage(test.body)
+		if !reflect.DeepEqual(got, test.want) || mismatch != test.mismatch {
+			t.Errorf("parseListPage(%s): got %+v, %q, want %+v, %q", test.body, got, mismatch, test.want,
+				test.mismatch)
+		}
+	}
+}
+
+func TestPageMismatch(t *testing.T) {
+	exec := &TestExecutor{listAPI: &ListAPI{PageSize: 2}}
+	seen, cursors := make(FileSet), make(map[string]bool)
+	tests := []struct {
+		page listPage
+		want string
+	}{
+		{listPage{items: []string{"ab1", "dir/ab2"}, next: "1"}, ""},
+		{listPage{items: []string{"ab3", "ab4", "ab5"}}, "page over limit"},
+		{listPage{items: []string{"ac1"}}, "file outside prefix"},
+		{listPage{items: []string{"ab1"}}, "file listed twice"},
+		{listPage{items: []string{"ab6"}, next: "1"}, "cursor repeated"},
+		{listPage{items: []string{"ab7"}}, ""},
+	}
+	for _, test := range tests {
+		if got := exec.pageMismatch(test.page, "ab", seen, cursors); got != test.want {
+			t.Errorf("pageMismatch(%+v): got %q, want %q", test.page, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package load_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ListCheck has consistency checks verify the server's listing endpoint, if it has one: a freshly created file must
+// be listed within Bound of the PUT + no longer listed within Bound of the DELETE. Listings are polled every Poll, up
+// to Max past the write, so how late violations are is measured rather than just counted. A file is listed if its name
+// appears anywhere in the listing's body, so any format listing file names works, I.E a JSON array or one per line.
+type ListCheck struct {
+	Path  string        // Listing endpoint, relative to the file's target, I.E api/fileserver/
+	Bound time.Duration // How long a file may take to appear in, or disappear from, listings
+	Max   time.Duration // How long to keep polling for a file that's late, >= Bound
+	Poll  time.Duration // Interval between listing requests
+}
+
+// ParseListCheck parses a list check spec, I.E "path=api/fileserver/ bound=1s max=10s poll=100ms". path is required.
+// An empty spec returns nil, which disables the check.
+func ParseListCheck(spec string) (*ListCheck, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid list check param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	check := &ListCheck{Bound: time.Second, Max: 10 * time.Second, Poll: 100 * time.Millisecond}
+	err := params.parse(map[string]interface{}{"path": &check.Path, "bound": &check.Bound, "max": &check.Max,
+		"poll": &check.Poll}, "path")
+	if err != nil {
+		return nil, fmt.Errorf("invalid list check spec: %w", err)
+	}
+	if check.Bound <= 0 || check.Poll <= 0 || check.Max < check.Bound {
+		return nil, fmt.Errorf("invalid list check spec: bound + poll must be > 0, + max >= bound")
+	}
+
+	return check, nil
+}
+
+func (c *ListCheck) String() string {
+	return fmt.Sprintf("%s within %s, polled every %s for up to %s", c.Path, c.Bound, c.Poll, c.Max)
+}
+
+// ListingObservation is how long after the write a consistency check's file took to appear in, or disappear from,
+// listings.
+type ListingObservation struct {
+	Step   ConsistencyStep // ConsistencyListed or ConsistencyUnlisted
+	Lag    time.Duration
+	Missed bool // Still wasn't (un)listed after ListCheck.Max, Lag is how long it was polled for
+}
+
+// late returns true if the observation violates bound.
+func (o ListingObservation) late(bound time.Duration) bool {
+	return o.Missed || o.Lag > bound
+}
+
+// listingURL returns the listing endpoint of fileName's target.
+func (tr *TestExecutor) listingURL(fileName string) string {
+	target := tr.targets.For(fileName)
+	return fmt.Sprintf("%s://%s:%s/%s", target.Proto, target.Host, target.Port, strings.TrimPrefix(tr.listCheck.Path, "/"))
+}
+
+// awaitListing polls fileName's listing until it's listed, or no longer listed if listed is false, measuring the lag
+// since written. If a poll fails, I.E a non 200, returns its response or error + an empty observation.
+func (tr *TestExecutor) awaitListing(ctx context.Context, fileName string, listed bool, written time.Time, trace traceContext, phases *RequestPhases) (ListingObservation, *http.Response, error) {
+	step := ConsistencyListed
+	if !listed {
+		step = ConsistencyUnlisted
+	}
+
+	for {
+		req, err := tr.newURLRequest(ctx, http.MethodGet, tr.listingURL(fileName), nil, trace, phases)
+		if err != nil {
+			return ListingObservation{}, nil, err
+		}
+		response, err := tr.do(req, phases)
+		if err != nil {
+			return ListingObservation{}, response, err
+		}
+		body := responseToString(response)
+		if response.StatusCode != http.StatusOK {
+			return ListingObservation{}, response, nil
+		}
+
+		lag := time.Now().Sub(written)
+		if strings.Contains(body, fileName) == listed {
+			return ListingObservation{Step: step, Lag: lag}, response, nil
+		}
+		if lag >= tr.listCheck.Max {
+			return ListingObservation{Step: step, Lag: lag, Missed: true}, response, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ListingObservation{}, nil, ctx.Err()
+		case <-time.After(tr.listCheck.Poll):
+		}
+	}
+}
+
+// listingFailure describes why a consistency check's listing step failed, or returns "" if it passed.
+func (tr *TestExecutor) listingFailure(fileName string, observation ListingObservation, response *http.Response, err error) (message string, mismatch string) {
+	verb := "listed"
+	if observation.Step == ConsistencyUnlisted {
+		verb = "unlisted"
+	}
+
+	switch {
+	case err != nil:
+		return fmt.Sprintf("Error listing files for consistency test. file: %s. Error: %s", fileName, err.Error()), ""
+	case response.StatusCode != http.StatusOK:
+		return fmt.Sprintf("Listing failed due to unexpected status code, got: %d but expected 200.", response.StatusCode),
+			statusMismatch(response.StatusCode, http.StatusOK)
+	case observation.Missed:
+		return fmt.Sprintf("File wasn't %s within %s for file: %s", verb, tr.listCheck.Max, fileName),
+			fmt.Sprintf("not %s within max", verb)
+	case observation.late(tr.listCheck.Bound):
+		return fmt.Sprintf("File was %s after %s, bound is %s for file: %s", verb, observation.Lag.Truncate(time.Millisecond),
+			tr.listCheck.Bound, fileName), fmt.Sprintf("%s after bound", verb)
+	}
+
+	return "", ""
+}
+
+// ListingSummary is how long files took to appear in + disappear from listings, see ListCheck.
+type ListingSummary struct {
+	BoundMs   float64           `json:"bound_ms"`
+	Appear    ListingLagSummary `json:"appear"`
+	Disappear ListingLagSummary `json:"disappear"`
+}
+
+type ListingLagSummary struct {
+	Checks     int            `json:"checks"`
+	Violations int            `json:"violations"` // Later than the bound, including missed
+	Missed     int            `json:"missed"`     // Not seen before polling gave up
+	Lag        LatencySummary `json:"lag"`
+	// How late violations were, missed ones counted as the time polled for.
+	ViolationLag LatencySummary `json:"violation_lag"`
+}
+
+func (s ListingLagSummary) String() string {
+	return fmt.Sprintf("%d / %d / %.0f / %.0f", s.Violations, s.Missed, s.ViolationLag.P50Ms, s.ViolationLag.P99Ms)
+}
+
+// listingStats are the lags observed for one listing step.
+type listingStats struct {
+	checks       int
+	violations   int
+	missed       int
+	lag          *LatencyHistogram
+	violationLag *LatencyHistogram
+}
+
+func newListingStats(check *ListCheck) map[ConsistencyStep]*listingStats {
+	if check == nil {
+		return nil
+	}
+
+	return map[ConsistencyStep]*listingStats{
+		ConsistencyListed:   {lag: NewLatencyHistogram(), violationLag: NewLatencyHistogram()},
+		ConsistencyUnlisted: {lag: NewLatencyHistogram(), violationLag: NewLatencyHistogram()},
+	}
+}
+
+// recordListing adds result's listing observations to their step's stats. Caller must hold resultLock.
+func (tr *TestResults) recordListing(result TestResult) {
+	for _, observation := range result.listing {
+		stats, ok := tr.listing[observation.Step]
+		if !ok {
+			continue
+		}
+
+		stats.checks++
+		stats.lag.Record(observation.Lag)
+		if observation.Missed {
+			stats.missed++
+		}
+		if observation.late(tr.listCheck.Bound) {
+			stats.violations++
+			stats.violationLag.Record(observation.Lag)
+		}
+	}
+}
+
+// listingSummary returns nil unless listings are checked. Caller must hold resultLock.
+func (tr *TestResults) listingSummary() *ListingSummary {
+	if tr.listCheck == nil {
+		return nil
+	}
+
+	return &ListingSummary{
+		BoundMs:   durationMs(tr.listCheck.Bound),
+		Appear:    tr.listing[ConsistencyListed].summary(),
+		Disappear: tr.listing[ConsistencyUnlisted].summary(),
+	}
+}
+
+func (s *listingStats) summary() ListingLagSummary {
+	return ListingLagSummary{Checks: s.checks, Violations: s.violations, Missed: s.missed, Lag: summarizeLatency(s.lag),
+		ViolationLag: summarizeLatency(s.violationLag)}
+}
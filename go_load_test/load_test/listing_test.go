@@ -0,0 +1,48 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseListCheck(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *ListCheck
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"path=api/fileserver/", &ListCheck{Path: "api/fileserver/", Bound: time.Second, Max: 10 * time.Second,
+			Poll: 100 * time.Millisecond}, false},
+		{"path=list bound=2s max=2s poll=1s", &ListCheck{Path: "list", Bound: 2 * time.Second, Max: 2 * time.Second,
+			Poll: time.Second}, false},
+		{"bound=1s", nil, true}, // No path
+		{"path=list bound=5s max=1s", nil, true},
+		{"path=list poll=0s", nil, true},
+		{"path", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseListCheck(test.spec)
+		if (err != nil) != test.wantErr || (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("ParseListCheck(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestListingObservationLate(t *testing.T) {
+	tests := []struct {
+		observation ListingObservation
+		want        bool
+	}{
+		{ListingObservation{Lag: 500 * time.Millisecond}, false},
+		{ListingObservation{Lag: time.Second}, false},
+		{ListingObservation{Lag: 1500 * time.Millisecond}, true},
+		{ListingObservation{Lag: 0, Missed: true}, true},
+	}
+	for _, test := range tests {
+		if got := test.observation.late(time.Second); got != test.want {
+			t.Errorf("%+v late: got %t, want %t", test.observation, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package load_test
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// LiveSettings override the run's rate, # of virtual users + mix of operations while it's going, so server behaviour
+// can be explored interactively rather than by restarting runs. Unset overrides leave the configured behaviour alone.
+// Safe for concurrent use, + a nil LiveSettings has no overrides.
+type LiveSettings struct {
+	lock    sync.RWMutex
+	rate    int          // If > 0, replaces the rate from the seed cadence, growth + load profile
+	mix     OperationMix // If set, replaces the configured + load profile mix
+	users   int          // # of virtual users, only for closed loop runs
+	changed chan struct{}
+}
+
+// SettingsUpdate changes the fields that are set. A rate of 0 or mix of "" removes the override.
+type SettingsUpdate struct {
+	Rate    *int    `json:"rate"`
+	Workers *int    `json:"workers"`
+	Mix     *string `json:"mix"`
+}
+
+type LiveSettingsStatus struct {
+	Rate    int    `json:"rate"`              // 0 if not overridden
+	Workers int    `json:"workers,omitempty"` // Only set for closed loop runs
+	Mix     string `json:"mix,omitempty"`
+}
+
+// NewLiveSettings starts with no overrides. users is the run's # of virtual users, 0 for open loop runs.
+func NewLiveSettings(users int) *LiveSettings {
+	return &LiveSettings{users: users, changed: make(chan struct{})}
+}
+
+func (s *LiveSettings) Rate() int {
+	if s == nil {
+		return 0
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.rate
+}
+
+func (s *LiveSettings) Mix() OperationMix {
+	if s == nil {
+		return OperationMix{}
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.mix
+}
+
+func (s *LiveSettings) Users() int {
+	if s == nil {
+		return 0
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.users
+}
+
+// Changed returns a chan that's closed the next time settings change. A nil LiveSettings never changes.
+func (s *LiveSettings) Changed() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.changed
+}
+
+// Update validates every field of update before applying any of them.
+func (s *LiveSettings) Update(update SettingsUpdate) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	closedLoop := s.users > 0
+	if update.Rate != nil && (*update.Rate < 0 || closedLoop) {
+		return fmt.Errorf("rate must be >= 0, + only applies to open loop runs")
+	}
+	if update.Workers != nil && (*update.Workers < 1 || !closedLoop) {
+		return fmt.Errorf("workers must be >= 1, + only applies to closed loop runs (--vus)")
+	}
+	var mix OperationMix
+	if update.Mix != nil {
+		var err error
+		mix, err = ParseOperationMix(*update.Mix)
+		if err != nil {
+			return err
+		}
+	}
+
+	if update.Rate != nil {
+		s.rate = *update.Rate
+		log.Infof("Live rate override set to %d req/sec (0 is no override)", s.rate)
+	}
+	if update.Workers != nil {
+		s.users = *update.Workers
+		log.Infof("Live virtual user count set to %d", s.users)
+	}
+	if update.Mix != nil {
+		s.mix = mix
+		log.Infof("Live operation mix override set to %q", *update.Mix)
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	return nil
+}
+
+func (s *LiveSettings) Status() LiveSettingsStatus {
+	if s == nil {
+		return LiveSettingsStatus{}
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	status := LiveSettingsStatus{Rate: s.rate, Workers: s.users}
+	if s.mix.IsSet() {
+		status.Mix = s.mix.String()
+	}
+
+	return status
+}
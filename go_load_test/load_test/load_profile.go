@@ -0,0 +1,269 @@
+package load_test
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Load profiles shape the rate tests are scheduled at over the course of a run, replacing the default seed + growth
+// cadence. Profiles are configured with a spec string of the profile name followed by key=value params, I.E
+//
+//	ramp from=10 to=500 over=5m
+//	burst size=500 every=10s
+
+// LoadProfile returns the # of tests to schedule per seed duration (I.E req/sec) at a point in the run.
+type LoadProfile interface {
+	Rate(elapsed time.Duration) int
+}
+
+const (
+	StageWarmup   = "warmup"   // Stage of intervals during warmup, before the measured phase + any load profile
+	StageMeasured = "measured" // Stage after warmup, for profiles without stages of their own
+)
+
+// StagedProfile is implemented by profiles made up of distinct stages, so interval output can be annotated with the
+// stage it ran in.
+type StagedProfile interface {
+	Stage(elapsed time.Duration) string
+}
+
+// MixedProfile is implemented by profiles that change the mix of operations over the run. An unset mix means the run's
+// mix.
+type MixedProfile interface {
+	Mix(elapsed time.Duration) OperationMix
+}
+
+// ParseLoadProfile parses a profile spec. An empty spec returns a nil profile.
+func ParseLoadProfile(spec string) (LoadProfile, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields)-1)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid load profile param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	switch fields[0] {
+	case "constant":
+		return newConstantRate(params)
+	case "ramp":
+		return newLinearRamp(params)
+	case "steps":
+		return newSteps(params)
+	case "spike":
+		return newSpike(params)
+	case "sine":
+		return newSine(params)
+	case "burst":
+		return newBurst(params)
+	case "autotune":
+		return newAutoTune(params)
+	default:
+		return nil, fmt.Errorf("unknown load profile: %s", fields[0])
+	}
+}
+
+// ConstantRate schedules a fixed # of tests per second for the whole run, regardless of how the server responds.
+type ConstantRate struct {
+	PerSecond int
+}
+
+func newConstantRate(params profileParams) (LoadProfile, error) {
+	constant := &ConstantRate{}
+	err := params.parse(map[string]interface{}{"rate": &constant.PerSecond}, "rate")
+	if err != nil {
+		return nil, fmt.Errorf("invalid constant profile: %w", err)
+	}
+
+	return constant, nil
+}
+
+func (c *ConstantRate) Rate(_ time.Duration) int {
+	return c.PerSecond
+}
+
+// LinearRamp grows the rate linearly From -> To over Over, then holds at To.
+type LinearRamp struct {
+	From int
+	To   int
+	Over time.Duration
+}
+
+func newLinearRamp(params profileParams) (LoadProfile, error) {
+	ramp := &LinearRamp{}
+	err := params.parse(map[string]interface{}{"from": &ramp.From, "to": &ramp.To, "over": &ramp.Over}, "to", "over")
+	if err != nil {
+		return nil, fmt.Errorf("invalid ramp profile: %w", err)
+	}
+
+	return ramp, nil
+}
+
+func (r *LinearRamp) Rate(elapsed time.Duration) int {
+	if elapsed >= r.Over || r.Over <= 0 {
+		return r.To
+	}
+
+	progress := float64(elapsed) / float64(r.Over)
+	return r.From + int(float64(r.To-r.From)*progress)
+}
+
+// Steps is a staircase, starting at Start and adding Step every Every, up to Max if set.
+type Steps struct {
+	Start int
+	Step  int
+	Every time.Duration
+	Max   int
+}
+
+func newSteps(params profileParams) (LoadProfile, error) {
+	steps := &Steps{}
+	err := params.parse(map[string]interface{}{"start": &steps.Start, "step": &steps.Step, "every": &steps.Every, "max": &steps.Max},
+		"step", "every")
+	if err != nil {
+		return nil, fmt.Errorf("invalid steps profile: %w", err)
+	}
+	if steps.Every <= 0 {
+		return nil, fmt.Errorf("invalid steps profile: every must be > 0")
+	}
+
+	return steps, nil
+}
+
+func (s *Steps) Rate(elapsed time.Duration) int {
+	rate := s.Start + s.step(elapsed)*s.Step
+	if s.Max > 0 && rate > s.Max {
+		return s.Max
+	}
+
+	return rate
+}
+
+// Stage returns the step # (from 0) + its rate, I.E "step-3@400".
+func (s *Steps) Stage(elapsed time.Duration) string {
+	return fmt.Sprintf("step-%d@%d", s.step(elapsed), s.Rate(elapsed))
+}
+
+func (s *Steps) step(elapsed time.Duration) int {
+	step := int(elapsed / s.Every)
+	if s.Max > 0 && s.Step > 0 {
+		step = Min(step, Max((s.Max-s.Start+s.Step-1)/s.Step, 0))
+	}
+
+	return step
+}
+
+const (
+	SpikeStageBaseline = "baseline"
+	SpikeStageBurst    = "spike"
+	SpikeStageRecovery = "recovery"
+)
+
+// Spike holds Base, bursts to Base * Multiplier at At for For, then returns to Base.
+type Spike struct {
+	Base       int
+	Multiplier float64
+	At         time.Duration
+	For        time.Duration
+}
+
+func newSpike(params profileParams) (LoadProfile, error) {
+	spike := &Spike{Multiplier: 5}
+	err := params.parse(map[string]interface{}{"base": &spike.Base, "x": &spike.Multiplier, "at": &spike.At, "for": &spike.For},
+		"base", "at", "for")
+	if err != nil {
+		return nil, fmt.Errorf("invalid spike profile: %w", err)
+	}
+
+	return spike, nil
+}
+
+func (s *Spike) Rate(elapsed time.Duration) int {
+	if s.Stage(elapsed) == SpikeStageBurst {
+		return int(float64(s.Base) * s.Multiplier)
+	}
+
+	return s.Base
+}
+
+func (s *Spike) Stage(elapsed time.Duration) string {
+	switch {
+	case elapsed < s.At:
+		return SpikeStageBaseline
+	case elapsed < s.At+s.For:
+		return SpikeStageBurst
+	default:
+		return SpikeStageRecovery
+	}
+}
+
+// Sine oscillates around Base by +/- Amplitude once every Period, I.E to emulate daily traffic over a soak test.
+type Sine struct {
+	Base      int
+	Amplitude int
+	Period    time.Duration
+}
+
+func newSine(params profileParams) (LoadProfile, error) {
+	sine := &Sine{}
+	err := params.parse(map[string]interface{}{"base": &sine.Base, "amplitude": &sine.Amplitude, "period": &sine.Period},
+		"base", "amplitude", "period")
+	if err != nil {
+		return nil, fmt.Errorf("invalid sine profile: %w", err)
+	}
+	if sine.Period <= 0 {
+		return nil, fmt.Errorf("invalid sine profile: period must be > 0")
+	}
+
+	return sine, nil
+}
+
+func (s *Sine) Rate(elapsed time.Duration) int {
+	angle := 2 * math.Pi * float64(elapsed) / float64(s.Period)
+	return s.Base + int(math.Round(float64(s.Amplitude)*math.Sin(angle)))
+}
+
+type profileParams map[string]string
+
+// parse sets each target (*int, *float64, *time.Duration or *string) from its param, if present. Unknown params + missing
+// required params are errors.
+func (p profileParams) parse(targets map[string]interface{}, required ...string) error {
+	for _, key := range required {
+		if _, ok := p[key]; !ok {
+			return fmt.Errorf("missing required param: %s", key)
+		}
+	}
+
+	for key, value := range p {
+		target, ok := targets[key]
+		if !ok {
+			return fmt.Errorf("unknown param: %s", key)
+		}
+
+		var err error
+		switch t := target.(type) {
+		case *int:
+			*t, err = strconv.Atoi(value)
+		case *float64:
+			*t, err = strconv.ParseFloat(value, 64)
+		case *time.Duration:
+			*t, err = time.ParseDuration(value)
+		case *string:
+			*t = value
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %s", key, value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLoadProfile(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    LoadProfile
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"constant rate=50", &ConstantRate{PerSecond: 50}, false},
+		{"ramp from=10 to=500 over=5m", &LinearRamp{From: 10, To: 500, Over: 5 * time.Minute}, false},
+		{"ramp to=500 over=5m", &LinearRamp{To: 500, Over: 5 * time.Minute}, false},
+		{"ramp from=10 over=5m", nil, true},
+		{"ramp from=10 to=500 over=5m speed=2", nil, true},
+		{"ramp from=ten to=500 over=5m", nil, true},
+		{"ramp to=500 over=5", nil, true},
+		{"ramp to", nil, true},
+		{"zigzag rate=5", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseLoadProfile(test.spec)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseLoadProfile(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestLinearRampRate(t *testing.T) {
+	ramp := &LinearRamp{From: 10, To: 110, Over: 10 * time.Second}
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 10},
+		{5 * time.Second, 60},
+		{9 * time.Second, 100},
+		{10 * time.Second, 110},
+		{time.Hour, 110},
+	}
+	for _, test := range tests {
+		if got := ramp.Rate(test.elapsed); got != test.want {
+			t.Errorf("Rate(%s): got %d, want %d", test.elapsed, got, test.want)
+		}
+	}
+	if got := (&LinearRamp{From: 100, To: 0, Over: 10 * time.Second}).Rate(5 * time.Second); got != 50 {
+		t.Errorf("ramping down: got %d, want 50", got)
+	}
+}
+
+func TestSteps(t *testing.T) {
+	if _, err := ParseLoadProfile("steps start=10 step=10 every=0s"); err == nil {
+		t.Errorf("steps every 0s: got no error")
+	}
+	profile, err := ParseLoadProfile("steps start=100 step=100 every=30s max=350")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		rate    int
+		stage   string
+	}{
+		{0, 100, "step-0@100"},
+		{29 * time.Second, 100, "step-0@100"},
+		{30 * time.Second, 200, "step-1@200"},
+		{60 * time.Second, 300, "step-2@300"},
+		// Capped at max, + the step # stops counting once it's reached
+		{90 * time.Second, 350, "step-3@350"},
+		{time.Hour, 350, "step-3@350"},
+	}
+	for _, test := range tests {
+		if rate, stage := profile.Rate(test.elapsed), profile.(StagedProfile).Stage(test.elapsed); rate != test.rate ||
+			stage != test.stage {
+			t.Errorf("at %s: got %d, %s, want %d, %s", test.elapsed, rate, stage, test.rate, test.stage)
+		}
+	}
+}
+
+func TestSpike(t *testing.T) {
+	profile, err := ParseLoadProfile("spike base=100 x=3 at=1m for=30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&Spike{Base: 100, Multiplier: 3, At: time.Minute, For: 30 * time.Second}); !reflect.DeepEqual(profile, want) {
+		t.Fatalf("got %+v, want %+v", profile, want)
+	}
+	if defaulted, _ := ParseLoadProfile("spike base=100 at=1m for=30s"); defaulted.(*Spike).Multiplier != 5 {
+		t.Errorf("got multiplier %g, want the default 5", defaulted.(*Spike).Multiplier)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		rate    int
+		stage   string
+	}{
+		{0, 100, SpikeStageBaseline},
+		{time.Minute - time.Millisecond, 100, SpikeStageBaseline},
+		{time.Minute, 300, SpikeStageBurst},
+		{90*time.Second - time.Millisecond, 300, SpikeStageBurst},
+		{90 * time.Second, 100, SpikeStageRecovery},
+	}
+	for _, test := range tests {
+		if rate, stage := profile.Rate(test.elapsed), profile.(StagedProfile).Stage(test.elapsed); rate != test.rate ||
+			stage != test.stage {
+			t.Errorf("at %s: got %d, %s, want %d, %s", test.elapsed, rate, stage, test.rate, test.stage)
+		}
+	}
+}
+
+func TestSine(t *testing.T) {
+	if _, err := ParseLoadProfile("sine base=100 amplitude=50 period=0s"); err == nil {
+		t.Errorf("sine with a period of 0s: got no error")
+	}
+	profile, err := ParseLoadProfile("sine base=100 amplitude=50 period=1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 100},
+		{15 * time.Minute, 150},
+		{30 * time.Minute, 100},
+		{45 * time.Minute, 50},
+		{75 * time.Minute, 150}, // The next period
+		{5 * time.Minute, 125},  // sin(30°) = 0.5
+	}
+	for _, test := range tests {
+		if got := profile.Rate(test.elapsed); got != test.want {
+			t.Errorf("at %s: got %d, want %d", test.elapsed, got, test.want)
+		}
+	}
+}
+
+func TestBurst(t *testing.T) {
+	for _, spec := range []string{"burst size=500", "burst size=0 every=10s", "burst size=500 every=0s"} {
+		if _, err := ParseLoadProfile(spec); err == nil {
+			t.Errorf("%q: got no error", spec)
+		}
+	}
+	profile, err := ParseLoadProfile("burst size=500 every=10s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&Burst{Size: 500, Every: 10 * time.Second}); !reflect.DeepEqual(profile, want) {
+		t.Fatalf("got %+v, want %+v", profile, want)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		rate    int
+		stage   string
+	}{
+		{0, 500, BurstStageBurst},
+		{time.Second - time.Millisecond, 500, BurstStageBurst},
+		{time.Second, 0, BurstStageIdle},
+		{10*time.Second - time.Millisecond, 0, BurstStageIdle},
+		{10 * time.Second, 500, BurstStageBurst},
+	}
+	for _, test := range tests {
+		if rate, stage := profile.Rate(test.elapsed), profile.(StagedProfile).Stage(test.elapsed); rate != test.rate ||
+			stage != test.stage {
+			t.Errorf("at %s: got %d, %s, want %d, %s", test.elapsed, rate, stage, test.rate, test.stage)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package load_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Exposes aggregated results in the Prometheus text exposition format so the load test can be scraped + graphed.
+
+// labelEscaper escapes a label value's backslashes, double quotes + newlines, as the exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue returns value escaped for use between a label's double quotes.
+func labelValue(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// latencyBucketBounds are the upper bounds used when rendering coarse latency histograms.
+var latencyBucketBounds = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	20 * time.Second,
+}
+
+// ServeMetrics blocks serving /metrics on the provided address until ctx is cancelled.
+func (ra *ResultAggregator) ServeMetrics(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", ra.HandleMetrics)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	log.Infof("Serving Prometheus metrics on %s/metrics", addr)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (ra *ResultAggregator) HandleMetrics(response http.ResponseWriter, _ *http.Request) {
+	response.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, err := response.Write([]byte(ra.Results.PrometheusMetrics()))
+	if err != nil {
+		log.Errorf("Failed to write metrics response: %+v", err)
+	}
+}
+
+// PrometheusMetrics renders all counters and latency histograms in the Prometheus text format.
+func (tr *TestResults) PrometheusMetrics() string {
+	tr.resultLock.RLock()
+	defer tr.resultLock.RUnlock()
+
+	var sb strings.Builder
+	writeCounter(&sb, "loadtest_requests_total", "Total requests issued.", tr.numRequests.Get())
+	writeCounter(&sb, "loadtest_successes_total", "Total successful requests.", tr.numSuccess.Get())
+	writeCounter(&sb, "loadtest_failures_total", "Total failed tests.", tr.numFailure.Get())
+	writeCounter(&sb, "loadtest_http_5xx_total", "Total 5XX responses.", tr.num500s.Get())
+	writeCounter(&sb, "loadtest_throttled_total", "Total 429 responses.", tr.numThrottled.Get())
+	writeCounter(&sb, "loadtest_consistency_failures_total", "Total failed consistency checks.", tr.numFailedConsistency.Get())
+
+	name := "loadtest_request_duration_seconds"
+	sb.WriteString(fmt.Sprintf("# HELP %s Request latency by operation.\n", name))
+	sb.WriteString(fmt.Sprintf("# TYPE %s histogram\n", name))
+	for _, op := range latencyOperations {
+		writeHistogram(&sb, name, fmt.Sprintf(`operation="%s"`, op), tr.opLatency[op])
+	}
+
+	name = "loadtest_response_duration_seconds"
+	sb.WriteString(fmt.Sprintf("# HELP %s Request latency by response status class.\n", name))
+	sb.WriteString(fmt.Sprintf("# TYPE %s histogram\n", name))
+	for _, class := range statusClasses {
+		writeHistogram(&sb, name, fmt.Sprintf(`status_class="%s"`, class), tr.statusLatency[class])
+	}
+
+	if tr.targetStats != nil {
+		tr.writeTargetMetrics(&sb)
+	}
+
+	return sb.String()
+}
+
+// writeTargetMetrics writes per target counters + latency, labelled by target.
+func (tr *TestResults) writeTargetMetrics(sb *strings.Builder) {
+	counters := []struct {
+		name  string
+		help  string
+		value func(t *TargetStats) int
+	}{
+		{"loadtest_target_requests_total", "Total requests issued per target.", func(t *TargetStats) int { return t.Requests }},
+		{"loadtest_target_successes_total", "Total successful requests per target.", func(t *TargetStats) int { return t.Successes }},
+		{"loadtest_target_failures_total", "Total failed tests per target.", func(t *TargetStats) int { return t.Failures }},
+		{"loadtest_target_throttled_total", "Total 429 responses per target.", func(t *TargetStats) int { return t.Throttled }},
+		{"loadtest_target_http_5xx_total", "Total 5XX responses per target.", func(t *TargetStats) int { return t.Http5XX }},
+	}
+	for _, counter := range counters {
+		sb.WriteString(fmt.Sprintf("# HELP %s %s\n", counter.name, counter.help))
+		sb.WriteString(fmt.Sprintf("# TYPE %s counter\n", counter.name))
+		for _, target := range tr.targets {
+			sb.WriteString(fmt.Sprintf("%s{target=\"%s\"} %d\n", counter.name, labelValue(target.Name()), counter.value(tr.targetStats[target.Name()])))
+		}
+	}
+
+	name := "loadtest_target_request_duration_seconds"
+	sb.WriteString(fmt.Sprintf("# HELP %s Request latency per target.\n", name))
+	sb.WriteString(fmt.Sprintf("# TYPE %s histogram\n", name))
+	for _, target := range tr.targets {
+		writeHistogram(sb, name, fmt.Sprintf(`target="%s"`, labelValue(target.Name())), tr.targetStats[target.Name()].latency)
+	}
+}
+
+func writeCounter(sb *strings.Builder, name string, help string, value int) {
+	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", name, help))
+	sb.WriteString(fmt.Sprintf("# TYPE %s counter\n", name))
+	sb.WriteString(fmt.Sprintf("%s %d\n", name, value))
+}
+
+func writeHistogram(sb *strings.Builder, name string, labels string, hist *LatencyHistogram) {
+	for _, bound := range latencyBucketBounds {
+		sb.WriteString(fmt.Sprintf("%s_bucket{%s,le=\"%g\"} %d\n", name, labels, bound.Seconds(), hist.CountAtOrBelow(bound)))
+	}
+	sb.WriteString(fmt.Sprintf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, hist.Count()))
+	sb.WriteString(fmt.Sprintf("%s_sum{%s} %g\n", name, labels, hist.Sum().Seconds()))
+	sb.WriteString(fmt.Sprintf("%s_count{%s} %d\n", name, labels, hist.Count()))
+}
@@ -0,0 +1,18 @@
+package load_test
+
+import "testing"
+
+func TestLabelValue(t *testing.T) {
+	for value, want := range map[string]string{
+		"http://host:8080": "http://host:8080",
+		`back\slash`:       `back\\slash`,
+		`"quoted"`:         `\"quoted\"`,
+		"new\nline":        `new\nline`,
+		"all\\\"\n":        `all\\\"\n`,
+		"":                 "",
+	} {
+		if got := labelValue(value); got != want {
+			t.Errorf("labelValue(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
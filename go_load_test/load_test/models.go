@@ -2,6 +2,7 @@ package load_test
 
 import (
 	"math/rand"
+	"sort"
 )
 
 type FileSet map[string]bool
@@ -25,16 +26,62 @@ func (s FileSet) RandomFile() string {
 		return ""
 	}
 
-	keys := make([]string, length)
-
-	i := 0
+	keys := make([]string, 0, length)
 	for k := range s {
-		keys[i] = k
-		i++
+		keys = append(keys, k)
+	}
+
+	return keys[rand.Intn(length)]
+}
+
+// SortedFileSet is a set of files that also keeps them sorted, so a seeded Rand picks the same file from the same files
+// whatever order they were added in, without sorting or copying every file on every pick.
+type SortedFileSet struct {
+	files  FileSet
+	sorted []string
+}
+
+func NewSortedFileSet(capacity int) *SortedFileSet {
+	return &SortedFileSet{files: make(FileSet, capacity), sorted: make([]string, 0, capacity)}
+}
+
+func (s *SortedFileSet) Has(item string) bool {
+	return s.files.Has(item)
+}
+
+func (s *SortedFileSet) Add(item string) {
+	if s.files.Has(item) {
+		return
+	}
+
+	s.files.Add(item)
+	i := sort.SearchStrings(s.sorted, item)
+	s.sorted = append(s.sorted, "")
+	copy(s.sorted[i+1:], s.sorted[i:])
+	s.sorted[i] = item
+}
+
+func (s *SortedFileSet) Delete(item string) {
+	if !s.files.Has(item) {
+		return
+	}
+
+	s.files.Delete(item)
+	i := sort.SearchStrings(s.sorted, item)
+	s.sorted = append(s.sorted[:i], s.sorted[i+1:]...)
+}
+
+func (s *SortedFileSet) Len() int {
+	return len(s.sorted)
+}
+
+// randomFile returns a random file drawn from r, or "" if s is empty.
+func (s *SortedFileSet) randomFile(r *Rand) string {
+	if len(s.sorted) == 0 {
+		return ""
 	}
 
-	randomIdx := rand.Intn(length)
-	return keys[randomIdx]
+	return s.sorted[r.Intn(len(s.sorted))]
 }
 
 type TestFunc func(fileName string)
@@ -0,0 +1,33 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortedFileSet(t *testing.T) {
+	forwards, backwards := NewSortedFileSet(0), NewSortedFileSet(0)
+	files := []string{"c", "a", "e", "b", "d"}
+	for i := range files {
+		forwards.Add(files[i])
+		backwards.Add(files[len(files)-1-i])
+	}
+	forwards.Add("a")
+	forwards.Delete("c")
+	backwards.Delete("c")
+	forwards.Delete("missing")
+
+	if !reflect.DeepEqual(forwards.sorted, []string{"a", "b", "d", "e"}) || forwards.Len() != 4 || forwards.Has("c") {
+		t.Fatalf("got files %v", forwards.sorted)
+	}
+	// The same seed picks the same files from the same set, whatever order it was built in.
+	r1, r2 := newSeededRand(7), newSeededRand(7)
+	for i := 0; i < 20; i++ {
+		if a, b := forwards.randomFile(r1), backwards.randomFile(r2); a != b {
+			t.Fatalf("pick %d: got %s + %s", i, a, b)
+		}
+	}
+	if NewSortedFileSet(0).randomFile(r1) != "" {
+		t.Errorf("picked a file from an empty set")
+	}
+}
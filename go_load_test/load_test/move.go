@@ -0,0 +1,148 @@
+package load_test
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MOVE tests PUT a new file, rename it with a WebDAV style MOVE to a new key given in the Destination header, then
+// check the old key 404s + the new key serves exactly the bytes uploaded. The file is deleted afterwards. Only run it
+// against servers that support MOVE, others fail it.
+const MOVE TestType = "MOVE"
+
+// moveNameSuffix is appended to a file's name to get the key it's moved to.
+const moveNameSuffix = ".moved"
+
+func (tr *TestExecutor) MoveFile(test Test) {
+	ctx, result, done := tr.beginHeldTest(test, MOVE)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+	movedName := fileName + moveNameSuffix
+
+	payload := newSeededRand(test.payloadSeed)
+	fileBytes := make([]byte, tr.randomFileSize(payload))
+	err := tr.fillPayload(payload, fileBytes)
+	if err != nil {
+		result.message = "Failed to generate random file bytes"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	byteString := b64.StdEncoding.EncodeToString(fileBytes)
+
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(byteString), trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for MoveFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	response, err := tr.do(req, phases)
+	// Whichever key holds the file at the end is deleted.
+	leftover := fileName
+	defer func() {
+		_, _ = tr.removeFile(leftover)
+	}()
+	result.response = response
+	result.bytesSent = int64(len(byteString))
+	if err != nil {
+		result.message = "Error executing http PUT request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	result.message = responseToString(response)
+	if response.StatusCode >= 400 {
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	req, err = tr.newRequest(ctx, "MOVE", fileName, nil, trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for MoveFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	req.Header.Set("Destination", tr.buildPath(movedName))
+	response, err = tr.do(req, phases)
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http MOVE request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	result.message = responseToString(response)
+	if response.StatusCode >= 400 {
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	leftover = movedName
+
+	response, err = tr.get(ctx, fileName, trace, phases)
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http GET request for the old key"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	_ = responseToString(response)
+	if response.StatusCode != http.StatusNotFound {
+		// The old key may still hold a copy of the file.
+		_, _ = tr.removeFile(fileName)
+		result.mismatch = fmt.Sprintf("old key %s", statusMismatch(response.StatusCode, http.StatusNotFound))
+		result.message = fmt.Sprintf("File was moved but its old key still returned %d", response.StatusCode)
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	response, err = tr.get(ctx, movedName, trace, phases)
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http GET request for the new key"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	body := responseToString(response)
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	if response.StatusCode != http.StatusOK {
+		result.mismatch = fmt.Sprintf("new key %s", statusMismatch(response.StatusCode, http.StatusOK))
+		result.message = fmt.Sprintf("File was moved but its new key returned %d", response.StatusCode)
+		result.failed = true
+	} else if body != byteString {
+		result.mismatch = bodyMismatch(byteString, body)
+		result.message = fmt.Sprintf("File was moved but its new key doesn't serve the bytes uploaded: %s", result.mismatch)
+		result.failed = true
+	} else {
+		result.message = ""
+	}
+	tr.emit(result)
+}
@@ -0,0 +1,165 @@
+package load_test
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+)
+
+// mixOperations are the operations an OperationMix weights, in the order they're written.
+var mixOperations = []TestType{GET, PUT, DELETE, CONSISTENCY}
+
+// OperationMix weights how often each operation is scheduled, I.E 80/15/4/1 for GET/PUT/DELETE/CONSISTENCY. Weights
+// don't need to add up to 100. PUTs of new files (CREATE) count as PUTs, + an operation picked before there are any
+// files to run it on becomes a CREATE, so the achieved mix drifts from the target early in a run.
+type OperationMix struct {
+	Get         int
+	Put         int
+	Delete      int
+	Consistency int
+}
+
+// ParseOperationMix parses GET/PUT/DELETE/CONSISTENCY weights separated by / or :, I.E 80/15/4/1. An empty value
+// returns an unset mix, which keeps the default mix.
+func ParseOperationMix(value string) (OperationMix, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return OperationMix{}, nil
+	}
+
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == ':' })
+	if len(fields) != len(mixOperations) {
+		return OperationMix{}, fmt.Errorf("invalid operation mix: %s. Expected GET/PUT/DELETE/CONSISTENCY weights, I.E 80/15/4/1", value)
+	}
+
+	weights := make([]int, len(fields))
+	for i, field := range fields {
+		weight, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || weight < 0 {
+			return OperationMix{}, fmt.Errorf("invalid %s weight in operation mix: %s", mixOperations[i], field)
+		}
+		weights[i] = weight
+	}
+
+	mix := OperationMix{Get: weights[0], Put: weights[1], Delete: weights[2], Consistency: weights[3]}
+	if !mix.IsSet() {
+		return OperationMix{}, fmt.Errorf("invalid operation mix: %s. At least one weight must be > 0", value)
+	}
+
+	return mix, nil
+}
+
+// UnmarshalYAML parses a mix in the same format as ParseOperationMix, I.E mix: 80/15/4/1.
+func (m *OperationMix) UnmarshalYAML(value *yaml.Node) error {
+	mix, err := ParseOperationMix(value.Value)
+	if err != nil {
+		return err
+	}
+
+	*m = mix
+	return nil
+}
+
+// IsSet returns false for the zero value, I.E the default mix is used.
+func (m OperationMix) IsSet() bool {
+	return m.Get+m.Put+m.Delete+m.Consistency > 0
+}
+
+func (m OperationMix) String() string {
+	return fmt.Sprintf("%d/%d/%d/%d", m.Get, m.Put, m.Delete, m.Consistency)
+}
+
+// Shares returns each operation's fraction of the mix.
+func (m OperationMix) Shares() map[TestType]float64 {
+	return mixShares(map[TestType]int{GET: m.Get, PUT: m.Put, DELETE: m.Delete, CONSISTENCY: m.Consistency})
+}
+
+// tests expands the mix into a list where each operation appears weight times, to pick from at random. Operations are
+// listed in mixOperations order, so a seeded run picks the same operations every time.
+func (m OperationMix) tests() []TestType {
+	var tests []TestType
+	weights := []int{m.Get, m.Put, m.Delete, m.Consistency}
+	for i, op := range mixOperations {
+		for j := 0; j < weights[i]; j++ {
+			tests = append(tests, op)
+		}
+	}
+
+	return tests
+}
+
+func mixShares(counts map[TestType]int) map[TestType]float64 {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+
+	shares := make(map[TestType]float64, len(counts))
+	for op, count := range counts {
+		if total > 0 {
+			shares[op] = float64(count) / float64(total)
+		}
+	}
+
+	return shares
+}
+
+// formatMix formats shares as GET/PUT/DELETE/CONSISTENCY percentages, I.E 79.8/15.1/4.0/1.1.
+func formatMix(shares map[TestType]float64) string {
+	percents := make([]string, len(mixOperations))
+	for i, op := range mixOperations {
+		percents[i] = strconv.FormatFloat(shares[op]*100, 'f', 1, 64)
+	}
+
+	return strings.Join(percents, "/")
+}
+
+// mixedTest picks an operation by OperationMix weight. As with the default mix, a PUT creates a new file while there
+// are fewer than MaxFileCount, + every operation other than a consistency check creates one if there are no files.
+func (ts *TestScheduler) mixedTest() Test {
+	testToRun := Test{TestType: ts.tests[ts.rand.Intn(len(ts.tests))]}
+	ts.trackedFileLock.Lock()
+	defer ts.trackedFileLock.Unlock()
+
+	fileCount := ts.trackedFiles.Len()
+	switch {
+	case testToRun.TestType == CONSISTENCY:
+		testToRun.fileName = ts.newFileName()
+		// This tests is 4 requests total, so add 3 extra.
+		ts.numScheduled += 3
+	case fileCount == 0 || (testToRun.TestType == PUT && ts.rand.Intn(ts.cfg.TestConfig.MaxFileCount) > fileCount):
+		testToRun.TestType = CREATE
+		testToRun.fileName = ts.newFileName()
+	default:
+		testToRun.fileName = ts.fileFor(testToRun.TestType)
+		if testToRun.TestType == DELETE {
+			ts.trackedFiles.Delete(testToRun.fileName)
+		}
+	}
+
+	return testToRun
+}
+
+// OperationMixSummary compares the achieved mix of operations to the configured one.
+type OperationMixSummary struct {
+	Target   map[TestType]float64 `json:"target,omitempty"` // Unset with the default mix
+	Achieved map[TestType]float64 `json:"achieved"`
+}
+
+// operationMix returns the share of each operation run so far. Caller must hold resultLock.
+func (tr *TestResults) operationMix() OperationMixSummary {
+	summary := OperationMixSummary{
+		Achieved: mixShares(map[TestType]int{
+			GET:         tr.numGet.Get(),
+			PUT:         tr.numPut.Get(),
+			DELETE:      tr.numDelete.Get(),
+			CONSISTENCY: tr.numConsistency.Get(),
+		}),
+	}
+	if tr.mix.IsSet() {
+		summary.Target = tr.mix.Shares()
+	}
+
+	return summary
+}
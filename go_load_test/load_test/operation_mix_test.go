@@ -0,0 +1,45 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOperationMix(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    OperationMix
+		wantErr bool
+	}{
+		{"", OperationMix{}, false},
+		{"80/15/4/1", OperationMix{Get: 80, Put: 15, Delete: 4, Consistency: 1}, false},
+		{" 8:1:1:0 ", OperationMix{Get: 8, Put: 1, Delete: 1}, false},
+		{"80/15/4", OperationMix{}, true},
+		{"80/15/4/1/1", OperationMix{}, true},
+		{"80/-1/4/1", OperationMix{}, true},
+		{"80/x/4/1", OperationMix{}, true},
+		{"0/0/0/0", OperationMix{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseOperationMix(test.value)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseOperationMix(%q): got %+v, %v, want %+v, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestOperationMixShares(t *testing.T) {
+	mix := OperationMix{Get: 6, Put: 2, Delete: 1, Consistency: 1}
+	want := map[TestType]float64{GET: 0.6, PUT: 0.2, DELETE: 0.1, CONSISTENCY: 0.1}
+	if got := mix.Shares(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got shares %v, want %v", got, want)
+	}
+	if got := formatMix(want); got != "60.0/20.0/10.0/10.0" {
+		t.Errorf("got %s, want 60.0/20.0/10.0/10.0", got)
+	}
+	wantTests := []TestType{GET, GET, GET, GET, GET, GET, PUT, PUT, DELETE, CONSISTENCY}
+	if got := mix.tests(); !reflect.DeepEqual(got, wantTests) {
+		t.Errorf("got tests %v, want %v", got, wantTests)
+	}
+}
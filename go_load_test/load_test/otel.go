@@ -0,0 +1,328 @@
+package load_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exports interval latency histograms + slow request spans to an OpenTelemetry collector using OTLP/HTTP with JSON
+// encoding. Requests carry a W3C traceparent header so client spans can be joined with server side traces.
+
+const (
+	otlpMaxBufferedSpans = 2048
+	otlpTemporalityDelta = 1
+	otlpSpanKindClient   = 3
+	otlpStatusOk         = 1
+	otlpStatusError      = 2
+)
+
+type traceContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+func newTraceContext() traceContext {
+	var tc traceContext
+	for !tc.Valid() {
+		_, _ = rand.Read(tc.traceID[:])
+		_, _ = rand.Read(tc.spanID[:])
+	}
+
+	return tc
+}
+
+// Valid returns false for the zero value, which is used when tracing is disabled.
+func (tc traceContext) Valid() bool {
+	return tc.traceID != [16]byte{} && tc.spanID != [8]byte{}
+}
+
+func (tc traceContext) TraceID() string {
+	return hex.EncodeToString(tc.traceID[:])
+}
+
+func (tc traceContext) SpanID() string {
+	return hex.EncodeToString(tc.spanID[:])
+}
+
+// TraceParent formats the context as a sampled W3C traceparent header.
+func (tc traceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-01", tc.TraceID(), tc.SpanID())
+}
+
+type OTLPExporter struct {
+	endpoint      string
+	client        *http.Client
+	resource      otlpResource
+	slowThreshold time.Duration
+	spans         []otlpSpan
+	spanLock      sync.Mutex
+	lastExport    time.Time
+}
+
+func NewOTLPExporter(endpoint string, serviceName string, slowThreshold time.Duration) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: time.Second * 10},
+		resource: otlpResource{Attributes: []otlpAttribute{
+			stringAttribute("service.name", serviceName),
+		}},
+		slowThreshold: slowThreshold,
+		lastExport:    time.Now(),
+	}
+}
+
+// RecordSpan buffers a client span for the result if it was slower than the configured threshold or failed.
+func (e *OTLPExporter) RecordSpan(result TestResult, completedAt time.Time) {
+	if !result.trace.Valid() || (result.Duration() < e.slowThreshold && !result.WasTestFailure()) {
+		return
+	}
+
+	status := otlpStatus{Code: otlpStatusOk}
+	if result.WasTestFailure() {
+		status = otlpStatus{Code: otlpStatusError, Message: result.ErrorMessage()}
+	}
+
+	span := otlpSpan{
+		TraceID:           result.trace.TraceID(),
+		SpanID:            result.trace.SpanID(),
+		Name:              string(result.TestType()),
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: uint64(completedAt.Add(-result.Duration()).UnixNano()),
+		EndTimeUnixNano:   uint64(completedAt.UnixNano()),
+		Attributes: []otlpAttribute{
+			stringAttribute("loadtest.file_name", result.FileName()),
+			stringAttribute("loadtest.test_type", string(result.TestType())),
+			intAttribute("http.status_code", int64(result.StatusCode())),
+		},
+		Status: status,
+	}
+
+	e.spanLock.Lock()
+	defer e.spanLock.Unlock()
+	if len(e.spans) < otlpMaxBufferedSpans {
+		e.spans = append(e.spans, span)
+	}
+}
+
+// Write exports the interval's metrics along with any spans buffered since the last interval.
+func (e *OTLPExporter) Write(stats IntervalStats) error {
+	start := uint64(e.lastExport.UnixNano())
+	end := uint64(stats.Timestamp.UnixNano())
+	e.lastExport = stats.Timestamp
+
+	metrics := []otlpMetric{
+		counterMetric("loadtest.requests", stats.Requests, start, end),
+		counterMetric("loadtest.successes", stats.Successes, start, end),
+		counterMetric("loadtest.failures", stats.Failures, start, end),
+		counterMetric("loadtest.throttled", stats.Throttles, start, end),
+	}
+
+	bounds := make([]float64, len(latencyBucketBounds))
+	for i, bound := range latencyBucketBounds {
+		bounds[i] = durationMs(bound)
+	}
+	histogram := otlpMetric{Name: "loadtest.request.duration", Unit: "ms", Histogram: &otlpHistogram{
+		AggregationTemporality: otlpTemporalityDelta,
+	}}
+	for _, op := range latencyOperations {
+		latency := stats.Latency[op]
+		if latency.Count == 0 {
+			continue
+		}
+		histogram.Histogram.DataPoints = append(histogram.Histogram.DataPoints, otlpHistogramDataPoint{
+			Attributes:        []otlpAttribute{stringAttribute("operation", string(op))},
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Count:             uint64(latency.Count),
+			Sum:               latency.MeanMs * float64(latency.Count),
+			BucketCounts:      toUint64s(stats.LatencyBuckets[op]),
+			ExplicitBounds:    bounds,
+		})
+	}
+	if len(histogram.Histogram.DataPoints) > 0 {
+		metrics = append(metrics, histogram)
+	}
+
+	err := e.post("/v1/metrics", otlpMetricsRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     e.resource,
+		ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: "load_test"}, Metrics: metrics}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	return e.flushSpans()
+}
+
+func (e *OTLPExporter) Close() error {
+	return e.flushSpans()
+}
+
+func (e *OTLPExporter) flushSpans() error {
+	e.spanLock.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.spanLock.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	return e.post("/v1/traces", otlpTracesRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   e.resource,
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "load_test"}, Spans: spans}},
+	}}})
+}
+
+func (e *OTLPExporter) post(path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	response, err := e.client.Post(e.endpoint+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to export to %s%s. Error: %w", e.endpoint, path, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("otlp export to %s%s returned %d: %s", e.endpoint, path, response.StatusCode, responseToString(response))
+	}
+
+	return nil
+}
+
+func counterMetric(name string, value int, start uint64, end uint64) otlpMetric {
+	return otlpMetric{Name: name, Sum: &otlpSum{
+		AggregationTemporality: otlpTemporalityDelta,
+		IsMonotonic:            true,
+		DataPoints: []otlpNumberDataPoint{{
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			AsInt:             int64(value),
+		}},
+	}}
+}
+
+func stringAttribute(key string, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{IntValue: &value}}
+}
+
+func toUint64s(counts []int64) []uint64 {
+	values := make([]uint64, len(counts))
+	for i, c := range counts {
+		values[i] = uint64(c)
+	}
+
+	return values
+}
+
+// OTLP JSON payloads. 64 bit integers are encoded as strings per the protobuf JSON mapping.
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name      string         `json:"name"`
+	Unit      string         `json:"unit,omitempty"`
+	Sum       *otlpSum       `json:"sum,omitempty"`
+	Histogram *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpSum struct {
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpNumberDataPoint struct {
+	StartTimeUnixNano uint64 `json:"startTimeUnixNano,string"`
+	TimeUnixNano      uint64 `json:"timeUnixNano,string"`
+	AsInt             int64  `json:"asInt,string"`
+}
+
+type otlpHistogram struct {
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano uint64          `json:"startTimeUnixNano,string"`
+	TimeUnixNano      uint64          `json:"timeUnixNano,string"`
+	Count             uint64          `json:"count,string"`
+	Sum               float64         `json:"sum"`
+	BucketCounts      []uint64        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano uint64          `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   uint64          `json:"endTimeUnixNano,string"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *int64  `json:"intValue,omitempty,string"`
+}
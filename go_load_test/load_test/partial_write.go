@@ -0,0 +1,167 @@
+package load_test
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PARTIAL tests PUT a new file, then send partialWrites PATCHes to it, each appending to the file or overwriting a
+// random sub-range of it, with a Content-Range of the bytes written, I.E "bytes 10-19/*". The client keeps a model of
+// what the file should hold after each write, + the final GET must match it. The file is deleted afterwards. Only run
+// it against servers that support partial writes, others fail it.
+const PARTIAL TestType = "PARTIAL"
+
+// partialWrites is how many PATCHes each PARTIAL test sends.
+const partialWrites = 4
+
+// partialWrite is a PATCH of data at offset first of the file.
+type partialWrite struct {
+	first int
+	data  string
+}
+
+// nextPartialWrite picks an append or an overwrite of a random sub-range of model, evenly, + up to a quarter of its
+// length.
+func nextPartialWrite(r *Rand, model []byte) partialWrite {
+	maxLength := len(model) / 4
+	if maxLength < 16 {
+		maxLength = 16
+	}
+
+	first := len(model)
+	if len(model) > 0 && r.Intn(2) == 0 {
+		first = r.Intn(len(model))
+		if maxLength > len(model)-first {
+			maxLength = len(model) - first
+		}
+	}
+
+	return partialWrite{first: first, data: r.String(1 + r.Intn(maxLength))}
+}
+
+// apply returns model after the write.
+func (w partialWrite) apply(model []byte) []byte {
+	if end := w.first + len(w.data); end > len(model) {
+		model = append(model, make([]byte, end-len(model))...)
+	}
+	copy(model[w.first:], w.data)
+
+	return model
+}
+
+func (w partialWrite) contentRange() string {
+	return fmt.Sprintf("bytes %d-%d/*", w.first, w.first+len(w.data)-1)
+}
+
+func (tr *TestExecutor) PartialWriteFile(test Test) {
+	ctx, result, done := tr.beginHeldTest(test, PARTIAL)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+
+	payload := newSeededRand(test.payloadSeed)
+	fileBytes := make([]byte, tr.randomFileSize(payload))
+	err := tr.fillPayload(payload, fileBytes)
+	if err != nil {
+		result.message = "Failed to generate random file bytes"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	model := []byte(b64.StdEncoding.EncodeToString(fileBytes))
+
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(string(model)), trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for PartialWriteFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	response, err := tr.do(req, phases)
+	defer func() {
+		_, _ = tr.removeFile(fileName)
+	}()
+	result.response = response
+	result.bytesSent += int64(len(model))
+	if err != nil {
+		result.message = "Error executing http PUT request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	result.message = responseToString(response)
+	if response.StatusCode >= 400 {
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	for i := 0; i < partialWrites; i++ {
+		write := nextPartialWrite(payload, model)
+		req, err = tr.newRequest(ctx, http.MethodPatch, fileName, strings.NewReader(write.data), trace, phases)
+		if err != nil {
+			result.message = "Failed to initialize request for PartialWriteFile"
+			result.err = err
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+		req.Header.Set("Content-Range", write.contentRange())
+
+		response, err = tr.do(req, phases)
+		result.response = response
+		result.bytesSent += int64(len(write.data))
+		if err != nil {
+			result.message = "Error executing http PATCH request"
+			result.err = err
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+		result.message = responseToString(response)
+		if response.StatusCode >= 400 {
+			result.failed = true
+			result.duration = time.Now().Sub(start)
+			tr.emit(result)
+			return
+		}
+		model = write.apply(model)
+	}
+
+	response, err = tr.get(ctx, fileName, trace, phases)
+	result.response = response
+	if err != nil {
+		result.message = "Error executing http GET request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	body := responseToString(response)
+	result.message = body
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	if response.StatusCode >= 400 {
+		result.failed = true
+	} else if body != string(model) {
+		result.mismatch = bodyMismatch(string(model), body)
+		result.message = fmt.Sprintf("Contents after %d partial writes don't match what was written: %s", partialWrites,
+			result.mismatch)
+		result.failed = true
+	}
+	tr.emit(result)
+}
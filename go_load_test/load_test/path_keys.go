@@ -0,0 +1,79 @@
+package load_test
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathKeys nests new files' keys under a hierarchy of directories rather than naming them flat, I.E
+// d12/d907/d3/d44/AbcDefGhiJklMno.bin, to exercise the server's path handling + directory indexing. Each key is
+// MinDepth to Depth directories deep, picked evenly, + each directory has FanOut subdirectories, named d0 up to
+// d<FanOut-1>, so the tree has up to FanOut^Depth leaf directories + few files share one when it's wide. Specs are
+// key=value params, I.E
+//
+//	depth=10 fanout=1000 min=1
+//
+// min defaults to depth, so every key is equally deep. Directories are sent as literal / separated segments, so the
+// server must route hierarchical paths, the bundled server's /api/fileserver/:filename route doesn't.
+type PathKeys struct {
+	Depth    int
+	MinDepth int
+	FanOut   int
+}
+
+// ParsePathKeys parses a path keys spec. An empty spec returns nil, keys are flat.
+func ParsePathKeys(spec string) (*PathKeys, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid path keys param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	keys := &PathKeys{MinDepth: -1}
+	err := params.parse(map[string]interface{}{"depth": &keys.Depth, "min": &keys.MinDepth, "fanout": &keys.FanOut},
+		"depth", "fanout")
+	if err != nil {
+		return nil, fmt.Errorf("invalid path keys spec: %w", err)
+	}
+	if keys.MinDepth < 0 {
+		keys.MinDepth = keys.Depth
+	}
+	if keys.Depth < 1 || keys.FanOut < 1 || keys.MinDepth > keys.Depth {
+		return nil, fmt.Errorf("invalid path keys spec: depth + fanout must be > 0, + min between 0 + depth")
+	}
+
+	return keys, nil
+}
+
+func (k *PathKeys) String() string {
+	return fmt.Sprintf("%d-%d directories deep, %d per directory", k.MinDepth, k.Depth, k.FanOut)
+}
+
+// newKey returns a new file's key drawn from r: a random 15 letter name, nested under directories if keys is set.
+// Flat keys draw exactly what they always have, so seeded runs without PathKeys are unchanged.
+func newKey(keys *PathKeys, r *Rand) string {
+	if keys == nil {
+		return r.String(15)
+	}
+
+	depth := keys.MinDepth + r.Intn(keys.Depth-keys.MinDepth+1)
+	segments := make([]string, 0, depth+1)
+	for i := 0; i < depth; i++ {
+		segments = append(segments, fmt.Sprintf("d%d", r.Intn(keys.FanOut)))
+	}
+
+	return strings.Join(append(segments, r.String(15)+".bin"), "/")
+}
+
+// newFileName returns a key for a new file, see newKey.
+func (ts *TestScheduler) newFileName() string {
+	return newKey(ts.cfg.TestConfig.Keys, ts.rand)
+}
@@ -0,0 +1,63 @@
+package load_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsePathKeys(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *PathKeys
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"depth=10 fanout=1000", &PathKeys{Depth: 10, MinDepth: 10, FanOut: 1000}, false},
+		{"depth=3 fanout=2 min=0", &PathKeys{Depth: 3, MinDepth: 0, FanOut: 2}, false},
+		{"depth=3", nil, true}, // No fanout
+		{"depth=0 fanout=2", nil, true},
+		{"depth=3 fanout=0", nil, true},
+		{"depth=3 fanout=2 min=4", nil, true},
+		{"depth", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParsePathKeys(test.spec)
+		if (err != nil) != test.wantErr || (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("ParsePathKeys(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	// Flat keys draw exactly what they always have.
+	if got, want := newKey(nil, NewRand(1)), NewRand(1).String(15); got != want {
+		t.Errorf("flat key: got %s, want %s", got, want)
+	}
+
+	keys := &PathKeys{Depth: 3, MinDepth: 1, FanOut: 4}
+	r := NewRand(1)
+	depths := make(map[int]int)
+	for i := 0; i < 3000; i++ {
+		key := newKey(keys, r)
+		segments := strings.Split(key, "/")
+		name := segments[len(segments)-1]
+		if len(name) != len("123456789012345.bin") || !strings.HasSuffix(name, ".bin") {
+			t.Fatalf("%s: got file name %s, want 15 letters + .bin", key, name)
+		}
+		for _, dir := range segments[:len(segments)-1] {
+			if n, err := strconv.Atoi(strings.TrimPrefix(dir, "d")); err != nil || !strings.HasPrefix(dir, "d") ||
+				n < 0 || n >= keys.FanOut {
+				t.Fatalf("%s: got directory %s, want d0 to d%d", key, dir, keys.FanOut-1)
+			}
+		}
+		depths[len(segments)-1]++
+	}
+	for depth := keys.MinDepth; depth <= keys.Depth; depth++ {
+		if depths[depth] < 900 || depths[depth] > 1100 {
+			t.Errorf("got depths %v, want ~1000 keys each 1-3 deep", depths)
+			break
+		}
+	}
+}
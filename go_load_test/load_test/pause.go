@@ -0,0 +1,134 @@
+package load_test
+
+import (
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// PauseControl pauses + resumes load generation mid run, I.E to take a server side profile under zero load then carry
+// on. While paused, the scheduler stops scheduling + runners stop starting queued tests. In flight tests still finish
+// + the aggregator keeps all of its state, so results carry on where they left off. Paused time doesn't count towards
+// load profiles, warmup or the run duration. Safe for concurrent use, + a nil PauseControl is never paused.
+type PauseControl struct {
+	lock    sync.Mutex
+	paused  bool
+	resumed chan struct{} // Closed on resume, replaced on pause
+	since   time.Time
+	total   time.Duration // Time spent paused, not counting a pause in progress
+}
+
+func NewPauseControl() *PauseControl {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &PauseControl{resumed: resumed}
+}
+
+// Pause returns false if already paused.
+func (p *PauseControl) Pause() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused {
+		return false
+	}
+
+	p.paused = true
+	p.since = time.Now()
+	p.resumed = make(chan struct{})
+	log.Infof("Load generation paused.")
+	return true
+}
+
+// Resume returns false if not paused.
+func (p *PauseControl) Resume() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.paused {
+		return false
+	}
+
+	p.paused = false
+	pausedFor := time.Now().Sub(p.since)
+	p.total += pausedFor
+	close(p.resumed)
+	log.Infof("Load generation resumed after %s.", pausedFor.Truncate(time.Millisecond))
+	return true
+}
+
+// Toggle pauses if running + resumes if paused. Returns true if now paused.
+func (p *PauseControl) Toggle() bool {
+	if p.Pause() {
+		return true
+	}
+
+	p.Resume()
+	return false
+}
+
+func (p *PauseControl) Paused() bool {
+	if p == nil {
+		return false
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused
+}
+
+// PausedFor returns the total time spent paused so far, including a pause in progress.
+func (p *PauseControl) PausedFor() time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused {
+		return p.total + time.Now().Sub(p.since)
+	}
+
+	return p.total
+}
+
+// Wait blocks while paused, or until shutdown. Returns true if it had to wait.
+func (p *PauseControl) Wait(shutdown chan bool) bool {
+	if p == nil {
+		return false
+	}
+
+	p.lock.Lock()
+	resumed := p.resumed
+	p.lock.Unlock()
+	select {
+	case <-resumed:
+		return false
+	default:
+	}
+
+	select {
+	case <-resumed:
+	case <-shutdown:
+	}
+	return true
+}
+
+// RunClock measures how far into the run it is, not counting time spent paused. The scheduler + aggregator share one,
+// so they agree on when warmup ends + which load profile stage the run is in. Safe for concurrent use.
+type RunClock struct {
+	start time.Time
+	pause *PauseControl
+}
+
+func NewRunClock(pause *PauseControl) *RunClock {
+	return &RunClock{start: time.Now(), pause: pause}
+}
+
+// Elapsed returns how long the run has been going, not counting time spent paused.
+func (c *RunClock) Elapsed() time.Duration {
+	return time.Now().Sub(c.start) - c.pause.PausedFor()
+}
+
+// InWarmup returns true until warmup has passed.
+func (c *RunClock) InWarmup(warmup time.Duration) bool {
+	return warmup > 0 && c.Elapsed() < warmup
+}
@@ -0,0 +1,191 @@
+package load_test
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Preseed creates a dataset on the server before load starts, so GETs + DELETEs run against a realistic populated
+// dataset rather than only files created during the run. Seeded files are uploaded the same way CREATE tests upload
+// them, but aren't reported as results, + a manifest of what was seeded is kept client side. Specs are key=value
+// params, I.E
+//
+//	count=10000 min=1024 max=65536 workers=32
+type Preseed struct {
+	Count   int
+	MinSize int64 // Bytes, before base64 encoding
+	MaxSize int64
+	Workers int // # of files uploaded concurrently
+}
+
+type PreseedManifest struct {
+	CreatedAt time.Time    `json:"created_at"`
+	Files     []SeededFile `json:"files"`
+	Failed    int          `json:"failed"` // Files that couldn't be seeded, not listed in Files
+}
+
+type SeededFile struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+type seedRequest struct {
+	SeededFile
+	payloadSeed int64
+}
+
+// ParsePreseed parses a preseed spec. An empty spec returns nil, no preseeding.
+func ParsePreseed(spec string) (*Preseed, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid preseed param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	preseed := &Preseed{Workers: 16}
+	minSize, maxSize := 1, 1024
+	err := params.parse(map[string]interface{}{"count": &preseed.Count, "min": &minSize, "max": &maxSize,
+		"workers": &preseed.Workers}, "count")
+	if err != nil {
+		return nil, fmt.Errorf("invalid preseed spec: %w", err)
+	}
+	if preseed.Count <= 0 || preseed.Workers <= 0 || minSize <= 0 || maxSize < minSize {
+		return nil, fmt.Errorf("invalid preseed spec: count + workers must be > 0, + 0 < min <= max")
+	}
+	preseed.MinSize, preseed.MaxSize = int64(minSize), int64(maxSize)
+
+	return preseed, nil
+}
+
+// Run seeds the dataset against the run's targets + writes the manifest to manifestPath, if set. Files that fail to
+// upload are logged + left out of the manifest. Returns an error if cancelled or if no file could be seeded.
+func (p *Preseed) Run(ctx context.Context, cfg TestSchedulerConfig, manifestPath string) (PreseedManifest, error) {
+	exec := NewTestExecutor(ctx, newHTTPClient(cfg.TestConfig.Timeouts), resolveTargets(cfg.EndpointCfg, cfg.Targets), cfg.TestConfig, cfg.Stores, nil)
+	manifest := PreseedManifest{CreatedAt: time.Now(), Files: make([]SeededFile, 0, p.Count)}
+	log.Infof("Pre-seeding %d files of %d-%d bytes with %d workers", p.Count, p.MinSize, p.MaxSize, p.Workers)
+	fmt.Printf("Pre-seeding %d files...\n", p.Count)
+
+	// Complemented so a seeded run's pre-seeded keys don't repeat the keys its scheduler goes on to create.
+	var r *Rand
+	if cfg.RandSeed != 0 {
+		r = NewRand(^cfg.RandSeed)
+	}
+
+	var lock sync.Mutex
+	var seeding sync.WaitGroup
+	files := make(chan seedRequest)
+	for i := 0; i < p.Workers; i++ {
+		seeding.Add(1)
+		go func() {
+			defer seeding.Done()
+			for file := range files {
+				err := exec.seedFile(file.Key, file.Size, newSeededRand(file.payloadSeed))
+				lock.Lock()
+				if err != nil {
+					manifest.Failed++
+					log.Warnf("Failed to pre-seed file: %s. Error: %+v", file.Key, err)
+				} else {
+					manifest.Files = append(manifest.Files, file.SeededFile)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	started := time.Now()
+	// Everything random is drawn here rather than by the workers, so a seeded run seeds the same files.
+	for i := 0; i < p.Count && ctx.Err() == nil; i++ {
+		files <- seedRequest{
+			SeededFile:  SeededFile{Key: newKey(cfg.TestConfig.Keys, r), Size: p.MinSize + r.Int63n(p.MaxSize-p.MinSize+1)},
+			payloadSeed: r.payloadSeed(),
+		}
+	}
+	close(files)
+	seeding.Wait()
+	if ctx.Err() != nil {
+		return manifest, fmt.Errorf("pre-seeding cancelled after %d files. Error: %w", len(manifest.Files), ctx.Err())
+	}
+	log.Infof("Pre-seeded %d files in %s, %d failed", len(manifest.Files), time.Now().Sub(started).Truncate(time.Millisecond),
+		manifest.Failed)
+	if len(manifest.Files) == 0 {
+		return manifest, fmt.Errorf("failed to pre-seed any of %d files, see the log for errors", p.Count)
+	}
+
+	if manifestPath != "" {
+		err := manifest.Write(manifestPath)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// Keys returns the key of every seeded file.
+func (m PreseedManifest) Keys() []string {
+	keys := make([]string, len(m.Files))
+	for i, file := range m.Files {
+		keys[i] = file.Key
+	}
+
+	return keys
+}
+
+func (m PreseedManifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode preseed manifest. Error: %w", err)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write preseed manifest: %s. Error: %w", path, err)
+	}
+
+	return nil
+}
+
+// seedFile uploads size random bytes from payload to fileName, like a CREATE test but without reporting a result.
+func (tr *TestExecutor) seedFile(fileName string, size int64, payload *Rand) error {
+	fileBytes := make([]byte, size)
+	err := tr.fillPayload(payload, fileBytes)
+	if err != nil {
+		return fmt.Errorf("failed to generate random file bytes. Error: %w", err)
+	}
+
+	ctx, cancel := tr.testContext(PUT)
+	defer cancel()
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(b64.StdEncoding.EncodeToString(fileBytes)),
+		traceContext{}, NewRequestPhases())
+	if err != nil {
+		return fmt.Errorf("failed to initialize request. Error: %w", err)
+	}
+
+	response, err := tr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body := responseToString(response)
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", response.StatusCode, body)
+	}
+
+	return nil
+}
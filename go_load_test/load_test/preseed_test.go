@@ -0,0 +1,31 @@
+package load_test
+
+import (
+	"testing"
+)
+
+func TestParsePreseed(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *Preseed
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"count=100", &Preseed{Count: 100, MinSize: 1, MaxSize: 1024, Workers: 16}, false},
+		{"count=100 min=1024 max=65536 workers=32", &Preseed{Count: 100, MinSize: 1024, MaxSize: 65536, Workers: 32},
+			false},
+		{"min=1", nil, true},
+		{"count=0", nil, true},
+		{"count=100 min=10 max=5", nil, true},
+		{"count=100 min=0", nil, true},
+		{"count=100 workers=0", nil, true},
+		{"count", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParsePreseed(test.spec)
+		if (err != nil) != test.wantErr || (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("ParsePreseed(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package load_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writes a compact single line progress record per interval, I.E for CI logs where redrawing a table doesn't work.
+// Lines are logfmt style key=value pairs, so they can be grepped or parsed:
+//
+//	progress ts=2023-04-01T12:00:00Z rps=120.0 ok_rps=118.0 err_rate=0.0167 throttled=2 get_p99_ms=12.5 ...
+
+type ProgressWriter struct {
+	w         io.Writer
+	lastStage string
+}
+
+func NewProgressWriter(w io.Writer) *ProgressWriter {
+	return &ProgressWriter{w: w}
+}
+
+func (p *ProgressWriter) Write(stats IntervalStats) error {
+	if p.lastStage == StageWarmup && stats.Stage != StageWarmup {
+		_, err := fmt.Fprintf(p.w, "measurement started ts=%s\n", stats.Timestamp.UTC().Format("2006-01-02T15:04:05Z"))
+		if err != nil {
+			return fmt.Errorf("failed to write progress. Error: %w", err)
+		}
+	}
+	p.lastStage = stats.Stage
+
+	fields := []string{
+		"progress",
+		fmt.Sprintf("ts=%s", stats.Timestamp.UTC().Format("2006-01-02T15:04:05Z")),
+		fmt.Sprintf("rps=%s", formatRate(stats.PerSecond(stats.Requests))),
+		fmt.Sprintf("ok_rps=%s", formatRate(stats.PerSecond(stats.Successes))),
+		fmt.Sprintf("err_rate=%.4f", 1-stats.SuccessRate()),
+		fmt.Sprintf("throttled=%d", stats.Throttles),
+	}
+	for _, op := range latencyOperations {
+		fields = append(fields, fmt.Sprintf("%s_p99_ms=%.1f", strings.ToLower(string(op)), stats.Latency[op].P99Ms))
+	}
+	fields = append(fields,
+		fmt.Sprintf("up_mbps=%s", formatRate(stats.PerSecond(int(stats.BytesSent))/1024/1024)),
+		fmt.Sprintf("down_mbps=%s", formatRate(stats.PerSecond(int(stats.BytesReceived))/1024/1024)),
+	)
+
+	if stats.Stage != "" {
+		fields = append(fields, fmt.Sprintf("stage=%s", stats.Stage))
+	}
+
+	_, err := fmt.Fprintln(p.w, strings.Join(fields, " "))
+	if err != nil {
+		return fmt.Errorf("failed to write progress. Error: %w", err)
+	}
+
+	return nil
+}
+
+func (p *ProgressWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package load_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Pushes the same metrics served by ServeMetrics to a Prometheus Pushgateway after every interval, grouped by job +
+// run_id, so short lived (I.E CI) runs land in Prometheus without having to be scraped while they're running.
+// Prometheus remote-write isn't supported, it requires protobuf + snappy encoding; point a Pushgateway at it instead.
+
+type PushgatewayPusher struct {
+	url     string // Full grouping key url, I.E http://localhost:9091/metrics/job/loadtest/run_id/abc
+	results *TestResults
+	client  *http.Client
+}
+
+func NewPushgatewayPusher(gatewayURL string, job string, runID string, results *TestResults) *PushgatewayPusher {
+	return &PushgatewayPusher{
+		url: fmt.Sprintf("%s/metrics/job/%s/run_id/%s", strings.TrimRight(gatewayURL, "/"),
+			url.PathEscape(job), url.PathEscape(runID)),
+		results: results,
+		client:  &http.Client{Timeout: time.Second * 10},
+	}
+}
+
+// Write pushes the cumulative metrics, the interval stats themselves aren't needed as Prometheus derives rates.
+func (p *PushgatewayPusher) Write(_ IntervalStats) error {
+	return p.push()
+}
+
+// Close pushes one last time so the final totals are kept.
+func (p *PushgatewayPusher) Close() error {
+	return p.push()
+}
+
+// push replaces all metrics in this run's group.
+func (p *PushgatewayPusher) push() error {
+	req, err := http.NewRequest(http.MethodPut, p.url, bytes.NewReader([]byte(p.results.PrometheusMetrics())))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	response, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to pushgateway: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway push returned %d: %s", response.StatusCode, responseToString(response))
+	}
+
+	return nil
+}
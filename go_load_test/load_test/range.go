@@ -0,0 +1,177 @@
+package load_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RANGE tests GET a random byte range of a file the run uploaded, + check the server returned a 206 with a Content-Range
+// matching the range asked for + the bytes the upload had at that range. Only files whose payload is known are
+// tested, see PayloadStore. If the payload is forgotten between scheduling + running the test, a plain GET runs
+// instead.
+const RANGE TestType = "RANGE"
+
+// DefaultPayloadStoreBytes caps the payloads remembered for range tests.
+const DefaultPayloadStoreBytes = 64 << 20
+
+// PayloadStore remembers what the run uploaded to each file, so tests can check the bytes the server returns. Payloads
+// are remembered once an upload succeeds + forgotten once the file is deleted, or if an upload fails + the content is
+// unknown. Once MaxBytes are remembered, payloads of new files are skipped until others are deleted.
+type PayloadStore struct {
+	MaxBytes int64
+
+	lock     sync.RWMutex
+	files    *SortedFileSet
+	payloads map[string]string
+	bytes    int64
+}
+
+// NewPayloadStore returns nil unless ops include one that needs the payloads uploaded, I.E RANGE.
+func NewPayloadStore(ops ExtraOps) *PayloadStore {
+	if _, ok := ops[RANGE]; !ok {
+		return nil
+	}
+
+	return &PayloadStore{MaxBytes: DefaultPayloadStoreBytes, files: NewSortedFileSet(0), payloads: make(map[string]string)}
+}
+
+// record remembers payload as fileName's content if the upload succeeded, otherwise forgets it. A nil PayloadStore
+// remembers nothing.
+func (s *PayloadStore) record(fileName string, payload string, response *http.Response, err error) {
+	if s == nil {
+		return
+	}
+	if err != nil || response == nil || response.StatusCode >= 400 {
+		s.forget(fileName)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.bytes -= int64(len(s.payloads[fileName]))
+	if s.bytes+int64(len(payload)) > s.MaxBytes {
+		s.files.Delete(fileName)
+		delete(s.payloads, fileName)
+		return
+	}
+	s.files.Add(fileName)
+	s.payloads[fileName] = payload
+	s.bytes += int64(len(payload))
+}
+
+func (s *PayloadStore) forget(fileName string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.bytes -= int64(len(s.payloads[fileName]))
+	s.files.Delete(fileName)
+	delete(s.payloads, fileName)
+}
+
+// payload returns fileName's remembered content, or false if it isn't known.
+func (s *PayloadStore) payload(fileName string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	payload, ok := s.payloads[fileName]
+
+	return payload, ok
+}
+
+// randomFile returns a random file with a known payload, or "" if there are none.
+func (s *PayloadStore) randomFile(r *Rand) string {
+	if s == nil {
+		return ""
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.files.randomFile(r)
+}
+
+func (tr *TestExecutor) RangeFile(test Test) {
+	// Wait out uploads + deletes of the file, so the payload checked against is the one the server has.
+	ctx, result, done := tr.beginHeldTest(test, RANGE)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+
+	payload, ok := tr.payloads.payload(fileName)
+	if !ok || len(payload) == 0 {
+		// Deleted or overwritten by a failed upload since the test was scheduled, there's nothing to check a range of.
+		tr.GetFile(test)
+		return
+	}
+	r := newSeededRand(test.payloadSeed)
+	first := r.Int63n(int64(len(payload)))
+	last := first + r.Int63n(int64(len(payload))-first)
+
+	req, err := tr.newRequest(ctx, http.MethodGet, fileName, nil, trace, phases)
+	if err != nil {
+		result.message = "Failed to build range request."
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first, last))
+
+	response, err := tr.do(req, phases)
+	if err != nil {
+		result.response = response
+		result.message = "Error executing http range GET request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	body := responseToString(response)
+	result.response = response
+	result.bytesReceived = int64(len(body))
+	result.duration = time.Now().Sub(start)
+	if response.StatusCode >= 400 {
+		result.message = body
+		result.failed = true
+		tr.emit(result)
+		return
+	}
+	result.mismatch = rangeMismatch(response, body, payload, first, last)
+	if result.mismatch != "" {
+		result.message = fmt.Sprintf("Range bytes=%d-%d of %d: %s", first, last, len(payload), result.mismatch)
+		result.failed = true
+	}
+	tr.emit(result)
+}
+
+// rangeMismatch describes how a response to a GET of bytes first-last of payload differs from what it should be, or
+// returns "" if it doesn't.
+func rangeMismatch(response *http.Response, body string, payload string, first int64, last int64) string {
+	if response.StatusCode != http.StatusPartialContent {
+		return fmt.Sprintf("status %d, expected %d", response.StatusCode, http.StatusPartialContent)
+	}
+
+	want := fmt.Sprintf("bytes %d-%d/%d", first, last, len(payload))
+	if contentRange := response.Header.Get("Content-Range"); contentRange != want {
+		return fmt.Sprintf("Content-Range %q, expected %q", contentRange, want)
+	}
+	if int64(len(body)) != last-first+1 {
+		return fmt.Sprintf("got %d bytes, expected %d", len(body), last-first+1)
+	}
+	if body != payload[first:last+1] {
+		return "bytes don't match the uploaded payload"
+	}
+
+	return ""
+}
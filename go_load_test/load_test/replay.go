@@ -0,0 +1,161 @@
+package load_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sort"
+	"time"
+)
+
+// Replay re-issues the operations recorded in a result log (see ResultLogWriter), in the order + at the offsets they
+// originally started at, so different server versions can be compared under exactly the same workload. Offsets are
+// divided by Speed, I.E 2 replays twice as fast. A Speed of 0 issues every operation as fast as the runner takes them.
+// Payloads aren't recorded, so uploads are regenerated at the configured file sizes. Scenario steps are replayed as
+// standalone operations. Retries aren't replayed, a replayed operation is retried only if it fails again.
+type Replay struct {
+	Path  string
+	Speed float64
+	ops   []replayOp
+}
+
+type replayOp struct {
+	offset   time.Duration // Since the first recorded operation started
+	testType TestType
+	fileName string
+}
+
+// LoadReplay reads the result log at path. Records are ordered by when their test started, the log itself is in the
+// order tests finished.
+func LoadReplay(path string, speed float64) (*Replay, error) {
+	if speed < 0 {
+		return nil, fmt.Errorf("invalid replay speed: %g. Must be >= 0", speed)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay log: %s. Error: %w", path, err)
+	}
+	defer file.Close()
+
+	type started struct {
+		at time.Time
+		replayOp
+	}
+	var records []started
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record ResultRecord
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse replay log: %s, line %d. Error: %w", path, line, err)
+		}
+		if !isTestType(record.TestType) || record.TestType == SCENARIO || record.FileName == "" {
+			return nil, fmt.Errorf("invalid replay log: %s, line %d. Unknown operation %q on key %q", path, line,
+				record.TestType, record.FileName)
+		}
+
+		if record.Attempt > 0 {
+			continue
+		}
+
+		at := record.Started
+		if at.IsZero() {
+			// Logs written before the start was recorded. The timestamp is when the result was merged, so this is late
+			// by however long merging took.
+			at = record.Timestamp.Add(-time.Duration(record.LatencyMs * float64(time.Millisecond)))
+		}
+		records = append(records, started{
+			at:       at,
+			replayOp: replayOp{testType: record.TestType, fileName: record.FileName},
+		})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read replay log: %s. Error: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("replay log is empty: %s", path)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].at.Before(records[j].at)
+	})
+	replay := &Replay{Path: path, Speed: speed, ops: make([]replayOp, len(records))}
+	for i, record := range records {
+		replay.ops[i] = record.replayOp
+		replay.ops[i].offset = record.at.Sub(records[0].at)
+	}
+
+	return replay, nil
+}
+
+// Len returns the # of operations to replay.
+func (r *Replay) Len() int {
+	return len(r.ops)
+}
+
+// Duration returns how long replaying takes at Speed, assuming the runner keeps up. 0 if replaying as fast as possible.
+func (r *Replay) Duration() time.Duration {
+	if r.Speed == 0 {
+		return 0
+	}
+
+	return r.scaled(r.ops[len(r.ops)-1].offset)
+}
+
+func (r *Replay) scaled(offset time.Duration) time.Duration {
+	return time.Duration(float64(offset) / r.Speed)
+}
+
+func (r *Replay) String() string {
+	if r.Speed == 0 {
+		return fmt.Sprintf("%d operations from %s, as fast as possible", r.Len(), r.Path)
+	}
+
+	return fmt.Sprintf("%d operations from %s at %gx speed, over %s", r.Len(), r.Path, r.Speed,
+		r.Duration().Truncate(time.Millisecond))
+}
+
+// replay schedules the replay's operations in order until they've all been scheduled, the request limit is reached or
+// the run shuts down. Time spent paused shifts the rest of the replay back rather than being caught up on.
+func (ts *TestScheduler) replay() {
+	replay := ts.cfg.Replay
+	start := time.Now()
+	for _, op := range replay.ops {
+		if ts.limitReached() {
+			return
+		}
+
+		test := Test{TestType: op.testType, fileName: op.fileName}
+		if replay.Speed > 0 {
+			test.scheduledAt = start.Add(replay.scaled(op.offset) + ts.cfg.Pause.PausedFor())
+			timer := time.NewTimer(time.Until(test.scheduledAt))
+			select {
+			case <-timer.C:
+			case <-ts.cfg.ShutdownChan:
+				timer.Stop()
+				return
+			}
+		}
+		ts.cfg.Pause.Wait(ts.cfg.ShutdownChan)
+		if !ts.admit(test) {
+			continue
+		}
+
+		select {
+		case ts.cfg.SchedulerChan <- test:
+			ts.totalScheduled++
+		case <-ts.cfg.ShutdownChan:
+			return
+		}
+	}
+
+	log.Infof("Scheduled all %d replayed operations, waiting for them to finish.", replay.Len())
+}
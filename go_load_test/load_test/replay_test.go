@@ -0,0 +1,42 @@
+package load_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadReplay(t *testing.T) {
+	start := time.Now()
+	records := []ResultRecord{
+		// Merged long after it finished, but started after b
+		{Timestamp: start.Add(5 * time.Second), Started: start.Add(2 * time.Second), TestType: GET, FileName: "a", LatencyMs: 10},
+		{Timestamp: start.Add(time.Second + 10*time.Millisecond), Started: start.Add(time.Second), TestType: PUT, FileName: "b", LatencyMs: 10},
+		{Timestamp: start.Add(6 * time.Second), Started: start.Add(3 * time.Second), TestType: GET, FileName: "a", LatencyMs: 10, Attempt: 1},
+	}
+	path := filepath.Join(t.TempDir(), "results.ndjson")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err = encoder.Encode(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	replay, err := LoadReplay(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Len() != 2 {
+		t.Fatalf("got %d operations, want 2 without the retry", replay.Len())
+	}
+	if replay.ops[0].fileName != "b" || replay.ops[1].fileName != "a" || replay.ops[1].offset != time.Second {
+		t.Errorf("got %+v, want b then a 1s later", replay.ops)
+	}
+}
@@ -0,0 +1,144 @@
+package load_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RequestLimit stops scheduling after a fixed # of requests, so short smoke + CI runs are reproducible. Unlike a
+// shutdown, every scheduled test still runs, so the final results cover exactly the requested counts. A consistency
+// check counts as the 4 requests it makes + a scenario as one per step, unless they fail part way through.
+type RequestLimit struct {
+	Total int              // If > 0, stop after this many requests of any type.
+	PerOp map[TestType]int // If set, only listed operations run, each up to its count of tests. See ParseRequestLimit.
+}
+
+// ParseRequestLimit parses either a total, I.E 1000, or per operation counts, I.E GET=500,PUT=100,CREATE=50.
+// Operations left out of a per operation limit don't run, except CREATE, which is uncapped unless listed as files still
+// need to be created to GET, PUT + DELETE.
+func ParseRequestLimit(value string) (RequestLimit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return RequestLimit{}, nil
+	}
+
+	if !strings.Contains(value, "=") {
+		total, err := strconv.Atoi(value)
+		if err != nil || total < 0 {
+			return RequestLimit{}, fmt.Errorf("invalid request limit: %s. Expected a count, I.E 1000", value)
+		}
+		return RequestLimit{Total: total}, nil
+	}
+
+	perOp, err := parseOpCounts(value)
+	if err != nil {
+		return RequestLimit{}, fmt.Errorf("invalid request limit: %w", err)
+	}
+
+	return RequestLimit{PerOp: perOp}, nil
+}
+
+// parseOpCounts parses comma separated OP=count pairs, I.E GET=500,PUT=100.
+func parseOpCounts(value string) (map[TestType]int, error) {
+	counts := make(map[TestType]int)
+	for _, entry := range strings.Split(value, ",") {
+		op, count, found := strings.Cut(strings.TrimSpace(entry), "=")
+		testType := TestType(strings.ToUpper(strings.TrimSpace(op)))
+		if !found || !isTestType(testType) {
+			return nil, fmt.Errorf("%s. Expected OP=count, I.E GET=500", entry)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid count for %s: %s", testType, count)
+		}
+		counts[testType] = n
+	}
+
+	return counts, nil
+}
+
+func isTestType(testType TestType) bool {
+	switch testType {
+	case GET, PUT, DELETE, CREATE, CONSISTENCY, SCENARIO, HEAD, RANGE, CONDITIONAL, STREAM, CONFLICT, PARTIAL, LIST, MOVE, COPY, FUZZ, BOUNDARY, DOWNLOAD:
+		return true
+	}
+
+	return false
+}
+
+// requests returns the # of requests test makes.
+func (t Test) requests() int64 {
+	switch t.TestType {
+	case CONSISTENCY, CONFLICT, COPY:
+		return 4
+	case HEAD:
+		return 2
+	case FUZZ, BOUNDARY:
+		// The PUT, + the GET + cleanup DELETE if the file is stored
+		return 3
+	case DOWNLOAD:
+		// The PUT, the GET + the cleanup DELETE
+		return 3
+	case PARTIAL:
+		// The PUT, the PATCHes, the GET + the cleanup DELETE
+		return partialWrites + 3
+	case MOVE:
+		// The PUT, the MOVE, the GETs of both keys + the cleanup DELETE
+		return 5
+	case SCENARIO:
+		return int64(len(t.scenario.Steps))
+	}
+
+	return 1
+}
+
+// admit counts test against the request limit. Returns false if it would go over the limit, in which case the test is
+// given back rather than scheduled.
+func (ts *TestScheduler) admit(test Test) bool {
+	limit := ts.cfg.RequestLimit
+	overTotal := limit.Total > 0 && ts.reqsScheduled+test.requests() > int64(limit.Total)
+	max, listed := limit.PerOp[test.TestType]
+	overOp := len(limit.PerOp) > 0 && (listed || test.TestType != CREATE) && ts.opsScheduled[test.TestType] >= max
+	if overTotal || overOp {
+		ts.unschedule(test)
+		return false
+	}
+
+	ts.opsScheduled[test.TestType]++
+	ts.reqsScheduled += test.requests()
+	return true
+}
+
+// unschedule undoes GetTestFunc's bookkeeping for a test that won't run.
+func (ts *TestScheduler) unschedule(test Test) {
+	switch test.TestType {
+	case DELETE:
+		ts.trackedFileLock.Lock()
+		ts.trackedFiles.Add(test.fileName)
+		ts.trackedFileLock.Unlock()
+	case CONSISTENCY, SCENARIO:
+		ts.numScheduled -= int(test.requests()) - 1
+	}
+}
+
+// limitReached returns true once every request allowed by the request limit has been scheduled.
+func (ts *TestScheduler) limitReached() bool {
+	limit := ts.cfg.RequestLimit
+	if limit.Total > 0 && ts.reqsScheduled >= int64(limit.Total) {
+		return true
+	}
+
+	if len(limit.PerOp) == 0 {
+		return false
+	}
+
+	for op, count := range limit.PerOp {
+		if ts.opsScheduled[op] < count {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,68 @@
+package load_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRequestLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RequestLimit
+		wantErr bool
+	}{
+		{"", RequestLimit{}, false},
+		{"1000", RequestLimit{Total: 1000}, false},
+		{" 0 ", RequestLimit{}, false},
+		{"GET=500,put=100, CREATE = 50", RequestLimit{PerOp: map[TestType]int{GET: 500, PUT: 100, CREATE: 50}}, false},
+		{"-5", RequestLimit{}, true},
+		{"lots", RequestLimit{}, true},
+		{"GET=500,FETCH=10", RequestLimit{}, true},
+		{"GET=-1", RequestLimit{}, true},
+		{"GET", RequestLimit{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseRequestLimit(test.value)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseRequestLimit(%q): got %+v, %v, want %+v, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestTestRequests(t *testing.T) {
+	tests := []struct {
+		test Test
+		want int64
+	}{
+		{Test{TestType: GET}, 1},
+		{Test{TestType: CONSISTENCY}, 4},
+		{Test{TestType: HEAD}, 2},
+		{Test{TestType: FUZZ}, 3},
+		{Test{TestType: PARTIAL}, partialWrites + 3},
+		{Test{TestType: MOVE}, 5},
+	}
+	for _, test := range tests {
+		if got := test.test.requests(); got != test.want {
+			t.Errorf("%s: got %d requests, want %d", test.test.TestType, got, test.want)
+		}
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	ts := NewTestScheduler(TestSchedulerConfig{RequestLimit: RequestLimit{Total: 6}})
+	admitted := []bool{ts.admit(Test{TestType: CONSISTENCY}), ts.admit(Test{TestType: CONSISTENCY}),
+		ts.admit(Test{TestType: GET}), ts.admit(Test{TestType: GET}), ts.admit(Test{TestType: GET})}
+	// The second check would go over, the GETs after it fill the limit exactly
+	if want := []bool{true, false, true, true, false}; !reflect.DeepEqual(admitted, want) || !ts.limitReached() {
+		t.Errorf("got %v, limit reached %t, want %v + reached", admitted, ts.limitReached(), want)
+	}
+
+	ts = NewTestScheduler(TestSchedulerConfig{RequestLimit: RequestLimit{PerOp: map[TestType]int{GET: 1}}})
+	admitted = []bool{ts.admit(Test{TestType: CREATE}), ts.admit(Test{TestType: PUT}), ts.admit(Test{TestType: GET}),
+		ts.admit(Test{TestType: GET})}
+	// CREATE is uncapped unless listed, other unlisted operations don't run
+	if want := []bool{true, false, true, false}; !reflect.DeepEqual(admitted, want) || !ts.limitReached() {
+		t.Errorf("got %v, limit reached %t, want %v + reached", admitted, ts.limitReached(), want)
+	}
+}
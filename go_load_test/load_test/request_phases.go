@@ -0,0 +1,185 @@
+package load_test
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Captures where time is spent within each request (connection setup vs. server processing vs. transfer) using
+// httptrace. Tests issuing several requests (I.E consistency checks) accumulate phases across all of them.
+
+type RequestPhase string
+
+const (
+	PhaseDNS      RequestPhase = "DNS"
+	PhaseConnect  RequestPhase = "CONNECT"
+	PhaseTLS      RequestPhase = "TLS"
+	PhaseSend     RequestPhase = "SEND"      // Connection acquired -> request fully written
+	PhaseTTFB     RequestPhase = "TTFB"      // Request written -> first response byte, I.E server processing time
+	PhaseBodyRead RequestPhase = "BODY_READ" // First response byte -> body fully read
+)
+
+var requestPhases = []RequestPhase{PhaseDNS, PhaseConnect, PhaseTLS, PhaseSend, PhaseTTFB, PhaseBodyRead}
+
+// RequestPhases is safe for concurrent use, httptrace hooks may fire from transport goroutines.
+type RequestPhases struct {
+	lock        sync.Mutex
+	durations   map[RequestPhase]time.Duration
+	marks       map[string]time.Time
+	reusedConns int // # of requests sent over a reused keep-alive connection
+	newConns    int // # of requests that had to open a new connection
+	// Sequence # of the most recent request, see RequestTags.
+	lastSeq int64
+}
+
+func NewRequestPhases() *RequestPhases {
+	return &RequestPhases{
+		durations: make(map[RequestPhase]time.Duration),
+		marks:     make(map[string]time.Time),
+	}
+}
+
+// Durations returns a copy of the accumulated time per phase. Phases that never happened are omitted.
+func (p *RequestPhases) Durations() map[RequestPhase]time.Duration {
+	if p == nil {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	durations := make(map[RequestPhase]time.Duration, len(p.durations))
+	for phase, d := range p.durations {
+		durations[phase] = d
+	}
+
+	return durations
+}
+
+// LastSeq returns the sequence # of the most recent request, or 0 if requests weren't tagged.
+func (p *RequestPhases) LastSeq() int64 {
+	if p == nil {
+		return 0
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.lastSeq
+}
+
+func (p *RequestPhases) sent(seq int64) {
+	if p == nil || seq == 0 {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lastSeq = seq
+}
+
+// Connections returns the # of requests sent over reused vs. newly opened connections.
+func (p *RequestPhases) Connections() (int, int) {
+	if p == nil {
+		return 0, 0
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.reusedConns, p.newConns
+}
+
+func (p *RequestPhases) gotConn(info httptrace.GotConnInfo) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if info.Reused {
+		p.reusedConns++
+	} else {
+		p.newConns++
+	}
+	p.marks["send"] = time.Now()
+}
+
+func (p *RequestPhases) mark(name string) {
+	p.lock.Lock()
+	p.marks[name] = time.Now()
+	p.lock.Unlock()
+}
+
+// finish records the time since the named mark against phase.
+func (p *RequestPhases) finish(name string, phase RequestPhase) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	started, ok := p.marks[name]
+	if !ok {
+		return
+	}
+	delete(p.marks, name)
+	p.durations[phase] += time.Now().Sub(started)
+}
+
+// ClientTrace returns hooks that record into p.
+func (p *RequestPhases) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { p.mark("dns") },
+		DNSDone:  func(httptrace.DNSDoneInfo) { p.finish("dns", PhaseDNS) },
+		ConnectStart: func(string, string) {
+			p.mark("connect")
+		},
+		ConnectDone: func(string, string, error) {
+			p.finish("connect", PhaseConnect)
+		},
+		TLSHandshakeStart: func() { p.mark("tls") },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.finish("tls", PhaseTLS)
+		},
+		GotConn: p.gotConn,
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			p.finish("send", PhaseSend)
+			p.mark("ttfb")
+		},
+		GotFirstResponseByte: func() {
+			p.finish("ttfb", PhaseTTFB)
+			p.mark("body")
+		},
+	}
+}
+
+// WrapResponse records PhaseBodyRead once the response body has been fully read or closed.
+func (p *RequestPhases) WrapResponse(response *http.Response) {
+	if response == nil || response.Body == nil {
+		return
+	}
+
+	response.Body = &phaseTrackingBody{ReadCloser: response.Body, phases: p}
+}
+
+type phaseTrackingBody struct {
+	io.ReadCloser
+	phases *RequestPhases
+	once   sync.Once
+}
+
+func (b *phaseTrackingBody) Read(buf []byte) (int, error) {
+	n, err := b.ReadCloser.Read(buf)
+	if err == io.EOF {
+		b.done()
+	}
+
+	return n, err
+}
+
+func (b *phaseTrackingBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *phaseTrackingBody) done() {
+	b.once.Do(func() {
+		b.phases.finish("body", PhaseBodyRead)
+	})
+}
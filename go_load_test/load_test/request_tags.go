@@ -0,0 +1,43 @@
+package load_test
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	RunIDHeader      = "X-Run-Id"
+	RequestSeqHeader = "X-Request-Seq"
+	// Agent N's sequence #s start after (N+1)*AgentRequestSeqStride, so they don't collide with each other or the
+	// coordinator's.
+	AgentRequestSeqStride = 1 << 40
+)
+
+// RequestTags tags every outgoing request with the run's ID + a sequence # unique within the run, so server side logs
+// can be joined against the result log when debugging a specific failure. One RequestTags is shared by every executor
+// in a process so sequence #s don't repeat. A nil RequestTags doesn't tag requests.
+type RequestTags struct {
+	RunID string
+	seq   atomic.Int64
+}
+
+// NewRequestTags returns tags for runID, with sequence #s counting up from seqBase+1.
+func NewRequestTags(runID string, seqBase int64) *RequestTags {
+	tags := &RequestTags{RunID: runID}
+	tags.seq.Store(seqBase)
+
+	return tags
+}
+
+// tag sets the run ID + the next sequence # on req, returning the sequence #, or 0 if untagged.
+func (t *RequestTags) tag(req *http.Request) int64 {
+	if t == nil {
+		return 0
+	}
+
+	seq := t.seq.Add(1)
+	req.Header.Set(RunIDHeader, t.RunID)
+	req.Header.Set(RequestSeqHeader, strconv.FormatInt(seq, 10))
+	return seq
+}
@@ -0,0 +1,24 @@
+package load_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestRequestTags(t *testing.T) {
+	tags := NewRequestTags("run-1", 2*AgentRequestSeqStride)
+	for want := int64(1); want <= 2; want++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost/f", nil)
+		seq := tags.tag(req)
+		if seq != 2*AgentRequestSeqStride+want || req.Header.Get(RunIDHeader) != "run-1" ||
+			req.Header.Get(RequestSeqHeader) != strconv.FormatInt(seq, 10) {
+			t.Errorf("got seq %d + headers %v, want seq %d", seq, req.Header, 2*AgentRequestSeqStride+want)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/f", nil)
+	if seq := (*RequestTags)(nil).tag(req); seq != 0 || len(req.Header) != 0 {
+		t.Errorf("nil tags: got seq %d + headers %v", seq, req.Header)
+	}
+}
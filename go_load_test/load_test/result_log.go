@@ -0,0 +1,144 @@
+package load_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Streams every test result to a newline delimited JSON file so runs can be re-analyzed offline.
+
+const resultLogFlushInterval = time.Second
+
+type ResultRecord struct {
+	Timestamp  time.Time `json:"timestamp"` // When the result was merged
+	Started    time.Time `json:"started"`   // When the test started
+	TestType   TestType  `json:"type"`
+	FileName   string    `json:"key"`
+	StatusCode int       `json:"status"`
+	LatencyMs  float64   `json:"latency_ms"`
+	Failed     bool      `json:"failed"`
+	Error      string    `json:"error,omitempty"`
+	Fault      string    `json:"fault,omitempty"` // Set if the client broke the request on purpose, see Chaos
+	Seq        int64     `json:"seq,omitempty"`   // Sequence # the last request was tagged with, see RequestTags
+	Timeout    bool      `json:"timeout,omitempty"`
+	Attempt    int       `json:"attempt,omitempty"` // # of times the test had been retried, see RetryQueue
+	Scenario   string    `json:"scenario,omitempty"`
+	Step       string    `json:"step,omitempty"`
+}
+
+func NewResultRecord(result TestResult, completedAt time.Time) ResultRecord {
+	record := ResultRecord{
+		Timestamp:  completedAt,
+		Started:    result.started,
+		TestType:   result.TestType(),
+		FileName:   result.FileName(),
+		StatusCode: result.StatusCode(),
+		LatencyMs:  durationMs(result.Duration()),
+		Failed:     result.WasTestFailure(),
+		Error:      result.ErrorMessage(),
+		Fault:      string(result.InjectedFault()),
+		Seq:        result.RequestSeq(),
+		Timeout:    result.WasTimeout(),
+		Attempt:    result.attempt,
+	}
+	if result.scenario != nil {
+		record.Scenario = result.scenario.Scenario
+		record.Step = result.scenario.Name
+	}
+
+	return record
+}
+
+// ResultLogWriter is not safe for concurrent use, it is written to from the aggregator's merge loop only.
+type ResultLogWriter struct {
+	file      *os.File
+	buffer    *bufio.Writer
+	encoder   *json.Encoder
+	lastFlush time.Time
+	// If rotateEvery > 0, results are written to a new timestamped file next to path every rotateEvery, see soak mode.
+	path        string
+	rotateEvery time.Duration
+	opened      time.Time
+}
+
+func NewResultLogWriter(path string) (*ResultLogWriter, error) {
+	w := &ResultLogWriter{path: path}
+	err := w.open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// NewRotatingResultLogWriter writes to a new timestamped file every rotateEvery, I.E results.ndjson is written as
+// results-20230401T120000Z.ndjson, results-20230401T121500Z.ndjson...
+func NewRotatingResultLogWriter(path string, rotateEvery time.Duration) (*ResultLogWriter, error) {
+	w := &ResultLogWriter{path: path, rotateEvery: rotateEvery}
+	err := w.open(timestampedPath(path, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *ResultLogWriter) open(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create result log: %s. Error: %w", path, err)
+	}
+
+	w.file = file
+	w.buffer = bufio.NewWriter(file)
+	w.encoder = json.NewEncoder(w.buffer)
+	w.lastFlush = time.Now()
+	w.opened = w.lastFlush
+	return nil
+}
+
+// rotate closes the current file + starts a new one stamped with now.
+func (w *ResultLogWriter) rotate(now time.Time) error {
+	err := w.Close()
+	if err != nil {
+		return err
+	}
+
+	return w.open(timestampedPath(w.path, now))
+}
+
+// Write appends a single result. Output is flushed at most once per resultLogFlushInterval.
+func (w *ResultLogWriter) Write(result TestResult) error {
+	now := time.Now()
+	if w.rotateEvery > 0 && now.Sub(w.opened) >= w.rotateEvery {
+		err := w.rotate(now)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := w.encoder.Encode(NewResultRecord(result, now))
+	if err != nil {
+		return err
+	}
+
+	if now.Sub(w.lastFlush) > resultLogFlushInterval {
+		w.lastFlush = now
+		return w.buffer.Flush()
+	}
+
+	return nil
+}
+
+func (w *ResultLogWriter) Close() error {
+	err := w.buffer.Flush()
+	if err != nil {
+		_ = w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
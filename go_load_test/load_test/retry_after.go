@@ -0,0 +1,140 @@
+package load_test
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Tracks the Retry-After backoff advertised on throttled (429 / 503) responses, and how often the client ignored it.
+// The server throttles globally, so any request sent after a throttled response arrived but before its Retry-After
+// elapsed counts as a violation. Requests already in flight when the throttle arrived couldn't have honoured it, so
+// they don't. Results are merged in completion order, so a request racing a throttled response may not be caught.
+
+// throttleWindowRetention is how long a Retry-After window is kept after it ends, for results of requests sent during
+// it that are merged late.
+const throttleWindowRetention = time.Minute
+
+// throttleWindow is when the server asked the client to hold off: from when a throttled response arrived until its
+// Retry-After elapsed.
+type throttleWindow struct {
+	received time.Time
+	until    time.Time
+}
+
+// RetryAfterStats is not safe for concurrent use; callers are expected to hold their own lock.
+type RetryAfterStats struct {
+	advertised *LatencyHistogram
+	throttled  int // 429 + 503 responses
+	missing    int // Throttled responses without a valid Retry-After
+	violations int // Requests sent after a throttle arrived but before its Retry-After elapsed
+	// Retry-After windows, sorted by when their throttle arrived. Overlapping windows are merged.
+	windows []throttleWindow
+}
+
+type RetryAfterSummary struct {
+	Throttled  int            `json:"throttled"`
+	Missing    int            `json:"missing"`
+	Violations int            `json:"violations"`
+	Advertised LatencySummary `json:"advertised"`
+}
+
+func NewRetryAfterStats() *RetryAfterStats {
+	return &RetryAfterStats{advertised: NewLatencyHistogram()}
+}
+
+// Record checks result against any outstanding Retry-After, then records the backoff it advertised, if any.
+func (r *RetryAfterStats) Record(result TestResult) {
+	if r.violated(result.started) {
+		r.violations++
+	}
+
+	code := result.StatusCode()
+	if code != http.StatusTooManyRequests && code != http.StatusServiceUnavailable {
+		return
+	}
+	r.throttled++
+
+	receivedAt := result.started.Add(result.duration)
+	backoff, ok := parseRetryAfter(result.response.Header.Get("Retry-After"), receivedAt)
+	if !ok {
+		r.missing++
+		return
+	}
+	r.advertised.Record(backoff)
+	if backoff > 0 {
+		r.addWindow(throttleWindow{received: receivedAt, until: receivedAt.Add(backoff)})
+	}
+}
+
+// violated returns true if a request sent at started was sent during a Retry-After window.
+func (r *RetryAfterStats) violated(started time.Time) bool {
+	if started.IsZero() {
+		return false
+	}
+
+	for _, window := range r.windows {
+		if started.After(window.received) && started.Before(window.until) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addWindow adds window, merging it with any it overlaps + dropping windows that ended throttleWindowRetention before
+// the latest throttle arrived.
+func (r *RetryAfterStats) addWindow(window throttleWindow) {
+	windows := append(r.windows, window)
+	sort.Slice(windows, func(i, j int) bool { return windows[i].received.Before(windows[j].received) })
+
+	latest := windows[len(windows)-1].received
+	merged := windows[:0]
+	for _, w := range windows {
+		if w.until.Before(latest.Add(-throttleWindowRetention)) {
+			continue
+		}
+		if last := len(merged) - 1; last >= 0 && !w.received.After(merged[last].until) {
+			if w.until.After(merged[last].until) {
+				merged[last].until = w.until
+			}
+			continue
+		}
+		merged = append(merged, w)
+	}
+	r.windows = merged
+}
+
+func (r *RetryAfterStats) Summary() RetryAfterSummary {
+	return RetryAfterSummary{
+		Throttled:  r.throttled,
+		Missing:    r.missing,
+		Violations: r.violations,
+		Advertised: summarizeLatency(r.advertised),
+	}
+}
+
+// parseRetryAfter parses either form of Retry-After, delay-seconds or an HTTP date, relative to receivedAt.
+func parseRetryAfter(value string, receivedAt time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	retryAt, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if retryAt.Before(receivedAt) {
+		return 0, true
+	}
+
+	return retryAt.Sub(receivedAt), true
+}
@@ -0,0 +1,75 @@
+package load_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterViolations(t *testing.T) {
+	start := time.Unix(1000, 0)
+	at := func(offset time.Duration) time.Time { return start.Add(offset) }
+	result := func(started time.Time, duration time.Duration, code int, retryAfter string) TestResult {
+		response := &http.Response{StatusCode: code, Header: http.Header{}}
+		if retryAfter != "" {
+			response.Header.Set("Retry-After", retryAfter)
+		}
+		return TestResult{started: started, duration: duration, response: response}
+	}
+
+	stats := NewRetryAfterStats()
+	// A throttle arriving 1s in, asking the client to wait until 3s in.
+	stats.Record(result(at(0), time.Second, http.StatusTooManyRequests, "2"))
+	// Sent before the throttle arrived, so it couldn't have honoured it.
+	stats.Record(result(at(500*time.Millisecond), time.Second, http.StatusOK, ""))
+	if got := stats.Summary().Violations; got != 0 {
+		t.Errorf("in flight request counted as a violation, got %d", got)
+	}
+	// Sent during the window.
+	stats.Record(result(at(2*time.Second), time.Millisecond, http.StatusOK, ""))
+	// Sent once it elapsed.
+	stats.Record(result(at(3*time.Second), time.Millisecond, http.StatusOK, ""))
+	if got := stats.Summary().Violations; got != 1 {
+		t.Errorf("got %d violations, want 1", got)
+	}
+
+	// A later window, not overlapping the first, merged before a late result from the gap between them.
+	stats.Record(result(at(10*time.Second), time.Second, http.StatusServiceUnavailable, "5"))
+	stats.Record(result(at(5*time.Second), 10*time.Second, http.StatusOK, ""))
+	stats.Record(result(at(12*time.Second), time.Millisecond, http.StatusOK, ""))
+	if got := stats.Summary().Violations; got != 2 {
+		t.Errorf("got %d violations, want 2", got)
+	}
+	if len(stats.windows) != 2 {
+		t.Errorf("got windows %+v, want 2", stats.windows)
+	}
+
+	// Windows that ended long before the latest throttle are dropped.
+	stats.Record(result(at(10*time.Minute), time.Second, http.StatusTooManyRequests, "1"))
+	if len(stats.windows) != 1 {
+		t.Errorf("got windows %+v, want only the latest", stats.windows)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	received := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{received.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{received.Add(-time.Minute).Format(http.TimeFormat), 0, true}, // Already passed
+	}
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value, received)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("parseRetryAfter(%q): got %s, %t, want %s, %t", test.value, got, ok, test.want, test.wantOk)
+		}
+	}
+}
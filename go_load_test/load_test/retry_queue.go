@@ -0,0 +1,236 @@
+package load_test
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryQueue retries failed idempotent operations (GETs + DELETEs) the way a real client would, waiting Backoff before
+// the first retry + doubling the wait for each retry after, up to MaxBackoff. Retries due to run go ahead of tests still
+// queued by the scheduler, soonest due first, rather than waiting behind them. At most Size retries wait at once +
+// retries that would overflow the queue are dropped. Every attempt is still counted as a request of its own, so retried
+// failures are counted as failures, + retry attempts + the tests that only succeeded after a retry are reported
+// separately. 404s, self throttled 429s + injected faults aren't retried. Specs are key=value params, I.E
+//
+//	attempts=3 backoff=100ms max=5s size=1000
+//
+// Agents retry their own failures + send the coordinator their queued, exhausted + dropped counts once they finish.
+type RetryQueue struct {
+	Attempts   int // Max retries per test
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Size       int
+
+	lock      sync.Mutex
+	pending   retryHeap
+	wake      chan struct{}
+	running   *sync.WaitGroup // Each queued retry is running until it finishes or is dropped
+	closed    bool
+	queued    Counter
+	exhausted Counter // Tests that still failed after their last retry
+	dropped   Counter // Retries dropped because the queue was full or the run shut down
+}
+
+type RetrySummary struct {
+	Attempts  int `json:"attempts"`  // Retries run
+	Succeeded int `json:"succeeded"` // Tests that succeeded after a retry
+	Queued    int `json:"queued"`
+	Exhausted int `json:"exhausted"`
+	Dropped   int `json:"dropped"`
+}
+
+// ParseRetryQueue parses a retry spec. An empty spec returns nil, failed tests aren't retried.
+func ParseRetryQueue(spec string) (*RetryQueue, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid retry param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	queue := &RetryQueue{Attempts: 3, Backoff: time.Millisecond * 100, MaxBackoff: time.Second * 5, Size: 1000}
+	err := params.parse(map[string]interface{}{"attempts": &queue.Attempts, "backoff": &queue.Backoff,
+		"max": &queue.MaxBackoff, "size": &queue.Size})
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry spec: %w", err)
+	}
+	if queue.Attempts <= 0 || queue.Backoff <= 0 || queue.MaxBackoff < queue.Backoff || queue.Size <= 0 {
+		return nil, fmt.Errorf("invalid retry spec: attempts, backoff + size must be > 0, + max must be >= backoff")
+	}
+
+	return queue, nil
+}
+
+func (q *RetryQueue) String() string {
+	return fmt.Sprintf("up to %d retries, backing off %s to %s, queue of %d", q.Attempts, q.Backoff, q.MaxBackoff, q.Size)
+}
+
+// retryable returns true if result is a failed GET or DELETE worth retrying.
+func retryable(result TestResult) bool {
+	if result.testType != GET && result.testType != DELETE {
+		return false
+	}
+	if result.scenario != nil || result.fault != "" || result.selfThrottled || result.Was404() || wasAbandoned(result.err) {
+		return false
+	}
+
+	return result.WasError()
+}
+
+// offer queues a retry of result's test if it failed + has retries left. A nil RetryQueue never retries.
+func (q *RetryQueue) offer(result TestResult) {
+	if q == nil || !retryable(result) {
+		return
+	}
+	if result.attempt >= q.Attempts {
+		q.exhausted.Inc()
+		return
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed || q.running == nil || q.pending.Len() >= q.Size {
+		q.dropped.Inc()
+		return
+	}
+
+	q.running.Add(1)
+	heap.Push(&q.pending, retryItem{
+		due:  time.Now().Add(q.backoff(result.attempt)),
+		test: Test{TestType: result.testType, fileName: result.fileName, attempt: result.attempt + 1},
+	})
+	q.queued.Inc()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// backoff returns how long to wait before retrying a test that has already been retried attempt times.
+func (q *RetryQueue) backoff(attempt int) time.Duration {
+	backoff := q.Backoff
+	for i := 0; i < attempt && backoff < q.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > q.MaxBackoff {
+		return q.MaxBackoff
+	}
+
+	return backoff
+}
+
+// run calls start with each retry once it's due, until shutdown. Queued retries are added to running, + start must
+// call running.Done once the retry finishes. Retries still queued at shutdown are dropped.
+func (q *RetryQueue) run(running *sync.WaitGroup, shutdown chan bool, start func(Test)) {
+	q.lock.Lock()
+	q.running = running
+	q.wake = make(chan struct{}, 1)
+	q.lock.Unlock()
+
+	for {
+		wait := time.Hour
+		q.lock.Lock()
+		for q.pending.Len() > 0 {
+			if until := time.Until(q.pending[0].due); until > 0 {
+				wait = until
+				break
+			}
+			start(heap.Pop(&q.pending).(retryItem).test)
+		}
+		q.lock.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-q.wake:
+		case <-timer.C:
+		case <-shutdown:
+			timer.Stop()
+			q.close()
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// close drops every queued retry + any offered from now on.
+func (q *RetryQueue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	for q.pending.Len() > 0 {
+		heap.Pop(&q.pending)
+		q.dropped.Inc()
+		q.running.Done()
+	}
+}
+
+type retryItem struct {
+	due  time.Time
+	test Test
+}
+
+// retryHeap is a min-heap of retries by when they're due, for container/heap.
+type retryHeap []retryItem
+
+func (h retryHeap) Len() int           { return len(h) }
+func (h retryHeap) Less(i, j int) bool { return h[i].due.Before(h[j].due) }
+func (h retryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *retryHeap) Push(x interface{}) {
+	*h = append(*h, x.(retryItem))
+}
+
+func (h *retryHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return x
+}
+
+// retryCounts are a RetryQueue's counts, sent from an agent to the coordinator once the agent's run finishes.
+type retryCounts struct {
+	Queued    int64
+	Exhausted int64
+	Dropped   int64
+}
+
+func (q *RetryQueue) counts() retryCounts {
+	return retryCounts{Queued: q.queued.Load(), Exhausted: q.exhausted.Load(), Dropped: q.dropped.Load()}
+}
+
+// add adds an agent's counts to the coordinator's. A nil RetryQueue ignores them.
+func (q *RetryQueue) add(counts retryCounts) {
+	if q == nil {
+		return
+	}
+
+	q.queued.Add(counts.Queued)
+	q.exhausted.Add(counts.Exhausted)
+	q.dropped.Add(counts.Dropped)
+}
+
+// retrySummary returns nil unless failed tests are retried. Caller must hold resultLock.
+func (tr *TestResults) retrySummary() *RetrySummary {
+	if tr.retries == nil {
+		return nil
+	}
+
+	return &RetrySummary{
+		Attempts:  tr.numRetries.Get(),
+		Succeeded: tr.numRetrySuccess.Get(),
+		Queued:    tr.retries.queued.Get(),
+		Exhausted: tr.retries.exhausted.Get(),
+		Dropped:   tr.retries.dropped.Get(),
+	}
+}
@@ -0,0 +1,135 @@
+package load_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryQueue(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *RetryQueue
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"attempts=5", &RetryQueue{Attempts: 5, Backoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second,
+			Size: 1000}, false},
+		{"attempts=2 backoff=1s max=10s size=10",
+			&RetryQueue{Attempts: 2, Backoff: time.Second, MaxBackoff: 10 * time.Second, Size: 10}, false},
+		{"attempts=0", nil, true},
+		{"backoff=1s max=500ms", nil, true},
+		{"size=0", nil, true},
+		{"attempts", nil, true},
+		{"jitter=1s", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseRetryQueue(test.spec)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseRetryQueue(%q): got error %v, want error %t", test.spec, err, test.wantErr)
+			continue
+		}
+		if (got == nil) != (test.want == nil) || (got != nil && (got.Attempts != test.want.Attempts ||
+			got.Backoff != test.want.Backoff || got.MaxBackoff != test.want.MaxBackoff || got.Size != test.want.Size)) {
+			t.Errorf("ParseRetryQueue(%q): got %v, want %v", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	queue := &RetryQueue{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{100, time.Second},
+	}
+	for _, test := range tests {
+		if got := queue.backoff(test.attempt); got != test.want {
+			t.Errorf("backoff(%d): got %s, want %s", test.attempt, got, test.want)
+		}
+	}
+}
+
+func failed(testType TestType, fileName string, attempt int) TestResult {
+	return TestResult{testType: testType, fileName: fileName, attempt: attempt,
+		response: &http.Response{StatusCode: http.StatusInternalServerError}}
+}
+
+func TestRetryable(t *testing.T) {
+	notFound := failed(GET, "a", 0)
+	notFound.response = &http.Response{StatusCode: http.StatusNotFound}
+	ok := failed(GET, "a", 0)
+	ok.response = &http.Response{StatusCode: http.StatusOK}
+	abandoned := failed(DELETE, "a", 0)
+	abandoned.err = context.Canceled
+	dropped := failed(GET, "a", 0)
+	dropped.fault, dropped.err = ChaosDrop, errors.New("dropped")
+	throttled := failed(GET, "a", 0)
+	throttled.selfThrottled = true
+
+	tests := []struct {
+		name   string
+		result TestResult
+		want   bool
+	}{
+		{"GET 500", failed(GET, "a", 0), true},
+		{"DELETE 500", failed(DELETE, "a", 0), true},
+		{"PUT 500", failed(PUT, "a", 0), false},
+		{"success", ok, false},
+		{"404", notFound, false},
+		{"abandoned", abandoned, false},
+		{"injected fault", dropped, false},
+		{"self throttled", throttled, false},
+	}
+	for _, test := range tests {
+		if got := retryable(test.result); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRetryQueue(t *testing.T) {
+	queue := &RetryQueue{Attempts: 2, Backoff: 20 * time.Millisecond, MaxBackoff: 40 * time.Millisecond, Size: 2}
+	var running sync.WaitGroup
+	shutdown := make(chan bool)
+	started := make(chan Test, 10)
+	go queue.run(&running, shutdown, func(test Test) {
+		started <- test
+		running.Done()
+	})
+	// Retries offered before run starts are dropped.
+	for ready := false; !ready; time.Sleep(time.Millisecond) {
+		queue.lock.Lock()
+		ready = queue.wake != nil
+		queue.lock.Unlock()
+	}
+
+	queue.offer(failed(GET, "later", 1)) // Due after 40ms
+	queue.offer(failed(GET, "sooner", 0))
+	queue.offer(failed(GET, "overflow", 0))
+	queue.offer(failed(GET, "exhausted", 2))
+
+	for _, want := range []string{"sooner", "later"} {
+		if test := <-started; test.fileName != want || test.TestType != GET {
+			t.Errorf("got a retry of %s %s, want GET %s", test.TestType, test.fileName, want)
+		}
+	}
+	queue.offer(failed(DELETE, "at shutdown", 0))
+	close(shutdown)
+	running.Wait()
+	queue.offer(failed(GET, "after shutdown", 0))
+
+	results := &TestResults{retries: queue}
+	want := RetrySummary{Queued: 3, Exhausted: 1, Dropped: 3}
+	if got := results.retrySummary(); *got != want {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+}
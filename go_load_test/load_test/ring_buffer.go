@@ -0,0 +1,57 @@
+package load_test
+
+// Fixed size ring buffer of strings. Keeps the most recent samples + a count of everything ever added, so error
+// storage stays bounded during multi-day soak tests.
+
+type StringRing struct {
+	items []string
+	next  int // Index the next item is written to
+	total int // # of items ever added
+}
+
+func NewStringRing(capacity int) *StringRing {
+	return &StringRing{items: make([]string, 0, Max(capacity, 1))}
+}
+
+// Add appends item, overwriting the oldest item once the ring is full.
+func (r *StringRing) Add(item string) {
+	r.total++
+	if len(r.items) < cap(r.items) {
+		r.items = append(r.items, item)
+		return
+	}
+
+	r.items[r.next] = item
+	r.next = (r.next + 1) % len(r.items)
+}
+
+// Len returns the # of items currently held.
+func (r *StringRing) Len() int {
+	return len(r.items)
+}
+
+// Total returns the # of items ever added, including those that have been overwritten.
+func (r *StringRing) Total() int {
+	return r.total
+}
+
+// Last returns a copy of the last n items, newest first.
+func (r *StringRing) Last(n int) []string {
+	n = Min(n, len(r.items))
+	items := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		items = append(items, r.items[(r.next-i+len(r.items))%len(r.items)])
+	}
+
+	return items
+}
+
+// Recent returns a copy of the last n items, oldest first.
+func (r *StringRing) Recent(n int) []string {
+	items := r.Last(n)
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+
+	return items
+}
@@ -0,0 +1,106 @@
+package load_test
+
+import (
+	crand "crypto/rand"
+	"math/rand"
+	"sync"
+)
+
+// Rand is a seedable source of randomness for the choices that make up a run: which operation to run, which key to
+// run it on + what to upload. Two runs with the same seed issue the same logical sequence of operations, as long as
+// the server responds the same way, which makes server side bugs reproducible. Timing (think time, jitter) isn't
+// seeded. Safe for concurrent use, + a nil Rand uses the global unseeded source.
+type Rand struct {
+	lock sync.Mutex
+	src  *rand.Rand
+}
+
+func NewRand(seed int64) *Rand {
+	return &Rand{src: rand.New(rand.NewSource(seed))}
+}
+
+// newSeededRand returns a Rand seeded with seed, or nil for an unseeded run (seed 0).
+func newSeededRand(seed int64) *Rand {
+	if seed == 0 {
+		return nil
+	}
+
+	return NewRand(seed)
+}
+
+func (r *Rand) Intn(n int) int {
+	if r == nil {
+		return rand.Intn(n)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.src.Intn(n)
+}
+
+func (r *Rand) Int63n(n int64) int64 {
+	if r == nil {
+		return rand.Int63n(n)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.src.Int63n(n)
+}
+
+func (r *Rand) Float64() float64 {
+	if r == nil {
+		return rand.Float64()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.src.Float64()
+}
+
+func (r *Rand) NormFloat64() float64 {
+	if r == nil {
+		return rand.NormFloat64()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.src.NormFloat64()
+}
+
+// String returns a random key of n letters, see RandStringBytes.
+func (r *Rand) String(n int) string {
+	if r == nil {
+		return RandStringBytes(n)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return randString(n, r.src.Int63)
+}
+
+// Read fills b with random bytes. Unseeded, they come from crypto/rand like they always have.
+func (r *Rand) Read(b []byte) error {
+	if r == nil {
+		_, err := crand.Read(b)
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, err := r.src.Read(b)
+	return err
+}
+
+// payloadSeed returns a seed for one test's payload, drawn from r. Tests run concurrently, so drawing payloads from a
+// shared Rand would make them depend on the order tests happen to run in. Returns 0 (unseeded) for a nil Rand.
+func (r *Rand) payloadSeed() int64 {
+	if r == nil {
+		return 0
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// 0 means unseeded, so never hand it out.
+	return r.src.Int63() | 1
+}
@@ -0,0 +1,53 @@
+package load_test
+
+import "time"
+
+// Keeps a latency histogram per interval for the last N intervals, so percentiles can be reported over a recent
+// window (I.E "p99 over the last 30 seconds") rather than the whole run.
+
+// RollingHistogram is not safe for concurrent use; callers are expected to hold their own lock.
+type RollingHistogram struct {
+	current *LatencyHistogram
+	window  []*LatencyHistogram // Completed intervals, oldest first
+	size    int                 // Max # of completed intervals kept
+}
+
+func NewRollingHistogram(size int) *RollingHistogram {
+	return &RollingHistogram{current: NewLatencyHistogram(), size: Max(size, 1)}
+}
+
+// Size returns the # of completed intervals covered by the window.
+func (r *RollingHistogram) Size() int {
+	return r.size
+}
+
+// Record adds a sample to the current interval.
+func (r *RollingHistogram) Record(d time.Duration) {
+	r.current.Record(d)
+}
+
+// Rotate completes the current interval, dropping the oldest once the window is full.
+func (r *RollingHistogram) Rotate() {
+	if len(r.window) == r.size {
+		oldest := r.window[0]
+		r.window = r.window[1:]
+		oldest.Reset()
+		r.window = append(r.window, r.current)
+		r.current = oldest
+		return
+	}
+
+	r.window = append(r.window, r.current)
+	r.current = NewLatencyHistogram()
+}
+
+// Merged returns a new histogram with every sample in the window, including the in-progress interval.
+func (r *RollingHistogram) Merged() *LatencyHistogram {
+	merged := NewLatencyHistogram()
+	for _, hist := range r.window {
+		merged.Merge(hist)
+	}
+	merged.Merge(r.current)
+
+	return merged
+}
@@ -0,0 +1,33 @@
+package load_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollingHistogram(t *testing.T) {
+	rolling := NewRollingHistogram(2)
+	tests := []struct {
+		latency time.Duration
+		count   int64
+		max     time.Duration
+	}{
+		{time.Second, 1, time.Second},
+		{10 * time.Millisecond, 2, time.Second},
+		{20 * time.Millisecond, 3, time.Second},
+		{30 * time.Millisecond, 3, 30 * time.Millisecond}, // 1s fell out of the window
+		{time.Millisecond, 3, 30 * time.Millisecond},
+	}
+	for i, test := range tests {
+		rolling.Record(test.latency)
+		merged := rolling.Merged()
+		if merged.Count() != test.count || merged.Max() != test.max {
+			t.Errorf("interval %d: got %d samples, max %s, want %d, max %s", i, merged.Count(), merged.Max(),
+				test.count, test.max)
+		}
+		rolling.Rotate()
+	}
+	if NewRollingHistogram(0).Size() != 1 {
+		t.Errorf("got a window of %d intervals, want at least 1", NewRollingHistogram(0).Size())
+	}
+}
@@ -0,0 +1,485 @@
+package load_test
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// SCENARIO tests run every step of a Scenario on one file, in order, as a single unit.
+const SCENARIO TestType = "SCENARIO"
+
+// BodyWritten is the StepAssertions.Body value that checks a GET returns what the scenario last PUT.
+const BodyWritten = "written"
+
+// How a PUT step sends its file. UploadMultipart POSTs it as a browser would, as the file field of a
+// multipart/form-data form, to the same path.
+const (
+	UploadRaw       = "raw"
+	UploadMultipart = "multipart"
+)
+
+// DefaultUploadField is the form field a multipart upload's file is sent in.
+const DefaultUploadField = "file"
+
+// Scenarios are multi step tests where each step depends on the ones before it, I.E create a file, read it back,
+// overwrite it, then delete it. A worker runs a scenario's steps in order on a fresh file, stopping at the first step
+// whose assertions fail. Every step is a request of its own in the results, + scenarios are also reported as a whole.
+// Scenarios are loaded from a YAML file, I.E
+//
+//	share: 0.2 # Fraction of scheduled tests that are scenarios, the rest follow the normal mix. Defaults to 1
+//	scenarios:
+//	  - name: read-your-writes
+//	    weight: 3 # Relative to other scenarios. Defaults to 1
+//	    steps:
+//	      - {name: create, op: PUT, expect: {status: 201}}
+//	      - {name: read, op: GET, expect: {status: 200, body: written, max_latency: 500ms}}
+//	      - {name: overwrite, op: PUT, size: 4096, expect: {status: 201}}
+//	      - {name: form-upload, op: PUT, upload: multipart, field: file, expect: {status: 201}}
+//	      - {name: delete, op: DELETE, expect: {status: 200}}
+//	      - {name: verify-delete, op: GET, expect: {status: 404}}
+type ScenarioFile struct {
+	Share     float64    `yaml:"share"`
+	Scenarios []Scenario `yaml:"scenarios"`
+}
+
+type Scenario struct {
+	Name   string         `yaml:"name"`
+	Weight int            `yaml:"weight"`
+	Steps  []ScenarioStep `yaml:"steps"`
+}
+
+type ScenarioStep struct {
+	Name   string         `yaml:"name"`   // Defaults to the step # + op, I.E 2-GET
+	Op     TestType       `yaml:"op"`     // GET, PUT or DELETE of the scenario's file
+	Size   int64          `yaml:"size"`   // PUT size in bytes, before base64 encoding. Random up to the max file size if 0
+	Upload string         `yaml:"upload"` // How a PUT sends the file, UploadRaw or UploadMultipart. Defaults to UploadRaw
+	Field  string         `yaml:"field"`  // Multipart uploads only, the form field the file is sent in. Defaults to file
+	Expect StepAssertions `yaml:"expect"`
+}
+
+// StepAssertions are checked in order against a step's response. Unset assertions aren't checked.
+type StepAssertions struct {
+	Status     int           `yaml:"status"` // Expected status code. Any 2XX if 0
+	Body       string        `yaml:"body"`   // Only BodyWritten is supported
+	MaxLatency time.Duration `yaml:"max_latency"`
+}
+
+// LoadScenarios reads + validates a scenario file.
+func LoadScenarios(path string) (*ScenarioFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %s. Error: %w", path, err)
+	}
+
+	file := &ScenarioFile{Share: 1}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(file)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse scenario file: %s. Error: %w", path, err)
+	}
+
+	err = file.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario file: %s. Error: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// validate checks every scenario can run, + fills in defaults.
+func (f *ScenarioFile) validate() error {
+	if len(f.Scenarios) == 0 {
+		return fmt.Errorf("no scenarios defined")
+	}
+	if f.Share <= 0 || f.Share > 1 {
+		return fmt.Errorf("share must be in (0, 1], got %g", f.Share)
+	}
+
+	names := make(map[string]bool, len(f.Scenarios))
+	for i := range f.Scenarios {
+		scenario := &f.Scenarios[i]
+		if scenario.Name == "" {
+			scenario.Name = fmt.Sprintf("scenario-%d", i+1)
+		}
+		if names[scenario.Name] {
+			return fmt.Errorf("duplicate scenario name: %s", scenario.Name)
+		}
+		names[scenario.Name] = true
+		if scenario.Weight == 0 {
+			scenario.Weight = 1
+		}
+		if scenario.Weight < 0 {
+			return fmt.Errorf("scenario %s: weight must be >= 0", scenario.Name)
+		}
+		if len(scenario.Steps) == 0 {
+			return fmt.Errorf("scenario %s: no steps defined", scenario.Name)
+		}
+
+		written := false
+		for j := range scenario.Steps {
+			step := &scenario.Steps[j]
+			step.Op = TestType(strings.ToUpper(string(step.Op)))
+			if step.Name == "" {
+				step.Name = fmt.Sprintf("%d-%s", j+1, step.Op)
+			}
+			switch step.Op {
+			case GET, PUT, DELETE:
+			default:
+				return fmt.Errorf("scenario %s step %s: unsupported op %q. Expected GET, PUT or DELETE", scenario.Name, step.Name, step.Op)
+			}
+			if step.Size < 0 || (step.Size > 0 && step.Op != PUT) {
+				return fmt.Errorf("scenario %s step %s: size is only valid for PUT + must be > 0", scenario.Name, step.Name)
+			}
+			if step.Upload == "" {
+				step.Upload = UploadRaw
+			}
+			if step.Upload != UploadRaw && step.Upload != UploadMultipart {
+				return fmt.Errorf("scenario %s step %s: unsupported upload %q. Expected %s or %s", scenario.Name, step.Name, step.Upload, UploadRaw, UploadMultipart)
+			}
+			if step.Op != PUT && (step.Upload != UploadRaw || step.Field != "") {
+				return fmt.Errorf("scenario %s step %s: upload + field are only valid for PUT", scenario.Name, step.Name)
+			}
+			if step.Field != "" && step.Upload != UploadMultipart {
+				return fmt.Errorf("scenario %s step %s: field is only valid for multipart uploads", scenario.Name, step.Name)
+			}
+			if step.Upload == UploadMultipart && step.Field == "" {
+				step.Field = DefaultUploadField
+			}
+			if step.Expect.Body != "" && step.Expect.Body != BodyWritten {
+				return fmt.Errorf("scenario %s step %s: unsupported body assertion %q. Expected %s", scenario.Name, step.Name, step.Expect.Body, BodyWritten)
+			}
+			if step.Expect.Body == BodyWritten && (step.Op != GET || !written) {
+				return fmt.Errorf("scenario %s step %s: body: %s needs a GET after a PUT", scenario.Name, step.Name, BodyWritten)
+			}
+			written = written || step.Op == PUT
+		}
+	}
+
+	return nil
+}
+
+// pick returns a scenario at random by weight.
+func (f *ScenarioFile) pick(r *Rand) *Scenario {
+	total := 0
+	for _, scenario := range f.Scenarios {
+		total += scenario.Weight
+	}
+
+	n := r.Intn(total)
+	for i := range f.Scenarios {
+		n -= f.Scenarios[i].Weight
+		if n < 0 {
+			return &f.Scenarios[i]
+		}
+	}
+
+	return &f.Scenarios[len(f.Scenarios)-1]
+}
+
+// scenarioTest schedules a scenario picked by weight, on a new file so its steps don't race other tests.
+func (ts *TestScheduler) scenarioTest() Test {
+	scenario := ts.cfg.Scenarios.pick(ts.rand)
+	// Each step is a request, so count the extra ones the same way as a consistency check.
+	ts.numScheduled += len(scenario.Steps) - 1
+	log.Debugf("Scheduling scenario %s", scenario.Name)
+
+	return Test{TestType: SCENARIO, fileName: ts.newFileName(), scenario: scenario}
+}
+
+// ScenarioStepResult identifies which step of a scenario a TestResult is for.
+type ScenarioStepResult struct {
+	Scenario       string
+	Step           int // From 0
+	Name           string
+	ExpectedStatus int  // If set, a response with this status isn't an error, even if it's a 404
+	Last           bool // True for the last step run, I.E the last step or the first one that failed
+}
+
+// RunScenario runs test's scenario steps in order, sending a result per step. Steps after the first failure are skipped.
+func (tr *TestExecutor) RunScenario(test Test) {
+	fileName := test.fileName
+	trace := tr.newTraceContext()
+	tr.waitForOpenInProcess(fileName)
+	defer func() {
+		tr.inProcessLock.Lock()
+		tr.inProcess.Delete(fileName)
+		tr.inProcessLock.Unlock()
+	}()
+
+	written := ""
+	payload := newSeededRand(test.payloadSeed)
+	for i, step := range test.scenario.Steps {
+		result := tr.runStep(test, step, trace, &written, payload)
+		if i > 0 {
+			// Only the first step can start late, later steps run as soon as the one before finishes.
+			result.lag = 0
+		}
+		result.scenario = &ScenarioStepResult{
+			Scenario:       test.scenario.Name,
+			Step:           i,
+			Name:           step.Name,
+			ExpectedStatus: step.Expect.Status,
+			Last:           result.failed || i == len(test.scenario.Steps)-1,
+		}
+		tr.emit(result)
+		if result.failed {
+			return
+		}
+	}
+}
+
+// runStep sends a single scenario step's request + checks its assertions. written is the body of the scenario's last
+// successful PUT, + PUT bodies are generated from payload.
+func (tr *TestExecutor) runStep(test Test, step ScenarioStep, trace traceContext, written *string, payload *Rand) TestResult {
+	ctx, cancel := tr.testContext(step.Op)
+	defer cancel()
+	phases := NewRequestPhases()
+	start := time.Now()
+	result := TestResult{
+		fileName: test.fileName,
+		trace:    trace,
+		phases:   phases,
+		lag:      scheduleLag(test.scheduledAt, start),
+		started:  start,
+		worker:   test.worker,
+		testType: step.Op,
+	}
+	fail := func(message string, err error) TestResult {
+		result.message = fmt.Sprintf("[%s/%s] %s", test.scenario.Name, step.Name, message)
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		return result
+	}
+
+	var body io.Reader
+	var byteString, contentType string
+	var bytesSent int64
+	method := string(step.Op)
+	if step.Op == PUT {
+		size := step.Size
+		if size == 0 {
+			size = tr.randomFileSize(payload)
+		}
+		fileBytes := make([]byte, size)
+		err := tr.fillPayload(payload, fileBytes)
+		if err != nil {
+			return fail("Failed to generate random file bytes", err)
+		}
+		byteString = b64.StdEncoding.EncodeToString(fileBytes)
+		body = strings.NewReader(byteString)
+		bytesSent = int64(len(byteString))
+		if step.Upload == UploadMultipart {
+			form, formType, err := multipartBody(step.Field, test.fileName, byteString)
+			if err != nil {
+				return fail("Failed to build multipart form", err)
+			}
+			body, contentType, bytesSent = form, formType, int64(form.Len())
+			method = http.MethodPost
+		}
+	}
+
+	req, err := tr.newRequest(ctx, method, test.fileName, body, trace, phases)
+	if err != nil {
+		return fail(fmt.Sprintf("Failed to initialize %s request", step.Op), err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := tr.do(req, phases)
+	result.response = response
+	if err != nil {
+		return fail(fmt.Sprintf("Error executing http %s request", step.Op), err)
+	}
+
+	responseBody := responseToString(response)
+	result.bytesSent = bytesSent
+	result.bytesReceived = int64(len(responseBody))
+	result.duration = time.Now().Sub(start)
+
+	expect := step.Expect
+	switch {
+	case expect.Status != 0 && response.StatusCode != expect.Status:
+		result.mismatch = statusMismatch(response.StatusCode, expect.Status)
+		return fail(fmt.Sprintf("Unexpected status code, got: %d but expected %d.", response.StatusCode, expect.Status), nil)
+	case expect.Status == 0 && (response.StatusCode < 200 || response.StatusCode >= 300):
+		return fail(fmt.Sprintf("Unexpected status code, got: %d but expected 2XX.", response.StatusCode), nil)
+	case expect.Body == BodyWritten && responseBody != *written:
+		result.mismatch = bodyMismatch(*written, responseBody)
+		return fail("Read body is not identical to the last written body! Inconsistent data returned", nil)
+	case expect.MaxLatency > 0 && result.duration > expect.MaxLatency:
+		return fail(fmt.Sprintf("Took %s, longer than max latency %s.", result.duration, expect.MaxLatency), nil)
+	}
+
+	if step.Op == PUT {
+		*written = byteString
+	}
+	result.message = responseBody
+	return result
+}
+
+// multipartBody returns a multipart/form-data form with content as the file fileName in field, + its Content-Type.
+func multipartBody(field string, fileName string, content string) (*bytes.Buffer, string, error) {
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	part, err := writer.CreateFormFile(field, path.Base(fileName))
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = io.WriteString(part, content)
+	if err != nil {
+		return nil, "", err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return form, writer.FormDataContentType(), nil
+}
+
+// ScenarioSummary reports how often a scenario ran to completion, + where it failed when it didn't.
+type ScenarioSummary struct {
+	Name     string                `json:"name"`
+	Runs     int                   `json:"runs"`
+	Passed   int                   `json:"passed"`
+	PassRate float64               `json:"pass_rate"`
+	Steps    []ScenarioStepSummary `json:"steps"`
+}
+
+type ScenarioStepSummary struct {
+	Name        string   `json:"name"`
+	Op          TestType `json:"op"`
+	Runs        int      `json:"runs"`
+	Failures    int      `json:"failures"`
+	MeanMs      float64  `json:"mean_ms"`
+	P99Ms       float64  `json:"p99_ms"`
+	LastFailure string   `json:"last_failure,omitempty"`
+}
+
+// scenarioStats accumulates results for one scenario.
+type scenarioStats struct {
+	scenario    *Scenario
+	runs        int
+	passed      int
+	stepRuns    []int
+	failures    []int
+	latency     []*LatencyHistogram
+	lastFailure []string
+}
+
+// newScenarioStats returns stats for each scenario in the order they're defined, or nil if there are no scenarios.
+func newScenarioStats(file *ScenarioFile) []*scenarioStats {
+	if file == nil {
+		return nil
+	}
+
+	stats := make([]*scenarioStats, len(file.Scenarios))
+	for i := range file.Scenarios {
+		scenario := &file.Scenarios[i]
+		stats[i] = &scenarioStats{
+			scenario:    scenario,
+			stepRuns:    make([]int, len(scenario.Steps)),
+			failures:    make([]int, len(scenario.Steps)),
+			latency:     make([]*LatencyHistogram, len(scenario.Steps)),
+			lastFailure: make([]string, len(scenario.Steps)),
+		}
+		for j := range scenario.Steps {
+			stats[i].latency[j] = NewLatencyHistogram()
+		}
+	}
+
+	return stats
+}
+
+// recordScenario records a scenario step result. Caller must hold resultLock.
+func (tr *TestResults) recordScenario(result TestResult, duration time.Duration) {
+	step := result.scenario
+	for _, s := range tr.scenarios {
+		if s.scenario.Name != step.Scenario || step.Step >= len(s.stepRuns) {
+			continue
+		}
+
+		s.stepRuns[step.Step]++
+		s.latency[step.Step].Record(duration)
+		failed := result.WasTestFailure()
+		if failed {
+			s.failures[step.Step]++
+			s.lastFailure[step.Step] = result.ErrorMessage()
+		}
+		if step.Last {
+			s.runs++
+			if !failed {
+				s.passed++
+			}
+		}
+		return
+	}
+}
+
+// scenarioSummaries returns a summary per scenario, in the order they're defined. Caller must hold resultLock.
+func (tr *TestResults) scenarioSummaries() []ScenarioSummary {
+	var summaries []ScenarioSummary
+	for _, s := range tr.scenarios {
+		summary := ScenarioSummary{
+			Name:     s.scenario.Name,
+			Runs:     s.runs,
+			Passed:   s.passed,
+			PassRate: passRate(s.runs-s.passed, s.runs),
+			Steps:    make([]ScenarioStepSummary, len(s.scenario.Steps)),
+		}
+		for i, step := range s.scenario.Steps {
+			summary.Steps[i] = ScenarioStepSummary{
+				Name:        step.Name,
+				Op:          step.Op,
+				Runs:        s.stepRuns[i],
+				Failures:    s.failures[i],
+				MeanMs:      durationMs(s.latency[i].Mean()),
+				P99Ms:       durationMs(s.latency[i].Percentile(99)),
+				LastFailure: s.lastFailure[i],
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+// PrintScenarios prints a row per scenario step, if scenarios ran.
+func (tr *TestResults) PrintScenarios() {
+	tr.resultLock.RLock()
+	scenarios := tr.scenarioSummaries()
+	tr.resultLock.RUnlock()
+	if len(scenarios) == 0 {
+		return
+	}
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+	tbl := table.New("Scenario", "Step", "Runs", "Failures", "Mean (ms)", "p99 (ms)")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, scenario := range scenarios {
+		tbl.AddRow(scenario.Name, "(all)", scenario.Runs, scenario.Runs-scenario.Passed, "", "")
+		for _, step := range scenario.Steps {
+			tbl.AddRow("", fmt.Sprintf("%s %s", step.Name, step.Op), step.Runs, step.Failures,
+				fmt.Sprintf("%.1f", step.MeanMs), fmt.Sprintf("%.1f", step.P99Ms))
+		}
+	}
+
+	fmt.Println()
+	tbl.Print()
+}
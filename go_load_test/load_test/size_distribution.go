@@ -0,0 +1,290 @@
+package load_test
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SizeFixed     = "fixed"
+	SizeUniform   = "uniform"
+	SizeLognormal = "lognormal"
+	SizeHistogram = "histogram"
+)
+
+// SizeDistribution is the distribution CREATEs' + PUTs' payload sizes are drawn from, replacing MaxFileSize + its
+// ramp, so a run can model a real workload's mix of small + large files. Sizes are of the random bytes, uploaded
+// base64 encoded so 4/3 larger, with KB, MB + GB suffixes in powers of 1024. Specs are either a fixed size or a
+// distribution name followed by params, I.E
+//
+//	4KB
+//	uniform min=1KB max=1MB
+//	lognormal mean=64KB stddev=256KB max=1GB
+//	histogram 4KB=80% 1MB=19% 500MB=1%
+//
+// lognormal's max is optional, its long tail is otherwise unbounded. histogram's shares must add up to 100%.
+type SizeDistribution struct {
+	Distribution string
+	Min          int64
+	Max          int64 // 0 for an unbounded lognormal
+	Mean         int64
+	StdDev       int64
+	Histogram    []SizeShare // Sizes in increasing order
+}
+
+// SizeShare is the share of payloads that are Size bytes, see SizeDistribution.
+type SizeShare struct {
+	Size  int64
+	Share float64
+}
+
+// ParseSizeDistribution parses a size distribution spec. An empty spec returns nil, sizes are picked up to
+// MaxFileSize.
+func ParseSizeDistribution(spec string) (*SizeDistribution, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	if fixed, err := parseByteSize(fields[0]); err == nil && len(fields) == 1 {
+		return &SizeDistribution{Distribution: SizeFixed, Mean: fixed}, nil
+	}
+
+	sizes := &SizeDistribution{Distribution: fields[0]}
+	if sizes.Distribution == SizeHistogram {
+		if err := sizes.parseHistogram(fields[1:]); err != nil {
+			return nil, fmt.Errorf("invalid histogram size distribution: %w", err)
+		}
+		return sizes, nil
+	}
+
+	params := make(profileParams, len(fields)-1)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid size distribution param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	var minSize, maxSize, mean, stddev string
+	var err error
+	switch sizes.Distribution {
+	case SizeUniform:
+		err = params.parse(map[string]interface{}{"min": &minSize, "max": &maxSize}, "max")
+	case SizeLognormal:
+		err = params.parse(map[string]interface{}{"mean": &mean, "stddev": &stddev, "max": &maxSize}, "mean", "stddev")
+	default:
+		return nil, fmt.Errorf("unknown size distribution: %s", sizes.Distribution)
+	}
+	for _, param := range []struct {
+		value  string
+		target *int64
+	}{{minSize, &sizes.Min}, {maxSize, &sizes.Max}, {mean, &sizes.Mean}, {stddev, &sizes.StdDev}} {
+		if err == nil && param.value != "" {
+			*param.target, err = parseByteSize(param.value)
+		}
+	}
+	if err == nil && sizes.Distribution == SizeUniform && sizes.Max < sizes.Min {
+		err = fmt.Errorf("max must be >= min")
+	}
+	if err == nil && sizes.Distribution == SizeLognormal && sizes.Max != 0 && sizes.Max < sizes.Mean {
+		err = fmt.Errorf("max must be >= mean")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s size distribution: %w", sizes.Distribution, err)
+	}
+
+	return sizes, nil
+}
+
+// parseHistogram parses size=share pairs, I.E 4KB=80% 1MB=20%.
+func (d *SizeDistribution) parseHistogram(fields []string) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("no sizes. Expected size=share pairs, I.E 4KB=80%% 1MB=20%%")
+	}
+
+	total := 0.0
+	for _, field := range fields {
+		size, share, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("invalid param: %s. Expected size=share, I.E 4KB=80%%", field)
+		}
+		bytes, err := parseByteSize(size)
+		if err != nil {
+			return err
+		}
+		fraction, err := parseFraction(share)
+		if err != nil {
+			return fmt.Errorf("invalid share for %s: %w", size, err)
+		}
+		d.Histogram = append(d.Histogram, SizeShare{Size: bytes, Share: fraction})
+		total += fraction
+	}
+	if math.Abs(total-1) > 0.001 {
+		return fmt.Errorf("shares add up to %.1f%%, not 100%%", total*100)
+	}
+	sort.Slice(d.Histogram, func(i, j int) bool { return d.Histogram[i].Size < d.Histogram[j].Size })
+
+	return nil
+}
+
+// parseByteSize parses a # of bytes with an optional KB, MB or GB suffix, I.E 4KB.
+func parseByteSize(value string) (int64, error) {
+	number, scale := strings.ToUpper(value), int64(1)
+	for _, unit := range []struct {
+		suffix string
+		scale  int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, scale = strings.TrimSuffix(number, unit.suffix), unit.scale
+			break
+		}
+	}
+
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size: %s. Expected bytes, I.E 512, 4KB or 1.5MB", value)
+	}
+
+	return int64(size * float64(scale)), nil
+}
+
+// next returns a payload size drawn from r. Never negative.
+func (d *SizeDistribution) next(r *Rand) int64 {
+	var size int64
+	switch d.Distribution {
+	case SizeFixed:
+		size = d.Mean
+	case SizeUniform:
+		size = d.Min + r.Int63n(d.Max-d.Min+1)
+	case SizeLognormal:
+		size = int64(lognormalFrom(float64(d.Mean), float64(d.StdDev), r.NormFloat64()))
+		if d.Max > 0 && size > d.Max {
+			size = d.Max
+		}
+	case SizeHistogram:
+		roll := r.Float64()
+		size = d.Histogram[len(d.Histogram)-1].Size
+		for _, bucket := range d.Histogram {
+			if roll < bucket.Share {
+				size = bucket.Size
+				break
+			}
+			roll -= bucket.Share
+		}
+	}
+
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// mean returns the mean payload size, ignoring lognormal's max.
+func (d *SizeDistribution) mean() float64 {
+	switch d.Distribution {
+	case SizeUniform:
+		return float64(d.Min+d.Max) / 2
+	case SizeHistogram:
+		mean := 0.0
+		for _, bucket := range d.Histogram {
+			mean += bucket.Share * float64(bucket.Size)
+		}
+		return mean
+	default:
+		return float64(d.Mean)
+	}
+}
+
+func (d *SizeDistribution) String() string {
+	switch d.Distribution {
+	case SizeFixed:
+		return fmt.Sprintf("%d bytes", d.Mean)
+	case SizeUniform:
+		return fmt.Sprintf("%s min=%d max=%d", d.Distribution, d.Min, d.Max)
+	case SizeLognormal:
+		return fmt.Sprintf("%s mean=%d stddev=%d max=%d", d.Distribution, d.Mean, d.StdDev, d.Max)
+	default:
+		shares := make([]string, len(d.Histogram))
+		for i, bucket := range d.Histogram {
+			shares[i] = fmt.Sprintf("%d=%.4g%%", bucket.Size, bucket.Share*100)
+		}
+		return fmt.Sprintf("%s %s", d.Distribution, strings.Join(shares, " "))
+	}
+}
+
+// sizeBucketBounds are the upper bounds of the file size buckets latencies are broken down by, see sizeBucket.
+var sizeBucketBounds = []int64{1 << 10, 16 << 10, 256 << 10, 4 << 20, 64 << 20, 1 << 30}
+
+// sizeBucketLabels label sizeBucketBounds, plus a bucket for larger files.
+var sizeBucketLabels = []string{"<1KB", "<16KB", "<256KB", "<4MB", "<64MB", "<1GB", ">=1GB"}
+
+// sizeBucket returns the label of the size bucket a file of size bytes falls in.
+func sizeBucket(size int64) string {
+	for i, bound := range sizeBucketBounds {
+		if size < bound {
+			return sizeBucketLabels[i]
+		}
+	}
+
+	return sizeBucketLabels[len(sizeBucketLabels)-1]
+}
+
+// newSizeLatency returns nil unless sizes are drawn from a SizeDistribution.
+func newSizeLatency(sizes *SizeDistribution) map[TestType]map[string]*LatencyHistogram {
+	if sizes == nil {
+		return nil
+	}
+
+	return map[TestType]map[string]*LatencyHistogram{GET: {}, PUT: {}}
+}
+
+// recordSizeLatency adds a successful GET's or upload's latency to the bucket of its size on the wire, if latency is
+// broken down by size. Caller must hold resultLock.
+func (tr *TestResults) recordSizeLatency(result TestResult, duration time.Duration) {
+	if tr.sizeLatency == nil || !result.WasSuccess() {
+		return
+	}
+
+	var size int64
+	switch result.testType {
+	case GET:
+		size = result.BytesReceived()
+	case PUT, CREATE:
+		size = result.BytesSent()
+	default:
+		return
+	}
+
+	buckets := tr.sizeLatency[latencyOperation(result.testType)]
+	bucket := sizeBucket(size)
+	if buckets[bucket] == nil {
+		buckets[bucket] = NewLatencyHistogram()
+	}
+	buckets[bucket].Record(duration)
+}
+
+// SizeLatencySummary is GET + PUT latency by size on the wire, see sizeBucket.
+type SizeLatencySummary map[TestType]map[string]LatencySummary
+
+// sizeLatencySummary returns nil unless latency is broken down by size. Caller must hold resultLock.
+func (tr *TestResults) sizeLatencySummary() SizeLatencySummary {
+	if tr.sizeLatency == nil {
+		return nil
+	}
+
+	summaries := make(SizeLatencySummary, len(tr.sizeLatency))
+	for op, buckets := range tr.sizeLatency {
+		summaries[op] = make(map[string]LatencySummary, len(buckets))
+		for bucket, latency := range buckets {
+			summaries[op][bucket] = summarizeLatency(latency)
+		}
+	}
+
+	return summaries
+}
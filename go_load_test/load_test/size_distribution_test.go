@@ -0,0 +1,114 @@
+package load_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"4KB", 4096, false},
+		{"1.5mb", 1572864, false},
+		{"2GB", 2 << 30, false},
+		{"-1KB", 0, true},
+		{"4TB", 0, true},
+		{"KB", 0, true},
+	}
+	for _, test := range tests {
+		got, err := parseByteSize(test.value)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("parseByteSize(%q): got %d, %v, want %d, error %t", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestParseSizeDistribution(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *SizeDistribution
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"4KB", &SizeDistribution{Distribution: SizeFixed, Mean: 4096}, false},
+		{"uniform min=1KB max=1MB", &SizeDistribution{Distribution: SizeUniform, Min: 1 << 10, Max: 1 << 20}, false},
+		{"uniform max=1KB", &SizeDistribution{Distribution: SizeUniform, Max: 1 << 10}, false},
+		{"lognormal mean=64KB stddev=256KB", &SizeDistribution{Distribution: SizeLognormal, Mean: 64 << 10,
+			StdDev: 256 << 10}, false},
+		{"histogram 1MB=19.5% 4KB=80% 500MB=0.5%", &SizeDistribution{Distribution: SizeHistogram,
+			Histogram: []SizeShare{{4 << 10, 0.8}, {1 << 20, 0.195}, {500 << 20, 0.005}}}, false},
+		{"uniform min=1MB max=1KB", nil, true},
+		{"uniform min=1KB", nil, true},
+		{"lognormal mean=64KB stddev=1KB max=1KB", nil, true},
+		{"lognormal mean=64KB", nil, true},
+		{"histogram 4KB=80%", nil, true}, // Doesn't add up to 100%
+		{"histogram", nil, true},
+		{"histogram 4KB", nil, true},
+		{"pareto min=1KB", nil, true},
+		{"uniform max", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseSizeDistribution(test.spec)
+		if !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("ParseSizeDistribution(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestSizeDistributionNext(t *testing.T) {
+	tests := []struct {
+		spec      string
+		min       int64
+		max       int64
+		checkMean bool // A lognormal's mean is pulled down by its max
+	}{
+		{"4KB", 4096, 4096, true},
+		{"uniform min=1KB max=2KB", 1024, 2048, true},
+		{"lognormal mean=64KB stddev=64KB", 0, math.MaxInt64, true},
+		{"lognormal mean=64KB stddev=256KB max=1MB", 0, 1 << 20, false},
+		{"histogram 4KB=50% 1MB=50%", 4096, 1 << 20, true},
+	}
+	for _, test := range tests {
+		sizes, err := ParseSizeDistribution(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewRand(1)
+		total := 0.0
+		for i := 0; i < 100000; i++ {
+			size := sizes.next(r)
+			if size < test.min || size > test.max {
+				t.Fatalf("%s: got %d, want within [%d, %d]", test.spec, size, test.min, test.max)
+			}
+			total += float64(size)
+		}
+		if mean := total / 100000; test.checkMean && math.Abs(mean-sizes.mean()) > sizes.mean()*0.05 {
+			t.Errorf("%s: got mean %.0f, want ~%.0f", test.spec, mean, sizes.mean())
+		}
+	}
+}
+
+func TestSizeBucket(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "<1KB"},
+		{1023, "<1KB"},
+		{1024, "<16KB"},
+		{5 << 20, "<64MB"},
+		{1 << 30, ">=1GB"},
+	}
+	for _, test := range tests {
+		if got := sizeBucket(test.size); got != test.want {
+			t.Errorf("sizeBucket(%d): got %s, want %s", test.size, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package load_test
+
+import (
+	"time"
+)
+
+// Point in time copies of TestResults for Go code embedding the load tester (a web UI, custom exporters, tests),
+// so consumers never touch TestResults fields or hold resultLock themselves.
+
+// CurrentRates are the smoothed "current" per second rates shown by PrintResults.
+type CurrentRates struct {
+	Requests           int
+	Successes          int
+	Gets               int
+	Puts               int
+	Deletes            int
+	Consistency        int
+	Throttles          int
+	BytesUploaded      int
+	BytesDownloaded    int
+	AvgGetDuration     time.Duration
+	AvgPutDuration     time.Duration
+	AvgDeleteDuration  time.Duration
+	AvgConsistencyTime time.Duration
+}
+
+// Snapshot is a copy of the results at a point in time. It shares no mutable state with TestResults, with the
+// exception of LastInterval's maps which are never modified once the interval completes, and must not be modified.
+type Snapshot struct {
+	Timestamp                   time.Time
+	Elapsed                     time.Duration
+	Requests                    int
+	Successes                   int
+	Failures                    int
+	ConsistencyChecks           int
+	ConsistencyFailures         int
+	Http5XX                     int
+	Throttled                   int
+	HttpErrors                  int
+	OtherErrors                 int
+	BytesUploaded               int64
+	BytesDownloaded             int64
+	MaxSuccessfulRequestsPerSec int
+	Current                     CurrentRates
+	Latency                     LatencySummary
+	Operations                  map[TestType]LatencySummary
+	StatusCodes                 map[int]int
+	LastInterval                IntervalStats // Zero value until the first interval completes
+}
+
+// Snapshot returns a copy of the results so far.
+func (tr *TestResults) Snapshot() Snapshot {
+	tr.resultLock.RLock()
+	defer tr.resultLock.RUnlock()
+
+	snapshot := Snapshot{
+		Timestamp:                   time.Now(),
+		Elapsed:                     tr.elapsed(),
+		Requests:                    tr.numRequests.Get(),
+		Successes:                   tr.numSuccess.Get(),
+		Failures:                    tr.numFailure.Get(),
+		ConsistencyChecks:           tr.numConsistency.Get(),
+		ConsistencyFailures:         tr.numFailedConsistency.Get(),
+		Http5XX:                     tr.num500s.Get(),
+		Throttled:                   tr.numThrottled.Get(),
+		HttpErrors:                  tr.httpErrors.Total(),
+		OtherErrors:                 tr.otherErrors.Total(),
+		BytesUploaded:               tr.bytesUploaded.Load(),
+		BytesDownloaded:             tr.bytesDownloaded.Load(),
+		MaxSuccessfulRequestsPerSec: tr.maxSeenSuccessfulRequestPerSec,
+		Current: CurrentRates{
+			Requests:           tr.numLastInterval,
+			Successes:          tr.numSuccessLastInterval,
+			Gets:               tr.numGetLastInterval,
+			Puts:               tr.numPutLastInterval,
+			Deletes:            tr.numDeleteLastInterval,
+			Consistency:        tr.numConsistencyLastInterval,
+			Throttles:          tr.numThrottledLastInterval,
+			BytesUploaded:      tr.bytesUploadedLastInterval,
+			BytesDownloaded:    tr.bytesDownloadedLastInterval,
+			AvgGetDuration:     tr.avgGetDurationLastInterval,
+			AvgPutDuration:     tr.avgPutDurationLastInterval,
+			AvgDeleteDuration:  tr.avgDeleteDurationLastInterval,
+			AvgConsistencyTime: tr.avgConsistencyDurationLastInterval,
+		},
+		Latency:     summarizeLatency(tr.latency),
+		Operations:  make(map[TestType]LatencySummary, len(latencyOperations)),
+		StatusCodes: make(map[int]int, len(tr.statusCodes)),
+	}
+	for _, op := range latencyOperations {
+		snapshot.Operations[op] = summarizeLatency(tr.opLatency[op])
+	}
+	for code, count := range tr.statusCodes {
+		snapshot.StatusCodes[code] = count
+	}
+	if len(tr.history) > 0 {
+		snapshot.LastInterval = tr.history[len(tr.history)-1]
+	}
+
+	return snapshot
+}
@@ -0,0 +1,84 @@
+package load_test
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Soak mode, for runs long enough that the process may not live to print its final summary. Every SoakEvery a full
+// RunSummary is written to its own timestamped file in SoakDir, I.E soak/summary-20230401T120000Z.json, so the reports
+// form a time series that can be analyzed even if the run dies at hour 47 of 48. Optionally the result log is rotated
+// to a new timestamped file on the same cadence, so no single file grows for the whole run.
+
+const soakTimestampFormat = "20060102T150405Z"
+
+// timestampedPath inserts t before path's extension, I.E results.ndjson -> results-20230401T120000Z.ndjson.
+func timestampedPath(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), t.UTC().Format(soakTimestampFormat), ext)
+}
+
+// WriteSoakReport writes the summary so far to a timestamped file in dir, returning its path.
+func (tr *TestResults) WriteSoakReport(dir string, at time.Time) (string, error) {
+	data, err := json.MarshalIndent(tr.Summary(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode soak report. Error: %w", err)
+	}
+
+	path := timestampedPath(filepath.Join(dir, "summary.json"), at)
+	// Written to a temp file + renamed, so a run dying mid write never leaves a truncated report behind.
+	tmp, err := os.CreateTemp(dir, ".summary.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create soak report: %s. Error: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("failed to write soak report: %s. Error: %w", path, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write soak report: %s. Error: %w", path, err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to save soak report: %s. Error: %w", path, err)
+	}
+
+	return path, nil
+}
+
+// runSoakReports writes a soak report every cfg.SoakEvery, and once more when stop is closed.
+func (ra *ResultAggregator) runSoakReports(stop <-chan struct{}) {
+	err := os.MkdirAll(ra.cfg.SoakDir, 0755)
+	if err != nil {
+		log.Errorf("Soak reports disabled, failed to create dir: %s. Error: %+v", ra.cfg.SoakDir, err)
+		return
+	}
+
+	ticker := time.NewTicker(ra.cfg.SoakEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			ra.writeSoakReport()
+			return
+		case <-ticker.C:
+			ra.writeSoakReport()
+		}
+	}
+}
+
+func (ra *ResultAggregator) writeSoakReport() {
+	path, err := ra.Results.WriteSoakReport(ra.cfg.SoakDir, time.Now())
+	if err != nil {
+		log.Errorf("Failed to write soak report: %+v", err)
+		return
+	}
+
+	log.Infof("Wrote soak report %s", path)
+}
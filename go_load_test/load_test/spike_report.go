@@ -0,0 +1,80 @@
+package load_test
+
+import (
+	"fmt"
+	"time"
+)
+
+// Measures how long the server takes to recover after a spike, I.E until error rate + latency are back to what they
+// were before the spike.
+
+const (
+	spikeErrorRateSlack = 0.01 // Error rate within this of baseline counts as recovered
+	spikeLatencySlack   = 1.2  // p99 within this factor of baseline counts as recovered
+)
+
+type SpikeRecovery struct {
+	BaselineErrorRate float64       `json:"baseline_error_rate"`
+	BaselineP99Ms     float64       `json:"baseline_p99_ms"`
+	Recovered         bool          `json:"recovered"`
+	RecoveryTime      time.Duration `json:"-"`
+	RecoverySeconds   float64       `json:"recovery_seconds"` // Time from the end of the spike until recovered
+}
+
+func (r SpikeRecovery) String() string {
+	if !r.Recovered {
+		return fmt.Sprintf("Did not recover to baseline (error rate %.2f%%, p99 %.0fms) after the spike.",
+			r.BaselineErrorRate*100, r.BaselineP99Ms)
+	}
+
+	return fmt.Sprintf("Recovered to baseline (error rate %.2f%%, p99 %.0fms) %s after the spike.",
+		r.BaselineErrorRate*100, r.BaselineP99Ms, r.RecoveryTime.Truncate(time.Millisecond))
+}
+
+// spikeRecovery returns nil unless a spike profile has reached its recovery stage. Caller must hold resultLock.
+func (tr *TestResults) spikeRecovery() *SpikeRecovery {
+	if _, ok := tr.profile.(*Spike); !ok {
+		return nil
+	}
+
+	var baselineRequests, baselineFailures, baselineIntervals int
+	var baselineP99 float64
+	var spikeEnd time.Time
+	for _, stats := range tr.history {
+		switch stats.Stage {
+		case SpikeStageBaseline:
+			if stats.Requests == 0 {
+				continue
+			}
+			baselineRequests += stats.Successes + stats.Failures
+			baselineFailures += stats.Failures
+			baselineP99 += worstP99Ms(stats)
+			baselineIntervals++
+		case SpikeStageBurst:
+			spikeEnd = stats.Timestamp
+		}
+	}
+	if spikeEnd.IsZero() || baselineIntervals == 0 {
+		return nil
+	}
+
+	recovery := &SpikeRecovery{
+		BaselineErrorRate: 1 - passRate(baselineFailures, baselineRequests),
+		BaselineP99Ms:     baselineP99 / float64(baselineIntervals),
+	}
+	for _, stats := range tr.history {
+		if stats.Stage != SpikeStageRecovery || stats.Requests == 0 {
+			continue
+		}
+
+		errorRate := 1 - passRate(stats.Failures, stats.Successes+stats.Failures)
+		if errorRate <= recovery.BaselineErrorRate+spikeErrorRateSlack && worstP99Ms(stats) <= recovery.BaselineP99Ms*spikeLatencySlack {
+			recovery.Recovered = true
+			recovery.RecoveryTime = stats.Timestamp.Sub(spikeEnd)
+			recovery.RecoverySeconds = recovery.RecoveryTime.Seconds()
+			break
+		}
+	}
+
+	return recovery
+}
@@ -0,0 +1,105 @@
+package load_test
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+)
+
+// Summarizes each load profile stage, so throughput + latency can be compared across stages to find where adding
+// load stops adding throughput (I.E the knee of the curve).
+
+type StageSummary struct {
+	Stage           string               `json:"stage"`
+	Intervals       int                  `json:"intervals"`
+	DurationSeconds float64              `json:"duration_seconds"`
+	Requests        int                  `json:"requests"`
+	RequestsPerSec  float64              `json:"requests_per_sec"`
+	SuccessesPerSec float64              `json:"successes_per_sec"`
+	ErrorRate       float64              `json:"error_rate"`
+	WorstP99Ms      float64              `json:"worst_p99_ms"` // Highest p99 of any operation in any interval of the stage
+	Mix             map[TestType]float64 `json:"mix"`          // Achieved share of each operation
+}
+
+// stageSummaries groups interval history by stage, in the order stages ran. Caller must hold resultLock.
+func (tr *TestResults) stageSummaries() []StageSummary {
+	var summaries []StageSummary
+	var successes, failures int
+	var ops map[TestType]int
+	for _, stats := range tr.history {
+		if stats.Stage == "" {
+			continue
+		}
+		if len(summaries) == 0 || summaries[len(summaries)-1].Stage != stats.Stage {
+			summaries = append(summaries, StageSummary{Stage: stats.Stage})
+			successes, failures = 0, 0
+			ops = make(map[TestType]int, len(mixOperations))
+		}
+
+		current := &summaries[len(summaries)-1]
+		current.Intervals++
+		current.Requests += stats.Requests
+		successes += stats.Successes
+		failures += stats.Failures
+		current.DurationSeconds += stats.Duration.Seconds()
+		ops[GET] += stats.Gets
+		ops[PUT] += stats.Puts
+		ops[DELETE] += stats.Deletes
+		ops[CONSISTENCY] += stats.Consistency
+		if p99 := worstP99Ms(stats); p99 > current.WorstP99Ms {
+			current.WorstP99Ms = p99
+		}
+		if current.DurationSeconds > 0 {
+			current.RequestsPerSec = float64(current.Requests) / current.DurationSeconds
+			current.SuccessesPerSec = float64(successes) / current.DurationSeconds
+		}
+		current.ErrorRate = 1 - passRate(failures, successes+failures)
+		current.Mix = mixShares(ops)
+	}
+
+	return summaries
+}
+
+// worstP99Ms returns the highest p99 of any operation in the interval.
+func worstP99Ms(stats IntervalStats) float64 {
+	worst := 0.0
+	for _, latency := range stats.Latency {
+		if latency.P99Ms > worst {
+			worst = latency.P99Ms
+		}
+	}
+
+	return worst
+}
+
+// PrintStages prints a row per load profile stage, if the profile has stages.
+func (tr *TestResults) PrintStages() {
+	tr.resultLock.RLock()
+	stages := tr.stageSummaries()
+	tr.resultLock.RUnlock()
+	if len(stages) == 0 {
+		return
+	}
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+	tbl := table.New("Stage", "Duration (s)", "Requests", "req/sec", "Successful req/sec", "Error rate", "Worst p99 (ms)",
+		"Mix % GET/PUT/DELETE/CONSISTENCY")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, stage := range stages {
+		tbl.AddRow(stage.Stage, fmt.Sprintf("%.0f", stage.DurationSeconds), stage.Requests, fmt.Sprintf("%.1f", stage.RequestsPerSec),
+			fmt.Sprintf("%.1f", stage.SuccessesPerSec), fmt.Sprintf("%.2f%%", stage.ErrorRate*100), fmt.Sprintf("%.0f", stage.WorstP99Ms),
+			formatMix(stage.Mix))
+	}
+
+	fmt.Println()
+	tbl.Print()
+
+	tr.resultLock.RLock()
+	recovery := tr.spikeRecovery()
+	tr.resultLock.RUnlock()
+	if recovery != nil {
+		fmt.Println()
+		fmt.Println(recovery)
+	}
+}
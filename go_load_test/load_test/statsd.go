@@ -0,0 +1,100 @@
+package load_test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Pushes per interval counters + latency gauges to a StatsD (or DogStatsD) endpoint over UDP.
+
+const statsDMaxPacketSize = 1400
+
+type StatsDEmitter struct {
+	conn      net.Conn
+	prefix    string
+	dogStatsD bool
+}
+
+func NewStatsDEmitter(addr string, prefix string, dogStatsD bool) (*StatsDEmitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial statsd: %s. Error: %w", addr, err)
+	}
+
+	if prefix == "" {
+		prefix = "loadtest"
+	}
+
+	return &StatsDEmitter{conn: conn, prefix: prefix, dogStatsD: dogStatsD}, nil
+}
+
+// Write sends all metrics for the interval, batched into as few packets as possible.
+func (e *StatsDEmitter) Write(stats IntervalStats) error {
+	lines := []string{
+		e.line("requests", "", stats.Requests, "c"),
+		e.line("successes", "", stats.Successes, "c"),
+		e.line("failures", "", stats.Failures, "c"),
+		e.line("throttled", "", stats.Throttles, "c"),
+		e.line("count", "get", stats.Gets, "c"),
+		e.line("count", "put", stats.Puts, "c"),
+		e.line("count", "delete", stats.Deletes, "c"),
+		e.line("count", "consistency", stats.Consistency, "c"),
+	}
+	for _, op := range latencyOperations {
+		latency, ok := stats.Latency[op]
+		if !ok || latency.Count == 0 {
+			continue
+		}
+		operation := strings.ToLower(string(op))
+		lines = append(lines,
+			e.line("latency.mean", operation, latency.MeanMs, "g"),
+			e.line("latency.p50", operation, latency.P50Ms, "g"),
+			e.line("latency.p99", operation, latency.P99Ms, "g"),
+			e.line("latency.max", operation, latency.MaxMs, "g"),
+		)
+	}
+
+	return e.send(lines)
+}
+
+func (e *StatsDEmitter) Close() error {
+	return e.conn.Close()
+}
+
+// line formats a single metric. Operation is either a DogStatsD tag or a name segment.
+func (e *StatsDEmitter) line(name string, operation string, value interface{}, metricType string) string {
+	if operation == "" {
+		return fmt.Sprintf("%s.%s:%v|%s", e.prefix, name, value, metricType)
+	}
+
+	if e.dogStatsD {
+		return fmt.Sprintf("%s.%s:%v|%s|#operation:%s", e.prefix, name, value, metricType, operation)
+	}
+
+	return fmt.Sprintf("%s.%s.%s:%v|%s", e.prefix, operation, name, value, metricType)
+}
+
+func (e *StatsDEmitter) send(lines []string) error {
+	var packet strings.Builder
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > statsDMaxPacketSize {
+			_, err := e.conn.Write([]byte(packet.String()))
+			if err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteString("\n")
+		}
+		packet.WriteString(line)
+	}
+
+	if packet.Len() == 0 {
+		return nil
+	}
+	_, err := e.conn.Write([]byte(packet.String()))
+
+	return err
+}
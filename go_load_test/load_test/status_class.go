@@ -0,0 +1,40 @@
+package load_test
+
+// Groups responses by status class, so fast 429s + error paths get their own latency distribution rather than
+// pulling down the latency of successful requests.
+
+type StatusClass string
+
+const (
+	Status2XX        StatusClass = "2xx"
+	Status3XX        StatusClass = "3xx"
+	Status4XX        StatusClass = "4xx"
+	Status5XX        StatusClass = "5xx"
+	StatusNoResponse StatusClass = "none" // Transport errors, timeouts, etc.
+)
+
+var statusClasses = []StatusClass{Status2XX, Status3XX, Status4XX, Status5XX, StatusNoResponse}
+
+func statusClass(code int) StatusClass {
+	switch {
+	case code >= 500:
+		return Status5XX
+	case code >= 400:
+		return Status4XX
+	case code >= 300:
+		return Status3XX
+	case code >= 200:
+		return Status2XX
+	default:
+		return StatusNoResponse
+	}
+}
+
+func newStatusClassHistograms() map[StatusClass]*LatencyHistogram {
+	histograms := make(map[StatusClass]*LatencyHistogram, len(statusClasses))
+	for _, class := range statusClasses {
+		histograms[class] = NewLatencyHistogram()
+	}
+
+	return histograms
+}
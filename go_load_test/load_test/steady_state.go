@@ -0,0 +1,380 @@
+package load_test
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SteadyState keeps the measured phase running until the server settles, then stops the run, so results describe
+// how the server performs once caches, connection pools + queues have settled rather than the ramp up to it. Every
+// Window, throughput (successful req/sec) + p99 latency are measured. Once Windows consecutive windows are all within
+// Tolerance of their mean, the run is steady + shuts down, + only those windows are reported as the steady state. Their
+// requests, success rate + latency are then the run's headline numbers, see steadyTotals. If the run hasn't settled
+// after Max, it shuts down anyway + reports that it never settled. Specs are key=value params, I.E
+//
+//	tolerance=5% windows=5 window=10s max=30m
+//
+// Like the Autoscaler, it gets every interval from the aggregator as an IntervalExporter. Warmup isn't measured.
+type SteadyState struct {
+	Tolerance float64 // Fraction of the mean each window may differ by, I.E 0.05
+	Windows   int
+	Window    time.Duration
+	Max       time.Duration
+
+	lock         sync.Mutex
+	shutdownChan chan bool
+	started      time.Time // When the first measured interval started
+	last         time.Time // Timestamp of the last interval written
+	current      steadyWindow
+	windows      []steadyWindow // The last Windows completed windows
+	result       *SteadyStateResult
+}
+
+// steadyWindow is the totals for a window of intervals.
+type steadyWindow struct {
+	start     time.Time
+	after     time.Time // Timestamp of the interval before the window, zero if there wasn't one
+	end       time.Time // Timestamp of the window's last interval
+	duration  time.Duration
+	successes int
+	failures  int
+	worstP99  float64
+}
+
+type SteadyStateResult struct {
+	Steady          bool    `json:"steady"`
+	StartSeconds    float64 `json:"start_seconds"` // Since the measured phase started
+	Seconds         float64 `json:"seconds"`
+	Windows         int     `json:"windows"`
+	SuccessesPerSec float64 `json:"successes_per_sec"`
+	ErrorRate       float64 `json:"error_rate"`
+	P99Ms           float64 `json:"p99_ms"` // Worst window's p99
+	TolerancePct    float64 `json:"tolerance_pct"`
+
+	// Timestamps of the intervals either side of the steady state, to find its totals by, see steadyMark.
+	after time.Time
+	until time.Time
+}
+
+func (r SteadyStateResult) String() string {
+	if !r.Steady {
+		return fmt.Sprintf("Throughput + p99 never settled within %.1f%% over %d windows.", r.TolerancePct, r.Windows)
+	}
+
+	return fmt.Sprintf("Steady state from %.0fs for %.0fs: %.1f successful req/sec, %.2f%% errors, p99 %.0fms.",
+		r.StartSeconds, r.Seconds, r.SuccessesPerSec, r.ErrorRate*100, r.P99Ms)
+}
+
+// NewSteadyState parses spec. The run is shut down by closing shutdownChan once it's steady or Max has passed.
+func NewSteadyState(spec string, shutdownChan chan bool) (*SteadyState, error) {
+	fields := strings.Fields(spec)
+	params := make(profileParams, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid steady state param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	steady := &SteadyState{Tolerance: 0.05, Windows: 5, Window: time.Second * 10, Max: time.Minute * 30,
+		shutdownChan: shutdownChan}
+	if tolerance, ok := params["tolerance"]; ok {
+		var err error
+		steady.Tolerance, err = parseFraction(tolerance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid steady state tolerance: %w", err)
+		}
+		delete(params, "tolerance")
+	}
+	err := params.parse(map[string]interface{}{"windows": &steady.Windows, "window": &steady.Window, "max": &steady.Max})
+	if err != nil {
+		return nil, fmt.Errorf("invalid steady state spec: %w", err)
+	}
+	if steady.Tolerance <= 0 || steady.Windows < 2 || steady.Window <= 0 || steady.Max < steady.Window*time.Duration(steady.Windows) {
+		return nil, fmt.Errorf("invalid steady state spec: tolerance + window must be > 0, windows >= 2, + max must " +
+			"fit windows * window")
+	}
+
+	return steady, nil
+}
+
+// Write adds an interval to the window in progress, checking whether the run is steady once it has run for Window.
+func (s *SteadyState) Write(stats IntervalStats) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.result != nil {
+		return nil
+	}
+	after := s.last
+	s.last = stats.Timestamp
+	if stats.Stage == StageWarmup {
+		return nil
+	}
+
+	start := stats.Timestamp.Add(-stats.Duration)
+	if s.started.IsZero() {
+		s.started = start
+	}
+	if s.current.duration == 0 {
+		s.current.start = start
+		s.current.after = after
+	}
+	s.current.end = stats.Timestamp
+	s.current.duration += stats.Duration
+	s.current.successes += stats.Successes
+	s.current.failures += stats.Failures
+	s.current.worstP99 = math.Max(s.current.worstP99, requestP99Ms(stats))
+	if s.current.duration < s.Window {
+		return nil
+	}
+
+	s.windows = append(s.windows, s.current)
+	if len(s.windows) > s.Windows {
+		s.windows = s.windows[1:]
+	}
+	s.current = steadyWindow{}
+	switch {
+	case s.steady():
+		s.finish(true)
+		log.Infof("Reached steady state, shutting down. %s", s.result)
+		CloseShutdownChan(s.shutdownChan)
+	case stats.Timestamp.Sub(s.started) >= s.Max:
+		s.finish(false)
+		log.Infof("No steady state after %s, shutting down.", s.Max)
+		CloseShutdownChan(s.shutdownChan)
+	}
+
+	return nil
+}
+
+func (s *SteadyState) Close() error {
+	return nil
+}
+
+// steady returns true if the last Windows windows' throughput + p99 are all within Tolerance of their means. Caller
+// must hold lock.
+func (s *SteadyState) steady() bool {
+	if len(s.windows) < s.Windows {
+		return false
+	}
+
+	var throughput, p99 float64
+	for _, window := range s.windows {
+		throughput += window.perSecond()
+		p99 += window.worstP99
+	}
+	throughput /= float64(len(s.windows))
+	p99 /= float64(len(s.windows))
+	if throughput == 0 {
+		return false
+	}
+
+	for _, window := range s.windows {
+		if math.Abs(window.perSecond()-throughput) > throughput*s.Tolerance ||
+			math.Abs(window.worstP99-p99) > p99*s.Tolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// finish records the result from the last windows. Caller must hold lock.
+func (s *SteadyState) finish(steady bool) {
+	s.result = &SteadyStateResult{Steady: steady, Windows: s.Windows, TolerancePct: s.Tolerance * 100}
+	if !steady || len(s.windows) == 0 {
+		return
+	}
+
+	var duration time.Duration
+	var successes, failures int
+	for _, window := range s.windows {
+		duration += window.duration
+		successes += window.successes
+		failures += window.failures
+		s.result.P99Ms = math.Max(s.result.P99Ms, window.worstP99)
+	}
+	s.result.StartSeconds = s.windows[0].start.Sub(s.started).Seconds()
+	s.result.Seconds = duration.Seconds()
+	s.result.SuccessesPerSec = float64(successes) / duration.Seconds()
+	s.result.ErrorRate = 1 - passRate(failures, successes+failures)
+	s.result.after = s.windows[0].after
+	s.result.until = s.windows[len(s.windows)-1].end
+}
+
+// Result returns nil until the run has either reached a steady state or given up on one.
+func (s *SteadyState) Result() *SteadyStateResult {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.result
+}
+
+func (w steadyWindow) perSecond() float64 {
+	if w.duration <= 0 {
+		return 0
+	}
+
+	return float64(w.successes) / w.duration.Seconds()
+}
+
+// markSpan returns how long the aggregator must keep steadyMarks for, so the marks either side of the last Windows
+// windows are kept. Windows can run over by up to an interval each.
+func (s *SteadyState) markSpan(interval time.Duration) time.Duration {
+	return time.Duration(s.Windows+1) * (s.Window + interval)
+}
+
+func (s *SteadyState) String() string {
+	return fmt.Sprintf("tolerance=%g%% windows=%d window=%s max=%s", s.Tolerance*100, s.Windows, s.Window, s.Max)
+}
+
+// steadyStateResult returns nil unless the run looked for a steady state + has finished looking.
+func (tr *TestResults) steadyStateResult() *SteadyStateResult {
+	if tr.steadyState == nil {
+		return nil
+	}
+
+	return tr.steadyState.Result()
+}
+
+// headlineTotals are the numbers a run's results lead with.
+type headlineTotals struct {
+	duration          time.Duration
+	requests          int
+	successes         int
+	failures          int
+	consistency       int
+	failedConsistency int
+	latency           *LatencyHistogram
+	opLatency         map[TestType]*LatencyHistogram
+}
+
+// steadyMark is the run's headline totals at the end of an interval. The steady state's totals are the difference
+// between the marks either side of its windows.
+type steadyMark struct {
+	at     time.Time // The interval's Timestamp
+	totals headlineTotals
+}
+
+// headlineTotals returns the whole run's headline totals. The histograms aren't copied. Caller must hold resultLock.
+func (tr *TestResults) headlineTotals() headlineTotals {
+	return headlineTotals{
+		duration:          tr.elapsed(),
+		requests:          tr.numRequests.Get(),
+		successes:         tr.numSuccess.Get(),
+		failures:          tr.numFailure.Get(),
+		consistency:       tr.numConsistency.Get(),
+		failedConsistency: tr.numFailedConsistency.Get(),
+		latency:           tr.latency,
+		opLatency:         tr.opLatency,
+	}
+}
+
+// markSteadyState records the headline totals at the end of the interval with Timestamp at, until the run has either
+// reached a steady state or given up on one. Caller must hold resultLock.
+func (tr *TestResults) markSteadyState(at time.Time) {
+	if tr.steadyState == nil || tr.steadyState.Result() != nil {
+		return
+	}
+
+	tr.steadyMarks = append(tr.steadyMarks, steadyMark{at: at, totals: tr.headlineTotals().copy()})
+	oldest := at.Add(-tr.steadyState.markSpan(tr.interval))
+	expired := 0
+	for expired < len(tr.steadyMarks) && tr.steadyMarks[expired].at.Before(oldest) {
+		expired++
+	}
+	tr.steadyMarks = tr.steadyMarks[expired:]
+}
+
+// steadyTotals returns the headline totals of the steady state's windows. Returns false unless the run reached a steady
+// state. Caller must hold resultLock.
+func (tr *TestResults) steadyTotals() (headlineTotals, bool) {
+	result := tr.steadyStateResult()
+	if result == nil || !result.Steady {
+		return headlineTotals{}, false
+	}
+
+	// The steady state started with the run, so there's nothing before it
+	before := &headlineTotals{latency: NewLatencyHistogram(), opLatency: newOperationHistograms()}
+	if !result.after.IsZero() {
+		before = tr.steadyMark(result.after)
+	}
+	after := tr.steadyMark(result.until)
+	if before == nil || after == nil {
+		return headlineTotals{}, false
+	}
+
+	totals := after.since(*before)
+	totals.duration = time.Duration(result.Seconds * float64(time.Second))
+	return totals, true
+}
+
+// steadyMark returns the totals marked at the end of the interval with Timestamp at, or nil if it wasn't kept. Caller
+// must hold resultLock.
+func (tr *TestResults) steadyMark(at time.Time) *headlineTotals {
+	for i := range tr.steadyMarks {
+		if tr.steadyMarks[i].at.Equal(at) {
+			return &tr.steadyMarks[i].totals
+		}
+	}
+
+	return nil
+}
+
+// copy returns h with copies of its histograms.
+func (h headlineTotals) copy() headlineTotals {
+	copied := h
+	copied.latency = h.latency.Copy()
+	copied.opLatency = make(map[TestType]*LatencyHistogram, len(h.opLatency))
+	for op, hist := range h.opLatency {
+		copied.opLatency[op] = hist.Copy()
+	}
+
+	return copied
+}
+
+// since returns the totals recorded between earlier + h.
+func (h headlineTotals) since(earlier headlineTotals) headlineTotals {
+	totals := h.copy()
+	totals.requests -= earlier.requests
+	totals.successes -= earlier.successes
+	totals.failures -= earlier.failures
+	totals.consistency -= earlier.consistency
+	totals.failedConsistency -= earlier.failedConsistency
+	totals.latency.Subtract(earlier.latency)
+	for op, hist := range totals.opLatency {
+		hist.Subtract(earlier.opLatency[op])
+	}
+
+	return totals
+}
+
+func (h headlineTotals) successRate() float64 {
+	return passRate(h.failures, h.successes+h.failures)
+}
+
+func (h headlineTotals) summary() RunHeadline {
+	headline := RunHeadline{
+		DurationSeconds:     h.duration.Seconds(),
+		Requests:            h.requests,
+		Successes:           h.successes,
+		Failures:            h.failures,
+		ConsistencyChecks:   h.consistency,
+		ConsistencyFailures: h.failedConsistency,
+		SuccessRate:         h.successRate(),
+		ConsistencyRate:     passRate(h.failedConsistency, h.consistency),
+		Latency:             summarizeLatency(h.latency),
+		Operations:          make(map[TestType]LatencySummary, len(latencyOperations)),
+	}
+	for _, op := range latencyOperations {
+		headline.Operations[op] = summarizeLatency(h.opLatency[op])
+	}
+
+	return headline
+}
@@ -0,0 +1,96 @@
+package load_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewSteadyState(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *SteadyState
+		wantErr bool
+	}{
+		{"", &SteadyState{Tolerance: 0.05, Windows: 5, Window: 10 * time.Second, Max: 30 * time.Minute}, false},
+		{"tolerance=10% windows=3 window=5s max=1m",
+			&SteadyState{Tolerance: 0.1, Windows: 3, Window: 5 * time.Second, Max: time.Minute}, false},
+		{"tolerance=0", nil, true},
+		{"windows=1", nil, true},
+		{"windows=3 window=1m max=2m", nil, true}, // Can't fit 3 windows
+		{"window", nil, true},
+	}
+	for _, test := range tests {
+		got, err := NewSteadyState(test.spec, make(chan bool))
+		if (err != nil) != test.wantErr {
+			t.Errorf("NewSteadyState(%q): got error %v, want error %t", test.spec, err, test.wantErr)
+			continue
+		}
+		if err == nil && (got.Tolerance != test.want.Tolerance || got.Windows != test.want.Windows ||
+			got.Window != test.want.Window || got.Max != test.want.Max) {
+			t.Errorf("NewSteadyState(%q): got tolerance %g, windows %d, window %s, max %s, want %+v", test.spec,
+				got.Tolerance, got.Windows, got.Window, got.Max, test.want)
+		}
+	}
+}
+
+func TestSteadyTotals(t *testing.T) {
+	steadyState, err := NewSteadyState("windows=2 window=1s max=1m", make(chan bool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := NewResultAggregator(TestSchedulerConfig{SteadyState: steadyState}).Results
+	start := time.Now()
+	// A slow ramp up, then 2 steady windows
+	intervals := []struct {
+		tests   int
+		latency time.Duration
+	}{{10, 500 * time.Millisecond}, {50, 10 * time.Millisecond}, {50, 10 * time.Millisecond}}
+	for i, interval := range intervals {
+		for j := 0; j < interval.tests; j++ {
+			results.Merge(TestResult{testType: GET, duration: interval.latency, phases: NewRequestPhases(),
+				response: &http.Response{StatusCode: http.StatusOK}})
+		}
+		results.flushPending()
+		stats := IntervalStats{Timestamp: start.Add(time.Duration(i+1) * time.Second), Duration: time.Second,
+			Successes: interval.tests}
+		results.resultLock.Lock()
+		results.markSteadyState(stats.Timestamp)
+		results.resultLock.Unlock()
+		if err := steadyState.Write(stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if result := steadyState.Result(); result == nil || !result.Steady {
+		t.Fatalf("got steady state %v, want steady", result)
+	}
+	summary := results.Summary()
+	if summary.Requests != 100 || summary.Successes != 100 || summary.DurationSeconds != 2 {
+		t.Errorf("got %d requests, %d successes over %.0fs, want 100, 100 over 2s", summary.Requests,
+			summary.Successes, summary.DurationSeconds)
+	}
+	if summary.Latency.Count != 100 || summary.Latency.MaxMs > 11 || summary.Operations[GET].P99Ms > 11 {
+		t.Errorf("got %d latencies, max %.0fms + GET p99 %.0fms, want 100 + the ramp up excluded",
+			summary.Latency.Count, summary.Latency.MaxMs, summary.Operations[GET].P99Ms)
+	}
+	if summary.WholeRun == nil || summary.WholeRun.Requests != 110 || summary.WholeRun.Latency.MaxMs != 500 {
+		t.Errorf("got whole run %+v, want 110 requests, max 500ms", summary.WholeRun)
+	}
+}
+
+func TestLatencyHistogramSubtract(t *testing.T) {
+	hist := NewLatencyHistogram()
+	hist.Record(time.Millisecond)
+	earlier := hist.Copy()
+	hist.Record(5 * time.Millisecond)
+	hist.Record(9 * time.Millisecond)
+	hist.Subtract(earlier)
+
+	if hist.Count() != 2 || hist.Sum() != 14*time.Millisecond {
+		t.Errorf("got %d samples summing to %s, want 2 summing to 14ms", hist.Count(), hist.Sum())
+	}
+	if hist.Min() < 4900*time.Microsecond || hist.Min() > 5*time.Millisecond || hist.Max() != 9*time.Millisecond {
+		t.Errorf("got min %s + max %s, want ~5ms + 9ms", hist.Min(), hist.Max())
+	}
+}
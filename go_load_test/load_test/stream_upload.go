@@ -0,0 +1,168 @@
+package load_test
+
+import (
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"time"
+)
+
+// STREAM tests upload a new file of TestConfig.StreamSize bytes, generating the payload as it's sent rather than
+// buffering it, so the load generator can test files of hundreds of MB without running out of memory. Each upload's
+// bandwidth is reported. Streamed files are tracked like created ones, so the mix reads + deletes them afterwards.
+const STREAM TestType = "STREAM"
+
+// DefaultStreamSize is the size of streamed uploads, before base64 encoding.
+const DefaultStreamSize int64 = 256 << 20
+
+// streamChunkSize is how many payload bytes are generated at a time, a multiple of 3 so chunks encode without padding.
+const streamChunkSize = 3 << 16
+
+// streamPayload generates size random bytes from r + reads them base64 encoded, a chunk at a time.
+type streamPayload struct {
+	r         *Rand
+	remaining int64
+	raw       []byte
+	encoded   []byte
+	pending   []byte // Encoded bytes not read yet
+}
+
+func newStreamPayload(r *Rand, size int64) *streamPayload {
+	return &streamPayload{
+		r:         r,
+		remaining: size,
+		raw:       make([]byte, streamChunkSize),
+		encoded:   make([]byte, b64.StdEncoding.EncodedLen(streamChunkSize)),
+	}
+}
+
+func (p *streamPayload) Read(b []byte) (int, error) {
+	if len(p.pending) == 0 {
+		if p.remaining <= 0 {
+			return 0, io.EOF
+		}
+		chunk := p.raw
+		if int64(len(chunk)) > p.remaining {
+			chunk = chunk[:p.remaining]
+		}
+		err := p.r.Read(chunk)
+		if err != nil {
+			return 0, err
+		}
+		p.remaining -= int64(len(chunk))
+		n := b64.StdEncoding.EncodedLen(len(chunk))
+		b64.StdEncoding.Encode(p.encoded[:n], chunk)
+		p.pending = p.encoded[:n]
+	}
+
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+
+	return n, nil
+}
+
+func (tr *TestExecutor) StreamFile(test Test) {
+	ctx, result, done := tr.beginHeldTest(test, STREAM)
+	defer done()
+	fileName := test.fileName
+	trace, phases, start := result.trace, result.phases, result.started
+
+	size := tr.streamSize
+	if size <= 0 {
+		size = DefaultStreamSize
+	}
+	var payload io.Reader = newStreamPayload(newSeededRand(test.payloadSeed), size)
+	var hasher hash.Hash
+	if tr.checksums != nil {
+		// The payload is hashed as it's sent, so its checksum is known without keeping it.
+		hasher = sha256.New()
+		payload = io.TeeReader(payload, hasher)
+	}
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, payload, trace, phases)
+	if err != nil {
+		result.message = "Failed to initialize request for StreamFile"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+	// The body's length is known up front, so it's sent with a Content-Length rather than chunked.
+	req.ContentLength = int64(b64.StdEncoding.EncodedLen(int(size)))
+
+	response, err := tr.do(req, phases)
+	// Streamed payloads aren't kept, so whatever was remembered for the file no longer matches it.
+	tr.payloads.forget(fileName)
+	tr.etags.forget(fileName)
+	if hasher != nil {
+		var sum [sha256.Size]byte
+		hasher.Sum(sum[:0])
+		tr.checksums.recordSum(fileName, sum, response, err)
+	}
+	if err != nil {
+		result.response = response
+		result.message = "Error executing http streaming PUT request"
+		result.err = err
+		result.failed = true
+		result.duration = time.Now().Sub(start)
+		tr.emit(result)
+		return
+	}
+
+	body := responseToString(response)
+	result.response = response
+	result.message = body
+	result.failed = response.StatusCode >= 400
+	result.duration = time.Now().Sub(start)
+	result.bytesSent = req.ContentLength
+	result.bytesReceived = int64(len(body))
+	tr.emit(result)
+}
+
+// BandwidthSummary is the bandwidth individual requests achieved, in MB/sec.
+type BandwidthSummary struct {
+	Min  float64 `json:"min_mb_per_sec"`
+	Mean float64 `json:"mean_mb_per_sec"`
+	Max  float64 `json:"max_mb_per_sec"`
+}
+
+func (b BandwidthSummary) String() string {
+	return fmt.Sprintf("%.1f / %.1f / %.1f", b.Min, b.Mean, b.Max)
+}
+
+// bandwidthStats accumulates per request bandwidth.
+type bandwidthStats struct {
+	count int
+	min   float64
+	max   float64
+	total float64
+}
+
+// record adds a request that sent bytes in duration. Requests that failed before sending anything aren't counted.
+func (b *bandwidthStats) record(bytes int64, duration time.Duration) {
+	if bytes <= 0 || duration <= 0 {
+		return
+	}
+
+	mbPerSec := float64(bytes) / 1024 / 1024 / duration.Seconds()
+	if b.count == 0 || mbPerSec < b.min {
+		b.min = mbPerSec
+	}
+	if mbPerSec > b.max {
+		b.max = mbPerSec
+	}
+	b.total += mbPerSec
+	b.count++
+}
+
+// summary returns nil if no requests were recorded.
+func (b *bandwidthStats) summary() *BandwidthSummary {
+	if b.count == 0 {
+		return nil
+	}
+
+	return &BandwidthSummary{Min: b.min, Mean: b.total / float64(b.count), Max: b.max}
+}
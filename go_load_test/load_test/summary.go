@@ -0,0 +1,253 @@
+package load_test
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+)
+
+// Structured end of run summary, used for machine readable output.
+
+const maxSummaryErrorSamples = 5
+
+type LatencySummary struct {
+	Count  int64   `json:"count"`
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	P999Ms float64 `json:"p99_9_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+type RunSummary struct {
+	StartTime                   time.Time                       `json:"start_time"`
+	DurationSeconds             float64                         `json:"duration_seconds"`
+	Requests                    int                             `json:"requests"`
+	Successes                   int                             `json:"successes"`
+	Failures                    int                             `json:"failures"`
+	ConsistencyChecks           int                             `json:"consistency_checks"`
+	ConsistencyFailures         int                             `json:"consistency_failures"`
+	Http5XX                     int                             `json:"http_5xx"`
+	Throttled                   int                             `json:"throttled"`
+	SelfThrottled               int                             `json:"self_throttled"` // 429s the client backed off from
+	RetryAfter                  RetryAfterSummary               `json:"retry_after"`
+	BytesUploaded               int64                           `json:"bytes_uploaded"`
+	BytesDownloaded             int64                           `json:"bytes_downloaded"`
+	AvgUploadMBPerSec           float64                         `json:"avg_upload_mb_per_sec"`
+	AvgDownloadMBPerSec         float64                         `json:"avg_download_mb_per_sec"`
+	ReusedConnections           int                             `json:"reused_connections"`
+	NewConnections              int                             `json:"new_connections"`
+	ConnectionReuseRatio        float64                         `json:"connection_reuse_ratio"`
+	SuccessRate                 float64                         `json:"success_rate"`
+	ConsistencyRate             float64                         `json:"consistency_rate"`
+	MaxSuccessfulRequestsPerSec int                             `json:"max_successful_requests_per_sec"`
+	Score                       int                             `json:"score"`
+	Latency                     LatencySummary                  `json:"latency"`
+	Operations                  map[TestType]LatencySummary     `json:"operations"`
+	Phases                      map[RequestPhase]LatencySummary `json:"phases"`
+	StatusClasses               map[StatusClass]LatencySummary  `json:"status_classes"`
+	ScheduleLag                 LatencySummary                  `json:"schedule_lag"`
+	CoordinatedOmissionFixed    bool                            `json:"coordinated_omission_corrected"`
+	WarmupSeconds               float64                         `json:"warmup_seconds"`
+	Warmup                      LatencySummary                  `json:"warmup"` // Excluded from all other stats
+	Apdex                       Apdex                           `json:"apdex"`
+	ErrorBudget                 ErrorBudget                     `json:"error_budget"`
+	ConsistencyFailureSteps     ConsistencyFailures             `json:"consistency_failure_steps"`
+	HttpErrors                  int                             `json:"http_errors"`
+	OtherErrors                 int                             `json:"other_errors"`
+	HttpErrorSamples            []string                        `json:"http_error_samples"`
+	OtherErrorSamples           []string                        `json:"other_error_samples"`
+	OperationMix                OperationMixSummary             `json:"operation_mix"`
+	Arrivals                    string                          `json:"arrivals,omitempty"` // Open loop runs only, see Arrivals
+	VirtualUsers                *VirtualUserSummary             `json:"virtual_users,omitempty"`
+	Stages                      []StageSummary                  `json:"stages,omitempty"`
+	SpikeRecovery               *SpikeRecovery                  `json:"spike_recovery,omitempty"`
+	AutoTune                    *AutoTuneResult                 `json:"auto_tune,omitempty"`
+	Autoscale                   *AutoscaleResult                `json:"autoscale,omitempty"`
+	SteadyState                 *SteadyStateResult              `json:"steady_state,omitempty"`
+	WholeRun                    *RunHeadline                    `json:"whole_run,omitempty"`
+	Chaos                       *ChaosSummary                   `json:"chaos,omitempty"` // Faults injected on purpose, see Chaos
+	Timeouts                    TimeoutSummary                  `json:"timeouts,omitempty"`
+	Retries                     *RetrySummary                   `json:"retries,omitempty"`
+	Checksums                   *ChecksumSummary                `json:"checksums,omitempty"`
+	Listing                     *ListingSummary                 `json:"listing,omitempty"`
+	SizeLatency                 SizeLatencySummary              `json:"size_latency,omitempty"`
+	ExtraOps                    map[TestType]ExtraOpSummary     `json:"extra_ops,omitempty"`
+	ConsistencyPool             *ConsistencyPoolSummary         `json:"consistency_pool,omitempty"`
+	Drain                       *DrainSummary                   `json:"drain,omitempty"`
+	Scenarios                   []ScenarioSummary               `json:"scenarios,omitempty"`
+	Thresholds                  []ThresholdResult               `json:"thresholds"`
+	StatusCodes                 map[int]int                     `json:"status_codes"`
+	Targets                     map[string]TargetSummary        `json:"targets,omitempty"` // Only set with multiple targets
+	TopErrors                   []ErrorGroup                    `json:"top_errors"`
+}
+
+// RunHeadline is the numbers a summary leads with. Once a run reaches a steady state, the summary's are the steady
+// state's + WholeRun holds the whole run's. Score, thresholds + every other stat still cover the whole run.
+type RunHeadline struct {
+	DurationSeconds     float64                     `json:"duration_seconds"`
+	Requests            int                         `json:"requests"`
+	Successes           int                         `json:"successes"`
+	Failures            int                         `json:"failures"`
+	ConsistencyChecks   int                         `json:"consistency_checks"`
+	ConsistencyFailures int                         `json:"consistency_failures"`
+	SuccessRate         float64                     `json:"success_rate"`
+	ConsistencyRate     float64                     `json:"consistency_rate"`
+	Latency             LatencySummary              `json:"latency"`
+	Operations          map[TestType]LatencySummary `json:"operations"`
+}
+
+// Summary builds a snapshot of the run so far.
+func (tr *TestResults) Summary() RunSummary {
+	tr.resultLock.RLock()
+	defer tr.resultLock.RUnlock()
+
+	elapsed := tr.elapsed()
+	consistencyRate, successRate, score := tr.score(elapsed)
+	summary := RunSummary{
+		StartTime:                   tr.startTime,
+		DurationSeconds:             elapsed.Seconds(),
+		Requests:                    tr.numRequests.Get(),
+		Successes:                   tr.numSuccess.Get(),
+		Failures:                    tr.numFailure.Get(),
+		ConsistencyChecks:           tr.numConsistency.Get(),
+		ConsistencyFailures:         tr.numFailedConsistency.Get(),
+		Http5XX:                     tr.num500s.Get(),
+		Throttled:                   tr.numThrottled.Get(),
+		SelfThrottled:               tr.numSelfThrottled.Get(),
+		RetryAfter:                  tr.retryAfter.Summary(),
+		BytesUploaded:               tr.bytesUploaded.Load(),
+		BytesDownloaded:             tr.bytesDownloaded.Load(),
+		AvgUploadMBPerSec:           float64(tr.bytesUploaded.Load()) / 1024 / 1024 / elapsed.Seconds(),
+		ReusedConnections:           tr.numReusedConns,
+		NewConnections:              tr.numNewConns,
+		ConnectionReuseRatio:        tr.connectionReuseRatio(),
+		AvgDownloadMBPerSec:         float64(tr.bytesDownloaded.Load()) / 1024 / 1024 / elapsed.Seconds(),
+		SuccessRate:                 successRate,
+		ConsistencyRate:             consistencyRate,
+		MaxSuccessfulRequestsPerSec: tr.maxSeenSuccessfulRequestPerSec,
+		Score:                       score,
+		Latency:                     summarizeLatency(tr.latency),
+		Operations:                  make(map[TestType]LatencySummary, len(latencyOperations)),
+		Phases:                      make(map[RequestPhase]LatencySummary, len(requestPhases)),
+		StatusClasses:               make(map[StatusClass]LatencySummary, len(statusClasses)),
+		ScheduleLag:                 summarizeLatency(tr.scheduleLag),
+		CoordinatedOmissionFixed:    tr.correctOmission,
+		WarmupSeconds:               tr.warmup.Seconds(),
+		Warmup:                      summarizeLatency(tr.warmupLatency),
+		Apdex:                       tr.apdex,
+		ErrorBudget:                 tr.budget(),
+		ConsistencyFailureSteps:     tr.checkFailures.Copy(),
+		HttpErrors:                  tr.httpErrors.Total(),
+		OtherErrors:                 tr.otherErrors.Total(),
+		HttpErrorSamples:            tr.httpErrors.Last(maxSummaryErrorSamples),
+		OtherErrorSamples:           tr.otherErrors.Last(maxSummaryErrorSamples),
+		OperationMix:                tr.operationMix(),
+		Arrivals:                    tr.arrivalsSummary(),
+		VirtualUsers:                tr.virtualUsers(),
+		Stages:                      tr.stageSummaries(),
+		SpikeRecovery:               tr.spikeRecovery(),
+		AutoTune:                    tr.autoTuneResult(),
+		Autoscale:                   tr.autoscaleResult(),
+		SteadyState:                 tr.steadyStateResult(),
+		Chaos:                       tr.chaosSummary(),
+		Timeouts:                    tr.timeoutSummary(),
+		Retries:                     tr.retrySummary(),
+		Checksums:                   tr.checksumSummary(),
+		Listing:                     tr.listingSummary(),
+		SizeLatency:                 tr.sizeLatencySummary(),
+		ExtraOps:                    tr.extraOpSummaries(),
+		ConsistencyPool:             tr.consistencyPoolSummary(),
+		Drain:                       tr.drain.Summary(),
+		Scenarios:                   tr.scenarioSummaries(),
+		Thresholds:                  tr.evaluateThresholds(),
+		StatusCodes:                 make(map[int]int, len(tr.statusCodes)),
+		TopErrors:                   tr.errorGroups.Top(TopErrorCount),
+	}
+	for code, count := range tr.statusCodes {
+		summary.StatusCodes[code] = count
+	}
+	if tr.targetStats != nil {
+		summary.Targets = make(map[string]TargetSummary, len(tr.targetStats))
+		total := tr.targetRequests()
+		for label, target := range tr.targetStats {
+			summary.Targets[label] = target.Summary(total)
+		}
+	}
+	for _, op := range latencyOperations {
+		summary.Operations[op] = summarizeLatency(tr.opLatency[op])
+	}
+	for _, phase := range requestPhases {
+		summary.Phases[phase] = summarizeLatency(tr.phaseLatency[phase])
+	}
+	for _, class := range statusClasses {
+		summary.StatusClasses[class] = summarizeLatency(tr.statusLatency[class])
+	}
+	if steady, ok := tr.steadyTotals(); ok {
+		wholeRun := tr.headlineTotals().summary()
+		summary.WholeRun = &wholeRun
+		summary.setHeadline(steady.summary())
+	}
+
+	return summary
+}
+
+// setHeadline replaces s's headline numbers with headline.
+func (s *RunSummary) setHeadline(headline RunHeadline) {
+	s.DurationSeconds = headline.DurationSeconds
+	s.Requests = headline.Requests
+	s.Successes = headline.Successes
+	s.Failures = headline.Failures
+	s.ConsistencyChecks = headline.ConsistencyChecks
+	s.ConsistencyFailures = headline.ConsistencyFailures
+	s.SuccessRate = headline.SuccessRate
+	s.ConsistencyRate = headline.ConsistencyRate
+	s.Latency = headline.Latency
+	s.Operations = headline.Operations
+}
+
+// WriteSummary writes the run summary as indented JSON.
+func (tr *TestResults) WriteSummary(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tr.Summary())
+}
+
+// score returns the consistency rate, success rate and total score for a run of the given length.
+func (tr *TestResults) score(elapsed time.Duration) (float64, float64, int) {
+	scoreModifier := elapsed.Minutes() // longer running = better.
+	consistencyRate := passRate(tr.numFailedConsistency.Get(), tr.numConsistency.Get())
+	successRate := passRate(tr.numFailure.Get(), tr.numSuccess.Get()+tr.numFailure.Get())
+	score := int(math.Round(float64(tr.maxSeenSuccessfulRequestPerSec) * scoreModifier * consistencyRate * successRate))
+
+	return consistencyRate, successRate, score
+}
+
+// passRate returns the fraction of total that did not fail. An empty total counts as a perfect rate.
+func passRate(failures int, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+
+	return float64(1) - float64(failures)/float64(total)
+}
+
+func summarizeLatency(hist *LatencyHistogram) LatencySummary {
+	return LatencySummary{
+		Count:  hist.Count(),
+		MinMs:  durationMs(hist.Min()),
+		MeanMs: durationMs(hist.Mean()),
+		P50Ms:  durationMs(hist.Percentile(50)),
+		P90Ms:  durationMs(hist.Percentile(90)),
+		P99Ms:  durationMs(hist.Percentile(99)),
+		P999Ms: durationMs(hist.Percentile(99.9)),
+		MaxMs:  durationMs(hist.Max()),
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
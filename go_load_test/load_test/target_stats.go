@@ -0,0 +1,135 @@
+package load_test
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+	"time"
+)
+
+// Per target breakdown of results when load is spread over multiple file servers, to compare nodes during the same run.
+// With weighted targets, each target's share of requests is shown next to its configured weight, so a skewed split is
+// easy to spot.
+
+// TargetStats is not safe for concurrent use; callers are expected to hold their own lock.
+type TargetStats struct {
+	Label     string
+	Requests  int
+	Successes int
+	Failures  int
+	Throttled int
+	Http5XX   int
+	Weight    float64 // Configured fraction of files mapped to the target
+	latency   *LatencyHistogram
+}
+
+type TargetSummary struct {
+	Requests    int            `json:"requests"`
+	Successes   int            `json:"successes"`
+	Failures    int            `json:"failures"`
+	Throttled   int            `json:"throttled"`
+	Http5XX     int            `json:"http_5xx"`
+	SuccessRate float64        `json:"success_rate"`
+	Weight      float64        `json:"weight"` // Configured fraction of files mapped to the target
+	Share       float64        `json:"share"`  // Actual fraction of requests sent to the target
+	Latency     LatencySummary `json:"latency"`
+}
+
+func NewTargetStats(label string, weight float64) *TargetStats {
+	return &TargetStats{Label: label, Weight: weight, latency: NewLatencyHistogram()}
+}
+
+func (t *TargetStats) record(result TestResult, duration time.Duration) {
+	t.Requests++
+	if result.WasSuccess() {
+		t.Successes++
+	}
+	if result.WasTestFailure() {
+		t.Failures++
+	}
+	if result.WasThrottled() {
+		t.Throttled++
+	}
+	if result.Was5XX() {
+		t.Http5XX++
+	}
+	t.latency.Record(duration)
+}
+
+// Summary summarizes the target's results, out of totalRequests across every target.
+func (t *TargetStats) Summary(totalRequests int) TargetSummary {
+	return TargetSummary{
+		Requests:    t.Requests,
+		Successes:   t.Successes,
+		Failures:    t.Failures,
+		Throttled:   t.Throttled,
+		Http5XX:     t.Http5XX,
+		SuccessRate: t.successRate(),
+		Weight:      t.Weight,
+		Share:       t.share(totalRequests),
+		Latency:     summarizeLatency(t.latency),
+	}
+}
+
+func (t *TargetStats) successRate() float64 {
+	if t.Requests == 0 {
+		return 0
+	}
+
+	return float64(t.Successes) / float64(t.Requests)
+}
+
+func (t *TargetStats) share(totalRequests int) float64 {
+	if totalRequests == 0 {
+		return 0
+	}
+
+	return float64(t.Requests) / float64(totalRequests)
+}
+
+// targetRequests returns the # of requests across every target. Caller must hold resultLock.
+func (tr *TestResults) targetRequests() int {
+	total := 0
+	for _, target := range tr.targetStats {
+		total += target.Requests
+	}
+
+	return total
+}
+
+// newTargetStats returns stats for each target, or nil if there's only one since the combined results cover it.
+func newTargetStats(targets Targets) map[string]*TargetStats {
+	if len(targets) < 2 {
+		return nil
+	}
+
+	stats := make(map[string]*TargetStats, len(targets))
+	for _, target := range targets {
+		stats[target.Name()] = NewTargetStats(target.Name(), targets.Share(target))
+	}
+
+	return stats
+}
+
+// printTargets prints a row per target. Caller must hold resultLock.
+func (tr *TestResults) printTargets() {
+	if len(tr.targetStats) == 0 {
+		return
+	}
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+	tbl := table.New("Target", "Weight", "Share", "Requests", "Success", "Success %", "Failures", "Throttled", "5XX",
+		"p50 (ms)", "p99 (ms)")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	total := tr.targetRequests()
+	for _, target := range tr.targets {
+		t := tr.targetStats[target.Name()]
+		tbl.AddRow(t.Label, fmt.Sprintf("%.1f%%", t.Weight*100), fmt.Sprintf("%.1f%%", t.share(total)*100), t.Requests,
+			t.Successes, fmt.Sprintf("%.1f%%", t.successRate()*100), t.Failures, t.Throttled, t.Http5XX,
+			t.latency.Percentile(50).Milliseconds(), t.latency.Percentile(99).Milliseconds())
+	}
+
+	fmt.Println()
+	tbl.Print()
+}
@@ -2,41 +2,112 @@ package load_test
 
 import (
 	"bytes"
-	crand "crypto/rand"
+	"context"
 	b64 "encoding/base64"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"math/rand"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync"
 	"time"
 )
 
 type TestExecutor struct {
+	ctx                   context.Context // Requests are aborted once cancelled
 	client                *http.Client
 	inProcess             FileSet
 	maxFileSize           int64
 	inProcessLock         sync.RWMutex
 	results               chan TestResult
-	endpointCfg           TestEndpointConfig
+	targets               Targets
 	fileSizeLock          sync.RWMutex
 	uploadRandomLargeFile bool
+	traceRequests         bool
+	backoff               *ClientBackoff // Set if the client backs off from throttled requests, see ClientBackoff.
+	chaos                 *chaosInjector // Set if the client injects faults into requests, see Chaos.
+	requestTags           *RequestTags   // Set if requests are tagged with the run ID + a sequence #, see RequestTags.
+	timeouts              OperationTimeouts
+	retries               *RetryQueue   // Set if failed GETs + DELETEs are retried, see RetryQueue.
+	payloads              *PayloadStore // Set if tests check bytes against what was uploaded, see PayloadStore.
+	etags                 *ETagStore    // Set if conditional GETs are run, see ETagStore.
+	streamSize            int64
+	checksums             *ChecksumStore // Set if GETs are checked for corrupt data, see ChecksumStore.
+	deleteRecheckDelay    time.Duration  // If set, consistency checks GET the deleted file again after this long.
+	listCheck             *ListCheck     // Set if consistency checks verify listings, see ListCheck.
+	listAPI               *ListAPI       // Set if LIST tests can run, see ListAPI.
+	// Set if payload sizes are drawn from a distribution, see SizeDistribution.
+	sizes        *SizeDistribution
+	compressible float64        // Share of payloads that are compressible text, see fillPayload.
+	boundaries   *BoundarySizes // The sizes BOUNDARY tests probe, see BoundarySizes.
+	// Set if some uploads are empty files, which GETs check come back empty, see EmptyFileStore.
+	emptyFiles *EmptyFileStore
+	// Size of DOWNLOAD files, before base64 encoding.
+	downloadSize int64
 }
 
-func NewTestExecutor(client *http.Client, config TestEndpointConfig, testConfig TestConfig, resultsChan chan TestResult) *TestExecutor {
+// RunStores are what a process remembers about the files its tests upload, shared by every executor it runs. Each
+// process builds its own, so they're kept out of TestConfig, which is sent to agents.
+type RunStores struct {
+	Payloads *PayloadStore // If set, uploads are remembered so tests can check the bytes downloaded, see PayloadStore.
+	ETags    *ETagStore    // If set, ETags the server returns are remembered for conditional GETs, see ETagStore.
+}
+
+// NewRunStores returns the stores ops need, see NewPayloadStore + NewETagStore.
+func NewRunStores(ops ExtraOps) RunStores {
+	return RunStores{Payloads: NewPayloadStore(ops), ETags: NewETagStore(ops)}
+}
+
+func NewTestExecutor(ctx context.Context, client *http.Client, targets Targets, testConfig TestConfig, stores RunStores, resultsChan chan TestResult) *TestExecutor {
+	var backoff *ClientBackoff
+	if testConfig.ClientBackoff {
+		backoff = NewClientBackoff()
+	}
+	var checksums *ChecksumStore
+	if testConfig.VerifyChecksums {
+		checksums = NewChecksumStore()
+	}
+
 	return &TestExecutor{
+		ctx:                   ctx,
 		client:                client,
-		endpointCfg:           config,
+		targets:               targets,
 		inProcess:             make(map[string]bool),
 		maxFileSize:           testConfig.MaxFileSize,
 		inProcessLock:         sync.RWMutex{},
 		results:               resultsChan,
 		uploadRandomLargeFile: testConfig.UploadRandomLargeFile,
+		traceRequests:         testConfig.TraceRequests,
+		backoff:               backoff,
+		requestTags:           testConfig.RequestTags,
+		timeouts:              testConfig.Timeouts,
+		payloads:              stores.Payloads,
+		etags:                 stores.ETags,
+		streamSize:            testConfig.StreamSize,
+		checksums:             checksums,
+		deleteRecheckDelay:    testConfig.DeleteRecheckDelay,
+		listCheck:             testConfig.ListCheck,
+		listAPI:               testConfig.ListAPI,
+		sizes:                 testConfig.Sizes,
+		compressible:          testConfig.Compressible,
+		boundaries:            testConfig.Boundaries,
+		emptyFiles:            NewEmptyFileStore(testConfig.EmptyFiles),
+		downloadSize:          testConfig.DownloadSize,
 	}
 }
 
+// emit publishes result, marking 429s the client backs off from as self throttled, requests broken on purpose as
+// injected faults + requests that ran out of time as timeouts. Failed tests are queued for a retry if retried.
+func (tr *TestExecutor) emit(result TestResult) {
+	result.selfThrottled = tr.backoff.observe(result)
+	result.fault = chaosFault(result.err)
+	result.timedOut = isTimeout(result.err)
+	tr.retries.offer(result)
+	tr.results <- result
+}
+
 func (tr *TestExecutor) waitForOpenInProcess(fileName string) {
 	jitter := rand.Intn(100)
 
@@ -55,409 +126,807 @@ func (tr *TestExecutor) waitForOpenInProcess(fileName string) {
 	tr.inProcess.Add(fileName)
 }
 
-func (tr *TestExecutor) PutFile(fileName string) {
+func (tr *TestExecutor) PutFile(test Test) {
+	ctx, cancel := tr.testContext(PUT)
+	defer cancel()
+	fileName := test.fileName
+	trace := tr.newTraceContext()
+	phases := NewRequestPhases()
 	start := time.Now()
+	lag := scheduleLag(test.scheduledAt, start)
 	tr.waitForOpenInProcess(fileName)
 	defer func() {
 		tr.inProcessLock.Lock()
 		tr.inProcess.Delete(fileName)
 		tr.inProcessLock.Unlock()
 	}()
-	fileSize := tr.randomFileSize()
+	payload := newSeededRand(test.payloadSeed)
+	fileSize := tr.uploadFileSize(payload)
 	fileBytes := make([]byte, fileSize)
-	_, err := crand.Read(fileBytes)
+	err := tr.fillPayload(payload, fileBytes)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: PUT,
 			response: nil,
 			message:  "Failed to generate random file bytes",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	byteString := b64.StdEncoding.EncodeToString(fileBytes)
-	req, err := http.NewRequest(http.MethodPut, tr.buildPath(fileName), strings.NewReader(byteString))
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(byteString), trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: PUT,
 			response: nil,
 			message:  "Failed to initialize request for PutFile",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	response, err := tr.client.Do(req)
+	response, err := tr.do(req, phases)
+	tr.payloads.record(fileName, byteString, response, err)
+	tr.etags.uploaded(fileName, response, err)
+	tr.checksums.record(fileName, byteString, response, err)
+	tr.emptyFiles.record(fileName, byteString, response, err)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: PUT,
 			response: response,
 			message:  "Error executing http request",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	tr.results <- TestResult{
-		fileName: fileName,
-		testType: PUT,
-		response: response,
-		message:  responseToString(response),
-		err:      err,
-		duration: time.Now().Sub(start),
-	}
+	body := responseToString(response)
+	tr.emit(TestResult{
+		fileName:      fileName,
+		trace:         trace,
+		phases:        phases,
+		lag:           lag,
+		started:       start,
+		worker:        test.worker,
+		testType:      PUT,
+		response:      response,
+		message:       body,
+		err:           err,
+		duration:      time.Now().Sub(start),
+		bytesSent:     int64(len(byteString)),
+		bytesReceived: int64(len(body)),
+	})
 }
 
-func (tr *TestExecutor) CreateFile(fileName string) {
+func (tr *TestExecutor) CreateFile(test Test) {
+	ctx, cancel := tr.testContext(CREATE)
+	defer cancel()
+	fileName := test.fileName
+	trace := tr.newTraceContext()
+	phases := NewRequestPhases()
 	tr.waitForOpenInProcess(fileName)
 	start := time.Now()
+	lag := scheduleLag(test.scheduledAt, start)
 	defer func() {
 		tr.inProcessLock.Lock()
 		tr.inProcess.Delete(fileName)
 		tr.inProcessLock.Unlock()
 	}()
-	fileSize := rand.Int63n(tr.maxFileSize) + 1
+	payload := newSeededRand(test.payloadSeed)
+	var fileSize int64
+	if !tr.emptyPayload(payload) {
+		fileSize = payload.Int63n(tr.maxFileSize) + 1
+		if tr.sizes != nil {
+			fileSize = tr.sizes.next(payload)
+		}
+	}
 	fileBytes := make([]byte, fileSize)
-	_, err := crand.Read(fileBytes)
+	err := tr.fillPayload(payload, fileBytes)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CREATE,
 			response: nil,
 			message:  "Failed to generate random file bytes",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	byteString := b64.StdEncoding.EncodeToString(fileBytes)
-	req, err := http.NewRequest(http.MethodPut, tr.buildPath(fileName), strings.NewReader(byteString))
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(byteString), trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CREATE,
 			response: nil,
 			message:  "Failed to initialize request for PutFile",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	response, err := tr.client.Do(req)
+	response, err := tr.do(req, phases)
+	tr.payloads.record(fileName, byteString, response, err)
+	tr.etags.uploaded(fileName, response, err)
+	tr.checksums.record(fileName, byteString, response, err)
+	tr.emptyFiles.record(fileName, byteString, response, err)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CREATE,
 			response: response,
 			message:  "Error executing http request",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	tr.results <- TestResult{
-		fileName: fileName,
-		testType: CREATE,
-		response: response,
-		message:  responseToString(response),
-		err:      err,
-		failed:   response.StatusCode >= 400,
-		duration: time.Now().Sub(start),
-	}
+	body := responseToString(response)
+	tr.emit(TestResult{
+		fileName:      fileName,
+		trace:         trace,
+		phases:        phases,
+		lag:           lag,
+		started:       start,
+		worker:        test.worker,
+		testType:      CREATE,
+		response:      response,
+		message:       body,
+		err:           err,
+		failed:        response.StatusCode >= 400,
+		duration:      time.Now().Sub(start),
+		bytesSent:     int64(len(byteString)),
+		bytesReceived: int64(len(body)),
+	})
 }
 
-func (tr *TestExecutor) GetFile(fileName string) {
+func (tr *TestExecutor) GetFile(test Test) {
+	ctx, cancel := tr.testContext(GET)
+	defer cancel()
+	fileName := test.fileName
+	trace := tr.newTraceContext()
+	phases := NewRequestPhases()
 	start := time.Now()
-	response, err := tr.client.Get(tr.buildPath(fileName))
+	lag := scheduleLag(test.scheduledAt, start)
+	check := tr.startChecksumCheck(fileName)
+	empty := tr.startEmptyCheck(fileName)
+	response, err := tr.get(ctx, fileName, trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
+			attempt:  test.attempt,
 			testType: GET,
 			response: response,
 			message:  "Error executing http GET request",
 			err:      err,
 			duration: time.Now().Sub(start),
 			failed:   true,
-		}
+		})
 		return
 	}
 
-	tr.results <- TestResult{
-		fileName: fileName,
-		testType: GET,
-		response: response,
-		message:  responseToString(response),
-		err:      err,
-		failed:   response.StatusCode >= 400,
-		duration: time.Now().Sub(start),
+	body := responseToString(response)
+	tr.etags.downloaded(fileName, response)
+	result := TestResult{
+		fileName:      fileName,
+		trace:         trace,
+		phases:        phases,
+		lag:           lag,
+		started:       start,
+		worker:        test.worker,
+		attempt:       test.attempt,
+		testType:      GET,
+		response:      response,
+		message:       body,
+		err:           err,
+		failed:        response.StatusCode >= 400,
+		duration:      time.Now().Sub(start),
+		bytesReceived: int64(len(body)),
+	}
+	if response.StatusCode == http.StatusOK && check.checked {
+		result.checksum = tr.verifyChecksum(check, fileName, body)
+		if result.checksum == checksumCorrupt {
+			result.mismatch = "checksum mismatch"
+			result.message = "Downloaded bytes don't match the checksum of the last upload"
+			result.failed = true
+		}
+	}
+	// Only checked if the file wasn't written or deleted during the GET, its content would be ambiguous.
+	if response.StatusCode == http.StatusOK && empty && tr.startEmptyCheck(fileName) {
+		if mismatch := emptyMismatch(response, body); mismatch != "" {
+			result.mismatch = mismatch
+			result.message = fmt.Sprintf("GET of an empty file returned Content-Length %d + %d bytes, expected 0",
+				response.ContentLength, len(body))
+			result.failed = true
+		}
 	}
+	tr.emit(result)
 }
 
-func (tr *TestExecutor) DeleteFile(fileName string) {
+func (tr *TestExecutor) DeleteFile(test Test) {
+	ctx, cancel := tr.testContext(DELETE)
+	defer cancel()
+	fileName := test.fileName
+	trace := tr.newTraceContext()
+	phases := NewRequestPhases()
 	tr.waitForOpenInProcess(fileName)
 	start := time.Now()
+	lag := scheduleLag(test.scheduledAt, start)
 
 	defer func() {
 		tr.inProcessLock.Lock()
 		tr.inProcess.Delete(fileName)
 		tr.inProcessLock.Unlock()
 	}()
+	// Whether or not the delete succeeds, the file's content is no longer known.
+	tr.payloads.forget(fileName)
+	tr.etags.forget(fileName)
+	tr.checksums.forget(fileName)
+	tr.emptyFiles.forget(fileName)
 
-	req, err := http.NewRequest(http.MethodDelete, tr.buildPath(fileName), nil)
+	req, err := tr.newRequest(ctx, http.MethodDelete, fileName, nil, trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
+			attempt:  test.attempt,
 			testType: DELETE,
 			response: nil,
 			message:  "Failed ot build delete request.",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	response, err := tr.client.Do(req)
+	response, err := tr.do(req, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
+			attempt:  test.attempt,
 			testType: DELETE,
 			response: response,
 			message:  "Error executing http DELETE request",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	tr.results <- TestResult{
-		fileName: fileName,
-		testType: DELETE,
-		response: response,
-		message:  responseToString(response),
-		err:      err,
-		failed:   response.StatusCode >= 400,
-		duration: time.Now().Sub(start),
-	}
+	body := responseToString(response)
+	tr.emit(TestResult{
+		fileName:      fileName,
+		trace:         trace,
+		phases:        phases,
+		lag:           lag,
+		started:       start,
+		worker:        test.worker,
+		attempt:       test.attempt,
+		testType:      DELETE,
+		response:      response,
+		message:       body,
+		err:           err,
+		failed:        response.StatusCode >= 400,
+		duration:      time.Now().Sub(start),
+		bytesReceived: int64(len(body)),
+	})
 }
 
-func (tr *TestExecutor) ConsistencyCheck(fileName string) {
+func (tr *TestExecutor) ConsistencyCheck(test Test) {
+	ctx, cancel := tr.testContext(CONSISTENCY)
+	defer cancel()
+	fileName := test.fileName
+	trace := tr.newTraceContext()
+	phases := NewRequestPhases()
 	tr.waitForOpenInProcess(fileName)
 	start := time.Now()
+	lag := scheduleLag(test.scheduledAt, start)
+	check := ConsistencyCreate
 	defer func() {
 		tr.inProcessLock.Lock()
 		tr.inProcess.Delete(fileName)
 		tr.inProcessLock.Unlock()
 	}()
 
-	fileSize := tr.randomFileSize()
+	payload := newSeededRand(test.payloadSeed)
+	fileSize := tr.uploadFileSize(payload)
 	fileBytes := make([]byte, fileSize)
-	_, err := crand.Read(fileBytes)
+	err := tr.fillPayload(payload, fileBytes)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: nil,
 			message:  "Failed to create file",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	// Perform write
 	byteString := b64.StdEncoding.EncodeToString(fileBytes)
-	req, err := http.NewRequest(http.MethodPut, tr.buildPath(fileName), strings.NewReader(byteString))
+	req, err := tr.newRequest(ctx, http.MethodPut, fileName, strings.NewReader(byteString), trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: nil,
 			message:  "Failed to initialize request for PutFile",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	response, err := tr.client.Do(req)
+	response, err := tr.do(req, phases)
+	written := time.Now()
 	if err != nil {
-		tr.results <- TestResult{
+		result := TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  "Error executing http request",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
 		}
+		if chaosFault(err) == ChaosTruncate {
+			result = tr.verifyTruncated(ctx, result)
+		}
+		tr.emit(result)
 		return
 	}
 
 	if response.StatusCode != http.StatusCreated {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  fmt.Sprintf("PUT failed due to unexpected status code, got: %d but expected 201.", response.StatusCode),
+			mismatch: statusMismatch(response.StatusCode, http.StatusCreated),
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	// Fetch immediately after write, verify data is consistent.
-	response, err = tr.client.Get(tr.buildPath(fileName))
+	check = ConsistencyRead
+	response, err = tr.get(ctx, fileName, trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  "Error executing http GET request",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	if response.StatusCode != http.StatusOK {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  fmt.Sprintf("GET failed due to unexpected status code, got: %d but expected 200.", response.StatusCode),
+			mismatch: statusMismatch(response.StatusCode, http.StatusOK),
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  fmt.Sprintf("Error decoding response body: %s", err.Error()),
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
+	if byteString == "" {
+		if mismatch := emptyMismatch(response, string(body)); mismatch != "" {
+			tr.emit(TestResult{
+				fileName: fileName,
+				trace:    trace,
+				phases:   phases,
+				lag:      lag,
+				started:  start,
+				worker:   test.worker,
+				testType: CONSISTENCY,
+				check:    check,
+				response: response,
+				message: fmt.Sprintf("GET of an empty file returned Content-Length %d + %d bytes, expected 0",
+					response.ContentLength, len(body)),
+				mismatch: mismatch,
+				failed:   true,
+				duration: time.Now().Sub(start),
+			})
+			return
+		}
+	}
+
 	if string(body) != byteString {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  "Written and read body are not identical! Inconsistent data returned",
+			mismatch: bodyMismatch(byteString, string(body)),
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	req, err = http.NewRequest(http.MethodDelete, tr.buildPath(fileName), nil)
+	var listing []ListingObservation
+	if tr.listCheck != nil {
+		check = ConsistencyListed
+		observation, listResponse, listErr := tr.awaitListing(ctx, fileName, true, written, trace, phases)
+		if observation.Step != "" {
+			listing = append(listing, observation)
+		}
+		if message, mismatch := tr.listingFailure(fileName, observation, listResponse, listErr); message != "" {
+			tr.emit(TestResult{
+				fileName: fileName,
+				trace:    trace,
+				phases:   phases,
+				lag:      lag,
+				started:  start,
+				worker:   test.worker,
+				testType: CONSISTENCY,
+				check:    check,
+				response: listResponse,
+				message:  message,
+				mismatch: mismatch,
+				err:      listErr,
+				failed:   true,
+				listing:  listing,
+				duration: time.Now().Sub(start),
+			})
+			return
+		}
+	}
+
+	check = ConsistencyDelete
+	req, err = tr.newRequest(ctx, http.MethodDelete, fileName, nil, trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: nil,
 			message:  "Failed to create delete request",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	response, err = tr.client.Do(req)
+	response, err = tr.do(req, phases)
+	deleted := time.Now()
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  "Error executing http DELETE request",
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	if response.StatusCode != http.StatusOK {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  fmt.Sprintf("DELETE failed due to unexpected status code, got: %d but expected 200.", response.StatusCode),
+			mismatch: statusMismatch(response.StatusCode, http.StatusOK),
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	response, err = tr.client.Get(tr.buildPath(fileName))
+	check = ConsistencyVerifyDelete
+	response, err = tr.get(ctx, fileName, trace, phases)
 	if err != nil {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  fmt.Sprintf("Error performing GET for deleted file in consistent test. file: %s. Error: %s", fileName, err.Error()),
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
 	if response.StatusCode != http.StatusNotFound {
-		tr.results <- TestResult{
+		tr.emit(TestResult{
 			fileName: fileName,
+			trace:    trace,
+			phases:   phases,
+			lag:      lag,
+			started:  start,
+			worker:   test.worker,
 			testType: CONSISTENCY,
+			check:    check,
 			response: response,
 			message:  fmt.Sprintf("File was deleted but received non-404 http code on immediate get. Got: %d for file: %s", response.StatusCode, fileName),
+			mismatch: statusMismatch(response.StatusCode, http.StatusNotFound),
 			err:      err,
 			failed:   true,
 			duration: time.Now().Sub(start),
-		}
+		})
 		return
 	}
 
-	tr.results <- TestResult{
-		fileName: fileName,
-		testType: CONSISTENCY,
-		response: response,
-		message:  "Consistency check passed!",
-		err:      nil,
-		failed:   false,
-		duration: time.Now().Sub(start),
+	if tr.listCheck != nil {
+		check = ConsistencyUnlisted
+		observation, listResponse, listErr := tr.awaitListing(ctx, fileName, false, deleted, trace, phases)
+		if observation.Step != "" {
+			listing = append(listing, observation)
+		}
+		if message, mismatch := tr.listingFailure(fileName, observation, listResponse, listErr); message != "" {
+			tr.emit(TestResult{
+				fileName: fileName,
+				trace:    trace,
+				phases:   phases,
+				lag:      lag,
+				started:  start,
+				worker:   test.worker,
+				testType: CONSISTENCY,
+				check:    check,
+				response: listResponse,
+				message:  message,
+				mismatch: mismatch,
+				err:      listErr,
+				failed:   true,
+				listing:  listing,
+				duration: time.Now().Sub(start),
+			})
+			return
+		}
+	}
+
+	result := TestResult{
+		fileName:      fileName,
+		trace:         trace,
+		phases:        phases,
+		lag:           lag,
+		started:       start,
+		worker:        test.worker,
+		testType:      CONSISTENCY,
+		check:         check,
+		response:      response,
+		message:       "Consistency check passed!",
+		err:           nil,
+		failed:        false,
+		bytesSent:     int64(len(byteString)),
+		bytesReceived: int64(len(body)),
+		listing:       listing,
+		duration:      time.Now().Sub(start),
+	}
+	if tr.deleteRecheckDelay > 0 {
+		result = tr.verifyDeletedLater(ctx, result)
+	}
+	tr.emit(result)
+}
+
+// verifyTruncated checks the server didn't keep the partial file from a consistency check's upload that was truncated
+// on purpose. Returns result unchanged if it didn't, or a failed result for the check if it did.
+func (tr *TestExecutor) verifyTruncated(ctx context.Context, result TestResult) TestResult {
+	response, err := tr.get(ctx, result.fileName, result.trace, result.phases)
+	if err != nil || response.StatusCode != http.StatusOK {
+		_ = responseToString(response)
+		return result
+	}
+
+	body := responseToString(response)
+	result.check = ConsistencyVerifyTruncate
+	result.response = response
+	result.message = fmt.Sprintf("Upload was truncated but the server kept a file of %d bytes for file: %s", len(body),
+		result.fileName)
+	result.mismatch = statusMismatch(response.StatusCode, http.StatusNotFound)
+	result.err = nil
+	result.duration = time.Now().Sub(result.started)
+	return result
+}
+
+// verifyDeletedLater GETs a consistency check's deleted file again once deleteRecheckDelay has passed, to catch
+// servers serving stale cached content after a delete. Returns result for the step if the file is still gone, or a
+// failed result if it isn't. The wait isn't counted in the check's duration, but does count against its timeout.
+func (tr *TestExecutor) verifyDeletedLater(ctx context.Context, result TestResult) TestResult {
+	waitStart := time.Now()
+	timer := time.NewTimer(tr.deleteRecheckDelay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		if tr.ctx.Err() != nil {
+			// Shutting down, the immediate check is all there is.
+			return result
+		}
+	case <-timer.C:
+	}
+	waited := time.Now().Sub(waitStart)
+
+	result.check = ConsistencyVerifyDeleteLater
+	response, err := tr.get(ctx, result.fileName, result.trace, result.phases)
+	result.response = response
+	result.duration = time.Now().Sub(result.started) - waited
+	if err != nil {
+		result.message = fmt.Sprintf("Error performing delayed GET for deleted file in consistent test. file: %s. Error: %s",
+			result.fileName, err.Error())
+		result.err = err
+		result.failed = true
+		return result
 	}
+
+	_ = responseToString(response)
+	if response.StatusCode != http.StatusNotFound {
+		result.message = fmt.Sprintf("File was deleted but received non-404 http code on get %s later. Got: %d for file: %s",
+			tr.deleteRecheckDelay, response.StatusCode, result.fileName)
+		result.mismatch = statusMismatch(response.StatusCode, http.StatusNotFound)
+		result.failed = true
+	}
+
+	return result
 }
 
 func (tr *TestExecutor) SetMaxFileSize(maxSize int64) {
@@ -472,16 +941,21 @@ func (tr *TestExecutor) GetMaxFileSize() int64 {
 	return tr.maxFileSize
 }
 
-// Returns a random file size that is less than the curren set maxFileSize
-func (tr *TestExecutor) randomFileSize() int64 {
+// Returns a random file size that is less than the curren set maxFileSize, drawn from r, or from the size
+// distribution if set.
+func (tr *TestExecutor) randomFileSize(r *Rand) int64 {
+	if tr.sizes != nil {
+		return tr.sizes.next(r)
+	}
+
 	// To prevent IO limits, prefer smaller sizes _most_ of the time.
 	tr.fileSizeLock.RLock()
 	defer tr.fileSizeLock.RUnlock()
-	size := rand.Int63n(tr.maxFileSize) + 1
+	size := r.Int63n(tr.maxFileSize) + 1
 	if size > tr.maxFileSize/2 {
 		// if size > 50% of max size, only keep it 20% of the time, else, reduce size by 50%, this means we'll have a
 		// lot more small files than large ones, but total size will continue to grow
-		keepSize := rand.Int63n(10) >= 8
+		keepSize := r.Int63n(10) >= 8
 		if !keepSize {
 			size = size / 2
 		}
@@ -490,7 +964,7 @@ func (tr *TestExecutor) randomFileSize() int64 {
 
 	// Roll 1 in 1000 chance to return a HUGE file
 	if tr.uploadRandomLargeFile {
-		uploadHugeFile := rand.Int63n(100) == 1
+		uploadHugeFile := r.Int63n(100) == 1
 		if uploadHugeFile {
 			log.Warnf("UPLOADING HUGE FILE!")
 			size = HugeFileSize
@@ -500,8 +974,81 @@ func (tr *TestExecutor) randomFileSize() int64 {
 	return size
 }
 
+// newRequest builds a request for fileName, propagating the test's trace context if tracing is enabled, and
+// recording request phase timings into phases.
+func (tr *TestExecutor) newRequest(ctx context.Context, method string, fileName string, body io.Reader, trace traceContext, phases *RequestPhases) (*http.Request, error) {
+	return tr.newURLRequest(ctx, method, tr.buildPath(fileName), body, trace, phases)
+}
+
+// newURLRequest is newRequest for a URL other than a file's, I.E a listing endpoint.
+func (tr *TestExecutor) newURLRequest(ctx context.Context, method string, url string, body io.Reader, trace traceContext, phases *RequestPhases) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if trace.Valid() {
+		req.Header.Set("traceparent", trace.TraceParent())
+	}
+	phases.sent(tr.requestTags.tag(req))
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), phases.ClientTrace())), nil
+}
+
+func (tr *TestExecutor) do(req *http.Request, phases *RequestPhases) (*http.Response, error) {
+	fault := tr.chaos.pick(req)
+	switch fault {
+	case ChaosDrop:
+		return nil, ChaosError{Fault: fault}
+	case ChaosTruncate:
+		truncate(req)
+	}
+
+	response, err := tr.client.Do(req)
+	phases.WrapResponse(response)
+	// A server that answers before reading the whole upload, or a download that isn't a file, isn't broken by the fault.
+	if fault == ChaosTruncate && err != nil {
+		return response, ChaosError{Fault: fault, Err: err}
+	}
+	if fault == ChaosAbort && err == nil && response.StatusCode == http.StatusOK {
+		abortDownload(response)
+		return response, ChaosError{Fault: fault}
+	}
+
+	return response, err
+}
+
+func (tr *TestExecutor) get(ctx context.Context, fileName string, trace traceContext, phases *RequestPhases) (*http.Response, error) {
+	req, err := tr.newRequest(ctx, http.MethodGet, fileName, nil, trace, phases)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.do(req, phases)
+}
+
+// scheduleLag returns how late a test started relative to when the scheduler intended it to. Tests with no intended
+// start time (I.E run outside the scheduler) have no lag.
+func scheduleLag(scheduledAt time.Time, start time.Time) time.Duration {
+	if scheduledAt.IsZero() || start.Before(scheduledAt) {
+		return 0
+	}
+
+	return start.Sub(scheduledAt)
+}
+
+// newTraceContext returns a fresh trace context, or an empty one if tracing is disabled.
+func (tr *TestExecutor) newTraceContext() traceContext {
+	if !tr.traceRequests {
+		return traceContext{}
+	}
+
+	return newTraceContext()
+}
+
 func (tr *TestExecutor) buildPath(fileName string) string {
-	return fmt.Sprintf("%s://%s:%s/%s/%s", tr.endpointCfg.Proto, tr.endpointCfg.Host, tr.endpointCfg.Port, tr.endpointCfg.PathPrefix, fileName)
+	target := tr.targets.For(fileName)
+	return fmt.Sprintf("%s://%s:%s/%s/%s", target.Proto, target.Host, target.Port, target.PathPrefix, fileName)
 }
 
 func responseToString(resp *http.Response) string {
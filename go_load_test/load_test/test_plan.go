@@ -0,0 +1,137 @@
+package load_test
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"time"
+)
+
+// TestPlan is a load profile made up of sequential named stages, I.E warmup, ramp, steady, spike + cooldown, each
+// with its own duration, rate + mix of operations. The run ends after the last stage. Plans are loaded from a YAML
+// file, I.E
+//
+//	stages:
+//	  - {name: warmup, duration: 30s, rate: 10}
+//	  - {name: ramp, duration: 2m, from: 10, rate: 200} # Ramps linearly from -> rate over the stage
+//	  - {name: steady, duration: 5m, rate: 200, mix: 80/15/4/1}
+//	  - {name: spike, duration: 30s, rate: 1000, mix: 95/4/1/0}
+//	  - {name: cooldown, duration: 1m, rate: 10}
+type TestPlan struct {
+	Stages []PlanStage `yaml:"stages"`
+}
+
+type PlanStage struct {
+	Name     string        `yaml:"name"`
+	Duration time.Duration `yaml:"duration"`
+	From     int           `yaml:"from"` // If set, the rate ramps linearly from this to Rate over the stage
+	Rate     int           `yaml:"rate"`
+	Mix      OperationMix  `yaml:"mix"` // If unset, the run's mix is used. See ParseOperationMix.
+}
+
+// LoadTestPlan reads + validates a test plan file.
+func LoadTestPlan(path string) (*TestPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test plan: %s. Error: %w", path, err)
+	}
+
+	plan := &TestPlan{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse test plan: %s. Error: %w", path, err)
+	}
+
+	err = plan.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid test plan: %s. Error: %w", path, err)
+	}
+
+	return plan, nil
+}
+
+func (p *TestPlan) validate() error {
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("no stages defined")
+	}
+
+	names := make(map[string]bool, len(p.Stages))
+	for i := range p.Stages {
+		stage := &p.Stages[i]
+		if stage.Name == "" {
+			stage.Name = fmt.Sprintf("stage-%d", i+1)
+		}
+		if names[stage.Name] {
+			return fmt.Errorf("duplicate stage name: %s", stage.Name)
+		}
+		names[stage.Name] = true
+		if stage.Duration <= 0 {
+			return fmt.Errorf("stage %s: duration must be > 0", stage.Name)
+		}
+		if stage.Rate < 0 || stage.From < 0 {
+			return fmt.Errorf("stage %s: rates must be >= 0", stage.Name)
+		}
+	}
+
+	return nil
+}
+
+// Duration returns the total length of every stage.
+func (p *TestPlan) Duration() time.Duration {
+	var total time.Duration
+	for _, stage := range p.Stages {
+		total += stage.Duration
+	}
+
+	return total
+}
+
+// at returns the stage running at elapsed + how far into it the run is. After the last stage, returns nil.
+func (p *TestPlan) at(elapsed time.Duration) (*PlanStage, time.Duration) {
+	for i := range p.Stages {
+		if elapsed < p.Stages[i].Duration {
+			return &p.Stages[i], elapsed
+		}
+		elapsed -= p.Stages[i].Duration
+	}
+
+	return nil, 0
+}
+
+// Rate returns the stage's rate, or 0 once the plan has finished.
+func (p *TestPlan) Rate(elapsed time.Duration) int {
+	stage, into := p.at(elapsed)
+	if stage == nil {
+		return 0
+	}
+	if stage.From == 0 {
+		return stage.Rate
+	}
+
+	progress := float64(into) / float64(stage.Duration)
+	return stage.From + int(float64(stage.Rate-stage.From)*progress)
+}
+
+// Stage returns the name of the stage running at elapsed. Results that arrive after the plan has finished belong to
+// the last stage.
+func (p *TestPlan) Stage(elapsed time.Duration) string {
+	stage, _ := p.at(elapsed)
+	if stage == nil {
+		return p.Stages[len(p.Stages)-1].Name
+	}
+
+	return stage.Name
+}
+
+// Mix returns the stage's mix of operations, unset if the stage uses the run's mix.
+func (p *TestPlan) Mix(elapsed time.Duration) OperationMix {
+	stage, _ := p.at(elapsed)
+	if stage == nil {
+		return OperationMix{}
+	}
+
+	return stage.Mix
+}
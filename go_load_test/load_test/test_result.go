@@ -13,12 +13,45 @@ type TestResult struct {
 	message  string
 	err      error
 	failed   bool
+	trace    traceContext
+	phases   *RequestPhases
+	// How far behind its scheduled start time the test started. See TestSchedulerConfig.CorrectOmission.
+	lag     time.Duration
+	started time.Time // When the test's first request was sent
+	worker  int       // ID of the runner worker slot that executed the test
+	// For CONSISTENCY results, the step the check reached + what was observed if the step failed.
+	check    ConsistencyStep
+	mismatch string
+	scenario *ScenarioStepResult // Set for scenario steps, see RunScenario.
+	// Request + response body sizes, only set for tests that completed their requests.
+	bytesSent     int64
+	bytesReceived int64
+	selfThrottled bool       // A 429 the client backed off from, so it's neither a success nor an error. See ClientBackoff.
+	fault         ChaosFault // Set if the client broke the request on purpose, so it's neither a success nor an error. See Chaos.
+	// Set if the test ran out of time, see OperationTimeouts. Still an error, but counted on its own.
+	timedOut bool
+	attempt  int // # of times the test had been retried before this attempt, see RetryQueue.
+	// For GETs, whether the body was checked against the checksum of the last upload, see ChecksumStore.
+	checksum checksumStatus
+	listing  []ListingObservation // For CONSISTENCY results, how long listings took to catch up, see ListCheck.
+	walk     *ListWalk            // For LIST results, the pages + files listed.
+	copied   *CopyTiming          // For COPY results, the size of the file copied + how long the COPY took.
+	fuzz     *FuzzKey             // For FUZZ results, the kind of key + whether the server stored it.
+	boundary *BoundaryCase        // For BOUNDARY results, the size uploaded + whether the server stored it.
+	// For DOWNLOAD results that got a 200, how much of the body was read + how long reading it took.
+	downloaded *DownloadTiming
 }
 
 func NewTestResult(response *http.Response) TestResult {
 	return TestResult{response: response}
 }
 
+// RequestSeq returns the sequence # the test's last request was tagged with, or 0 if requests weren't tagged. For tests
+// issuing several requests, the last is the one the result is about, I.E the step a consistency check failed at.
+func (tr *TestResult) RequestSeq() int64 {
+	return tr.phases.LastSeq()
+}
+
 func (tr *TestResult) WasSuccess() bool {
 	if tr.response == nil {
 		return false
@@ -28,10 +61,22 @@ func (tr *TestResult) WasSuccess() bool {
 		return true
 	}
 
+	if tr.TestType() == CONDITIONAL && tr.response.StatusCode == http.StatusNotModified {
+		return true
+	}
+
+	if tr.expectedStatus() {
+		return true
+	}
+
 	return 200 <= tr.response.StatusCode && tr.response.StatusCode < 300
 }
 
 func (tr *TestResult) WasError() bool {
+	if tr.fault != "" {
+		return false
+	}
+
 	if tr.response == nil || tr.err != nil {
 		return true
 	}
@@ -40,6 +85,10 @@ func (tr *TestResult) WasError() bool {
 		return false
 	}
 
+	if tr.expectedStatus() || tr.selfThrottled {
+		return false
+	}
+
 	return tr.response.StatusCode >= 400
 }
 
@@ -52,13 +101,27 @@ func (tr *TestResult) Was5XX() bool {
 }
 
 func (tr *TestResult) WasTestFailure() bool {
-	if tr.TestType() == CONSISTENCY {
+	if tr.selfThrottled || tr.fault != "" {
+		return false
+	}
+
+	if tr.TestType() == CONSISTENCY || tr.scenario != nil {
 		return tr.failed || tr.err != nil || tr.WasThrottled()
 	}
 
 	return tr.failed || !tr.WasSuccess() || tr.err != nil || tr.WasThrottled()
 }
 
+// expectedStatus returns true if the result is a scenario step that asserted the status code it got, I.E an expected
+// 404, or a fuzzed key or oversized file the server rejected cleanly, see FUZZ + BOUNDARY.
+func (tr *TestResult) expectedStatus() bool {
+	if (tr.TestType() == FUZZ || tr.TestType() == BOUNDARY) && !tr.failed {
+		return cleanRejection(tr.response.StatusCode)
+	}
+
+	return tr.scenario != nil && tr.scenario.ExpectedStatus != 0 && tr.response.StatusCode == tr.scenario.ExpectedStatus
+}
+
 func (tr *TestResult) Was404() bool {
 	if tr.response == nil {
 		return false
@@ -67,6 +130,26 @@ func (tr *TestResult) Was404() bool {
 	return tr.response.StatusCode == 404
 }
 
+// WasSelfThrottled returns true if the result was a 429 the client backed off from, see ClientBackoff.
+func (tr *TestResult) WasSelfThrottled() bool {
+	return tr.selfThrottled
+}
+
+// WasTimeout returns true if the test ran out of time, see OperationTimeouts.
+func (tr *TestResult) WasTimeout() bool {
+	return tr.timedOut
+}
+
+// WasCorrupt returns true if a GET returned bytes that don't match the last upload, see ChecksumStore.
+func (tr *TestResult) WasCorrupt() bool {
+	return tr.checksum == checksumCorrupt
+}
+
+// InjectedFault returns the fault the client broke the request with on purpose, or "". See Chaos.
+func (tr *TestResult) InjectedFault() ChaosFault {
+	return tr.fault
+}
+
 func (tr *TestResult) WasThrottled() bool {
 	if tr.response == nil {
 		return false
@@ -75,10 +158,75 @@ func (tr *TestResult) WasThrottled() bool {
 	return tr.response.StatusCode == http.StatusTooManyRequests
 }
 
+// ErrorMessage returns a description of what went wrong, or "" if the result is not an error.
+func (tr *TestResult) ErrorMessage() string {
+	if tr.WasError() {
+		if tr.response != nil {
+			return tr.message
+		} else if tr.err != nil {
+			return tr.err.Error()
+		}
+	}
+
+	if tr.WasTestFailure() && (tr.TestType() == CONSISTENCY || tr.scenario != nil || tr.mismatch != "") {
+		return tr.message
+	}
+
+	return ""
+}
+
+// StatusCode returns the HTTP status code of the response, or 0 if no response was received.
+func (tr *TestResult) StatusCode() int {
+	if tr.response == nil {
+		return 0
+	}
+
+	return tr.response.StatusCode
+}
+
 func (tr *TestResult) TestType() TestType {
 	return tr.testType
 }
 
+func (tr *TestResult) Duration() time.Duration {
+	return tr.duration
+}
+
+func (tr *TestResult) BytesSent() int64 {
+	return tr.bytesSent
+}
+
+func (tr *TestResult) BytesReceived() int64 {
+	return tr.bytesReceived
+}
+
 func (tr *TestResult) FileName() string {
 	return tr.fileName
 }
+
+// requests returns the # of requests the test made, like Test.requests but only counting the GET + cleanup DELETE of
+// FUZZ + BOUNDARY files the server stored, + every page of a LIST.
+func (tr *TestResult) requests() int64 {
+	switch tr.testType {
+	case CONSISTENCY, CONFLICT, COPY:
+		return 4
+	case HEAD:
+		return 2
+	case DOWNLOAD:
+		return 3
+	case PARTIAL:
+		return partialWrites + 3
+	case MOVE:
+		return 5
+	case FUZZ, BOUNDARY:
+		if (tr.fuzz != nil && tr.fuzz.Stored) || (tr.boundary != nil && tr.boundary.Stored) {
+			return 3
+		}
+	case LIST:
+		if tr.walk != nil && tr.walk.Pages > 1 {
+			return int64(tr.walk.Pages)
+		}
+	}
+
+	return 1
+}
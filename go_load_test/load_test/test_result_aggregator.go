@@ -1,34 +1,46 @@
 package load_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/rodaine/table"
 	log "github.com/sirupsen/logrus"
 	"math"
+	"net/http"
+	"sort"
 	"sync"
 	"time"
 )
 
 // Listens to a channel of test results. Aggregates results + provides metrics.
 
+// latencyOperations are the operation groups that get their own latency distribution. CREATE is folded into PUT.
+var latencyOperations = []TestType{GET, PUT, DELETE, CONSISTENCY}
+
 type TestResults struct {
 	startTime                          time.Time
-	numRequests                        int
-	numSuccess                         int
-	numGet                             int
-	numPut                             int
-	numDelete                          int
-	numConsistency                     int
-	numFailure                         int
-	numFailedConsistency               int
-	numThrottled                       int
-	intervalCount                      int
+	stopTime                           time.Time // Set once shutdown is requested, so draining doesn't count towards run time
+	numRequests                        Counter
+	numSuccess                         Counter
+	numGet                             Counter
+	numPut                             Counter
+	numDelete                          Counter
+	numConsistency                     Counter
+	numFailure                         Counter
+	numFailedConsistency               Counter
+	numThrottled                       Counter
+	numSelfThrottled                   Counter // 429s the client backed off from, see ClientBackoff
+	numDropped                         Counter // Requests the client dropped on purpose, see Chaos
+	numTruncated                       Counter // Uploads the client truncated on purpose
+	numAborted                         Counter // Downloads the client aborted on purpose
+	intervalCount                      Counter
 	interval                           time.Duration
-	num500s                            int
-	httpErrors                         []string
-	otherErrors                        []string
+	num500s                            Counter
+	httpErrors                         *StringRing // Last ErrorSampleSize http error messages
+	otherErrors                        *StringRing // Last ErrorSampleSize consistency + transport error messages
 	resultLock                         sync.RWMutex
+	shards                             []*mergeShard // Results merged since the last flush, see flushPending
 	numLastInterval                    int
 	numSuccessLastInterval             int
 	numGetLastInterval                 int
@@ -41,77 +53,362 @@ type TestResults struct {
 	numConsistencyLastInterval         int
 	numThrottledLastInterval           int
 	maxSeenSuccessfulRequestPerSec     int
-	lastPrintedNumSuccess              int
-	lastPrintedNumFailure              int
-	lastPrintedNumRequests             int
 	totalGetDuration                   time.Duration
 	totalPutDuration                   time.Duration
 	totalDeleteDuration                time.Duration
 	totalConsistencyDuration           time.Duration
+	latency                            *LatencyHistogram
+	opLatency                          map[TestType]*LatencyHistogram
+	thresholds                         Thresholds
+	history                            []IntervalStats // Stats for every interval of the run, older ones merged, see appendHistory
+	statusCodes                        map[int]int     // HTTP status code -> count. 0 means no response was received.
+	errorGroups                        ErrorGroups
+	intervalLatency                    map[TestType]*LatencyHistogram // Reset at the end of every interval
+	numTimeouts                        map[TestType]*Counter          // Tests that ran out of time, see OperationTimeouts
+	recentErrors                       *StringRing                    // Last maxRecentErrors error messages
+	bytesUploaded                      Counter
+	bytesDownloaded                    Counter
+	bytesUploadedLastInterval          int // Avg bytes/sec uploaded over the rate window
+	bytesDownloadedLastInterval        int // Avg bytes/sec downloaded over the rate window
+	phaseLatency                       map[RequestPhase]*LatencyHistogram
+	numReusedConns                     int
+	numNewConns                        int
+	scheduleLag                        *LatencyHistogram
+	correctOmission                    bool // If true, schedule lag is included in recorded latencies
+	apdex                              Apdex
+	workers                            map[int]*WorkerStats // Worker ID -> stats
+	checkFailures                      ConsistencyFailures  // Failed consistency checks by step + mismatch
+	errorBudget                        float64              // Allowed failure fraction
+	recentLatency                      *RollingHistogram    // Latency over the last N intervals
+	warmup                             time.Duration        // Results in the first warmup of the run are excluded from final stats
+	warmupLatency                      *LatencyHistogram
+	statusLatency                      map[StatusClass]*LatencyHistogram
+	retryAfter                         *RetryAfterStats
+	targets                            Targets
+	targetStats                        map[string]*TargetStats // Target label -> stats, only set with multiple targets
+	profile                            LoadProfile
+	mix                                OperationMix
+	users                              int // # of virtual users, if the run is closed loop
+	thinkTime                          ThinkTime
+	arrivals                           Arrivals
+	pacing                             time.Duration
+	scenarios                          []*scenarioStats // In the order they're defined, only set if scenarios are configured
+	autoscaler                         *Autoscaler
+	steadyState                        *SteadyState
+	steadyMarks                        []steadyMark // Only with a SteadyState, see steadyTotals
+	retries                            *RetryQueue
+	numRetries                         Counter       // Retry attempts, see RetryQueue
+	numRetrySuccess                    Counter       // Retry attempts that succeeded
+	numVerified                        Counter       // GETs checked against the checksum of the last upload, see ChecksumStore
+	numCorrupt                         Counter       // GETs that returned bytes not matching the last upload
+	created                            FileSet       // Files the run may have left on the server, nil unless tracked for Cleanup
+	pause                              *PauseControl // Only shown while paused, run time is measured by clock
+	clock                              *RunClock     // Shared with the scheduler, so warmup + load profile stages end together
+	consistencyPool                    *ConsistencyPool
+	consistencyQueue                   consistencyQueueStats // Sampled once per interval, only with a consistency pool
+	drain                              *Drain
+	extraOps                           map[TestType]*extraOpStats // Only set if extra ops are run, see ExtraOps
+	verifyChecksums                    bool
+	listCheck                          *ListCheck
+	listing                            map[ConsistencyStep]*listingStats // Only set with a ListCheck
+	// GET + PUT latency by size bucket, only set with a SizeDistribution.
+	sizeLatency map[TestType]map[string]*LatencyHistogram
+}
+
+// recentWindow returns the length of time covered by recentLatency.
+func (tr *TestResults) recentWindow() time.Duration {
+	return time.Duration(tr.recentLatency.Size()) * tr.interval
+}
+
+// stage returns the current load profile stage, or "" if the profile doesn't have stages. With a warmup, it's
+// StageWarmup then StageMeasured for profiles without stages, so output marks when measurement began.
+func (tr *TestResults) stage() string {
+	if tr.inWarmup() {
+		return StageWarmup
+	}
+
+	staged, ok := tr.profile.(StagedProfile)
+	if !ok {
+		if tr.warmup > 0 {
+			return StageMeasured
+		}
+		return ""
+	}
+
+	return staged.Stage(tr.clock.Elapsed() - tr.warmup)
+}
+
+// inWarmup returns true until the warmup period has passed, by the same clock the scheduler runs warmup by.
+func (tr *TestResults) inWarmup() bool {
+	return tr.clock.InWarmup(tr.warmup)
 }
 
+// budget returns the error budget for all completed tests.
+func (tr *TestResults) budget() ErrorBudget {
+	failures := tr.numFailure.Get()
+	return NewErrorBudget(tr.errorBudget, failures, tr.numSuccess.Get()+failures)
+}
+
+// mergeShards is how many shards Merge queues results on, by worker.
+const mergeShards = 16
+
+// mergeFlushInterval is how often merged results are applied to the run's stats, so readers lag by at most this much.
+const mergeFlushInterval = time.Millisecond * 50
+
+// maxPendingResults is how many results a shard queues before Merge flushes them itself, so queues stay small if the
+// flusher falls behind.
+const maxPendingResults = 1024
+
+// pendingResult is a result Merge has queued but not yet applied to the run's stats.
+type pendingResult struct {
+	result     TestResult
+	mergedAt   time.Time
+	warmup     bool   // Merged during warmup, see applyWarmup
+	httpError  string // Formatted + logged as it was merged
+	otherError string
+}
+
+// mergeShard queues a share of the workers' results between flushes, so merging never waits on readers of the stats.
+type mergeShard struct {
+	lock    sync.Mutex
+	pending []pendingResult
+	spare   []pendingResult // The queue applied by the last flush, reused for the next. Guarded by resultLock
+}
+
+func newMergeShards() []*mergeShard {
+	shards := make([]*mergeShard, mergeShards)
+	for i := range shards {
+		shards[i] = &mergeShard{}
+	}
+
+	return shards
+}
+
+// add returns true once the shard holds maxPendingResults.
+func (s *mergeShard) add(pending pendingResult) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending = append(s.pending, pending)
+	return len(s.pending) >= maxPendingResults
+}
+
+// take swaps the queue for the spare one, so queues are reused rather than regrown every flush.
+func (s *mergeShard) take() []pendingResult {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	pending := s.pending
+	s.pending = s.spare[:0]
+	s.spare = nil
+	return pending
+}
+
+// release keeps an applied queue as the spare, cleared so applied results can be collected.
+func (s *mergeShard) release(applied []pendingResult) {
+	for i := range applied {
+		applied[i] = pendingResult{}
+	}
+	s.spare = applied
+}
+
+// Merge is safe for concurrent use + only takes resultLock to flush a full shard. The result is queued on its worker's
+// shard + applied to the run's stats by the next flushPending, so counters + histograms always agree. Results that finish during warmup
+// are only counted towards the live request rate + warmup latency, see applyWarmup.
 func (tr *TestResults) Merge(result TestResult) {
-	tr.numRequests++
+	pending := pendingResult{result: result, mergedAt: time.Now(), warmup: tr.inWarmup()}
+
+	// Format + log before queueing, so errors show up as they happen.
+	if result.WasError() {
+		if result.response != nil {
+			pending.httpError = fmt.Sprintf("File: %s, Error: %s", result.FileName(), result.message)
+			log.Error(pending.httpError)
+		} else if result.err != nil {
+			pending.otherError = fmt.Sprintf("File: %s, Error: %s", result.FileName(), result.err.Error())
+			log.Error(pending.otherError)
+		}
+	}
+
+	if tr.shards[uint(result.worker)%mergeShards].add(pending) {
+		tr.flushPending()
+	}
+}
+
+// flushPending applies every queued result to the run's stats, in the order they were merged. The aggregator flushes
+// every mergeFlushInterval, before every interval's stats + once the result chan is closed.
+func (tr *TestResults) flushPending() {
+	tr.resultLock.Lock()
+	defer tr.resultLock.Unlock()
+
+	queues := make([][]pendingResult, len(tr.shards))
+	for i, shard := range tr.shards {
+		queues[i] = shard.take()
+	}
+	// Each queue is already in merge order, so apply the earliest head of any queue until they're all applied.
+	next := make([]int, len(queues))
+	for {
+		earliest := -1
+		for i, queue := range queues {
+			if next[i] < len(queue) && (earliest < 0 || queue[next[i]].mergedAt.Before(queues[earliest][next[earliest]].mergedAt)) {
+				earliest = i
+			}
+		}
+		if earliest < 0 {
+			break
+		}
+		tr.apply(&queues[earliest][next[earliest]])
+		next[earliest]++
+	}
+	for i, shard := range tr.shards {
+		shard.release(queues[i])
+	}
+}
+
+// apply applies a merged result to the run's stats. Caller must hold resultLock.
+func (tr *TestResults) apply(pending *pendingResult) {
+	result := pending.result
+	requests := result.requests()
+	tr.intervalCount.Add(requests)
+
+	duration := result.duration
+	if tr.correctOmission {
+		duration += result.lag
+	}
+	if pending.warmup {
+		tr.applyWarmup(result, duration)
+		return
+	}
+
+	tr.numRequests.Add(requests)
 
 	if result.WasSuccess() {
-		tr.numSuccess++
+		tr.numSuccess.Add(requests)
 	}
 
 	if result.WasTestFailure() {
-		tr.numFailure++
+		tr.numFailure.Inc()
 	}
 
 	if result.Was5XX() {
-		tr.num500s++
+		tr.num500s.Inc()
 	}
 
 	if result.WasThrottled() {
-		tr.numThrottled++
+		tr.numThrottled.Inc()
 	}
 
-	if result.WasError() {
-		if result.response != nil {
-			msg := fmt.Sprintf("File: %s, Error: %s", result.FileName(), result.message)
-			log.Error(msg)
-			tr.httpErrors = append(tr.httpErrors, msg)
-		} else if result.err != nil {
-			msg := fmt.Sprintf("File: %s, Error: %s", result.FileName(), result.err.Error())
-			log.Error(msg)
-			tr.otherErrors = append(tr.otherErrors, msg)
+	if result.WasSelfThrottled() {
+		tr.numSelfThrottled.Inc()
+	}
+
+	switch result.InjectedFault() {
+	case ChaosDrop:
+		tr.numDropped.Inc()
+	case ChaosTruncate:
+		tr.numTruncated.Inc()
+	case ChaosAbort:
+		tr.numAborted.Inc()
+	}
+
+	if result.WasTimeout() {
+		tr.numTimeouts[latencyOperation(result.testType)].Inc()
+	}
+
+	if result.attempt > 0 {
+		tr.numRetries.Inc()
+		if result.WasSuccess() {
+			tr.numRetrySuccess.Inc()
 		}
 	}
 
 	if result.WasTestFailure() && result.TestType() == CONSISTENCY {
-		tr.numFailedConsistency++
-		tr.otherErrors = append(tr.otherErrors, result.message)
+		tr.numFailedConsistency.Inc()
 	}
 
-	// Increment items that are read by another goroutine with lock
-	defer tr.resultLock.Unlock()
-	tr.resultLock.Lock()
+	switch result.checksum {
+	case checksumVerified:
+		tr.numVerified.Inc()
+	case checksumCorrupt:
+		tr.numVerified.Inc()
+		tr.numCorrupt.Inc()
+	}
+
+	tr.bytesUploaded.Add(result.BytesSent())
+	tr.bytesDownloaded.Add(result.BytesReceived())
+	phaseDurations := result.phases.Durations()
+	reusedConns, newConns := result.phases.Connections()
 
-	tr.intervalCount++
+	tr.trackCreated(result)
+	if pending.httpError != "" {
+		tr.httpErrors.Add(pending.httpError)
+	}
+	if pending.otherError != "" {
+		tr.otherErrors.Add(pending.otherError)
+	}
+	tr.recordListing(result)
+	if result.WasTestFailure() && (result.TestType() == CONSISTENCY || result.TestType() == CONFLICT) {
+		tr.checkFailures.Record(result.check, result.mismatch)
+		tr.otherErrors.Add(fmt.Sprintf("[%s] File: %s, Error: %s", result.check, result.FileName(), result.message))
+	} else if result.WasTestFailure() && (result.scenario != nil || tr.extraOps[result.testType] != nil || result.WasCorrupt()) &&
+		!result.WasError() {
+		tr.otherErrors.Add(fmt.Sprintf("File: %s, Error: %s", result.FileName(), result.message))
+	}
+
+	tr.statusCodes[result.StatusCode()]++
+	tr.retryAfter.Record(result)
+	worker, ok := tr.workers[result.worker]
+	if !ok {
+		worker = &WorkerStats{ID: result.worker}
+		tr.workers[result.worker] = worker
+	}
+	worker.record(result, pending.mergedAt)
+	if msg := result.ErrorMessage(); msg != "" {
+		tr.errorGroups.Record(result, msg, pending.mergedAt)
+		tr.recentErrors.Add(fmt.Sprintf("%s %s %s %s", pending.mergedAt.Format("15:04:05"), result.TestType(),
+			statusCodeLabel(result.StatusCode()), msg))
+	}
+	tr.recentLatency.Record(duration)
+	if result.scenario != nil {
+		tr.recordScenario(result, duration)
+	}
+	tr.recordExtraOp(result, duration)
+	tr.recordSizeLatency(result, duration)
+	if tr.targetStats != nil {
+		tr.targetStats[tr.targets.For(result.FileName()).Name()].record(result, duration)
+	}
+	tr.intervalLatency[latencyOperation(result.testType)].Record(duration)
+	tr.scheduleLag.Record(result.lag)
+	tr.latency.Record(duration)
+	tr.apdex.Record(duration, result.WasTestFailure())
+	tr.opLatency[latencyOperation(result.testType)].Record(duration)
+	tr.statusLatency[statusClass(result.StatusCode())].Record(duration)
+	for phase, d := range phaseDurations {
+		tr.phaseLatency[phase].Record(d)
+	}
+	tr.numReusedConns += reusedConns
+	tr.numNewConns += newConns
 
 	if result.testType == GET {
-		tr.numGet++
-		tr.totalGetDuration += result.duration
+		tr.numGet.Inc()
+		tr.totalGetDuration += duration
 	} else if result.testType == PUT || result.testType == CREATE {
-		tr.numPut++
-		tr.totalPutDuration += result.duration
+		tr.numPut.Inc()
+		tr.totalPutDuration += duration
 	} else if result.testType == DELETE {
-		tr.numDelete++
-		tr.totalDeleteDuration += result.duration
+		tr.numDelete.Inc()
+		tr.totalDeleteDuration += duration
 	} else if result.testType == CONSISTENCY {
-		tr.totalConsistencyDuration += result.duration
-		tr.numConsistency++
-		tr.numRequests += 3
-		tr.intervalCount += 3
-		if result.WasSuccess() {
-			tr.numSuccess += 3
-		}
+		tr.totalConsistencyDuration += duration
+		tr.numConsistency.Inc()
 	}
 }
 
+// applyWarmup applies a result that finished during warmup. Connection setup + cold caches skew the run's stats, so
+// warmup latency is only tracked on its own + no other stats, scores or averages count it. Files it may have created
+// are still tracked for Cleanup. Caller must hold resultLock.
+func (tr *TestResults) applyWarmup(result TestResult, duration time.Duration) {
+	tr.trackCreated(result)
+	tr.warmupLatency.Record(duration)
+}
+
 func (tr *TestResults) PrintResults() {
 	tr.resultLock.RLock()
 	defer tr.resultLock.RUnlock()
@@ -124,26 +421,181 @@ func (tr *TestResults) PrintResults() {
 	tbl := table.New("Metric", "Count", "", "")
 	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
 
-	tbl.AddRow("# Requests", tr.numRequests, "", "")
-	tbl.AddRow("# Test Success", tr.numSuccess, "", "")
-	tbl.AddRow("# Test Failures", tr.numFailure, "")
-	tbl.AddRow("# Consistency Test Success", tr.numConsistency-tr.numFailedConsistency, "")
-	tbl.AddRow("# Consistency Test Failures", tr.numFailedConsistency, "")
-	tbl.AddRow("# 5XX Errors", tr.num500s, "")
-	tbl.AddRow("# Throttled", tr.numThrottled, "")
+	// With a steady state, requests, success rate + latency are the steady state's
+	totals, steady := tr.steadyTotals()
+	if steady {
+		wholeRun := tr.headlineTotals()
+		tbl.AddRow("Steady state (s)", fmt.Sprintf("%.0f", totals.duration.Seconds()),
+			"Whole run requests / failures / p99 (ms): ", fmt.Sprintf("%d / %d / %d", wholeRun.requests,
+				wholeRun.failures, wholeRun.latency.Percentile(99).Milliseconds()))
+	} else {
+		totals = tr.headlineTotals()
+	}
+	tbl.AddRow("# Requests", totals.requests, "", "")
+	tbl.AddRow("# Test Success", totals.successes, "", "")
+	tbl.AddRow("# Test Failures", totals.failures, "")
+	tbl.AddRow("# Consistency Test Success", totals.consistency-totals.failedConsistency, "")
+	tbl.AddRow("# Consistency Test Failures", totals.failedConsistency, "")
+	tbl.AddRow("# 5XX Errors", tr.num500s.Get(), "")
+	tbl.AddRow("# Throttled", tr.numThrottled.Get(), "Self throttled: ", tr.numSelfThrottled.Get())
+	tbl.AddRow("# HTTP Errors", tr.httpErrors.Total(), "Other: ", tr.otherErrors.Total())
+	if chaos := tr.chaosSummary(); chaos != nil {
+		tbl.AddRow("# Injected faults", chaos.Total(), "Drop / Truncate / Abort: ",
+			fmt.Sprintf("%d / %d / %d", chaos.Dropped, chaos.Truncated, chaos.Aborted))
+	}
+	if timeouts := tr.timeoutSummary(); timeouts != nil {
+		tbl.AddRow("# Timeouts", timeouts.Total(), "GET / PUT / DELETE / CONSISTENCY: ",
+			fmt.Sprintf("%d / %d / %d / %d", timeouts[GET], timeouts[PUT], timeouts[DELETE], timeouts[CONSISTENCY]))
+	}
+	if retries := tr.retrySummary(); retries != nil {
+		tbl.AddRow("# Retries", retries.Attempts, "Succeeded / Exhausted / Dropped: ",
+			fmt.Sprintf("%d / %d / %d", retries.Succeeded, retries.Exhausted, retries.Dropped))
+	}
+	if checksums := tr.checksumSummary(); checksums != nil {
+		tbl.AddRow("# Corrupt downloads", checksums.Corrupt, "Checksums verified: ", checksums.Verified)
+	}
+	extraOps := tr.extraOpSummaries()
+	for _, op := range sortedExtraOps(extraOps) {
+		extraOp := extraOps[op]
+		tbl.AddRow("# "+string(op), extraOp.Count, "Failures / p99 (ms): ",
+			fmt.Sprintf("%d / %.0f", extraOp.Failures, extraOp.Latency.P99Ms))
+		if extraOp.Upload != nil {
+			tbl.AddRow("# "+string(op)+" MB/sec", "", "Min / Mean / Max: ", extraOp.Upload.String())
+		}
+		if extraOp.Download != nil {
+			tbl.AddRow("# "+string(op)+" download MB/sec", "", "Min / Mean / Max: ", extraOp.Download.String())
+		}
+		for _, bucket := range sizeBucketLabels {
+			if latency, ok := extraOp.BySize[bucket]; ok {
+				tbl.AddRow("# "+string(op)+" "+bucket, latency.Count, "p50 / p99 (ms): ",
+					fmt.Sprintf("%.0f / %.0f", latency.P50Ms, latency.P99Ms))
+			}
+		}
+		if extraOp.Pages > 0 {
+			tbl.AddRow("# "+string(op)+" pages", extraOp.Pages, "Files / Avg files per page: ",
+				fmt.Sprintf("%d / %.1f", extraOp.Items, float64(extraOp.Items)/float64(extraOp.Pages)))
+		}
+		if op == FUZZ {
+			tbl.AddRow("# FUZZ rejected", extraOp.Rejected, "Stored: ", extraOp.Count-extraOp.Rejected-extraOp.Failures)
+		}
+		for _, name := range boundaryCaseNames {
+			if outcome, ok := extraOp.Cases[name]; ok {
+				tbl.AddRow("# BOUNDARY "+name, outcome.Count, "Failures: ", outcome.Failures)
+			}
+		}
+		if op == CONDITIONAL {
+			tbl.AddRow("# CONDITIONAL 304s", extraOp.NotModified, "Hit rate: ",
+				fmt.Sprintf("%.2f%%", extraOp.conditionalHitRate()*100))
+		}
+	}
+	sizeLatency := tr.sizeLatencySummary()
+	for _, op := range []TestType{GET, PUT} {
+		for _, bucket := range sizeBucketLabels {
+			if latency, ok := sizeLatency[op][bucket]; ok {
+				tbl.AddRow("# "+string(op)+" "+bucket, latency.Count, "p50 / p99 (ms): ",
+					fmt.Sprintf("%.0f / %.0f", latency.P50Ms, latency.P99Ms))
+			}
+		}
+	}
+	if listing := tr.listingSummary(); listing != nil {
+		tbl.AddRow("# Listed", listing.Appear.Checks, "Late / Missed / Late p50 / p99 (ms): ", listing.Appear.String())
+		tbl.AddRow("# Unlisted", listing.Disappear.Checks, "Late / Missed / Late p50 / p99 (ms): ",
+			listing.Disappear.String())
+	}
+	if pool := tr.consistencyPoolSummary(); pool != nil {
+		tbl.AddRow("# Consistency queue", pool.QueueDepth, "Max / Avg / Skipped: ",
+			fmt.Sprintf("%d / %.1f / %d", pool.MaxQueueDepth, pool.AvgQueueDepth, pool.Skipped))
+	}
+	retryAfter := tr.retryAfter.Summary()
+	tbl.AddRow("# Retry-After p50 (ms)", fmt.Sprintf("%.0f", retryAfter.Advertised.P50Ms), "Missing / Violations: ",
+		fmt.Sprintf("%d / %d", retryAfter.Missing, retryAfter.Violations))
 	tbl.AddRow("# Current THROTTLE/sec", tr.numThrottledLastInterval, "")
 	tbl.AddRow("# Current GET/sec", tr.numGetLastInterval, "Avg Duration: ", tr.avgGetDurationLastInterval.Milliseconds())
 	tbl.AddRow("# Current PUT/sec", tr.numPutLastInterval, "Avg Duration: ", tr.avgPutDurationLastInterval.Milliseconds())
 	tbl.AddRow("# Current DELETE/sec", tr.numDeleteLastInterval, "Avg Duration: ", tr.avgDeleteDurationLastInterval.Milliseconds())
 	tbl.AddRow("# Current CONSISTENCY/sec", tr.numConsistencyLastInterval, "(4 requests per check)", tr.avgConsistencyDurationLastInterval.Milliseconds())
-	tbl.AddRow("Current req/sec", currentThroughput, "", "")
+	mix := tr.operationMix()
+	target := "default"
+	if mix.Target != nil {
+		target = formatMix(mix.Target)
+	}
+	tbl.AddRow("Mix % GET/PUT/DELETE/CONSISTENCY", formatMix(mix.Achieved), "Target: ", target)
+	if users := tr.virtualUsers(); users != nil {
+		tbl.AddRow("Virtual users", users.Users, "Avg pacing / think (ms): ", fmt.Sprintf("%.0f / %.0f", users.AvgPacingMs, users.AvgThinkMs))
+	}
+	tbl.AddRow("Current req/sec", currentThroughput, "Stage: ", tr.stage())
 	tbl.AddRow("Current Successful req/sec", currentSuccessful, "", "")
 	tbl.AddRow("Max Successful req/sec", tr.maxSeenSuccessfulRequestPerSec, "", "")
+	tbl.AddRow("Connections reused", tr.numReusedConns, "New: ", tr.numNewConns)
+	tbl.AddRow("Connection reuse ratio", fmt.Sprintf("%.2f%%", tr.connectionReuseRatio()*100), "", "")
+	tbl.AddRow("Current upload MB/sec", bytesToMB(int64(tr.bytesUploadedLastInterval)), "Total MB: ", bytesToMB(tr.bytesUploaded.Load()))
+	tbl.AddRow("Current download MB/sec", bytesToMB(int64(tr.bytesDownloadedLastInterval)), "Total MB: ", bytesToMB(tr.bytesDownloaded.Load()))
+	tbl.AddRow("Latency p50 (ms)", totals.latency.Percentile(50).Milliseconds(), "", "")
+	tbl.AddRow("Latency p90 (ms)", totals.latency.Percentile(90).Milliseconds(), "", "")
+	tbl.AddRow("Latency p99 (ms)", totals.latency.Percentile(99).Milliseconds(), "", "")
+	tbl.AddRow("Latency p99.9 (ms)", totals.latency.Percentile(99.9).Milliseconds(), "", "")
+	recent := tr.recentLatency.Merged()
+	if tr.warmup > 0 {
+		tbl.AddRow(fmt.Sprintf("Warmup %s (excluded)", tr.warmup), tr.warmupLatency.Count(), "p99 (ms): ", tr.warmupLatency.Percentile(99).Milliseconds())
+	}
+	tbl.AddRow(fmt.Sprintf("Last %s p50 (ms)", tr.recentWindow()), recent.Percentile(50).Milliseconds(), "p99 (ms): ", recent.Percentile(99).Milliseconds())
+	tbl.AddRow(fmt.Sprintf("Apdex (T=%dms)", tr.apdex.Target.Milliseconds()), tr.apdex.String(),
+		"Satisfied/Tolerating/Frustrated: ", fmt.Sprintf("%d/%d/%d", tr.apdex.Satisfied, tr.apdex.Tolerating, tr.apdex.Frustrated))
+	tbl.AddRow("Schedule lag p99 (ms)", tr.scheduleLag.Percentile(99).Milliseconds(), "CO corrected: ", tr.correctOmission)
+	budget := tr.budget()
+	tbl.AddRow("Success rate", fmt.Sprintf("%.3f%%", totals.successRate()*100), "", "")
+	tbl.AddRow(fmt.Sprintf("Error budget (%.2f%%)", budget.Allowed*100), budget.String(),
+		"Failures/Allowed: ", fmt.Sprintf("%d/%d", budget.Failures, budget.AllowedFailures))
 	tbl.Print()
 
-	tr.lastPrintedNumFailure = tr.numFailure
-	tr.lastPrintedNumSuccess = tr.numSuccess
-	tr.lastPrintedNumRequests = tr.numRequests
+	fmt.Println()
+	opTbl := table.New("Operation", "Count", "Min (ms)", "Avg (ms)", "p99 (ms)", "Max (ms)")
+	opTbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, op := range latencyOperations {
+		hist := totals.opLatency[op]
+		opTbl.AddRow(op, hist.Count(), hist.Min().Milliseconds(), hist.Mean().Milliseconds(),
+			hist.Percentile(99).Milliseconds(), hist.Max().Milliseconds())
+	}
+	opTbl.Print()
+
+	fmt.Println()
+	statusTbl := table.New("Status code", "Count")
+	statusTbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, code := range sortedStatusCodes(tr.statusCodes) {
+		statusTbl.AddRow(statusCodeLabel(code), tr.statusCodes[code])
+	}
+	statusTbl.Print()
+	tr.printTargets()
+
+	fmt.Println()
+	classTbl := table.New("Status class", "Count", "Avg (ms)", "p50 (ms)", "p99 (ms)", "Max (ms)")
+	classTbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, class := range statusClasses {
+		hist := tr.statusLatency[class]
+		classTbl.AddRow(class, hist.Count(), hist.Mean().Milliseconds(), hist.Percentile(50).Milliseconds(),
+			hist.Percentile(99).Milliseconds(), hist.Max().Milliseconds())
+	}
+	classTbl.Print()
+
+	fmt.Println()
+	phaseTbl := table.New("Request phase", "Count", "Avg (ms)", "p50 (ms)", "p99 (ms)", "Max (ms)")
+	phaseTbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, phase := range requestPhases {
+		hist := tr.phaseLatency[phase]
+		phaseTbl.AddRow(phase, hist.Count(), hist.Mean().Milliseconds(), hist.Percentile(50).Milliseconds(),
+			hist.Percentile(99).Milliseconds(), hist.Max().Milliseconds())
+	}
+	phaseTbl.Print()
+
+	if len(tr.checkFailures) > 0 {
+		fmt.Println()
+		consistencyTbl := table.New("Consistency step", "Failures", "Observed")
+		consistencyTbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+		for _, step := range consistencySteps {
+			consistencyTbl.AddRow(step, tr.checkFailures.Total(step), tr.checkFailures.Describe(step))
+		}
+		consistencyTbl.Print()
+	}
 }
 
 func (tr *TestResults) PrintErrors() {
@@ -151,16 +603,13 @@ func (tr *TestResults) PrintErrors() {
 	defer tr.resultLock.RUnlock()
 
 	fmt.Println()
-	fmt.Println("HTTP Errors:")
-	fmt.Println("---------------------------------------------")
-	for i := 0; i < Min(len(tr.httpErrors), 5); i++ {
-		fmt.Println(tr.httpErrors[len(tr.httpErrors)-i-1])
-	}
-	fmt.Println("")
-	fmt.Println("Other Errors: ")
+	fmt.Printf("Top %d Errors:", TopErrorCount)
+	fmt.Println()
 	fmt.Println("---------------------------------------------")
-	for i := 0; i < Min(len(tr.otherErrors), 5); i++ {
-		fmt.Println(tr.otherErrors[len(tr.otherErrors)-i-1])
+	for _, group := range tr.errorGroups.Top(TopErrorCount) {
+		fmt.Printf("%6dx [%s %s] first: %s last: %s  %s", group.Count, group.TestType, statusCodeLabel(group.StatusCode),
+			group.FirstSeen.Format("15:04:05"), group.LastSeen.Format("15:04:05"), group.Message)
+		fmt.Println()
 	}
 	fmt.Println()
 	fmt.Println()
@@ -169,159 +618,542 @@ func (tr *TestResults) PrintErrors() {
 type ResultAggregator struct {
 	resultsChan chan TestResult
 	cfg         TestSchedulerConfig
+	rateAlpha   float64 // EWMA smoothing factor for "current" rates
+	otlp        *OTLPExporter
+	done        chan struct{}
 	Results     *TestResults
 }
 
 func NewResultAggregator(cfg TestSchedulerConfig) *ResultAggregator {
+	apdexTarget := cfg.ApdexTarget
+	if apdexTarget <= 0 {
+		apdexTarget = DefaultApdexTarget
+	}
+
+	targets := resolveTargets(cfg.EndpointCfg, cfg.Targets)
+
+	recentWindow := cfg.RecentWindow
+	if recentWindow <= 0 {
+		recentWindow = DefaultRecentWindow
+	}
+
+	rateAlpha := cfg.RateAlpha
+	if rateAlpha <= 0 || rateAlpha > 1 {
+		rateAlpha = DefaultRateAlpha
+	}
+
+	clock := cfg.Clock
+	if clock == nil {
+		clock = NewRunClock(cfg.Pause)
+	}
+
+	// A coordinator's agents queue checks themselves, so there's no queue here to report on.
+	consistencyPool := cfg.ConsistencyPool
+	if cfg.ConsistencyChan == nil {
+		consistencyPool = nil
+	}
+
 	return &ResultAggregator{
 		resultsChan: cfg.ResultChan,
 		cfg:         cfg,
+		rateAlpha:   rateAlpha,
+		done:        make(chan struct{}),
 		Results: &TestResults{
-			startTime: time.Now(),
-			interval:  cfg.SeedCadence.Duration,
+			startTime:       time.Now(),
+			shards:          newMergeShards(),
+			interval:        cfg.SeedCadence.Duration,
+			latency:         NewLatencyHistogram(),
+			opLatency:       newOperationHistograms(),
+			numTimeouts:     newOperationCounters(),
+			thresholds:      cfg.Thresholds,
+			statusCodes:     make(map[int]int),
+			errorGroups:     make(ErrorGroups),
+			intervalLatency: newOperationHistograms(),
+			phaseLatency:    newPhaseHistograms(),
+			httpErrors:      NewStringRing(ErrorSampleSize),
+			otherErrors:     NewStringRing(ErrorSampleSize),
+			recentErrors:    NewStringRing(maxRecentErrors),
+			scheduleLag:     NewLatencyHistogram(),
+			correctOmission: cfg.CorrectOmission,
+			apdex:           NewApdex(apdexTarget),
+			workers:         make(map[int]*WorkerStats),
+			checkFailures:   make(ConsistencyFailures),
+			errorBudget:     cfg.ErrorBudget,
+			recentLatency:   NewRollingHistogram(recentWindow),
+			warmup:          cfg.Warmup,
+			warmupLatency:   NewLatencyHistogram(),
+			statusLatency:   newStatusClassHistograms(),
+			retryAfter:      NewRetryAfterStats(),
+			targets:         targets,
+			targetStats:     newTargetStats(targets),
+			profile:         cfg.Profile,
+			mix:             cfg.OperationMix,
+			users:           cfg.VirtualUsers,
+			thinkTime:       cfg.ThinkTime,
+			arrivals:        cfg.Arrivals,
+			pacing:          cfg.Pacing,
+			scenarios:       newScenarioStats(cfg.Scenarios),
+			pause:           cfg.Pause,
+			clock:           clock,
+			autoscaler:      cfg.Autoscaler,
+			steadyState:     cfg.SteadyState,
+			retries:         cfg.Retries,
+			extraOps:        newExtraOpStats(cfg.ExtraOps),
+			verifyChecksums: cfg.TestConfig.VerifyChecksums,
+			listCheck:       cfg.TestConfig.ListCheck,
+			listing:         newListingStats(cfg.TestConfig.ListCheck),
+			sizeLatency:     newSizeLatency(cfg.TestConfig.Sizes),
+			created:         newCreatedFiles(cfg),
+			consistencyPool: consistencyPool,
+			drain:           cfg.Drain,
 		},
 	}
 }
 
-func (ra *ResultAggregator) Run() {
+// Done is closed once Run has merged every result and stopped exporting, I.E after the result chan is closed.
+func (ra *ResultAggregator) Done() <-chan struct{} {
+	return ra.done
+}
+
+// Run merges results until the result chan is closed, then flushes exporters and closes Done. Cancelling ctx shuts the
+// run down + stops interval stats, exporters + the metrics server, results still in flight are merged.
+func (ra *ResultAggregator) Run(ctx context.Context) {
+	defer close(ra.done)
+	go closeShutdownOnCancel(ctx, ra.cfg.ShutdownChan)
+
+	go func() {
+		<-ra.cfg.ShutdownChan
+		ra.Results.resultLock.Lock()
+		ra.Results.stopTime = time.Now()
+		ra.Results.resultLock.Unlock()
+	}()
+	if ra.cfg.OTLPEndpoint != "" {
+		ra.otlp = NewOTLPExporter(ra.cfg.OTLPEndpoint, ra.cfg.OTLPServiceName, ra.cfg.OTLPSlowSpanThreshold)
+	}
+
+	if ra.cfg.MetricsAddr != "" {
+		go func() {
+			err := ra.ServeMetrics(ctx, ra.cfg.MetricsAddr)
+			if err != nil {
+				log.Errorf("Metrics server stopped: %+v", err)
+			}
+		}()
+	}
+
+	stopIntervals := make(chan struct{})
+	var intervalsStopped sync.WaitGroup
+	intervalsStopped.Add(1)
+	defer func() {
+		close(stopIntervals)
+		intervalsStopped.Wait()
+	}()
+
+	if ra.cfg.CheckpointPath != "" {
+		intervalsStopped.Add(1)
+		go func() {
+			defer intervalsStopped.Done()
+			ra.runCheckpoints(stopIntervals)
+		}()
+	}
+
+	if ra.cfg.SoakEvery > 0 && ra.cfg.SoakDir != "" {
+		intervalsStopped.Add(1)
+		go func() {
+			defer intervalsStopped.Done()
+			ra.runSoakReports(stopIntervals)
+		}()
+	}
+
 	go func() {
-		var lastFiveIntervals, lastFiveIntervalsSuccess, lastFiveIntervalsGets,
-			lastFiveIntervalsPuts, lastFiveIntervalsDeletes, lastFiveIntervalsThrottles,
-			lastFiveIntervalsConsistency []int
+		defer intervalsStopped.Done()
+		if ra.cfg.SnapshotChan != nil {
+			defer close(ra.cfg.SnapshotChan)
+		}
+		exporters := ra.openIntervalExporters()
+		defer func() {
+			for _, exporter := range exporters {
+				_ = exporter.Close()
+			}
+		}()
 
-		var lastFiveIntervalsConsistencyDuration, lastFiveIntervalsGetDuration, lastFiveIntervalsPutDuration,
-			lastFiveIntervalsDeleteDuration []time.Duration
-		var totalSuccessLastInterval, totalGetLastInterval, totalPutLastInterval,
+		rates := newIntervalRates(ra.rateAlpha)
+		var totalBytesUploadedLastInterval, totalBytesDownloadedLastInterval int64
+		var totalSuccessLastInterval, totalFailureLastInterval, totalGetLastInterval, totalPutLastInterval,
 			totalDeleteLastInterval, totalThrottlesLastInterval, totalConsistencyLastInterval int
 
 		var totalGetDurationLastInterval, totalPutDurationLastInterval, totalDeleteDurationLastInterval,
 			totalConsistencyDurationLastInterval time.Duration
+		// Totals at the end of the last interval. Starts from the current totals rather than 0 so results restored
+		// from a checkpoint aren't counted as a single huge interval.
+		markTotals := func() {
+			totalSuccessLastInterval = ra.Results.numSuccess.Get()
+			totalBytesUploadedLastInterval = ra.Results.bytesUploaded.Load()
+			totalBytesDownloadedLastInterval = ra.Results.bytesDownloaded.Load()
+			totalFailureLastInterval = ra.Results.numFailure.Get()
+			totalGetLastInterval = ra.Results.numGet.Get()
+			totalPutLastInterval = ra.Results.numPut.Get()
+			totalDeleteLastInterval = ra.Results.numDelete.Get()
+			totalThrottlesLastInterval = ra.Results.numThrottled.Get()
+			totalConsistencyLastInterval = ra.Results.numConsistency.Get()
+			totalGetDurationLastInterval = ra.Results.totalGetDuration
+			totalPutDurationLastInterval = ra.Results.totalPutDuration
+			totalDeleteDurationLastInterval = ra.Results.totalDeleteDuration
+			totalConsistencyDurationLastInterval = ra.Results.totalConsistencyDuration
+		}
+		ra.Results.resultLock.RLock()
+		markTotals()
+		ra.Results.resultLock.RUnlock()
 		lastUpdate := time.Now()
 
 		for {
-			time.Sleep(time.Millisecond * 50)
+			select {
+			case <-stopIntervals:
+				return
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Millisecond * 50):
+			}
 			if time.Now().Sub(lastUpdate) > ra.Results.interval {
-				lastFiveIntervals = append(lastFiveIntervals, ra.Results.intervalCount)
-				lastFiveIntervalsSuccess = append(lastFiveIntervalsSuccess, ra.Results.numSuccess-totalSuccessLastInterval)
-				lastFiveIntervalsGets = append(lastFiveIntervalsGets, ra.Results.numGet-totalGetLastInterval)
-				lastFiveIntervalsPuts = append(lastFiveIntervalsPuts, ra.Results.numPut-totalPutLastInterval)
-				lastFiveIntervalsDeletes = append(lastFiveIntervalsDeletes, ra.Results.numDelete-totalDeleteLastInterval)
-				lastFiveIntervalsThrottles = append(lastFiveIntervalsThrottles, ra.Results.numThrottled-totalThrottlesLastInterval)
-				lastFiveIntervalsConsistency = append(lastFiveIntervalsConsistency, ra.Results.numConsistency-totalConsistencyLastInterval)
-				lastFiveIntervalsGetDuration = append(lastFiveIntervalsGetDuration, getIntervalAvgDuration(ra.Results.totalGetDuration, totalGetDurationLastInterval, ra.Results.numGetLastInterval))
-				lastFiveIntervalsPutDuration = append(lastFiveIntervalsPutDuration, getIntervalAvgDuration(ra.Results.totalPutDuration, totalPutDurationLastInterval, ra.Results.numPutLastInterval))
-				lastFiveIntervalsDeleteDuration = append(lastFiveIntervalsDeleteDuration, getIntervalAvgDuration(ra.Results.totalDeleteDuration, totalDeleteDurationLastInterval, ra.Results.numDeleteLastInterval))
-				lastFiveIntervalsConsistencyDuration = append(lastFiveIntervalsConsistencyDuration, getIntervalAvgDuration(ra.Results.totalConsistencyDuration, totalConsistencyDurationLastInterval, ra.Results.numConsistencyLastInterval))
-				totalSuccessLastInterval = ra.Results.numSuccess
-				totalGetLastInterval = ra.Results.numGet
-				totalPutLastInterval = ra.Results.numPut
-				totalDeleteLastInterval = ra.Results.numDelete
-				totalThrottlesLastInterval = ra.Results.numThrottled
-				totalConsistencyLastInterval = ra.Results.numConsistency
-				totalGetDurationLastInterval = ra.Results.totalGetDuration
-				totalPutDurationLastInterval = ra.Results.totalPutDuration
-				totalDeleteDurationLastInterval = ra.Results.totalDeleteDuration
-				totalConsistencyDurationLastInterval = ra.Results.totalConsistencyDuration
-
-				if len(lastFiveIntervalsSuccess) > 4 {
-					lastFiveIntervalsSuccess = lastFiveIntervalsSuccess[1:]
-					lastFiveIntervals = lastFiveIntervals[1:]
-					lastFiveIntervalsGets = lastFiveIntervalsGets[1:]
-					lastFiveIntervalsPuts = lastFiveIntervalsPuts[1:]
-					lastFiveIntervalsDeletes = lastFiveIntervalsDeletes[1:]
-					lastFiveIntervalsThrottles = lastFiveIntervalsThrottles[1:]
-					lastFiveIntervalsConsistency = lastFiveIntervalsConsistency[1:]
-					lastFiveIntervalsGetDuration = lastFiveIntervalsGetDuration[1:]
-					lastFiveIntervalsPutDuration = lastFiveIntervalsPutDuration[1:]
-					lastFiveIntervalsDeleteDuration = lastFiveIntervalsDeleteDuration[1:]
-					lastFiveIntervalsConsistencyDuration = lastFiveIntervalsConsistencyDuration[1:]
+				ra.Results.flushPending()
+				ra.Results.resultLock.Lock()
+				stats := IntervalStats{
+					Timestamp:     time.Now(),
+					Duration:      time.Now().Sub(lastUpdate),
+					Requests:      int(ra.Results.intervalCount.Reset()),
+					Successes:     ra.Results.numSuccess.Get() - totalSuccessLastInterval,
+					Failures:      ra.Results.numFailure.Get() - totalFailureLastInterval,
+					Gets:          ra.Results.numGet.Get() - totalGetLastInterval,
+					Puts:          ra.Results.numPut.Get() - totalPutLastInterval,
+					Deletes:       ra.Results.numDelete.Get() - totalDeleteLastInterval,
+					Consistency:   ra.Results.numConsistency.Get() - totalConsistencyLastInterval,
+					Throttles:     ra.Results.numThrottled.Get() - totalThrottlesLastInterval,
+					BytesSent:     ra.Results.bytesUploaded.Load() - totalBytesUploadedLastInterval,
+					BytesReceived: ra.Results.bytesDownloaded.Load() - totalBytesDownloadedLastInterval,
+					Stage:         ra.Results.stage(),
+				}
+				if ra.cfg.ConsistencyChan != nil {
+					ra.Results.consistencyQueue.record(len(ra.cfg.ConsistencyChan))
 				}
+				rates.update(stats)
+				rates.getDuration.UpdateDuration(getIntervalAvgDuration(ra.Results.totalGetDuration, totalGetDurationLastInterval, stats.Gets))
+				rates.putDuration.UpdateDuration(getIntervalAvgDuration(ra.Results.totalPutDuration, totalPutDurationLastInterval, stats.Puts))
+				rates.deleteDuration.UpdateDuration(getIntervalAvgDuration(ra.Results.totalDeleteDuration, totalDeleteDurationLastInterval, stats.Deletes))
+				rates.consistencyDuration.UpdateDuration(getIntervalAvgDuration(ra.Results.totalConsistencyDuration, totalConsistencyDurationLastInterval, stats.Consistency))
+				markTotals()
 
-				ra.Results.resultLock.Lock()
 				lastUpdate = time.Now()
-				ra.Results.numLastInterval = average(lastFiveIntervals)
-				ra.Results.numSuccessLastInterval = average(lastFiveIntervalsSuccess)
-				ra.Results.numGetLastInterval = average(lastFiveIntervalsGets)
-				ra.Results.numPutLastInterval = average(lastFiveIntervalsPuts)
-				ra.Results.numDeleteLastInterval = average(lastFiveIntervalsDeletes)
-				ra.Results.numThrottledLastInterval = average(lastFiveIntervalsThrottles)
-				ra.Results.numConsistencyLastInterval = average(lastFiveIntervalsConsistency)
-				ra.Results.avgGetDurationLastInterval = avgDuration(lastFiveIntervalsGetDuration)
-				ra.Results.avgPutDurationLastInterval = avgDuration(lastFiveIntervalsPutDuration)
-				ra.Results.avgDeleteDurationLastInterval = avgDuration(lastFiveIntervalsDeleteDuration)
-				ra.Results.avgConsistencyDurationLastInterval = avgDuration(lastFiveIntervalsConsistencyDuration)
-				ra.Results.intervalCount = 0
+				stats.Latency = make(map[TestType]LatencySummary, len(latencyOperations))
+				stats.LatencyBuckets = make(map[TestType][]int64, len(latencyOperations))
+				for _, op := range latencyOperations {
+					stats.Latency[op] = summarizeLatency(ra.Results.intervalLatency[op])
+					stats.LatencyBuckets[op] = ra.Results.intervalLatency[op].CoarseCounts(latencyBucketBounds)
+					ra.Results.intervalLatency[op].Reset()
+				}
+				ra.Results.recentLatency.Rotate()
+				ra.Results.numLastInterval = rates.requests.Int()
+				ra.Results.numSuccessLastInterval = rates.successes.Int()
+				ra.Results.numGetLastInterval = rates.gets.Int()
+				ra.Results.numPutLastInterval = rates.puts.Int()
+				ra.Results.numDeleteLastInterval = rates.deletes.Int()
+				ra.Results.numThrottledLastInterval = rates.throttles.Int()
+				ra.Results.numConsistencyLastInterval = rates.consistency.Int()
+				ra.Results.bytesUploadedLastInterval = rates.bytesUploaded.Int()
+				ra.Results.bytesDownloadedLastInterval = rates.bytesDownloaded.Int()
+				ra.Results.avgGetDurationLastInterval = rates.getDuration.Duration()
+				ra.Results.avgPutDurationLastInterval = rates.putDuration.Duration()
+				ra.Results.avgDeleteDurationLastInterval = rates.deleteDuration.Duration()
+				ra.Results.avgConsistencyDurationLastInterval = rates.consistencyDuration.Duration()
+				ra.Results.appendHistory(stats)
+				ra.Results.markSteadyState(stats.Timestamp)
 				if ra.Results.numSuccessLastInterval > ra.Results.maxSeenSuccessfulRequestPerSec {
 					ra.Results.maxSeenSuccessfulRequestPerSec = ra.Results.numSuccessLastInterval
 				}
 				ra.Results.resultLock.Unlock()
 
-				if ra.Results.numFailure > MaxFailuresBeforeExit {
-					close(ra.cfg.ShutdownChan)
-					break
+				for _, exporter := range exporters {
+					err := exporter.Write(stats)
+					if err != nil {
+						log.Errorf("Failed to export interval stats: %+v", err)
+					}
 				}
+				ra.publishSnapshot()
 
+				if ra.Results.numFailure.Get() > MaxFailuresBeforeExit {
+					CloseShutdownChan(ra.cfg.ShutdownChan)
+				}
+
+			}
+		}
+	}()
+
+	// Results still arrive while a cancelled run drains, so they're flushed until every result has been merged.
+	go func() {
+		for {
+			select {
+			case <-ra.done:
+				return
+			case <-time.After(mergeFlushInterval):
+				ra.Results.flushPending()
 			}
 		}
 	}()
 
-	keepRunning := true
-	for keepRunning {
-		var testResult TestResult
-		testResult, keepRunning = <-ra.resultsChan
+	defer close(ra.cfg.FailureChan)
+	defer close(ra.cfg.SuccessChan)
+
+	var resultLog *ResultLogWriter
+	if ra.cfg.ResultLogPath != "" {
+		var err error
+		if ra.cfg.SoakEvery > 0 && ra.cfg.SoakRotateLog {
+			resultLog, err = NewRotatingResultLogWriter(ra.cfg.ResultLogPath, ra.cfg.SoakEvery)
+		} else {
+			resultLog, err = NewResultLogWriter(ra.cfg.ResultLogPath)
+		}
+		if err != nil {
+			log.Errorf("Result log disabled: %+v", err)
+		} else {
+			defer resultLog.Close()
+		}
+	}
+
+	for testResult := range ra.resultsChan {
+		// Tests aborted once the drain's grace period ran out didn't fail, they were cut off. Counting them as errors
+		// would skew the run's tail.
+		if ra.cfg.Drain != nil && wasAbandoned(testResult.err) {
+			ra.cfg.Drain.abandon()
+			continue
+		}
 		ra.Results.Merge(testResult)
-		if (testResult.WasTestFailure() || testResult.Was404()) && keepRunning {
-			ra.cfg.FailureChan <- testResult
+		if ra.otlp != nil {
+			ra.otlp.RecordSpan(testResult, time.Now())
+		}
+		if resultLog != nil {
+			err := resultLog.Write(testResult)
+			if err != nil {
+				log.Errorf("Failed to write result log entry: %+v", err)
+			}
+		}
+		publishTracking(testResult, ra.cfg.FailureChan, ra.cfg.SuccessChan)
+	}
+	ra.Results.flushPending()
+
+}
+
+// publishTracking sends result to the chans the scheduler tracks which files exist from.
+func publishTracking(result TestResult, failureChan chan TestResult, successChan chan TestResult) {
+	if result.WasTestFailure() || result.Was404() || result.WasSelfThrottled() || result.InjectedFault() != "" {
+		failureChan <- result
+	}
+
+	if result.WasSuccess() {
+		successChan <- result
+	}
+}
+
+// publishSnapshot sends a snapshot to the configured SnapshotChan without blocking the aggregator on slow consumers.
+func (ra *ResultAggregator) publishSnapshot() {
+	if ra.cfg.SnapshotChan == nil {
+		return
+	}
+
+	select {
+	case ra.cfg.SnapshotChan <- ra.Results.Snapshot():
+	default:
+		log.Debugf("Snapshot chan full, dropping snapshot.")
+	}
+}
+
+// openIntervalExporters opens every configured per interval exporter. Exporters that fail to open are skipped.
+func (ra *ResultAggregator) openIntervalExporters() []IntervalExporter {
+	var exporters []IntervalExporter
+
+	if ra.cfg.IntervalCSVPath != "" {
+		csvWriter, err := NewIntervalCSVWriter(ra.cfg.IntervalCSVPath)
+		if err != nil {
+			log.Errorf("Interval csv export disabled: %+v", err)
+		} else {
+			exporters = append(exporters, csvWriter)
+		}
+	}
+
+	if ra.cfg.HeatmapCSVPath != "" {
+		heatmap, err := NewHeatmapCSVWriter(ra.cfg.HeatmapCSVPath)
+		if err != nil {
+			log.Errorf("Heatmap export disabled: %+v", err)
+		} else {
+			exporters = append(exporters, heatmap)
+		}
+	}
+
+	if ra.cfg.ProgressOutput != nil {
+		exporters = append(exporters, NewProgressWriter(ra.cfg.ProgressOutput))
+	}
+
+	if ra.cfg.StatsDAddr != "" {
+		emitter, err := NewStatsDEmitter(ra.cfg.StatsDAddr, ra.cfg.StatsDPrefix, ra.cfg.DogStatsD)
+		if err != nil {
+			log.Errorf("StatsD export disabled: %+v", err)
+		} else {
+			exporters = append(exporters, emitter)
 		}
+	}
+
+	if ra.otlp != nil {
+		exporters = append(exporters, ra.otlp)
+	}
+
+	if tune, ok := ra.cfg.Profile.(*AutoTune); ok {
+		exporters = append(exporters, tune)
+	}
+
+	if ra.cfg.Autoscaler != nil {
+		exporters = append(exporters, ra.cfg.Autoscaler)
+	}
+
+	if ra.cfg.SteadyState != nil {
+		exporters = append(exporters, ra.cfg.SteadyState)
+	}
+
+	if ra.cfg.PushgatewayURL != "" {
+		exporters = append(exporters, NewPushgatewayPusher(ra.cfg.PushgatewayURL, ra.cfg.PushgatewayJob, ra.cfg.RunID, ra.Results))
+	}
 
-		if testResult.WasSuccess() && keepRunning {
-			ra.cfg.SuccessChan <- testResult
+	if ra.cfg.InfluxWriteURL != "" || ra.cfg.InfluxFilePath != "" {
+		influx, err := NewInfluxWriter(ra.cfg.RunID, ra.cfg.InfluxWriteURL, ra.cfg.InfluxToken, ra.cfg.InfluxFilePath)
+		if err != nil {
+			log.Errorf("Influx export disabled: %+v", err)
+		} else {
+			exporters = append(exporters, influx)
 		}
 	}
 
+	return exporters
 }
 
 func (ra *ResultAggregator) PrintScore() {
-	scoreModifier := time.Now().Sub(ra.Results.startTime).Minutes() // longer running = better.
-
-	consistencyRate := float64(1) - float64(ra.Results.numFailedConsistency)/float64(ra.Results.numConsistency)
-	successRate := float64(1) - float64(ra.Results.numFailure)/float64(ra.Results.numSuccess+ra.Results.numFailure)
+	ra.Results.resultLock.RLock()
+	elapsed := ra.Results.elapsed()
+	consistencyRate, successRate, score := ra.Results.score(elapsed)
+	maxSeenSuccessfulRequestPerSec := ra.Results.maxSeenSuccessfulRequestPerSec
+	autoTune := ra.Results.autoTuneResult()
+	autoscale := ra.Results.autoscaleResult()
+	ra.Results.resultLock.RUnlock()
+	drain := ra.Results.drain.Summary()
+	steadyState := ra.Results.steadyStateResult()
 	fmt.Printf("Your consistency accuracy was %f percent", math.Round(consistencyRate*10000)/10000*100)
 	fmt.Println()
 	fmt.Printf("Your success rate was %f percent", math.Round(successRate*10000)/10000*100)
 	fmt.Println()
-	fmt.Printf("Your maximum achieved successful requests/sec was %d", ra.Results.maxSeenSuccessfulRequestPerSec)
+	fmt.Printf("Your maximum achieved successful requests/sec was %d", maxSeenSuccessfulRequestPerSec)
 	fmt.Println()
-	fmt.Printf("Your test completed after %d seconds.", int(time.Now().Sub(ra.Results.startTime).Seconds()))
+	fmt.Printf("Your test completed after %d seconds.", int(elapsed.Seconds()))
 	fmt.Println()
-	score := int(math.Round(float64(ra.Results.maxSeenSuccessfulRequestPerSec) * scoreModifier * consistencyRate * successRate))
 	fmt.Printf("Your total score is: %d.", score)
 	fmt.Println()
+	if autoTune != nil {
+		fmt.Println(autoTune)
+	}
+	if autoscale != nil {
+		fmt.Println(autoscale)
+	}
+	if steadyState != nil {
+		fmt.Println(steadyState)
+	}
+	if drain != nil && drain.InFlight > 0 {
+		fmt.Printf("%d tests were in flight at shutdown, %d finished within the %.0fs grace period + %d were abandoned.",
+			drain.InFlight, drain.Completed, drain.GraceSeconds, drain.Abandoned)
+		fmt.Println()
+	}
+}
+
+func newOperationHistograms() map[TestType]*LatencyHistogram {
+	histograms := make(map[TestType]*LatencyHistogram, len(latencyOperations))
+	for _, op := range latencyOperations {
+		histograms[op] = NewLatencyHistogram()
+	}
+
+	return histograms
+}
+
+func newOperationCounters() map[TestType]*Counter {
+	counters := make(map[TestType]*Counter, len(latencyOperations))
+	for _, op := range latencyOperations {
+		counters[op] = &Counter{}
+	}
+
+	return counters
+}
+
+// elapsed returns the run time so far, or until shutdown was requested. Caller must hold resultLock.
+func (tr *TestResults) elapsed() time.Duration {
+	if tr.stopTime.IsZero() {
+		return time.Now().Sub(tr.startTime)
+	}
+
+	return tr.stopTime.Sub(tr.startTime)
+}
+
+// connectionReuseRatio returns the fraction of requests that were sent over a reused connection.
+func (tr *TestResults) connectionReuseRatio() float64 {
+	total := tr.numReusedConns + tr.numNewConns
+	if total == 0 {
+		return 0
+	}
+
+	return float64(tr.numReusedConns) / float64(total)
 }
 
-func average(items []int) int {
-	sum := 0
-	for i := 0; i < len(items); i++ {
-		sum = sum + items[i]
+func newPhaseHistograms() map[RequestPhase]*LatencyHistogram {
+	histograms := make(map[RequestPhase]*LatencyHistogram, len(requestPhases))
+	for _, phase := range requestPhases {
+		histograms[phase] = NewLatencyHistogram()
 	}
 
-	return sum / len(items)
+	return histograms
 }
 
-func avgDuration(items []time.Duration) time.Duration {
-	var sum int64
-	for i := 0; i < len(items); i++ {
-		sum = sum + items[i].Nanoseconds()
+// latencyOperation maps a test type to the latency group it is reported under.
+func latencyOperation(testType TestType) TestType {
+	switch testType {
+	case CREATE, STREAM, PARTIAL, MOVE, COPY, FUZZ, BOUNDARY:
+		return PUT
+	case PUT, DELETE, CONSISTENCY:
+		return testType
+	case CONFLICT:
+		return CONSISTENCY
+	default:
+		return GET
 	}
+}
+
+func sortedStatusCodes(statusCodes map[int]int) []int {
+	codes := make([]int, 0, len(statusCodes))
+	for code := range statusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	return codes
+}
+
+func statusCodeLabel(code int) string {
+	if code == 0 {
+		return "No response"
+	}
+
+	return fmt.Sprintf("%d %s", code, http.StatusText(code))
+}
 
-	return time.Duration(sum / int64(len(items)))
+func bytesToMB(bytes int64) string {
+	return fmt.Sprintf("%.2f", float64(bytes)/1024/1024)
 }
 
+// getIntervalAvgDuration returns the avg duration of requests in the interval, or 0 if there were none.
 func getIntervalAvgDuration(totalDuration, totalDurationLastInterval time.Duration, totalRequestsOfTypeLastInterval int) time.Duration {
 	intervalDur := totalDuration - totalDurationLastInterval
 
 	if totalRequestsOfTypeLastInterval == 0 {
-		totalRequestsOfTypeLastInterval = 1
+		return 0
 	}
 
 	return time.Duration(intervalDur.Nanoseconds() / int64(totalRequestsOfTypeLastInterval))
@@ -0,0 +1,123 @@
+package load_test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMergeExcludesWarmup(t *testing.T) {
+	results := NewResultAggregator(TestSchedulerConfig{Warmup: time.Hour}).Results
+	result := TestResult{
+		testType: CONSISTENCY,
+		duration: 10 * time.Millisecond,
+		response: &http.Response{StatusCode: http.StatusOK},
+		phases:   NewRequestPhases(),
+		worker:   1,
+	}
+	results.Merge(result)
+	results.flushPending()
+
+	if results.warmupLatency.Count() != 1 {
+		t.Errorf("got %d warmup results, want 1", results.warmupLatency.Count())
+	}
+	if results.intervalCount.Get() != 4 {
+		t.Errorf("got %d requests this interval, want 4", results.intervalCount.Get())
+	}
+	if results.numRequests.Get() != 0 || results.numSuccess.Get() != 0 || results.numConsistency.Get() != 0 ||
+		results.totalConsistencyDuration != 0 {
+		t.Errorf("warmup counted: %d requests, %d successes, %d checks, %s", results.numRequests.Get(),
+			results.numSuccess.Get(), results.numConsistency.Get(), results.totalConsistencyDuration)
+	}
+	if results.latency.Count() != 0 || results.intervalLatency[CONSISTENCY].Count() != 0 ||
+		results.recentLatency.Merged().Count() != 0 || len(results.workers) != 0 || len(results.statusCodes) != 0 {
+		t.Errorf("warmup latency recorded in the run's stats")
+	}
+
+	results = NewResultAggregator(TestSchedulerConfig{}).Results
+	results.Merge(result)
+	results.flushPending()
+	if results.numRequests.Get() != 4 || results.numSuccess.Get() != 4 || results.latency.Count() != 1 ||
+		results.warmupLatency.Count() != 0 {
+		t.Errorf("got %d requests, %d successes, %d latencies + %d warmup latencies, want 4, 4, 1 + 0",
+			results.numRequests.Get(), results.numSuccess.Get(), results.latency.Count(), results.warmupLatency.Count())
+	}
+}
+
+func TestMergeSharesWarmupClock(t *testing.T) {
+	pause := NewPauseControl()
+	clock := NewRunClock(pause)
+	cfg := TestSchedulerConfig{Warmup: 50 * time.Millisecond, Pause: pause, Clock: clock}
+	results := NewResultAggregator(cfg).Results
+	scheduler := NewTestScheduler(cfg)
+
+	pause.Pause()
+	time.Sleep(100 * time.Millisecond)
+	// Paused time doesn't count towards warmup, for the scheduler or the aggregator.
+	if !scheduler.inWarmup() || !results.inWarmup() {
+		t.Errorf("warmup ended while paused: scheduler %t, aggregator %t", scheduler.inWarmup(), results.inWarmup())
+	}
+	pause.Resume()
+	time.Sleep(100 * time.Millisecond)
+	if scheduler.inWarmup() || results.inWarmup() {
+		t.Errorf("warmup didn't end: scheduler %t, aggregator %t", scheduler.inWarmup(), results.inWarmup())
+	}
+}
+
+// BenchmarkMerge merges results while they're flushed like the aggregator does + a reader polls the stats like the
+// dashboard + metrics server do.
+func BenchmarkMerge(b *testing.B) {
+	benchmarkMerge(b, false)
+}
+
+func benchmarkMerge(b *testing.B, parallel bool) {
+	results := NewResultAggregator(TestSchedulerConfig{}).Results
+	response := &http.Response{StatusCode: http.StatusOK}
+	merge := func(i int, worker int) {
+		results.Merge(TestResult{
+			testType: latencyOperations[i%len(latencyOperations)],
+			duration: time.Duration(i%1000) * time.Microsecond,
+			response: response,
+			phases:   NewRequestPhases(),
+			worker:   worker,
+		})
+	}
+	stop := make(chan struct{})
+	poll := func(every time.Duration, read func()) {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(every):
+				read()
+			}
+		}
+	}
+	go poll(time.Millisecond, func() { _ = results.Snapshot() })
+	go poll(mergeFlushInterval, results.flushPending)
+	defer close(stop)
+
+	b.ResetTimer()
+	start := time.Now()
+	if parallel {
+		var workers int64
+		b.RunParallel(func(pb *testing.PB) {
+			worker := int(atomic.AddInt64(&workers, 1))
+			for i := 0; pb.Next(); i++ {
+				merge(i, worker)
+			}
+		})
+	} else {
+		for i := 0; i < b.N; i++ {
+			merge(i, i%64)
+		}
+	}
+	results.flushPending()
+	b.ReportMetric(float64(b.N)/time.Now().Sub(start).Seconds(), "results/sec")
+}
+
+// BenchmarkMergeParallel is BenchmarkMerge with results merged from several goroutines at once, each for its own workers.
+func BenchmarkMergeParallel(b *testing.B) {
+	benchmarkMerge(b, true)
+}
@@ -1,39 +1,102 @@
 package load_test
 
 import (
+	"container/heap"
+	"context"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"sync"
 	"time"
 )
 
-// Listens to a channel of requested tests + runs them
+// Listens to a channel of requested tests + runs them. Every test runs on its own goroutine, so load is open loop: the
+// rate tests start at is set by the scheduler alone, and never slows down because responses do.
 
 func NewTestRunner(cfg TestRunnerConfig) *TestRunner {
 	return &TestRunner{
-		cfg: cfg}
+		cfg:   cfg,
+		slots: newConcurrencySlots(cfg.ConcurrencyLimits)}
 }
 
 type TestRunner struct {
-	cfg TestRunnerConfig
+	cfg   TestRunnerConfig
+	slots *concurrencySlots
 }
 
 type TestRunnerConfig struct {
 	TestConfig
 	EndpointCfg  TestEndpointConfig
+	Targets      Targets // If set, files are spread over these rather than sent to EndpointCfg.
 	ResultChan   chan TestResult
 	ScheduleChan chan Test
+	ShutdownChan chan bool     // Once closed, queued tests are dropped rather than started.
+	VirtualUsers int           // If > 0, runs closed loop with this many users, see runVirtualUsers.
+	ThinkTime    ThinkTime     // Pause between each virtual user's tests.
+	Pacing       time.Duration // If > 0, each virtual user starts a test at most once per Pacing.
+	Jitter       Jitter        // With Pacing, offsets each virtual user's first test so users don't start in lockstep.
+	Pause        *PauseControl // If set, queued tests aren't started while paused.
+	Live         *LiveSettings // If set, the # of virtual users can be changed mid run.
+	Chaos        *Chaos        // If set, faults are injected into requests, see Chaos.
+	// If set, consistency checks queued on ConsistencyChan run from their own pool of workers, see ConsistencyPool.
+	ConsistencyPool *ConsistencyPool
+	ConsistencyChan chan Test
+	Drain           *Drain      // If set, tracks tests in flight so they can finish on shutdown.
+	Retries         *RetryQueue // If set, failed GETs + DELETEs are retried, see RetryQueue.
+	Stores          RunStores   // Shared with the scheduler, see RunStores.
 }
 
-// Run Listens to scheduler test chan and runs tests
-func (tr *TestRunner) Run() {
-	client := &http.Client{
-		Transport: &http.Transport{
-			MaxIdleConns:    45000,
-			MaxConnsPerHost: 0,
-		},
-		Timeout: time.Second * 20,
+// workerIDs hands out the lowest free worker ID to each in-flight test, so IDs stay stable + dense even though every
+// test runs on its own goroutine. A worker ID identifies a concurrency slot rather than a particular goroutine.
+type workerIDs struct {
+	lock sync.Mutex
+	free intHeap
+	next int
+}
+
+func (w *workerIDs) Acquire() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.free.Len() == 0 {
+		w.next++
+		return w.next - 1
 	}
-	exec := NewTestExecutor(client, tr.cfg.EndpointCfg, tr.cfg.TestConfig, tr.cfg.ResultChan)
+
+	return heap.Pop(&w.free).(int)
+}
+
+func (w *workerIDs) Release(id int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	heap.Push(&w.free, id)
+}
+
+// intHeap is a min-heap of ints for container/heap.
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *intHeap) Push(x interface{}) {
+	*h = append(*h, x.(int))
+}
+
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return x
+}
+
+// Run Listens to scheduler test chan and runs tests. Once the schedule chan is closed, waits for in-flight tests to
+// finish then closes the result chan. Cancelling ctx shuts the run down + aborts in-flight requests.
+func (tr *TestRunner) Run(ctx context.Context) {
+	go closeShutdownOnCancel(ctx, tr.cfg.ShutdownChan)
+	exec := NewTestExecutor(ctx, newHTTPClient(tr.cfg.TestConfig.Timeouts), resolveTargets(tr.cfg.EndpointCfg, tr.cfg.Targets), tr.cfg.TestConfig, tr.cfg.Stores, tr.cfg.ResultChan)
+	// Only the runner misbehaves, pre-seeding + cleanup share the executor but should always succeed where they can.
+	exec.chaos = newChaosInjector(tr.cfg.Chaos)
 
 	lastFileSizeUpdate := time.Now()
 
@@ -48,47 +111,281 @@ func (tr *TestRunner) Run() {
 					log.Infof("Increasing max file size due to ramp. New max size is: %d bytes", fileSize)
 					lastFileSizeUpdate = time.Now()
 				}
-				time.Sleep(time.Second)
+				select {
+				case <-time.After(time.Second):
+				case <-tr.cfg.ShutdownChan:
+					return
+				}
 			}
 		}
 	}()
 
+	var inFlight sync.WaitGroup
+	workers := &workerIDs{}
+	defer func() {
+		inFlight.Wait()
+		close(tr.cfg.ResultChan)
+	}()
+
+	if tr.cfg.Retries != nil {
+		exec.retries = tr.cfg.Retries
+		go tr.cfg.Retries.run(&inFlight, tr.cfg.ShutdownChan, func(test Test) {
+			go func() {
+				defer inFlight.Done()
+				if !tr.slots.acquire(test.TestType, tr.cfg.ShutdownChan) {
+					return
+				}
+				defer tr.slots.release(test.TestType)
+				test.worker = workers.Acquire()
+				defer workers.Release(test.worker)
+				tr.runTest(exec, test)
+			}()
+		})
+	}
+
+	if tr.cfg.ConsistencyPool != nil {
+		// Its own client, so the rest of the load can't tie up the connections checks need.
+		checks := NewTestExecutor(ctx, newHTTPClient(tr.cfg.TestConfig.Timeouts), resolveTargets(tr.cfg.EndpointCfg, tr.cfg.Targets), tr.cfg.TestConfig,
+			tr.cfg.Stores, tr.cfg.ResultChan)
+		checks.chaos = exec.chaos
+		tr.runConsistencyPool(checks, &inFlight)
+	}
+
+	if tr.cfg.VirtualUsers > 0 {
+		tr.runVirtualUsers(exec, &inFlight)
+		return
+	}
+
 	keepRunning := true
-	var test Test
 	for keepRunning {
-		var funcToRun func()
+		var test Test
 		test, keepRunning = <-tr.cfg.ScheduleChan
 		if !keepRunning {
 			break
 		}
 
-		switch test.TestType {
-		case GET:
-			funcToRun = func() {
-				exec.GetFile(test.fileName)
-			}
-		case PUT:
-			funcToRun = func() {
-				exec.PutFile(test.fileName)
-			}
-		case DELETE:
-			funcToRun = func() {
-				exec.DeleteFile(test.fileName)
-			}
-		case CREATE:
-			funcToRun = func() {
-				exec.CreateFile(test.fileName)
+		tr.waitWhilePaused(&test)
+		select {
+		case <-tr.cfg.ShutdownChan:
+			continue
+		default:
+		}
+
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			// Wait for a slot before taking a worker ID, worker IDs are for tests actually in flight.
+			if !tr.slots.acquire(test.TestType, tr.cfg.ShutdownChan) {
+				return
 			}
-		case CONSISTENCY:
-			funcToRun = func() {
-				exec.ConsistencyCheck(test.fileName)
+			defer tr.slots.release(test.TestType)
+			test.worker = workers.Acquire()
+			defer workers.Release(test.worker)
+			tr.runTest(exec, test)
+		}()
+	}
+}
+
+// newHTTPClient returns a client whose timeout is long enough for any operation, see OperationTimeouts.
+func newHTTPClient(timeouts OperationTimeouts) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConns:    45000,
+			MaxConnsPerHost: 0,
+		},
+		Timeout: timeouts.clientTimeout(),
+	}
+}
+
+// runVirtualUsers runs closed loop: each virtual user runs one test at a time, pausing for think time in between, so
+// load is set by the # of users + how fast the server responds rather than by the scheduler. With pacing, a user that
+// finishes a test + its think time early waits out the rest of the pacing interval before starting the next. The # of
+// users can be changed mid run with LiveSettings: extra users start straight away, + surplus users stop once they
+// finish their current test.
+func (tr *TestRunner) runVirtualUsers(exec *TestExecutor, running *sync.WaitGroup) {
+	log.Infof("Running %d virtual users with %s think time, %s pacing", tr.cfg.VirtualUsers, tr.cfg.ThinkTime, tr.cfg.Pacing)
+	pool := &userPool{ids: &workerIDs{}, finished: make(chan struct{})}
+	start := func() {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			tr.runVirtualUser(exec, pool)
+		}()
+	}
+	pool.grow(tr.cfg.VirtualUsers, start)
+	if tr.cfg.Live == nil {
+		return
+	}
+
+	// Holding running open while watching for changes means users can always be added, until every user has seen the
+	// schedule chan close.
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		for {
+			select {
+			case <-tr.cfg.Live.Changed():
+				pool.grow(tr.cfg.Live.Users(), start)
+			case <-pool.finished:
+				return
+			case <-tr.cfg.ShutdownChan:
+				return
 			}
+		}
+	}()
+}
+
+// runVirtualUser runs tests one at a time until the schedule chan closes, or the pool has more users than it needs.
+func (tr *TestRunner) runVirtualUser(exec *TestExecutor, pool *userPool) {
+	user := pool.ids.Acquire()
+	defer pool.ids.Release(user)
+	if tr.cfg.Pacing > 0 {
+		tr.pause(tr.cfg.Jitter.offset(tr.cfg.Pacing))
+	}
+	for !pool.retire(tr.users()) {
+		test, ok := <-tr.cfg.ScheduleChan
+		if !ok {
+			pool.finish()
+			return
+		}
+
+		tr.waitWhilePaused(&test)
+		select {
+		case <-tr.cfg.ShutdownChan:
+			continue
 		default:
-			funcToRun = func() {
-				exec.GetFile(test.fileName)
-			}
 		}
 
-		go funcToRun()
+		if !tr.slots.acquire(test.TestType, tr.cfg.ShutdownChan) {
+			continue
+		}
+		start := time.Now()
+		test.worker = user
+		tr.runTest(exec, test)
+		tr.slots.release(test.TestType)
+		tr.pause(tr.cfg.ThinkTime.Next())
+		if tr.cfg.Pacing > 0 {
+			tr.pause(time.Until(start.Add(tr.cfg.Pacing)))
+		}
+	}
+}
+
+// users returns the # of virtual users to run.
+func (tr *TestRunner) users() int {
+	if tr.cfg.Live == nil {
+		return tr.cfg.VirtualUsers
+	}
+
+	return tr.cfg.Live.Users()
+}
+
+// userPool tracks the # of running virtual users, so it can be grown + shrunk mid run.
+type userPool struct {
+	lock     sync.Mutex
+	active   int
+	ids      *workerIDs
+	closed   bool
+	finished chan struct{} // Closed once a user has seen the schedule chan close
+}
+
+// grow calls start for each user needed to reach target.
+func (p *userPool) grow(target int, start func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for ; !p.closed && p.active < target; p.active++ {
+		start()
+	}
+}
+
+// retire returns true if the pool has more than target users, in which case the caller should stop.
+func (p *userPool) retire(target int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.active <= target {
+		return false
+	}
+
+	p.active--
+	return true
+}
+
+// finish is called by each user that sees the schedule chan close.
+func (p *userPool) finish() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.active--
+	if !p.closed {
+		p.closed = true
+		close(p.finished)
+	}
+}
+
+// waitWhilePaused holds test back while load generation is paused. A test held back by a pause didn't start late
+// because the system under test was slow, so it's left out of schedule lag.
+func (tr *TestRunner) waitWhilePaused(test *Test) {
+	if tr.cfg.Pause.Wait(tr.cfg.ShutdownChan) {
+		test.scheduledAt = time.Time{}
+	}
+}
+
+// pause sleeps for d, or until shutdown so long think times don't hold up draining.
+func (tr *TestRunner) pause(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-tr.cfg.ShutdownChan:
+	}
+}
+
+// runTest runs test on the calling goroutine.
+func (tr *TestRunner) runTest(exec *TestExecutor, test Test) {
+	tr.cfg.Drain.start()
+	defer tr.cfg.Drain.finish()
+	// Time spent backing off is left in schedule lag, it's the server that held the test back.
+	exec.backoff.Wait(tr.cfg.ShutdownChan)
+	switch test.TestType {
+	case GET:
+		exec.GetFile(test)
+	case PUT:
+		exec.PutFile(test)
+	case DELETE:
+		exec.DeleteFile(test)
+	case CREATE:
+		exec.CreateFile(test)
+	case CONSISTENCY:
+		exec.ConsistencyCheck(test)
+	case SCENARIO:
+		exec.RunScenario(test)
+	case HEAD:
+		exec.HeadFile(test)
+	case RANGE:
+		exec.RangeFile(test)
+	case CONDITIONAL:
+		exec.ConditionalGetFile(test)
+	case STREAM:
+		exec.StreamFile(test)
+	case CONFLICT:
+		exec.ConflictFile(test)
+	case PARTIAL:
+		exec.PartialWriteFile(test)
+	case LIST:
+		exec.ListFiles(test)
+	case MOVE:
+		exec.MoveFile(test)
+	case COPY:
+		exec.CopyFile(test)
+	case FUZZ:
+		exec.FuzzFile(test)
+	case BOUNDARY:
+		exec.BoundaryFile(test)
+	case DOWNLOAD:
+		exec.DownloadFile(test)
+	default:
+		exec.GetFile(test)
 	}
 }
@@ -1,8 +1,9 @@
 package load_test
 
 import (
+	"context"
 	log "github.com/sirupsen/logrus"
-	"math/rand"
+	"io"
 	"sync"
 	"time"
 )
@@ -21,7 +22,12 @@ const (
 
 type Test struct {
 	TestType
-	fileName string
+	fileName    string
+	scheduledAt time.Time // When the scheduler intended the test to start, assuming perfectly even pacing.
+	worker      int       // Set by the runner, see workerIDs.
+	scenario    *Scenario // Steps to run, for SCENARIO tests.
+	payloadSeed int64     // If set, the test's payload is generated from this seed, see Rand.
+	attempt     int       // # of times the test has been retried, see RetryQueue.
 }
 
 type TestCadenceConfig struct {
@@ -34,19 +40,108 @@ type TestConfig struct {
 	MaxFileCount          int
 	FileSizeRamp          bool
 	UploadRandomLargeFile bool
+	TraceRequests         bool              // If true, a W3C traceparent header is sent with every request.
+	ConcurrencyLimits     ConcurrencyLimits // If set, caps in flight tests per operation. See ParseConcurrencyLimits.
+	ClientBackoff         bool              // If true, 429s with a Retry-After hold back new tests, see ClientBackoff.
+	RequestTags           *RequestTags      // If set, every request is tagged with the run ID + a sequence #.
+	// How long each operation may take before it times out, see OperationTimeouts.
+	Timeouts OperationTimeouts
+	// Size of STREAM uploads in bytes, before base64 encoding. DefaultStreamSize if 0.
+	StreamSize int64
+	// If true, GETs are checked against the checksum of the last upload, see ChecksumStore. Each process keeps its own.
+	VerifyChecksums bool
+	// If set, consistency checks GET the deleted file again this long after the DELETE, catching stale caches.
+	DeleteRecheckDelay time.Duration
+	ListCheck          *ListCheck // If set, consistency checks verify the server's listing endpoint.
+	ListAPI            *ListAPI   // The server's paginated listing endpoint, LIST tests need one.
+	// If set, CREATE + PUT payload sizes are drawn from it instead of up to MaxFileSize, see SizeDistribution.
+	Sizes *SizeDistribution
+	// Share of payloads that are compressible text rather than random bytes, between 0 + 1, see fillPayload.
+	Compressible float64
+	Keys         *PathKeys // If set, new files' keys are nested under directories, see PathKeys.
+	// The server's buffer + max file sizes BOUNDARY tests probe, only 0 + 1 byte files if nil. See BoundarySizes.
+	Boundaries *BoundarySizes
+	// Share of CREATEs, PUTs + consistency checks that upload an empty file, between 0 + 1, see EmptyFileStore.
+	EmptyFiles float64
+	// Size of DOWNLOAD files in bytes, before base64 encoding. DefaultDownloadSize if 0.
+	DownloadSize int64
 }
 
 type TestSchedulerConfig struct {
-	EndpointCfg       TestEndpointConfig
-	SeedCadence       TestCadenceConfig
-	SeedGrowthAmount  float64
-	EnableRequestRamp bool
-	TestConfig        TestConfig
-	SchedulerChan     chan Test
-	ResultChan        chan TestResult
-	FailureChan       chan TestResult // All test failures are published here.
-	SuccessChan       chan TestResult // All test successes published here.
-	ShutdownChan      chan bool
+	RunID                 string // Identifies this run in exported metrics.
+	EndpointCfg           TestEndpointConfig
+	Targets               Targets // If set, files are spread over these rather than sent to EndpointCfg.
+	SeedCadence           TestCadenceConfig
+	SeedGrowthAmount      float64
+	Profile               LoadProfile // If set, replaces the seed cadence, growth + request ramp. See ParseLoadProfile.
+	VirtualUsers          int         // If > 0, tests aren't paced, the runner's virtual users pull them as fast as they run them.
+	ThinkTime             ThinkTime   // Virtual user think time + pacing, only used to report on the pacing achieved.
+	Pacing                time.Duration
+	Jitter                Jitter   // Randomly offsets each test's start within its pacing slot, see Jitter.
+	Arrivals              Arrivals // Distribution of gaps between open loop tests' starts, see Arrivals.
+	EnableRequestRamp     bool
+	Duration              time.Duration // If > 0, scheduling stops + the run shuts down after this long.
+	RequestLimit          RequestLimit  // If set, scheduling stops + the run ends once its tests have all run.
+	TestConfig            TestConfig
+	Stores                RunStores     // State shared by this process's tests, never sent to agents. See RunStores.
+	OperationMix          OperationMix  // If set, replaces the default mix of operations. See ParseOperationMix.
+	Scenarios             *ScenarioFile // If set, Scenarios.Share of tests are multi step scenarios. See LoadScenarios.
+	Preseeded             []string      // Keys of files already on the server before the run, see Preseed.
+	Zipf                  float64       // If > 0, GETs pick preseeded files by a Zipf distribution of this exponent, see zipfKeys.
+	RandSeed              int64         // If set, operations, keys + payloads are picked from a seeded Rand, see Rand.
+	Chaos                 *Chaos        // If set, the runner injects faults into requests, see Chaos.
+	SchedulerChan         chan Test
+	ResultChan            chan TestResult
+	FailureChan           chan TestResult // All test failures are published here.
+	SuccessChan           chan TestResult // All test successes published here.
+	ShutdownChan          chan bool
+	MetricsAddr           string  // If set, Prometheus metrics are served on this address. I.E :9100
+	PushgatewayURL        string  // If set, Prometheus metrics are pushed here every interval. I.E http://localhost:9091
+	PushgatewayJob        string  // Job label pushed metrics are grouped under, along with run_id.
+	IntervalCSVPath       string  // If set, per interval counts + rates are appended to this CSV file.
+	HeatmapCSVPath        string  // If set, per interval latency bucket counts are appended to this CSV file.
+	RateAlpha             float64 // EWMA smoothing factor (0, 1] for "current" rates. Defaults to DefaultRateAlpha.
+	Thresholds            Thresholds
+	ResultLogPath         string // If set, every test result is streamed to this file as NDJSON.
+	StatsDAddr            string // If set, per interval metrics are pushed to this StatsD host:port over UDP.
+	StatsDPrefix          string // Prefix for all StatsD metric names.
+	DogStatsD             bool   // If true, operation is sent as a DogStatsD tag rather than part of the metric name.
+	OTLPEndpoint          string // If set, metrics + slow request spans are exported here via OTLP/HTTP. I.E http://localhost:4318
+	OTLPServiceName       string
+	OTLPSlowSpanThreshold time.Duration // Requests slower than this (or failed) are exported as spans.
+	InfluxWriteURL        string        // If set, interval stats are written here in line protocol.
+	InfluxToken           string
+	InfluxFilePath        string        // If set, interval stats are appended to this file in line protocol.
+	SnapshotChan          chan Snapshot // If set, receives a Snapshot per interval, dropped when full. Closed when the aggregator finishes.
+	// If true, latency is measured from when a test was scheduled to start rather than when it actually started, so
+	// a saturated scheduler/runner can't hide queueing delay from percentiles. I.E wrk2 style coordinated omission correction.
+	CorrectOmission bool
+	ApdexTarget     time.Duration // Target latency T for the Apdex score. Defaults to DefaultApdexTarget.
+	ErrorBudget     float64       // Allowed failure fraction [0, 1), I.E 0.001 for 0.1%, 0 allows no failures. See ParseErrorBudget
+	RecentWindow    int           // # of intervals covered by recent latency percentiles. Defaults to DefaultRecentWindow.
+	CheckpointPath  string        // If set, cumulative results are periodically saved here, see Checkpoint.
+	CheckpointEvery time.Duration // Defaults to CheckpointInterval.
+	SoakEvery       time.Duration // If > 0, a timestamped summary is written to SoakDir this often, see soak mode.
+	SoakDir         string
+	SoakRotateLog   bool          // If true with SoakEvery, the result log is rotated to a new timestamped file this often too.
+	Warmup          time.Duration // Results in the first Warmup of the run only count towards warmup latency + live rates.
+	WarmupRate      int           // req/sec scheduled during Warmup, before the measured phase. Defaults to DefaultWarmupRate.
+	ProgressOutput  io.Writer     // If set, a single line progress record is written here per interval, see ProgressWriter.
+	Pause           *PauseControl // If set, scheduling can be paused + resumed mid run.
+	Clock           *RunClock     // Shared by the scheduler + aggregator, see RunClock. Each makes its own if nil.
+	Live            *LiveSettings // If set, the rate + mix can be overridden mid run.
+	Autoscaler      *Autoscaler   // If set, scales virtual users to keep p99 near a target, see Autoscaler.
+	SteadyState     *SteadyState  // If set, the run stops once throughput + p99 settle, see SteadyState.
+	TrackCreated    bool          // If true, files the run may have left on the server are tracked for Cleanup.
+	// If set, consistency checks are queued on ConsistencyChan at their own rate, see ConsistencyPool.
+	ConsistencyPool *ConsistencyPool
+	ConsistencyChan chan Test
+	// If > 0, consistency checks are started at this many per second regardless of load, see consistencyRate.
+	ConsistencyRate float64
+	Drain           *Drain      // If set, in-flight tests get a grace period to finish on shutdown, see Drain.
+	Replay          *Replay     // If set, replaces scheduling with a recorded result log's operations, see Replay.
+	Retries         *RetryQueue // If set, failed GETs + DELETEs are retried, see RetryQueue.
+	ExtraOps        ExtraOps    // Operations run on existing files alongside the mix, I.E HEAD. See ExtraOps.
 }
 
 type TestScheduler struct {
@@ -54,31 +149,57 @@ type TestScheduler struct {
 	seedResetTime  time.Time
 	numScheduled   int
 	totalScheduled int64
+	opsScheduled   map[TestType]int
+	reqsScheduled  int64
 	growthFactor   int // each time growth cadence is met, growth factor increases by 1. Total growth = growth config * growth factor
 	tests          []TestType
-	trackedFiles   FileSet
+	mix            OperationMix // Mix tests are currently picked by, unset for the default mix
+	trackedFiles   *SortedFileSet
+	hotKeys        *zipfKeys // Nil unless GETs are skewed, see TestSchedulerConfig.Zipf
+	rand           *Rand     // Nil unless the run is seeded
 
 	trackedFileLock sync.RWMutex
 	startTime       time.Time
 	rampAmount      int
 	rampFactor      int
 	lastRamp        time.Time
+	bursts          int // # of bursts released so far, see Burst
 }
 
 // NewTestScheduler - Tests are immediately scheduled at the seed cadence, and will grow at a rate of seed + repeating growth cadence.
 // I.E if seed is 5 req/s and growth is 1 req/sec, tests will schedule at 5/sec, then 1 sec later, 6/sec, then
 // one sec later, 7/sec, etc.
 func NewTestScheduler(cfg TestSchedulerConfig) TestScheduler {
-	tests := []TestType{PUT, DELETE}
-	for i := 0; i < 75; i++ {
-		tests = append(tests, GET)
+	tests := defaultTests()
+	if cfg.OperationMix.IsSet() {
+		tests = cfg.OperationMix.tests()
+	}
+	tests = withoutPacedChecks(cfg, tests)
+	if cfg.WarmupRate <= 0 {
+		cfg.WarmupRate = DefaultWarmupRate
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = NewRunClock(cfg.Pause)
+	}
+	trackedFiles := NewSortedFileSet(len(cfg.Preseeded))
+	for _, key := range cfg.Preseeded {
+		trackedFiles.Add(key)
+	}
+	hotKeys := newZipfKeys(cfg.Zipf, cfg.Preseeded)
+	if hotKeys != nil {
+		log.Infof("GETs follow a Zipf distribution, s=%.2f over %d files: the hottest 1%% get %.0f%% of GETs", hotKeys.s,
+			len(hotKeys.keys), hotKeys.topShare(0.01)*100)
 	}
 
 	return TestScheduler{
 		cfg:          cfg,
 		growthFactor: 0,
 		tests:        tests,
-		trackedFiles: make(FileSet),
+		mix:          cfg.OperationMix,
+		trackedFiles: trackedFiles,
+		hotKeys:      hotKeys,
+		rand:         newSeededRand(cfg.RandSeed),
+		opsScheduled: make(map[TestType]int),
 		startTime:    time.Now(),
 		rampFactor:   1,
 		rampAmount:   0,
@@ -86,15 +207,67 @@ func NewTestScheduler(cfg TestSchedulerConfig) TestScheduler {
 	}
 }
 
-func (ts *TestScheduler) Run() {
+// Run schedules tests until ctx is cancelled, the shutdown chan is closed or the request limit is reached, then closes
+// the schedule chan.
+func (ts *TestScheduler) Run(ctx context.Context) {
+	go closeShutdownOnCancel(ctx, ts.cfg.ShutdownChan)
 	keepRunning := true
 	ts.seedResetTime = time.Now().Add(ts.cfg.SeedCadence.Duration)
 	go ts.MergeFailedTestResults()
 	go ts.MergeSuccessfulTestResults()
+	if ts.cfg.Warmup > 0 {
+		time.AfterFunc(ts.cfg.Warmup, func() {
+			log.Infof("Warmup of %s finished, measurement started.", ts.cfg.Warmup)
+		})
+	}
+	if ts.cfg.Duration > 0 {
+		go ts.stopAfterDuration()
+	}
 
-	for keepRunning {
-		// Schedule tests.
-		ts.ScheduleTests()
+	// Paced checks aren't counted towards the request limit, so they stop with the rest of the load.
+	stopChecks, checksStopped := make(chan struct{}), make(chan struct{})
+	if ts.cfg.consistencyRate() > 0 {
+		go ts.scheduleConsistencyChecks(stopChecks, checksStopped)
+	} else {
+		close(checksStopped)
+	}
+
+	// Closing the schedule chan rather than the shutdown chan once the request limit is reached lets queued tests run.
+	defer func() {
+		if ts.limitReached() {
+			log.Infof("Scheduled all %d requests allowed by the request limit, waiting for them to finish.", ts.reqsScheduled)
+		}
+		close(stopChecks)
+		<-checksStopped
+		close(ts.cfg.SchedulerChan)
+		if ts.cfg.ConsistencyChan != nil {
+			close(ts.cfg.ConsistencyChan)
+		}
+	}()
+
+	if ts.cfg.Replay != nil {
+		ts.replay()
+		return
+	}
+
+	if ts.cfg.VirtualUsers > 0 {
+		ts.feedVirtualUsers()
+		return
+	}
+
+	for keepRunning && !ts.limitReached() {
+		if ts.cfg.Pause.Wait(ts.cfg.ShutdownChan) {
+			// Start a fresh seed duration, rather than trying to catch up on the tests the pause skipped.
+			ts.seedResetTime = time.Now().Add(ts.cfg.SeedCadence.Duration)
+			ts.numScheduled = 0
+		}
+
+		// Schedule tests. Bursts are released all at once rather than paced, once warmup is over.
+		if burst, ok := ts.cfg.Profile.(*Burst); ok && !ts.inWarmup() && ts.cfg.Live.Rate() <= 0 {
+			ts.scheduleBurst(burst)
+		} else {
+			ts.ScheduleTests()
+		}
 
 		select {
 		case _, keepRunning = <-ts.cfg.ShutdownChan:
@@ -102,40 +275,76 @@ func (ts *TestScheduler) Run() {
 		}
 		time.Sleep(time.Microsecond * 50)
 	}
+}
+
+// stopAfterDuration shuts the run down once it has run for Duration, not counting time spent paused.
+func (ts *TestScheduler) stopAfterDuration() {
+	for remaining := ts.cfg.Duration; remaining > 0; remaining = ts.cfg.Duration - ts.elapsed() {
+		select {
+		case <-time.After(remaining):
+		case <-ts.cfg.ShutdownChan:
+			return
+		}
+	}
+
+	log.Infof("Reached run duration of %s, shutting down.", ts.cfg.Duration)
+	CloseShutdownChan(ts.cfg.ShutdownChan)
+}
+
+// feedVirtualUsers keeps the schedule chan full for closed loop runs. Virtual users take a test each time they finish
+// one, so a full chan is what paces scheduling. Tests have no intended start, so there's no schedule lag to measure.
+func (ts *TestScheduler) feedVirtualUsers() {
+	for !ts.limitReached() {
+		test := ts.GetTestFunc()
+		if !ts.admit(test) {
+			continue
+		}
 
-	close(ts.cfg.SchedulerChan)
+		select {
+		case ts.cfg.SchedulerChan <- test:
+			ts.totalScheduled++
+		case <-ts.cfg.ShutdownChan:
+			return
+		}
+	}
 }
 
 // ScheduleTests schedules tests on the channel if we haven't met our quota based on seed configs
 func (ts *TestScheduler) ScheduleTests() {
-	targetSeed := ts.cfg.SeedCadence.TestsPerDuration + int(float64(ts.growthFactor)*float64(ts.cfg.SeedGrowthAmount)) + ts.rampAmount
+	targetSeed := ts.targetRate()
 	seedCount := targetSeed // num in this seed that need to be scheduled.
 	startTime := time.Now()
+	// Tests are paced evenly over the remaining seed duration, so the intended start of each is a fixed slot apart.
+	alreadyScheduled := ts.numScheduled
+	slot := time.Duration(0)
+	if seedCount > alreadyScheduled {
+		slot = ts.cfg.SeedCadence.Duration / time.Duration(seedCount-alreadyScheduled)
+	}
 
-	for ts.numScheduled < seedCount {
-		scheduleStart := time.Now()
-		ts.cfg.SchedulerChan <- ts.GetTestFunc()
-		ts.numScheduled++
-		ts.totalScheduled++
-		remainingTime := ts.cfg.SeedCadence.Duration - time.Now().Sub(startTime) // remaining time before reset
-
-		// Calculates time it takes to schedule a job.
-		scheduleDur := time.Now().Sub(scheduleStart)
-
-		// Spaces out scheduling of requests over the duration the seed duration so we don't
-		// schedule + run all N requests instantly. This ensures a smooth rate of scheduled / executed tests.
-		// also removes a flat 50 microseconds to account for time for this calculation and provide a buffer
-		seedsLeft := seedCount - ts.numScheduled
-		if seedsLeft > 0 {
-			time.Sleep((remainingTime / time.Duration(seedsLeft)) - scheduleDur - time.Microsecond*50)
+	next := time.Duration(0)
+	for ts.numScheduled < seedCount && !ts.limitReached() {
+		// Spaces out scheduling of requests over the seed duration so we don't schedule + run all N requests
+		// instantly. Sleeping until each test's intended start (rather than for a fixed gap) keeps the arrival rate
+		// open loop: time spent scheduling, or blocked on a full schedule chan, is caught up rather than added on.
+		scheduledAt := startTime.Add(next + ts.cfg.Jitter.offset(slot))
+		next += ts.cfg.Arrivals.next(slot)
+		time.Sleep(time.Until(scheduledAt))
+		test := ts.GetTestFunc()
+		test.scheduledAt = scheduledAt
+		if ts.admit(test) {
+			ts.cfg.SchedulerChan <- test
+			ts.totalScheduled++
 		}
+		ts.numScheduled++
 	}
 
 	// If we are after our reset time, reset to a new time, and reset num scheduled to whatever's left, or 0
 	if time.Now().UnixMicro() > ts.seedResetTime.UnixMicro() {
 		ts.seedResetTime = time.Now().Add(ts.cfg.SeedCadence.Duration)
 		ts.numScheduled = seedCount - ts.numScheduled
-		ts.growthFactor++
+		if !ts.inWarmup() {
+			ts.growthFactor++
+		}
 
 		// If request ramp is eanbled, ramp requests rates
 		if ts.cfg.EnableRequestRamp {
@@ -153,20 +362,75 @@ func (ts *TestScheduler) ScheduleTests() {
 
 }
 
+// targetRate returns the # of tests to schedule this seed duration, from the load profile if there is one. Profiles
+// start once warmup is over. A live rate override replaces all of these.
+func (ts *TestScheduler) targetRate() int {
+	if rate := ts.cfg.Live.Rate(); rate > 0 {
+		return rate
+	}
+
+	if ts.inWarmup() {
+		return ts.cfg.WarmupRate
+	}
+
+	if ts.cfg.Profile != nil {
+		return Max(ts.cfg.Profile.Rate(ts.profileElapsed()), 0)
+	}
+
+	return ts.cfg.SeedCadence.TestsPerDuration + int(float64(ts.growthFactor)*float64(ts.cfg.SeedGrowthAmount)) + ts.rampAmount
+}
+
+// profileElapsed returns how far into the load profile the run is. Profiles start once warmup is over.
+func (ts *TestScheduler) profileElapsed() time.Duration {
+	return ts.elapsed() - ts.cfg.Warmup
+}
+
+// elapsed returns how long the run has been going, not counting time spent paused.
+func (ts *TestScheduler) elapsed() time.Duration {
+	return ts.cfg.Clock.Elapsed()
+}
+
+// inWarmup returns true until Warmup has passed. Warmup runs the normal mix of operations at the low WarmupRate, to
+// prime server caches + connection pools before the measured phase. Closed loop runs aren't rate limited during warmup.
+func (ts *TestScheduler) inWarmup() bool {
+	return ts.cfg.Clock.InWarmup(ts.cfg.Warmup)
+}
+
 // TrackedFiles assumes all reads/writes/deletes were success. It doesn't add file back if delete was failure, etc.
 
 // GetTestFunc selects a psuedo random test function to run
 func (ts *TestScheduler) GetTestFunc() Test {
+	test := ts.pickTest()
+	test.payloadSeed = ts.rand.payloadSeed()
+	return test
+}
+
+func (ts *TestScheduler) pickTest() Test {
+	if ts.cfg.Scenarios != nil && ts.rand.Float64() < ts.cfg.Scenarios.Share {
+		return ts.scenarioTest()
+	}
+	if op := ts.cfg.ExtraOps.pick(ts.rand); op != "" {
+		if test, ok := ts.extraOpTest(op); ok {
+			return test
+		}
+	}
+	ts.updateMix()
+	if ts.mix.IsSet() {
+		return ts.mixedTest()
+	}
+
 	//rand.Seed(time.Now().UnixNano())
-	createNewFile := rand.Intn(ts.cfg.TestConfig.MaxFileCount) > len(ts.trackedFiles)
+	createNewFile := ts.rand.Intn(ts.cfg.TestConfig.MaxFileCount) > ts.trackedFiles.Len()
 	var testToRun = Test{}
 
 	if createNewFile {
-		testToRun.fileName = RandStringBytes(15)
+		testToRun.fileName = ts.newFileName()
 		// Give 2% chance to execute consistency test, or a higher % chance the more tracked files there are
-		// If the load test just started, only run consistency tests for the first 5 seconds.
-		bonus := Min(ts.cfg.TestConfig.MaxFileCount/(ts.cfg.TestConfig.MaxFileCount-len(ts.trackedFiles)+1), 8)
-		runConsistencyTest := rand.Intn(100)+bonus >= 98 || time.Now().Sub(ts.startTime) < time.Second*5
+		// If the load test just started, only run consistency tests for the first 5 seconds. With a consistency rate,
+		// checks are paced separately instead.
+		bonus := Min(ts.cfg.TestConfig.MaxFileCount/(ts.cfg.TestConfig.MaxFileCount-ts.trackedFiles.Len()+1), 8)
+		runConsistencyTest := ts.cfg.consistencyRate() <= 0 &&
+			(ts.rand.Intn(100)+bonus >= 98 || time.Now().Sub(ts.startTime) < time.Second*5)
 		if runConsistencyTest {
 			// This tests is 4 requests total, so add 3 extra.
 			ts.numScheduled += 3
@@ -178,11 +442,11 @@ func (ts *TestScheduler) GetTestFunc() Test {
 			testToRun.TestType = CREATE
 		}
 	} else {
-		testId := rand.Intn(len(ts.tests))
+		testId := ts.rand.Intn(len(ts.tests))
+		testToRun.TestType = ts.tests[testId]
 		ts.trackedFileLock.RLock()
-		testToRun.fileName = ts.trackedFiles.RandomFile()
+		testToRun.fileName = ts.fileFor(testToRun.TestType)
 		ts.trackedFileLock.RUnlock()
-		testToRun.TestType = ts.tests[testId]
 		if testToRun.TestType == DELETE {
 			ts.trackedFileLock.Lock()
 			ts.trackedFiles.Delete(testToRun.fileName)
@@ -194,6 +458,43 @@ func (ts *TestScheduler) GetTestFunc() Test {
 	return testToRun
 }
 
+// updateMix switches to the load profile's mix for the current point in the run, if the profile has mixes of its own.
+// A live mix override replaces both the profile's + the configured mix.
+func (ts *TestScheduler) updateMix() {
+	mix := ts.cfg.OperationMix
+	if profile, ok := ts.cfg.Profile.(MixedProfile); ok && !ts.inWarmup() {
+		if profileMix := profile.Mix(ts.profileElapsed()); profileMix.IsSet() {
+			mix = profileMix
+		}
+	}
+	if live := ts.cfg.Live.Mix(); live.IsSet() {
+		mix = live
+	}
+	if mix == ts.mix {
+		return
+	}
+
+	ts.mix = mix
+	ts.tests = defaultTests()
+	name := "default"
+	if mix.IsSet() {
+		ts.tests = mix.tests()
+		name = mix.String()
+	}
+	ts.tests = withoutPacedChecks(ts.cfg, ts.tests)
+	log.Infof("Now scheduling operation mix: %s", name)
+}
+
+// defaultTests is the default mix of operations on existing files: mostly GETs, with an occasional PUT or DELETE.
+func defaultTests() []TestType {
+	tests := []TestType{PUT, DELETE}
+	for i := 0; i < 75; i++ {
+		tests = append(tests, GET)
+	}
+
+	return tests
+}
+
 // MergeFailedTestResults listens to failed tests and updates trackedFiles based on results.
 func (ts *TestScheduler) MergeFailedTestResults() {
 	// Cleanup tracked files that were write / delete failures
@@ -203,13 +504,18 @@ func (ts *TestScheduler) MergeFailedTestResults() {
 			break
 		}
 
+		// Scenarios run on their own files, which are never tracked.
+		if result.scenario != nil {
+			continue
+		}
+
 		ts.trackedFileLock.Lock()
-		if result.WasTestFailure() {
+		if result.WasTestFailure() || result.WasSelfThrottled() || result.InjectedFault() != "" {
 			if result.TestType() == DELETE {
 				ts.trackedFiles.Add(result.FileName())
 			}
 
-			if result.TestType() == CREATE {
+			if result.TestType() == CREATE || result.TestType() == STREAM {
 				ts.trackedFiles.Delete(result.FileName())
 			}
 
@@ -236,7 +542,7 @@ func (ts *TestScheduler) MergeSuccessfulTestResults() {
 		}
 
 		ts.trackedFileLock.Lock()
-		if result.TestType() == CREATE {
+		if result.TestType() == CREATE || result.TestType() == STREAM {
 			ts.trackedFiles.Add(result.FileName())
 		}
 		ts.trackedFileLock.Unlock()
@@ -0,0 +1,173 @@
+package load_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	ThinkFixed       = "fixed"
+	ThinkUniform     = "uniform"
+	ThinkExponential = "exponential"
+	ThinkNormal      = "normal"
+	ThinkLognormal   = "lognormal"
+)
+
+// ThinkTime is the pause between each of a virtual user's tests, to model clients that do something with a response
+// before sending their next request rather than looping as fast as the server answers. Specs are either a fixed
+// duration or a distribution name followed by key=value params, I.E
+//
+//	250ms
+//	uniform min=50ms max=500ms
+//	exponential mean=200ms
+//	normal mean=200ms stddev=50ms
+//	lognormal mean=200ms stddev=300ms
+type ThinkTime struct {
+	Distribution string
+	Min          time.Duration
+	Max          time.Duration
+	Mean         time.Duration
+	StdDev       time.Duration
+}
+
+// ParseThinkTime parses a think time spec. An empty spec is no think time.
+func ParseThinkTime(spec string) (ThinkTime, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return ThinkTime{}, nil
+	}
+
+	if fixed, err := time.ParseDuration(fields[0]); err == nil && len(fields) == 1 {
+		return ThinkTime{Distribution: ThinkFixed, Mean: fixed}, nil
+	}
+
+	params := make(profileParams, len(fields)-1)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return ThinkTime{}, fmt.Errorf("invalid think time param: %s. Expected key=value", field)
+		}
+		params[key] = value
+	}
+
+	think := ThinkTime{Distribution: fields[0]}
+	var err error
+	switch think.Distribution {
+	case ThinkUniform:
+		err = params.parse(map[string]interface{}{"min": &think.Min, "max": &think.Max}, "max")
+		if err == nil && think.Max < think.Min {
+			err = fmt.Errorf("max must be >= min")
+		}
+	case ThinkExponential:
+		err = params.parse(map[string]interface{}{"mean": &think.Mean}, "mean")
+	case ThinkNormal, ThinkLognormal:
+		err = params.parse(map[string]interface{}{"mean": &think.Mean, "stddev": &think.StdDev}, "mean", "stddev")
+	default:
+		return ThinkTime{}, fmt.Errorf("unknown think time distribution: %s", think.Distribution)
+	}
+	if err != nil {
+		return ThinkTime{}, fmt.Errorf("invalid %s think time: %w", think.Distribution, err)
+	}
+
+	return think, nil
+}
+
+// Next returns a pause drawn from the distribution. Never negative.
+func (t ThinkTime) Next() time.Duration {
+	var next time.Duration
+	switch t.Distribution {
+	case ThinkFixed:
+		next = t.Mean
+	case ThinkUniform:
+		next = t.Min + time.Duration(rand.Int63n(int64(t.Max-t.Min)+1))
+	case ThinkExponential:
+		next = time.Duration(rand.ExpFloat64() * float64(t.Mean))
+	case ThinkNormal:
+		next = time.Duration(rand.NormFloat64()*float64(t.StdDev) + float64(t.Mean))
+	case ThinkLognormal:
+		next = time.Duration(lognormal(float64(t.Mean), float64(t.StdDev)))
+	}
+
+	return time.Duration(math.Max(float64(next), 0))
+}
+
+func (t ThinkTime) String() string {
+	switch t.Distribution {
+	case ThinkFixed:
+		return t.Mean.String()
+	case ThinkUniform:
+		return fmt.Sprintf("%s min=%s max=%s", t.Distribution, t.Min, t.Max)
+	case ThinkExponential:
+		return fmt.Sprintf("%s mean=%s", t.Distribution, t.Mean)
+	case ThinkNormal, ThinkLognormal:
+		return fmt.Sprintf("%s mean=%s stddev=%s", t.Distribution, t.Mean, t.StdDev)
+	default:
+		return "none"
+	}
+}
+
+// lognormal returns a sample from the lognormal distribution with the given mean + standard deviation, which unlike a
+// normal distribution is never negative + has a long tail of occasional long values.
+func lognormal(mean float64, stddev float64) float64 {
+	return lognormalFrom(mean, stddev, rand.NormFloat64())
+}
+
+// lognormalFrom is lognormal for a sample norm of the standard normal distribution, I.E from a seeded Rand.
+func lognormalFrom(mean float64, stddev float64, norm float64) float64 {
+	if mean <= 0 {
+		return 0
+	}
+
+	sigma := math.Sqrt(math.Log(1 + (stddev*stddev)/(mean*mean)))
+	mu := math.Log(mean) - sigma*sigma/2
+	return math.Exp(mu + sigma*norm)
+}
+
+// VirtualUserSummary reports the pacing virtual users actually achieved, which can differ from the configured think
+// time + pacing when tests take longer than expected.
+type VirtualUserSummary struct {
+	Users              int     `json:"users"`
+	ThinkTime          string  `json:"think_time"`
+	PacingTargetMs     float64 `json:"pacing_target_ms"`
+	AvgPacingMs        float64 `json:"avg_pacing_ms"` // Mean time between the starts of a user's consecutive tests
+	AvgThinkMs         float64 `json:"avg_think_ms"`  // Mean time between a user finishing one test + starting the next
+	TestsPerUserPerSec float64 `json:"tests_per_user_per_sec"`
+}
+
+// virtualUsers returns nil unless the run is closed loop. Caller must hold resultLock.
+func (tr *TestResults) virtualUsers() *VirtualUserSummary {
+	if tr.users == 0 {
+		return nil
+	}
+
+	var span, duration time.Duration
+	var gaps, tests int
+	for _, w := range tr.workers {
+		if w.Requests > 1 {
+			span += w.LastStart.Sub(w.FirstStart)
+			gaps += w.Requests - 1
+		}
+		duration += w.TotalDuration
+		tests += w.Requests
+	}
+
+	summary := &VirtualUserSummary{
+		Users:          tr.users,
+		ThinkTime:      tr.thinkTime.String(),
+		PacingTargetMs: float64(tr.pacing.Milliseconds()),
+	}
+	if gaps > 0 {
+		pacing := span / time.Duration(gaps)
+		think := pacing - duration/time.Duration(tests)
+		summary.AvgPacingMs = float64(pacing.Microseconds()) / 1000
+		summary.AvgThinkMs = math.Max(float64(think.Microseconds())/1000, 0)
+		if pacing > 0 {
+			summary.TestsPerUserPerSec = 1 / pacing.Seconds()
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,99 @@
+package load_test
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    ThinkTime
+		wantErr bool
+	}{
+		{"", ThinkTime{}, false},
+		{"250ms", ThinkTime{Distribution: ThinkFixed, Mean: 250 * time.Millisecond}, false},
+		{"uniform min=50ms max=500ms", ThinkTime{Distribution: ThinkUniform, Min: 50 * time.Millisecond,
+			Max: 500 * time.Millisecond}, false},
+		{"uniform max=1s", ThinkTime{Distribution: ThinkUniform, Max: time.Second}, false},
+		{"exponential mean=200ms", ThinkTime{Distribution: ThinkExponential, Mean: 200 * time.Millisecond}, false},
+		{"normal mean=200ms stddev=50ms", ThinkTime{Distribution: ThinkNormal, Mean: 200 * time.Millisecond,
+			StdDev: 50 * time.Millisecond}, false},
+		{"uniform min=1s max=500ms", ThinkTime{}, true},
+		{"uniform min=50ms", ThinkTime{}, true},
+		{"exponential mean=200", ThinkTime{}, true},
+		{"normal mean=200ms", ThinkTime{}, true},
+		{"normal mean=200ms stddev", ThinkTime{}, true},
+		{"250ms 300ms", ThinkTime{}, true},
+		{"gamma mean=1s", ThinkTime{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseThinkTime(test.spec)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseThinkTime(%q): got %+v, %v, want %+v, error %t", test.spec, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestThinkTimeNext(t *testing.T) {
+	const samples = 100000
+	tests := []struct {
+		spec string
+		mean time.Duration
+		min  time.Duration
+		max  time.Duration
+	}{
+		{"250ms", 250 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond},
+		{"uniform min=100ms max=300ms", 200 * time.Millisecond, 100 * time.Millisecond, 300 * time.Millisecond},
+		{"exponential mean=200ms", 200 * time.Millisecond, 0, time.Hour},
+		{"normal mean=200ms stddev=50ms", 200 * time.Millisecond, 0, time.Hour},
+	}
+	for _, test := range tests {
+		think, err := ParseThinkTime(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var total time.Duration
+		for i := 0; i < samples; i++ {
+			next := think.Next()
+			if next < test.min || next > test.max {
+				t.Fatalf("%s: got %s, want %s-%s", test.spec, next, test.min, test.max)
+			}
+			total += next
+		}
+		if got := total / samples; math.Abs(float64(got-test.mean)) > float64(test.mean)*0.05 {
+			t.Errorf("%s: got a mean of %s, want ~%s", test.spec, got, test.mean)
+		}
+	}
+	// Normal think times are clamped at 0 rather than going negative
+	wide := ThinkTime{Distribution: ThinkNormal, Mean: time.Millisecond, StdDev: time.Second}
+	for i := 0; i < 1000; i++ {
+		if next := wide.Next(); next < 0 {
+			t.Fatalf("got a negative think time %s", next)
+		}
+	}
+}
+
+func TestLognormalThinkTime(t *testing.T) {
+	think, err := ParseThinkTime("lognormal mean=200ms stddev=300ms")
+	if want := (ThinkTime{Distribution: ThinkLognormal, Mean: 200 * time.Millisecond,
+		StdDev: 300 * time.Millisecond}); err != nil || think != want {
+		t.Fatalf("got %+v, %v, want %+v", think, err, want)
+	}
+
+	// The median of a lognormal sits below its mean, by the factor its stddev implies
+	mean, stddev := 200.0, 300.0
+	median := lognormalFrom(mean, stddev, 0)
+	if want := mean / math.Sqrt(1+stddev*stddev/(mean*mean)); math.Abs(median-want) > 1e-9 {
+		t.Errorf("got a median of %g, want %g", median, want)
+	}
+	if lognormalFrom(mean, stddev, 2) <= lognormalFrom(mean, stddev, 1) || lognormalFrom(0, stddev, 1) != 0 {
+		t.Errorf("lognormal isn't increasing in norm, or has a mean of 0 that isn't 0")
+	}
+	if math.Abs(lognormalFrom(mean, 0, 3)-mean) > 1e-9 {
+		t.Errorf("got %g with no stddev, want the mean", lognormalFrom(mean, 0, 3))
+	}
+}
@@ -0,0 +1,108 @@
+package load_test
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+	"time"
+)
+
+// Pass / fail criteria evaluated against the aggregated results at the end of a run. Used to gate CI.
+
+// Thresholds - nil fields are not checked.
+type Thresholds struct {
+	MaxErrorRate           *float64       // Fraction of failed tests, I.E 0.01 for 1%
+	MaxP99Latency          *time.Duration // p99 latency across all operations
+	MaxConsistencyFailures *int           // # of failed consistency checks
+}
+
+type ThresholdResult struct {
+	Name   string `json:"name"`
+	Limit  string `json:"limit"`
+	Actual string `json:"actual"`
+	Passed bool   `json:"passed"`
+}
+
+// EvaluateThresholds checks every configured threshold against the results so far.
+func (tr *TestResults) EvaluateThresholds() []ThresholdResult {
+	tr.resultLock.RLock()
+	defer tr.resultLock.RUnlock()
+
+	return tr.evaluateThresholds()
+}
+
+// evaluateThresholds expects the caller to hold resultLock.
+func (tr *TestResults) evaluateThresholds() []ThresholdResult {
+	var results []ThresholdResult
+
+	if tr.thresholds.MaxErrorRate != nil {
+		errorRate := 1 - passRate(tr.numFailure.Get(), tr.numSuccess.Get()+tr.numFailure.Get())
+		results = append(results, ThresholdResult{
+			Name:   "Error rate",
+			Limit:  fmt.Sprintf("<= %.4f%%", *tr.thresholds.MaxErrorRate*100),
+			Actual: fmt.Sprintf("%.4f%%", errorRate*100),
+			Passed: errorRate <= *tr.thresholds.MaxErrorRate,
+		})
+	}
+
+	if tr.thresholds.MaxP99Latency != nil {
+		p99 := tr.latency.Percentile(99)
+		results = append(results, ThresholdResult{
+			Name:   "p99 latency",
+			Limit:  fmt.Sprintf("<= %dms", tr.thresholds.MaxP99Latency.Milliseconds()),
+			Actual: fmt.Sprintf("%dms", p99.Milliseconds()),
+			Passed: p99 <= *tr.thresholds.MaxP99Latency,
+		})
+	}
+
+	if tr.thresholds.MaxConsistencyFailures != nil {
+		results = append(results, ThresholdResult{
+			Name:   "Consistency failures",
+			Limit:  fmt.Sprintf("<= %d", *tr.thresholds.MaxConsistencyFailures),
+			Actual: fmt.Sprintf("%d", tr.numFailedConsistency.Get()),
+			Passed: tr.numFailedConsistency.Get() <= *tr.thresholds.MaxConsistencyFailures,
+		})
+	}
+
+	return results
+}
+
+// PrintThresholds prints pass/fail per threshold and returns true if all thresholds passed.
+func (tr *TestResults) PrintThresholds() bool {
+	results := tr.EvaluateThresholds()
+	if len(results) == 0 {
+		return true
+	}
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+	tbl := table.New("Threshold", "Limit", "Actual", "Result")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	allPassed := true
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+			allPassed = false
+		}
+		tbl.AddRow(result.Name, result.Limit, result.Actual, status)
+	}
+
+	fmt.Println()
+	tbl.Print()
+	fmt.Println()
+
+	return allPassed
+}
+
+// ThresholdsPassed returns true if no configured threshold was violated.
+func (tr *TestResults) ThresholdsPassed() bool {
+	for _, result := range tr.EvaluateThresholds() {
+		if !result.Passed {
+			return false
+		}
+	}
+
+	return true
+}
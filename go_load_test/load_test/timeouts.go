@@ -0,0 +1,136 @@
+package load_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultRequestTimeout is how long a GET, PUT or DELETE may take before it times out, unless OperationTimeouts says
+// otherwise.
+const DefaultRequestTimeout = 20 * time.Second
+
+// OperationTimeouts sets how long each operation may take before it's abandoned + counted as a timeout, I.E
+// GET=5s,PUT=3m,DELETE=5s,CONSISTENCY=1m, since large uploads legitimately take far longer than a GET. A timeout covers
+// the whole request, including reading the response body. CONSISTENCY's timeout covers the check's whole sequence of
+// requests, + if unset each request in the sequence only has the client's timeout, the longest of any operation's.
+// CREATE, PARTIAL, MOVE, COPY, FUZZ, BOUNDARY + DOWNLOAD use PUT's timeout + CONFLICT CONSISTENCY's unless listed themselves, + scenario steps use their
+// operation's timeout.
+type OperationTimeouts map[TestType]time.Duration
+
+// ParseOperationTimeouts parses OP=duration pairs, I.E GET=5s,PUT=3m. An empty value uses DefaultRequestTimeout.
+func ParseOperationTimeouts(value string) (OperationTimeouts, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	timeouts := make(OperationTimeouts)
+	for _, entry := range strings.Split(value, ",") {
+		op, duration, found := strings.Cut(strings.TrimSpace(entry), "=")
+		testType := TestType(strings.ToUpper(strings.TrimSpace(op)))
+		if !found || !isTestType(testType) || testType == SCENARIO {
+			return nil, fmt.Errorf("invalid operation timeout: %s. Expected OP=duration, I.E PUT=3m", entry)
+		}
+
+		timeout, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout for %s: %s. Must be a duration > 0", testType, duration)
+		}
+		timeouts[testType] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// For returns op's timeout, or 0 if a consistency check's sequence has no timeout of its own.
+func (t OperationTimeouts) For(op TestType) time.Duration {
+	if timeout, ok := t[op]; ok {
+		return timeout
+	}
+	if op == CREATE || op == STREAM || op == PARTIAL || op == MOVE || op == COPY || op == FUZZ || op == BOUNDARY || op == DOWNLOAD {
+		return t.For(PUT)
+	}
+	if op == CONFLICT {
+		return t.For(CONSISTENCY)
+	}
+	if op == CONSISTENCY {
+		return 0
+	}
+
+	return DefaultRequestTimeout
+}
+
+// clientTimeout returns the HTTP client's timeout, long enough for the slowest operation. Each operation's own
+// timeout is enforced by its request context.
+func (t OperationTimeouts) clientTimeout() time.Duration {
+	timeout := DefaultRequestTimeout
+	for _, opTimeout := range t {
+		if opTimeout > timeout {
+			timeout = opTimeout
+		}
+	}
+
+	return timeout
+}
+
+func (t OperationTimeouts) String() string {
+	ops := make([]string, 0, len(latencyOperations))
+	for _, op := range latencyOperations {
+		if timeout := t.For(op); timeout > 0 {
+			ops = append(ops, fmt.Sprintf("%s=%s", op, timeout))
+		}
+	}
+
+	return strings.Join(ops, ",")
+}
+
+// testContext returns the context for a test of op's requests, cancelled once op's timeout passes.
+func (tr *TestExecutor) testContext(op TestType) (context.Context, context.CancelFunc) {
+	timeout := tr.timeouts.For(op)
+	if timeout <= 0 {
+		return context.WithCancel(tr.ctx)
+	}
+
+	return context.WithTimeout(tr.ctx, timeout)
+}
+
+// isTimeout returns true if err is a request that ran out of time, rather than one that failed or was cancelled by
+// shutdown.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// TimeoutSummary is the # of tests that timed out per operation, CREATE folded into PUT.
+type TimeoutSummary map[TestType]int
+
+// Total returns the # of timeouts across all operations.
+func (s TimeoutSummary) Total() int {
+	total := 0
+	for _, n := range s {
+		total += n
+	}
+
+	return total
+}
+
+// timeoutSummary returns the # of timeouts per operation, or nil if nothing timed out.
+func (tr *TestResults) timeoutSummary() TimeoutSummary {
+	summary := make(TimeoutSummary, len(latencyOperations))
+	for _, op := range latencyOperations {
+		summary[op] = tr.numTimeouts[op].Get()
+	}
+	if summary.Total() == 0 {
+		return nil
+	}
+
+	return summary
+}
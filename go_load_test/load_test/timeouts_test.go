@@ -0,0 +1,79 @@
+package load_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOperationTimeouts(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    OperationTimeouts
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"GET=5s, put=3m,CONSISTENCY=1m", OperationTimeouts{GET: 5 * time.Second, PUT: 3 * time.Minute,
+			CONSISTENCY: time.Minute}, false},
+		{"SCENARIO=1m", nil, true},
+		{"GET=0s", nil, true},
+		{"GET=5", nil, true},
+		{"GET", nil, true},
+		{"UPLOAD=1m", nil, true},
+	}
+	for _, test := range tests {
+		got, err := ParseOperationTimeouts(test.value)
+		if !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("ParseOperationTimeouts(%q): got %v, %v, want %v, error %t", test.value, got, err, test.want,
+				test.wantErr)
+		}
+	}
+}
+
+func TestOperationTimeoutsFor(t *testing.T) {
+	timeouts := OperationTimeouts{PUT: 3 * time.Minute, CONSISTENCY: time.Minute, MOVE: time.Second}
+	tests := []struct {
+		timeouts OperationTimeouts
+		op       TestType
+		want     time.Duration
+	}{
+		{timeouts, PUT, 3 * time.Minute},
+		{timeouts, CREATE, 3 * time.Minute},
+		{timeouts, MOVE, time.Second},
+		{timeouts, CONFLICT, time.Minute},
+		{timeouts, GET, DefaultRequestTimeout},
+		{nil, CREATE, DefaultRequestTimeout},
+		{nil, CONSISTENCY, 0},
+		{nil, CONFLICT, 0},
+	}
+	for _, test := range tests {
+		if got := test.timeouts.For(test.op); got != test.want {
+			t.Errorf("%v For(%s): got %s, want %s", test.timeouts, test.op, got, test.want)
+		}
+	}
+	if got := timeouts.clientTimeout(); got != 3*time.Minute {
+		t.Errorf("got client timeout %s, want the longest, 3m", got)
+	}
+	if got := (OperationTimeouts{GET: time.Second}).clientTimeout(); got != DefaultRequestTimeout {
+		t.Errorf("got client timeout %s, want at least %s", got, DefaultRequestTimeout)
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("get: %w", context.DeadlineExceeded), true},
+		{context.Canceled, false},
+		{errors.New("connection refused"), false},
+	}
+	for _, test := range tests {
+		if got := isTimeout(test.err); got != test.want {
+			t.Errorf("isTimeout(%v): got %t, want %t", test.err, got, test.want)
+		}
+	}
+}
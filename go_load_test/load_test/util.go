@@ -1,10 +1,16 @@
 package load_test
 
 import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"math/rand"
 	"os"
 	"os/exec"
 	"runtime"
+	"sync"
+	"time"
 )
 
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -16,11 +22,16 @@ const (
 )
 
 func RandStringBytes(n int) string {
+	return randString(n, rand.Int63)
+}
+
+// randString returns n random letters, drawing random bits from int63.
+func randString(n int, int63 func() int64) string {
 	b := make([]byte, n)
 	// A rand.Int63() generates 63 random bits, enough for letterIdxMax letters!
-	for i, cache, remain := n-1, rand.Int63(), letterIdxMax; i >= 0; {
+	for i, cache, remain := n-1, int63(), letterIdxMax; i >= 0; {
 		if remain == 0 {
-			cache, remain = rand.Int63(), letterIdxMax
+			cache, remain = int63(), letterIdxMax
 		}
 		if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
 			b[i] = letterBytes[idx]
@@ -33,6 +44,34 @@ func RandStringBytes(n int) string {
 	return string(b)
 }
 
+var shutdownLock sync.Mutex
+
+// CloseShutdownChan closes the shutdown channel unless it is already closed. Safe to call from several goroutines,
+// I.E a signal handler + the aggregator's failure limit.
+func CloseShutdownChan(shutdownChan chan bool) {
+	shutdownLock.Lock()
+	defer shutdownLock.Unlock()
+
+	select {
+	case _, ok := <-shutdownChan:
+		if !ok {
+			return
+		}
+	default:
+	}
+	close(shutdownChan)
+}
+
+// closeShutdownOnCancel closes shutdownChan once ctx is cancelled, so cancelling the run's context shuts it down the same
+// way Ctrl+C does. Returns once either has happened.
+func closeShutdownOnCancel(ctx context.Context, shutdownChan chan bool) {
+	select {
+	case <-ctx.Done():
+		CloseShutdownChan(shutdownChan)
+	case <-shutdownChan:
+	}
+}
+
 var clear map[string]func() //create a map for storing clear funcs
 
 func InitClear() {
@@ -63,6 +102,16 @@ func CallClear() {
 	}
 }
 
+// IsTerminal returns true if f is an interactive terminal, I.E false when output is piped or redirected in CI.
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// DisableColor turns off ANSI colors + styling in all table and dashboard output.
+func DisableColor() {
+	color.NoColor = true
+}
+
 func Min(a, b int) int {
 	if a < b {
 		return a
@@ -71,12 +120,17 @@ func Min(a, b int) int {
 }
 
 func Max(a, b int) int {
-	if a < b {
+	if a > b {
 		return a
 	}
 	return b
 }
 
+// NewRunID returns an identifier for this run, I.E 20230405-150405-AbCdEf
+func NewRunID() string {
+	return fmt.Sprintf("%s-%s", time.Now().Format("20060102-150405"), RandStringBytes(6))
+}
+
 func GetEnv(varName string, dephault string) string {
 	val := os.Getenv(varName)
 	if val == "" {
@@ -0,0 +1,83 @@
+package load_test
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+	"sort"
+	"time"
+)
+
+// Per worker breakdown of results, to diagnose a single worker stalling or hogging the connection pool.
+
+type WorkerStats struct {
+	ID            int
+	Requests      int
+	Failures      int
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+	LastSeen      time.Time
+	FirstStart    time.Time // When the worker's earliest + latest tests started, to measure virtual user pacing
+	LastStart     time.Time
+}
+
+func (w *WorkerStats) record(result TestResult, now time.Time) {
+	w.Requests++
+	if result.WasTestFailure() {
+		w.Failures++
+	}
+	w.TotalDuration += result.duration
+	if result.duration > w.MaxDuration {
+		w.MaxDuration = result.duration
+	}
+	w.LastSeen = now
+	if result.started.IsZero() {
+		return
+	}
+	if w.FirstStart.IsZero() || result.started.Before(w.FirstStart) {
+		w.FirstStart = result.started
+	}
+	if result.started.After(w.LastStart) {
+		w.LastStart = result.started
+	}
+}
+
+func (w *WorkerStats) AvgDuration() time.Duration {
+	if w.Requests == 0 {
+		return 0
+	}
+
+	return w.TotalDuration / time.Duration(w.Requests)
+}
+
+// Workers returns a copy of the stats for every worker, ordered by ID.
+func (tr *TestResults) Workers() []WorkerStats {
+	tr.resultLock.RLock()
+	defer tr.resultLock.RUnlock()
+
+	workers := make([]WorkerStats, 0, len(tr.workers))
+	for _, w := range tr.workers {
+		workers = append(workers, *w)
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID < workers[j].ID
+	})
+
+	return workers
+}
+
+// PrintWorkers prints a row per worker.
+func (tr *TestResults) PrintWorkers() {
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+	tbl := table.New("Worker", "Requests", "Failures", "Avg (ms)", "Max (ms)", "Last seen")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, w := range tr.Workers() {
+		tbl.AddRow(w.ID, w.Requests, w.Failures, w.AvgDuration().Milliseconds(), w.MaxDuration.Milliseconds(),
+			fmt.Sprintf("%s ago", time.Now().Sub(w.LastSeen).Truncate(time.Second)))
+	}
+
+	fmt.Println()
+	tbl.Print()
+}
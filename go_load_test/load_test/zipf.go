@@ -0,0 +1,66 @@
+package load_test
+
+import (
+	"math"
+	"sort"
+)
+
+// zipfKeys picks GETs' files from the preseeded dataset by a Zipf distribution, so the file of rank k is picked in
+// proportion to 1/k^s + a few hot files get most of the traffic, I.E with s=1 over 10000 files the hottest 1% get
+// about half of GETs. Uniform picks, the default, spread GETs evenly, which exercises a server's cache very
+// differently. Files are ranked in preseed order. A picked file the run has since deleted falls back to a uniform pick
+// of the tracked files, so the skew fades as DELETEs eat into the dataset.
+type zipfKeys struct {
+	s    float64
+	keys []string
+	cdf  []float64 // Cumulative weight of the files up to + including each rank
+}
+
+// newZipfKeys returns nil if s <= 0 or there are no keys, in which case files are picked uniformly.
+func newZipfKeys(s float64, keys []string) *zipfKeys {
+	if s <= 0 || len(keys) == 0 {
+		return nil
+	}
+
+	cdf := make([]float64, len(keys))
+	total := 0.0
+	for i := range keys {
+		total += 1 / math.Pow(float64(i+1), s)
+		cdf[i] = total
+	}
+
+	return &zipfKeys{s: s, keys: keys, cdf: cdf}
+}
+
+// pick returns a file, hotter files more often.
+func (z *zipfKeys) pick(r *Rand) string {
+	total := z.cdf[len(z.cdf)-1]
+	i := sort.SearchFloat64s(z.cdf, r.Float64()*total)
+	if i >= len(z.keys) {
+		i = len(z.keys) - 1
+	}
+
+	return z.keys[i]
+}
+
+// topShare returns the share of picks that go to the hottest fraction of files, I.E 0.01 for the top 1%.
+func (z *zipfKeys) topShare(fraction float64) float64 {
+	top := int(math.Ceil(fraction * float64(len(z.keys))))
+	if top < 1 {
+		top = 1
+	}
+
+	return z.cdf[top-1] / z.cdf[len(z.cdf)-1]
+}
+
+// fileFor returns a tracked file for a test of testType, picking GETs' by zipfKeys if set. Caller must hold
+// trackedFileLock.
+func (ts *TestScheduler) fileFor(testType TestType) string {
+	if testType == GET && ts.hotKeys != nil {
+		if fileName := ts.hotKeys.pick(ts.rand); ts.trackedFiles.Has(fileName) {
+			return fileName
+		}
+	}
+
+	return ts.trackedFiles.randomFile(ts.rand)
+}
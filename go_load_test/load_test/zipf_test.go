@@ -0,0 +1,64 @@
+package load_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestNewZipfKeys(t *testing.T) {
+	if newZipfKeys(0, []string{"a"}) != nil || newZipfKeys(1, nil) != nil {
+		t.Errorf("got zipf keys without an exponent or keys, want uniform picks")
+	}
+}
+
+func TestZipfTopShare(t *testing.T) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	tests := []struct {
+		s        float64
+		fraction float64
+		want     float64
+	}{
+		{1, 0.01, 0.53}, // H(100) / H(10000)
+		{1, 1, 1},
+		{1, 0, 1 / 9.7876}, // Still the hottest file
+		{2, 0.0001, 1 / (math.Pi * math.Pi / 6)},
+	}
+	for _, test := range tests {
+		if got := newZipfKeys(test.s, keys).topShare(test.fraction); math.Abs(got-test.want) > 0.005 {
+			t.Errorf("s=%g, top %g: got %.3f, want ~%.3f", test.s, test.fraction, got, test.want)
+		}
+	}
+}
+
+func TestZipfPick(t *testing.T) {
+	zipf := newZipfKeys(1, []string{"a", "b", "c"})
+	r := NewRand(1)
+	counts := make(map[string]int)
+	for i := 0; i < 110000; i++ {
+		counts[zipf.pick(r)]++
+	}
+	// Weights 1, 1/2 + 1/3 of 11/6
+	want := map[string]int{"a": 60000, "b": 30000, "c": 20000}
+	for key, n := range want {
+		if counts[key] < n*95/100 || counts[key] > n*105/100 {
+			t.Errorf("got %v, want ~%v", counts, want)
+			break
+		}
+	}
+}
+
+func TestFileForFallsBackToTracked(t *testing.T) {
+	ts := NewTestScheduler(TestSchedulerConfig{Zipf: 1, Preseeded: []string{"hot", "cold"}, RandSeed: 1})
+	ts.trackedFiles.Delete("hot")
+	ts.trackedFiles.Add("new")
+	for i := 0; i < 100; i++ {
+		if got := ts.fileFor(GET); got == "hot" {
+			t.Fatalf("picked a deleted file")
+		}
+	}
+}